@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxDuration 为 -max-duration，整次压测的硬性时长上限：到达后立即取消进行中的请求并照常输出汇总，
+// 避免目标卡住时 CI 任务一直挂起
+var maxDuration time.Duration
+
+// maxErrors 为 -max-errors，失败请求总数超过该值时中止压测，0 表示不限制
+var maxErrors int64
+
+// runContext 为所有压测请求的 context，中止压测时取消，进行中的请求随之失败返回；
+// runStopped 在中止时关闭，供阻塞等待的 worker 退出
+var (
+	runContext, cancelRun = context.WithCancel(context.Background())
+	runStopped            = make(chan struct{})
+	abortOnce             sync.Once
+)
+
+// abortReason 为中止压测的原因，须通过 abortedReason 读取
+var abortReason string
+
+// abortRun 立即中止压测：不再发出新请求并取消进行中的请求；只有第一次调用生效
+func abortRun(reason string) {
+	abortOnce.Do(func() {
+		abortReason = reason
+		atomic.StoreInt32(&interrupted, 1)
+		fmt.Printf("\n🛑  Aborting: %s\n", reason)
+		cancelRun()
+		close(runStopped)
+	})
+}
+
+// startMaxDuration 在 -max-duration 之后中止压测；d 为 0 时不限制
+func startMaxDuration(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	time.AfterFunc(d, func() {
+		abortRun(fmt.Sprintf("-max-duration %s reached", d))
+	})
+}
+
+// abortedReason 返回中止压测的原因，未中止时返回空字符串
+func abortedReason() string {
+	select {
+	case <-runStopped:
+		return abortReason
+	default:
+		return ""
+	}
+}
+
+// countFailure 累计一个失败请求，超过 -max-errors 时中止压测
+func countFailure() {
+	failed := atomic.AddInt64(&globalFailedRequests, 1)
+	if maxErrors > 0 && failed > maxErrors {
+		abortRun(fmt.Sprintf("-max-errors %d exceeded", maxErrors))
+	}
+}
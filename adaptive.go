@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// targetP99、targetErrorRate 为 -adaptive 要保持的目标，为 0 时不作为约束
+var (
+	targetP99       time.Duration
+	targetErrorRate float64
+)
+
+// 每个趋势窗口结束时按目标调整速率：满足目标且有余量时加速，超出目标时减速
+const (
+	adaptiveIncrease = 1.2
+	adaptiveDecrease = 0.75
+	// adaptiveHeadroom 为加速所需的余量：p99 低于目标的该比例、且实际吞吐量达到已提供速率的该比例时才加速，避免在目标附近来回震荡
+	adaptiveHeadroom = 0.9
+	// adaptiveMinFactor 为速率倍数的下限，保证始终有请求用于测量
+	adaptiveMinFactor = 0.01
+	// adaptiveEquilibriumWindows 为计算平衡吞吐量时使用的最近窗口数
+	adaptiveEquilibriumWindows = 3
+)
+
+// adaptiveStep 为一次调整：窗口内的实际结果与调整后的速率
+type adaptiveStep struct {
+	Time      time.Time
+	Offered   float64
+	QPS       float64
+	P99Ms     float64
+	ErrorRate float64
+	Met       bool
+	Action    string
+}
+
+// adaptiveController 是一个闭环的速率模式：根据每个趋势窗口的 p99 与错误率调整 -rate 的倍数
+type adaptiveController struct {
+	mu       sync.Mutex
+	baseRate float64
+	scale    float64
+	seen     int
+	steps    []adaptiveStep
+}
+
+// newAdaptiveController 以 baseRate 为起始速率创建控制器
+func newAdaptiveController(baseRate float64) *adaptiveController {
+	return &adaptiveController{baseRate: baseRate, scale: 1}
+}
+
+func (c *adaptiveController) factor(elapsed time.Duration) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scale
+}
+
+// observe 读取最新的趋势点并调整速率，在 collectTrendWindow 之后调用；没有新的趋势点或窗口内没有请求时不调整
+func (c *adaptiveController) observe() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(trendTimes) == c.seen {
+		return
+	}
+	c.seen = len(trendTimes)
+	i := c.seen - 1
+	qps := qpsHistory[i]
+	if qps <= 0 {
+		return
+	}
+	step := adaptiveStep{Time: trendTimes[i], QPS: qps, P99Ms: p99History[i], ErrorRate: (1 - tpsHistory[i]/qps) * 100}
+	p99Target := durationMs(targetP99)
+	over := (targetP99 > 0 && step.P99Ms > p99Target) || (targetErrorRate > 0 && step.ErrorRate > targetErrorRate)
+	step.Met = !over
+	offered := c.baseRate * c.scale
+	switch {
+	case over:
+		c.scale *= adaptiveDecrease
+		if c.scale < adaptiveMinFactor {
+			c.scale = adaptiveMinFactor
+		}
+		step.Action = "decrease"
+	case targetP99 > 0 && step.P99Ms > p99Target*adaptiveHeadroom:
+		step.Action = "hold"
+	case qps < offered*adaptiveHeadroom:
+		// 实际吞吐量跟不上已提供的速率，说明 -c 个 worker 已全部占满，继续加速没有意义
+		step.Action = "hold (workers busy)"
+	default:
+		c.scale *= adaptiveIncrease
+		step.Action = "increase"
+	}
+	step.Offered = c.baseRate * c.scale
+	c.steps = append(c.steps, step)
+}
+
+// describeTargets 返回目标的描述，如 "p99 <= 200ms, error rate <= 1%"
+func describeTargets() string {
+	desc := ""
+	if targetP99 > 0 {
+		desc = fmt.Sprintf("p99 <= %s", targetP99)
+	}
+	if targetErrorRate > 0 {
+		if desc != "" {
+			desc += ", "
+		}
+		desc += fmt.Sprintf("error rate <= %g%%", targetErrorRate)
+	}
+	return desc
+}
+
+// maxAdaptiveRows 为调整记录表格的最大行数，只保留最近的记录
+const maxAdaptiveRows = 20
+
+// report 输出最近的调整记录，以及满足目标的最高吞吐量与最近几个窗口的平衡吞吐量
+func (c *adaptiveController) report() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Printf("\n🎛️   Adaptive Load (target: %s):\n", describeTargets())
+	if len(c.steps) == 0 {
+		fmt.Printf("  Not enough data: the run ended before the first %s window\n", trendWindow)
+		return
+	}
+	steps := c.steps
+	if len(steps) > maxAdaptiveRows {
+		fmt.Printf("  (last %d of %d adjustments)\n", maxAdaptiveRows, len(steps))
+		steps = steps[len(steps)-maxAdaptiveRows:]
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Window End", "QPS", "P99 (ms)", "Error Rate", "Target Met", "Action", "Next Rate"})
+	for _, s := range steps {
+		met := "yes"
+		if !s.Met {
+			met = "no"
+		}
+		table.Append([]string{
+			s.Time.Format("15:04:05"),
+			fmt.Sprintf("%.2f", s.QPS),
+			fmt.Sprintf("%.0f", s.P99Ms),
+			fmt.Sprintf("%.2f%%", s.ErrorRate),
+			met,
+			s.Action,
+			fmt.Sprintf("%.2f", s.Offered),
+		})
+	}
+	table.Render()
+
+	var best float64
+	for _, s := range c.steps {
+		if s.Met && s.QPS > best {
+			best = s.QPS
+		}
+	}
+	recent := c.steps
+	if len(recent) > adaptiveEquilibriumWindows {
+		recent = recent[len(recent)-adaptiveEquilibriumWindows:]
+	}
+	var sum float64
+	for _, s := range recent {
+		sum += s.QPS
+	}
+	fmt.Printf("  - Equilibrium Throughput: %.2f req/s (average of the last %d windows)\n", sum/float64(len(recent)), len(recent))
+	if best > 0 {
+		fmt.Printf("  - Highest Throughput Meeting Target: %.2f req/s\n", best)
+	} else {
+		fmt.Println("  - Highest Throughput Meeting Target: none, the target was missed in every window")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import "time"
+
+// apdexThreshold 为 Apdex 的目标时延 T，0 表示不计算 Apdex
+var apdexThreshold time.Duration
+
+// apdexHistory 为 Apdex 分数的趋势数组
+var apdexHistory []float64
+
+// apdexCounts 保存 Apdex 的三个分桶计数：满意（<= T）、可容忍（<= 4T）、失望（> 4T 或请求失败）
+type apdexCounts struct {
+	Satisfied  int64
+	Tolerating int64
+	Frustrated int64
+}
+
+// apdexSummary 为 Apdex 的计算结果
+type apdexSummary struct {
+	ThresholdMs float64 `json:"threshold_ms"`
+	Score       float64 `json:"score"`
+	Satisfied   int64   `json:"satisfied"`
+	Tolerating  int64   `json:"tolerating"`
+	Frustrated  int64   `json:"frustrated"`
+}
+
+// record 按时延把一次成功的请求计入对应分桶
+func (c *apdexCounts) record(d time.Duration) {
+	switch {
+	case d <= apdexThreshold:
+		c.Satisfied++
+	case d <= 4*apdexThreshold:
+		c.Tolerating++
+	default:
+		c.Frustrated++
+	}
+}
+
+// add 合并另一组计数
+func (c *apdexCounts) add(other apdexCounts) {
+	c.Satisfied += other.Satisfied
+	c.Tolerating += other.Tolerating
+	c.Frustrated += other.Frustrated
+}
+
+// summary 计算 Apdex 分数：(满意数 + 可容忍数 / 2) / 总数；未启用时返回 nil
+func (c apdexCounts) summary() *apdexSummary {
+	if apdexThreshold <= 0 {
+		return nil
+	}
+	s := &apdexSummary{
+		ThresholdMs: float64(apdexThreshold) / float64(time.Millisecond),
+		Satisfied:   c.Satisfied,
+		Tolerating:  c.Tolerating,
+		Frustrated:  c.Frustrated,
+	}
+	if total := c.Satisfied + c.Tolerating + c.Frustrated; total > 0 {
+		s.Score = (float64(c.Satisfied) + float64(c.Tolerating)/2) / float64(total)
+	}
+	return s
+}
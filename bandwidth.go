@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bandwidth 为 -bandwidth 解析后的每条连接、每个方向的带宽上限（字节/秒），0 表示不限速；
+// 用于模拟受限的最后一公里链路，配合并发数可以模拟大量慢速的移动端用户
+var bandwidth float64
+
+// bandwidthValue 为 -bandwidth 的原始取值
+var bandwidthValue string
+
+// bandwidthBurst 为限速时单次读写的最大字节数对应的时长，数据按该粒度均匀发送与接收
+const bandwidthBurst = 20 * time.Millisecond
+
+// parseBandwidth 解析 -bandwidth：以 bit 或 bps 结尾时按比特计算、k/m/g 为 1000 进制（如 5mbit、512kbps），
+// 否则按字节计算、与 -chunk-size 等相同为 1024 进制（如 1MB 即每秒 1 MiB）；返回字节/秒
+func parseBandwidth(s string) (float64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	var unit string
+	for _, suffix := range []string{"bit", "bps"} {
+		if strings.HasSuffix(value, suffix) {
+			value, unit = strings.TrimSuffix(value, suffix), suffix
+			break
+		}
+	}
+	if unit == "" {
+		n, err := parseByteSize(value)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid -bandwidth %q, expected e.g. 5mbit, 512kbit or 1MB", s)
+		}
+		return float64(n), nil
+	}
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1e3
+	case strings.HasSuffix(value, "m"):
+		multiplier = 1e6
+	case strings.HasSuffix(value, "g"):
+		multiplier = 1e9
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid -bandwidth %q, expected e.g. 5mbit, 512kbit or 1MB", s)
+	}
+	return n * multiplier / 8, nil
+}
+
+// formatBandwidth 输出易读的带宽，如 5.0 Mbit/s
+func formatBandwidth(bytesPerSecond float64) string {
+	bits := bytesPerSecond * 8
+	switch {
+	case bits >= 1e9:
+		return fmt.Sprintf("%.1f Gbit/s", bits/1e9)
+	case bits >= 1e6:
+		return fmt.Sprintf("%.1f Mbit/s", bits/1e6)
+	case bits >= 1e3:
+		return fmt.Sprintf("%.1f kbit/s", bits/1e3)
+	}
+	return fmt.Sprintf("%.0f bit/s", bits)
+}
+
+// linkRate 为一个方向的限速器：记录已发送的数据按带宽传完的时刻，空闲时不积累额度
+type linkRate struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait 等待 n 字节按带宽传完
+func (r *linkRate) wait(n int) {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	r.next = r.next.Add(time.Duration(float64(n) / bandwidth * float64(time.Second)))
+	wait := r.next.Sub(now)
+	r.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// throttledConn 为按 -bandwidth 限速的连接，读写两个方向各自限速
+type throttledConn struct {
+	net.Conn
+	read, write linkRate
+	// chunk 为单次读写的最大字节数
+	chunk int
+}
+
+func newThrottledConn(conn net.Conn) *throttledConn {
+	return &throttledConn{Conn: conn, chunk: max(int(bandwidth*bandwidthBurst.Seconds()), 512)}
+}
+
+// Write 按 chunk 分段，每段先等它按带宽传完再交给连接，对端在传完时才收到
+func (c *throttledConn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		end := min(written+c.chunk, len(b))
+		c.write.wait(end - written)
+		n, err := c.Conn.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Read 每次最多读取 chunk 字节，读到后等它按带宽传完再返回
+func (c *throttledConn) Read(b []byte) (int, error) {
+	if len(b) > c.chunk {
+		b = b[:c.chunk]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.read.wait(n)
+	}
+	return n, err
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// bodyMode 决定如何处理响应 body：
+//   - discard  读完并丢弃（默认），时延包含完整的传输
+//   - read     读入内存，额外计入分配与拷贝的开销
+//   - hash     读完并计算 SHA-256，统计同一接口返回了多少种不同的内容
+//   - ignore   不读取 body，收到响应头即结束，只衡量首字节时间；连接因此无法复用
+var bodyMode string
+
+// copyBuffers 复用读取响应 body 的 32KB 缓冲区，避免 io.Copy 每个请求分配一次
+var copyBuffers = sync.Pool{New: func() any {
+	buf := make([]byte, 32*1024)
+	return &buf
+}}
+
+// readBody 按 bodyMode 处理响应 body，返回读取的字节数、body 内容（read 模式或 wantData 时）
+// 与 SHA-256（hash 模式或 wantHash 时）
+func readBody(body io.Reader, wantHash, wantData bool) (int64, []byte, string, error) {
+	body = throttleBody(body)
+	if bodyMode == "hash" {
+		wantHash = true
+	}
+	switch {
+	case bodyMode == "ignore":
+		return 0, nil, "", nil
+	case bodyMode == "read" || wantData:
+		data, err := io.ReadAll(body)
+		if !wantHash {
+			return int64(len(data)), data, "", err
+		}
+		sum := sha256.Sum256(data)
+		return int64(len(data)), data, hex.EncodeToString(sum[:]), err
+	case wantHash:
+		buf := copyBuffers.Get().(*[]byte)
+		defer copyBuffers.Put(buf)
+		h := sha256.New()
+		n, err := io.CopyBuffer(h, body, *buf)
+		return n, nil, hex.EncodeToString(h.Sum(nil)), err
+	default:
+		buf := copyBuffers.Get().(*[]byte)
+		defer copyBuffers.Put(buf)
+		// 包一层隐藏 io.Discard 的 ReadFrom，让 CopyBuffer 使用 buf 按 32KB 读取
+		n, err := io.CopyBuffer(struct{ io.Writer }{io.Discard}, body, *buf)
+		return n, nil, "", err
+	}
+}
+
+// checkBodyMode 校验 -body-mode；ignore 模式不读取 body，无法校验内容
+func checkBodyMode() error {
+	switch bodyMode {
+	case "discard", "read", "hash":
+		return nil
+	case "ignore":
+		if expectSHA256 != "" || responseSchema != nil || len(xpathAssertions) > 0 {
+			return fmt.Errorf("-expect-sha256, -response-schema and -xpath cannot be combined with -body-mode ignore")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown -body-mode %q (expected discard, read, hash or ignore)", bodyMode)
+}
+
+// bodyHashes 为 hash 模式下每个接口（key 与按 URL 统计相同）各 SHA-256 出现的次数，只统计 2xx 响应
+type bodyHashes map[string]map[string]int64
+
+// record 记录 key 返回的一个 body 的哈希
+func (h bodyHashes) record(key, sum string) {
+	counts, ok := h[key]
+	if !ok {
+		counts = make(map[string]int64)
+		h[key] = counts
+	}
+	counts[sum]++
+}
+
+// add 合并另一个 worker 的统计
+func (h bodyHashes) add(other bodyHashes) {
+	for key, counts := range other {
+		agg, ok := h[key]
+		if !ok {
+			agg = make(map[string]int64)
+			h[key] = agg
+		}
+		for sum, n := range counts {
+			agg[sum] += n
+		}
+	}
+}
+
+// reportBodyHashes 按接口输出响应内容的种类数与最常见内容的哈希，内容应当固定的接口出现多种内容即说明有问题
+func reportBodyHashes(h bodyHashes) {
+	if bodyMode != "hash" || len(h) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println("\n🔐  Response Body Hashes (2xx responses):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Endpoint", "Responses", "Distinct Bodies", "Most Common SHA-256", "Share"})
+	for i, key := range keys {
+		if i == maxURLStatsRows {
+			break
+		}
+		var total, top int64
+		var topSum string
+		for sum, n := range h[key] {
+			total += n
+			if n > top || (n == top && sum < topSum) {
+				top, topSum = n, sum
+			}
+		}
+		table.Append([]string{
+			key,
+			fmt.Sprintf("%d", total),
+			fmt.Sprintf("%d", len(h[key])),
+			topSum[:16] + "…",
+			fmt.Sprintf("%.1f%%", float64(top)/float64(total)*100),
+		})
+	}
+	table.Render()
+	if len(keys) > maxURLStatsRows {
+		fmt.Printf("  ... and %d more endpoints\n", len(keys)-maxURLStatsRows)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// burstSize、burstInterval 为 -burst 与 -burst-interval：每隔 burstInterval 同时放出 burstSize 个请求，
+// 用于测试缓存击穿、锁竞争等只在请求同时到达时才出现的问题；稳定的负载触发不了它们
+var (
+	burstSize     int
+	burstInterval time.Duration
+)
+
+// feedBurstRequests 按 -burst 投递共 total 个请求：每一轮先把 burstSize 个请求交给空闲的 worker，
+// 它们在 release 关闭前都等在屏障上，到点后同时发出；计划发送时间为该轮的开始时间，
+// 上一轮的请求未结束导致这一轮晚于计划放出时，延迟体现在排队时间与调度延迟中。
+// worker 数须不少于 burstSize，否则一轮的请求无法同时就位
+func feedBurstRequests(total int, next func(i int) *requestSpec, jobs chan<- *requestSpec) {
+	defer close(jobs)
+	start := time.Now()
+	for round, i := 0, 0; i < total && !shouldStop(); round++ {
+		releaseAt := start.Add(time.Duration(round) * burstInterval)
+		release := make(chan struct{})
+		for n := 0; n < burstSize && i < total; n, i = n+1, i+1 {
+			spec := *next(i)
+			spec.Scheduled = releaseAt
+			spec.release = release
+			jobs <- &spec
+		}
+		if wait := time.Until(releaseAt); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-runStopped:
+				close(release)
+				return
+			}
+		}
+		close(release)
+	}
+}
+
+// describeBurst 返回启动时输出的突发负载说明
+func describeBurst(total int) string {
+	rounds := (total + burstSize - 1) / burstSize
+	return fmt.Sprintf("%d simultaneous requests every %s (%d bursts)", burstSize, burstInterval, rounds)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// calibrate、calibrateLevels 为 -calibrate 与 -calibrate-levels：对进程内的回环服务器按各并发数压测，
+// 测出工具自身的测量开销与调度抖动
+var (
+	calibrate       bool
+	calibrateLevels string
+)
+
+// calibrationProbeInterval 为调度抖动探测 goroutine 每次睡眠的时长
+const calibrationProbeInterval = time.Millisecond
+
+// calibrationResult 为一个并发数下的校准结果
+type calibrationResult struct {
+	concurrency int
+	requests    int
+	failed      int64
+	elapsed     time.Duration
+	// reported 为工具按正常压测口径记录的时延，roundTrip 为 sendRequest 调用前后的总耗时，jitter 为探测 goroutine 的超时唤醒
+	reported, roundTrip, jitter []time.Duration
+}
+
+// parseCalibrateLevels 解析逗号分隔的并发数
+func parseCalibrateLevels(s string) ([]int, error) {
+	var levels []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid -calibrate-levels %q, expected e.g. 1,10,100", s)
+		}
+		levels = append(levels, n)
+	}
+	return levels, nil
+}
+
+// calibrateLevel 以 concurrency 个 worker 向 target 发送 total 个请求，同时由一个 goroutine 反复睡眠 calibrationProbeInterval，
+// 记录每次比预期晚醒来的时长，作为该负载下的调度抖动
+func calibrateLevel(target string, concurrency, total int) calibrationResult {
+	result := calibrationResult{concurrency: concurrency, requests: total}
+	var next int64
+	var mu sync.Mutex
+	stop := make(chan struct{})
+	probeDone := make(chan struct{})
+	go func() {
+		defer close(probeDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			before := time.Now()
+			time.Sleep(calibrationProbeInterval)
+			if late := time.Since(before) - calibrationProbeInterval; late > 0 {
+				result.jitter = append(result.jitter, late)
+			} else {
+				result.jitter = append(result.jitter, 0)
+			}
+		}
+	}()
+	spec := &requestSpec{}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ws := newWorkerStats(total / concurrency)
+			var roundTrips []time.Duration
+			for atomic.AddInt64(&next, 1) <= int64(total) {
+				before := time.Now()
+				sendRequest(ws, clientKeepAlive, spec, target, http.MethodGet, nil)
+				roundTrips = append(roundTrips, time.Since(before))
+			}
+			mu.Lock()
+			result.reported = append(result.reported, ws.ResponseTimes...)
+			result.roundTrip = append(result.roundTrip, roundTrips...)
+			result.failed += ws.FailedRequests
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	result.elapsed = time.Since(start)
+	close(stop)
+	<-probeDone
+	for _, samples := range [][]time.Duration{result.reported, result.roundTrip, result.jitter} {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	}
+	return result
+}
+
+// runCalibration 启动进程内的回环服务器（不做任何处理，立即返回 200），依次在各并发数下发送 total 个请求并输出：
+// 工具记录的时延与完整往返耗时都是工具与本机网络栈自身的开销，实际压测中低于它们的差异无法分辨
+func runCalibration(levels []int, total int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	target := "http://" + listener.Addr().String() + "/"
+	fmt.Printf("\n🎯  Calibrating against a loopback server (%s), %d requests per level\n", target, total)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Concurrency", "RPS", "Reported P50", "Reported P99", "Round Trip P50", "Round Trip P99", "Round Trip Max", "Sched Jitter P50", "Sched Jitter P99"})
+	format := func(d time.Duration) string { return fmt.Sprintf("%.3f ms", float64(d)/float64(time.Millisecond)) }
+	for _, c := range levels {
+		r := calibrateLevel(target, c, total)
+		if r.failed > 0 {
+			slog.Warn("Requests failed during calibration", "failed", r.failed, "requests", r.requests, "concurrency", c)
+		}
+		var max time.Duration
+		if n := len(r.roundTrip); n > 0 {
+			max = r.roundTrip[n-1]
+		}
+		table.Append([]string{
+			strconv.Itoa(c),
+			fmt.Sprintf("%.0f", float64(r.requests)/r.elapsed.Seconds()),
+			format(percentile(r.reported, 50)),
+			format(percentile(r.reported, 99)),
+			format(percentile(r.roundTrip, 50)),
+			format(percentile(r.roundTrip, 99)),
+			format(max),
+			format(percentile(r.jitter, 50)),
+			format(percentile(r.jitter, 99)),
+		})
+	}
+	table.Render()
+	fmt.Println("  Reported: latency as the tool records it in a normal run; Round Trip: the whole request as seen by the worker.")
+	fmt.Println("  The server does no work, so these figures are the tool's own overhead at each concurrency; Sched Jitter is how late a")
+	fmt.Printf("  goroutine sleeping %s wakes up under that load. Differences in real results smaller than these cannot be told apart.\n", calibrationProbeInterval)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/guptarohit/asciigraph"
+	"github.com/olekukonko/tablewriter"
+)
+
+// capacityFlags 为 capacity 子命令自身的选项，其余参数原样传给每一级的压测
+var capacityFlags = map[string]bool{"from": true, "to": true, "step": true, "hold": true, "csv": true}
+
+// capacityStripped 为每一级压测由 capacity 子命令指定、须从传入参数中去掉的选项
+var capacityStripped = map[string]bool{"c": true, "n": true, "soak": true, "format": true, "o": true, "checkpoint": true}
+
+// capacityLevel 为一个并发级别的压测结果；Status 为空表示得到了汇总
+type capacityLevel struct {
+	Concurrency int
+	Status      string
+	Summary     statsSummary
+}
+
+// splitFlags 把命令行参数分为 names 中的选项（及其取值）与其余参数，支持 -name value、-name=value 与 --name 形式
+func splitFlags(args []string, names map[string]bool) (picked, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if !strings.HasPrefix(arg, "-") || !names[name] {
+			rest = append(rest, arg)
+			continue
+		}
+		picked = append(picked, arg)
+		if !strings.Contains(arg, "=") && i+1 < len(args) {
+			i++
+			picked = append(picked, args[i])
+		}
+	}
+	return picked, rest
+}
+
+// capacityLevels 返回 from 到 to 之间按 step 递增的并发级别，to 总是最后一级
+func capacityLevels(from, to, step int) []int {
+	var levels []int
+	for c := from; c < to; c += step {
+		levels = append(levels, c)
+	}
+	return append(levels, to)
+}
+
+// runCapacityCommand 解析 capacity 子命令参数，依次以各并发级别在子进程中压测 -hold 时长，
+// 输出吞吐量与时延随并发变化的表格、曲线与 CSV，以及按各级结果估计的拐点
+func runCapacityCommand(args []string) {
+	own, rest := splitFlags(args, capacityFlags)
+	fs := flag.NewFlagSet("capacity", flag.ExitOnError)
+	var from, to, step int
+	var hold time.Duration
+	var csvFile string
+	fs.IntVar(&from, "from", 10, "Concurrency of the first level")
+	fs.IntVar(&to, "to", 100, "Concurrency of the last level")
+	fs.IntVar(&step, "step", 10, "Concurrency added at each level")
+	fs.DurationVar(&hold, "hold", 30*time.Second, "How long each level runs")
+	fs.StringVar(&csvFile, "csv", "capacity.csv", "File the curve is written to as CSV (empty to skip)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: capacity [-from N] [-to N] [-step N] [-hold duration] [-csv file] [load test options]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(own)
+	if from < 1 || to < from || step < 1 || hold <= 0 {
+		slog.Error("capacity needs 1 <= -from <= -to, -step >= 1 and a positive -hold")
+		exit(1)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	base := stripFlags(rest, capacityStripped)
+	levels := capacityLevels(from, to, step)
+	fmt.Printf("\n📐  Capacity Sweep: concurrency %d to %d in steps of %d, %s per level (%d levels, about %s)\n",
+		from, to, step, hold, len(levels), (hold * time.Duration(len(levels))).Round(time.Second))
+
+	// 子进程同样收到 Ctrl+C 并输出已完成部分的汇总，本进程在当前级别结束后停止
+	var stopped int32
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		atomic.StoreInt32(&stopped, 1)
+	}()
+
+	var results []capacityLevel
+	for i, c := range levels {
+		if atomic.LoadInt32(&stopped) == 1 {
+			break
+		}
+		fmt.Printf("🚀  Level %d/%d: -c %d for %s\n", i+1, len(levels), c, hold)
+		level := runCapacityLevel(exe, base, c, hold)
+		if level.Status == "" {
+			fmt.Printf("  - %.2f req/s, P50 %.0f ms, P99 %.0f ms, %d failed\n", level.Summary.QPS, level.Summary.P50Ms, level.Summary.P99Ms, level.Summary.FailedRequests)
+		} else {
+			fmt.Printf("  - %s\n", level.Status)
+		}
+		results = append(results, level)
+	}
+	reportCapacity(results)
+	if csvFile != "" && len(results) > 0 {
+		if err := writeCapacityCSV(csvFile, results); err != nil {
+			slog.Error("Unable to write capacity curve", "err", err)
+			exit(1)
+		}
+		fmt.Printf("📄  Capacity curve written to %s\n", csvFile)
+	}
+}
+
+// runCapacityLevel 在子进程中以 concurrency 个 worker 压测 hold 时长，读取其 -format json 的汇总
+func runCapacityLevel(exe string, base []string, concurrency int, hold time.Duration) capacityLevel {
+	level := capacityLevel{Concurrency: concurrency}
+	args := append(append([]string(nil), base...), "-c", strconv.Itoa(concurrency), "-soak", hold.String(),
+		"-checkpoint", "0", "-format", "json")
+	var stdout bytes.Buffer
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	var report runReport
+	if json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &report) != nil {
+		level.Status = "no summary"
+		if err != nil {
+			level.Status += " (" + err.Error() + ")"
+		}
+		return level
+	}
+	level.Summary = report.Summary
+	return level
+}
+
+// errorRate 返回汇总中失败请求的百分比
+func (l capacityLevel) errorRate() float64 {
+	if l.Summary.TotalRequests == 0 {
+		return 0
+	}
+	return float64(l.Summary.FailedRequests) / float64(l.Summary.TotalRequests) * 100
+}
+
+// inFlight 返回该级别实际达到的平均并发
+func (l capacityLevel) inFlight() float64 {
+	if l.Summary.Capacity == nil {
+		return 0
+	}
+	return l.Summary.Capacity.AvgInFlight
+}
+
+// reportCapacity 输出各并发级别的吞吐量与时延表格、吞吐量与 P99 曲线，以及按各级结果估计的拐点
+func reportCapacity(results []capacityLevel) {
+	fmt.Println("\n======================================")
+	fmt.Println("📐  Capacity Curve:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Concurrency", "Requests", "Error Rate", "TPS", "QPS", "In Flight", "P50", "P95", "P99"})
+	var labels []string
+	var qps, p50, p95, p99 []float64
+	for _, l := range results {
+		if l.Status != "" {
+			table.Append([]string{strconv.Itoa(l.Concurrency), l.Status, "", "", "", "", "", "", ""})
+			continue
+		}
+		s := l.Summary
+		table.Append([]string{
+			strconv.Itoa(l.Concurrency),
+			fmt.Sprintf("%d", s.TotalRequests),
+			fmt.Sprintf("%.2f%%", l.errorRate()),
+			fmt.Sprintf("%.2f", s.TPS),
+			fmt.Sprintf("%.2f", s.QPS),
+			fmt.Sprintf("%.1f", l.inFlight()),
+			fmt.Sprintf("%.0f ms", s.P50Ms),
+			fmt.Sprintf("%.0f ms", s.P95Ms),
+			fmt.Sprintf("%.0f ms", s.P99Ms),
+		})
+		labels = append(labels, strconv.Itoa(l.Concurrency))
+		qps = append(qps, s.QPS)
+		p50 = append(p50, s.P50Ms)
+		p95 = append(p95, s.P95Ms)
+		p99 = append(p99, s.P99Ms)
+	}
+	table.Render()
+	if len(qps) == 0 {
+		return
+	}
+	axis := fmt.Sprintf("concurrency %s - %s", labels[0], labels[len(labels)-1])
+	fmt.Println("\n📊  Throughput (req/s) by concurrency:")
+	fmt.Println(asciigraph.Plot(qps, asciigraph.Height(10), asciigraph.Caption(axis)))
+	fmt.Println("\n📉  P99 (ms) by concurrency:")
+	fmt.Println(asciigraph.Plot(p99, asciigraph.Height(5), asciigraph.Caption(axis)))
+
+	knee, note := detectKnee(qps, p50, p95)
+	if knee == nil {
+		fmt.Printf("\n🧮  Knee: not found (%s)\n", note)
+		return
+	}
+	for _, l := range results {
+		if l.Status == "" && l.Summary.QPS == knee.Rate {
+			fmt.Printf("\n🧮  Knee: throughput saturates around %.1f req/s at -c %d (%.1f requests in flight), where P95 was %.0f ms (baseline %.0f ms); beyond it P95 more than doubles\n",
+				knee.Rate, l.Concurrency, l.inFlight(), knee.P95Ms, knee.BaselineP95Ms)
+			return
+		}
+	}
+}
+
+// writeCapacityCSV 把各并发级别的结果写为 CSV，每级一行
+func writeCapacityCSV(filename string, results []capacityLevel) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"concurrency", "status", "requests", "failed", "error_rate", "tps", "qps", "in_flight", "p50_ms", "p95_ms", "p99_ms"})
+	for _, l := range results {
+		s := l.Summary
+		status := l.Status
+		if status == "" {
+			status = "ok"
+		}
+		w.Write([]string{
+			strconv.Itoa(l.Concurrency),
+			status,
+			strconv.FormatInt(s.TotalRequests, 10),
+			strconv.FormatInt(s.FailedRequests, 10),
+			strconv.FormatFloat(l.errorRate(), 'f', 4, 64),
+			strconv.FormatFloat(s.TPS, 'f', 2, 64),
+			strconv.FormatFloat(s.QPS, 'f', 2, 64),
+			strconv.FormatFloat(l.inFlight(), 'f', 2, 64),
+			strconv.FormatFloat(s.P50Ms, 'f', 1, 64),
+			strconv.FormatFloat(s.P95Ms, 'f', 1, 64),
+			strconv.FormatFloat(s.P99Ms, 'f', 1, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
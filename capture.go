@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// captureHeaders 为 -capture-header 指定的响应 header，统计每个取值出现的次数，如 X-Cache 的 HIT/MISS、X-Backend 的实例
+var captureHeaders captureHeaderFlags
+
+type captureHeaderFlags []string
+
+func (f *captureHeaderFlags) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *captureHeaderFlags) Set(value string) error {
+	name := http.CanonicalHeaderKey(strings.TrimSpace(value))
+	if name == "" {
+		return fmt.Errorf("empty header name")
+	}
+	*f = append(*f, name)
+	return nil
+}
+
+const (
+	// maxCapturedValues 为每个 header 单独统计的取值个数上限，之后出现的新取值计入 capturedOther
+	maxCapturedValues = 200
+	capturedOther     = "(other)"
+	capturedMissing   = "(missing)"
+	// maxCapturedRows 为每个 header 输出的取值行数上限
+	maxCapturedRows = 20
+)
+
+// headerValues 为每个 -capture-header 各取值出现的次数，统计所有得到响应的请求
+type headerValues map[string]map[string]int64
+
+// record 记录一个响应中各 -capture-header 的取值，没有该 header 时记为 capturedMissing
+func (h headerValues) record(header http.Header) {
+	for _, name := range captureHeaders {
+		value := header.Get(name)
+		if value == "" {
+			value = capturedMissing
+		}
+		h.count(name, value, 1)
+	}
+}
+
+func (h headerValues) count(name, value string, n int64) {
+	counts, ok := h[name]
+	if !ok {
+		counts = make(map[string]int64)
+		h[name] = counts
+	}
+	if _, seen := counts[value]; !seen && len(counts) >= maxCapturedValues {
+		value = capturedOther
+	}
+	counts[value] += n
+}
+
+// add 合并另一个 worker 的统计
+func (h headerValues) add(other headerValues) {
+	for name, counts := range other {
+		for value, n := range counts {
+			h.count(name, value, n)
+		}
+	}
+}
+
+// reportHeaderValues 按 -capture-header 的顺序输出各 header 取值的分布；取值多于一个时给出最多与最少的取值之比，
+// 用于判断负载均衡是否均匀（capturedMissing 与 capturedOther 不参与比较）
+func reportHeaderValues(h headerValues) {
+	if len(captureHeaders) == 0 {
+		return
+	}
+	fmt.Println("\n📬  Response Header Values:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Header", "Value", "Responses", "Share"})
+	var notes []string
+	for _, name := range captureHeaders {
+		counts := h[name]
+		values := make([]string, 0, len(counts))
+		var total int64
+		for value, n := range counts {
+			values = append(values, value)
+			total += n
+		}
+		if total == 0 {
+			table.Append([]string{name, "-", "0", "-"})
+			continue
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+		var most, least int64 = 0, -1
+		var distinct int
+		for i, value := range values {
+			n := counts[value]
+			if i < maxCapturedRows {
+				table.Append([]string{name, value, fmt.Sprintf("%d", n), fmt.Sprintf("%.2f%%", float64(n)*100/float64(total))})
+			}
+			if value == capturedMissing || value == capturedOther {
+				continue
+			}
+			distinct++
+			if n > most {
+				most = n
+			}
+			if least < 0 || n < least {
+				least = n
+			}
+		}
+		if len(values) > maxCapturedRows {
+			table.Append([]string{name, fmt.Sprintf("... %d more values", len(values)-maxCapturedRows), "", ""})
+		}
+		if distinct > 1 {
+			notes = append(notes, fmt.Sprintf("  - %s: %d distinct values, most/least frequent = %.2f", name, distinct, float64(most)/float64(least)))
+		}
+	}
+	table.Render()
+	for _, note := range notes {
+		fmt.Println(note)
+	}
+}
+
+// summarizeHeaderValues 返回写入 JSON 汇总的各 header 取值分布，未设置 -capture-header 时为 nil
+func summarizeHeaderValues(h headerValues) map[string]map[string]int64 {
+	if len(captureHeaders) == 0 {
+		return nil
+	}
+	return h
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// chaosResetRate、chaosStallValue 为 -chaos-reset-rate 与 -chaos-stall：按比例让请求在发送到一半时所用的连接被 RST 重置，
+// 或停顿一段时间后再发送剩余部分，模拟不稳定的网络，用于验证服务端与代理对中断和超时的处理。
+// 是否注入在每个请求（连接上每一次发送）开始时决定，与 -add-latency、-bandwidth 一起作用于两种引擎
+var (
+	chaosResetRate  float64
+	chaosStallValue string
+	// chaosStallRate、chaosStall 为 -chaos-stall 解析后的比例与停顿时长
+	chaosStallRate float64
+	chaosStall     time.Duration
+)
+
+// chaosResets、chaosStalls 为已注入的连接重置与停顿次数
+var chaosResets, chaosStalls int64
+
+// errChaosReset 为注入的连接重置返回的错误
+var errChaosReset = errors.New("chaos: injected connection reset")
+
+// chaosEnabled 返回是否启用了连接故障注入
+func chaosEnabled() bool {
+	return chaosResetRate > 0 || chaosStallRate > 0
+}
+
+// parseChaosStall 解析 -chaos-stall：比例:时长，如 0.005:2s
+func parseChaosStall(value string) error {
+	rate, duration, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("invalid -chaos-stall %q (expected ratio:duration, e.g. 0.005:2s)", value)
+	}
+	r, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+	if err != nil || r < 0 || r > 1 {
+		return fmt.Errorf("invalid -chaos-stall ratio %q (expected 0 to 1)", rate)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(duration))
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid -chaos-stall duration %q", duration)
+	}
+	chaosStallRate, chaosStall = r, d
+	return nil
+}
+
+// describeChaos 返回启动时输出的故障注入说明
+func describeChaos() string {
+	var parts []string
+	if chaosResetRate > 0 {
+		parts = append(parts, fmt.Sprintf("%.2f%% of requests reset", chaosResetRate*100))
+	}
+	if chaosStallRate > 0 {
+		parts = append(parts, fmt.Sprintf("%.2f%% stalled for %s", chaosStallRate*100, chaosStall))
+	}
+	return strings.Join(parts, ", ") + " halfway through sending"
+}
+
+// chaosConn 为注入故障的连接，包装拨号得到的 TCP 连接
+type chaosConn struct {
+	net.Conn
+	// sending 为一次发送是否已经开始，收到数据后复位，下一次 Write 即下一个请求
+	sending atomic.Bool
+	// closed 在连接关闭时关闭，使停顿中的 Write 随超时或取消及时返回
+	closed    chan struct{}
+	closeOnce sync.Once
+	// rng 为该连接决定是否注入故障的随机数生成器
+	rng *rand.Rand
+}
+
+func newChaosConn(conn net.Conn) *chaosConn {
+	return &chaosConn{Conn: conn, closed: make(chan struct{}), rng: connRand()}
+}
+
+// Write 在一次发送开始时按比例决定是否注入故障：重置时只发出前一半数据即以 RST 关闭连接，
+// 停顿时发出前一半数据后等待 chaosStall 再发送其余部分
+func (c *chaosConn) Write(b []byte) (int, error) {
+	if len(b) < 2 || c.sending.Swap(true) {
+		return c.Conn.Write(b)
+	}
+	roll := c.rng.Float64()
+	switch {
+	case roll < chaosResetRate:
+		atomic.AddInt64(&chaosResets, 1)
+		n, _ := c.Conn.Write(b[:len(b)/2])
+		c.reset()
+		return n, errChaosReset
+	case roll < chaosResetRate+chaosStallRate:
+		atomic.AddInt64(&chaosStalls, 1)
+		n, err := c.Conn.Write(b[:len(b)/2])
+		if err != nil {
+			return n, err
+		}
+		select {
+		case <-time.After(chaosStall):
+		case <-c.closed:
+			return n, net.ErrClosed
+		}
+		m, err := c.Conn.Write(b[n:])
+		return n + m, err
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *chaosConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.sending.Store(false)
+	}
+	return n, err
+}
+
+func (c *chaosConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+// reset 以 RST 而不是 FIN 关闭连接
+func (c *chaosConn) reset() {
+	if tcp, ok := c.Conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	c.Close()
+}
+
+// chaosSummary 为写入 JSON 汇总的故障注入统计
+type chaosSummary struct {
+	ResetRate float64 `json:"reset_rate"`
+	StallRate float64 `json:"stall_rate"`
+	StallMs   float64 `json:"stall_ms"`
+	Resets    int64   `json:"resets"`
+	Stalls    int64   `json:"stalls"`
+}
+
+// summarizeChaos 返回故障注入的统计，未启用时返回 nil
+func summarizeChaos() *chaosSummary {
+	if !chaosEnabled() {
+		return nil
+	}
+	return &chaosSummary{
+		ResetRate: chaosResetRate,
+		StallRate: chaosStallRate,
+		StallMs:   durationMs(chaosStall),
+		Resets:    atomic.LoadInt64(&chaosResets),
+		Stalls:    atomic.LoadInt64(&chaosStalls),
+	}
+}
+
+// reportChaos 输出注入的连接重置与停顿次数，受影响请求的结果见状态码与错误统计
+func reportChaos(s *chaosSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("\n🌪️   Chaos: %d connection resets and %d stalls injected (%s)\n", s.Resets, s.Stalls, describeChaos())
+	if s.Resets > 0 {
+		fmt.Printf("  - Reset requests fail with %q, except on a reused keep-alive connection, where they are retried once on a new connection as after a stale connection\n", errChaosReset.Error())
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chunkedBody 为 true 时请求体以 Transfer-Encoding: chunked 分块发送，每块 chunkSize 字节，
+// bodyRate 大于 0 时按该速率（字节/秒）发送，用于模拟上传缓慢的客户端
+var (
+	chunkedBody bool
+	chunkSize   = 64 * 1024
+	bodyRate    float64
+)
+
+// parseByteSize 解析 "64k"、"1MB"、"512" 形式的字节数，单位按 1024 进制，大小写与结尾的 b/ib 均可省略
+func parseByteSize(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "b"), "i")
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "g"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 64k or 1MB", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// formatBytes 按 1024 进制输出易读的字节数，如 512 B、64.0 KiB
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// parseByteRate 解析 "1MB/s"、"256kb/s" 形式的速率，返回字节/秒；结尾的 /s 可省略
+func parseByteRate(s string) (float64, error) {
+	size, err := parseByteSize(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q, expected e.g. 1MB/s or 256kb/s", s)
+	}
+	return float64(size), nil
+}
+
+// pacedReader 每次最多返回 chunk 字节，rate 大于 0 时按 rate（字节/秒）限速；
+// 作为请求体时每次 Read 的内容即为一个 chunk
+type pacedReader struct {
+	data  []byte
+	chunk int
+	rate  float64
+	sent  int
+	start time.Time
+}
+
+// newPacedReader 创建按 chunkSize 与 bodyRate 发送 body 的 reader
+func newPacedReader(body string) *pacedReader {
+	return &pacedReader{data: []byte(body), chunk: chunkSize, rate: bodyRate}
+}
+
+func (r *pacedReader) Read(p []byte) (int, error) {
+	n := r.next(len(p))
+	if n == 0 {
+		return 0, io.EOF
+	}
+	copy(p, r.data[r.sent:r.sent+n])
+	r.sent += n
+	return n, nil
+}
+
+// WriteTo 让 io.Copy 按 chunk 直接写出，不受 io.Copy 32KB 缓冲区的限制
+func (r *pacedReader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for {
+		n := r.next(r.chunk)
+		if n == 0 {
+			return total, nil
+		}
+		written, err := w.Write(r.data[r.sent : r.sent+n])
+		r.sent += written
+		total += int64(written)
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// next 按速率等待到下一块的发送时间，返回下一块的大小（不超过 max），发送完毕时返回 0
+func (r *pacedReader) next(max int) int {
+	if r.sent >= len(r.data) {
+		return 0
+	}
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	if r.rate > 0 {
+		// 已发送的字节按速率应耗费的时间，未到时等待
+		due := r.start.Add(time.Duration(float64(r.sent) / r.rate * float64(time.Second)))
+		if wait := time.Until(due); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	n := r.chunk
+	if n > max {
+		n = max
+	}
+	if remaining := len(r.data) - r.sent; n > remaining {
+		n = remaining
+	}
+	return n
+}
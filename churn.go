@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// closeEvery 为 -close-every：每 N 个 Keep-Alive 请求中有一个带 Connection: close，发完即关闭所用的连接，
+// 使每条连接平均承载 N 个请求后重建，模拟位于激进 NAT 之后或短生命周期（如 serverless）的客户端；
+// 报告新连接与复用连接上的请求时延对比及握手开销
+var closeEvery int
+
+// churnRequests 为 -close-every 下已发出的 Keep-Alive 请求数
+var churnRequests int64
+
+// churnClose 按 -close-every 决定该请求发完后是否关闭连接
+func churnClose(req *http.Request, client *http.Client) {
+	if closeEvery > 0 && usesKeepAlive(client) && atomic.AddInt64(&churnRequests, 1)%int64(closeEvery) == 0 {
+		req.Close = true
+	}
+}
+
+// churnStats 为 -close-every 下按连接是否新建区分的请求统计；Handshake 为新连接上 DNS、建连与 TLS 握手的总耗时
+type churnStats struct {
+	New       URLStats
+	Reused    URLStats
+	Handshake time.Duration
+}
+
+// record 记录一个得到响应的请求，d 为请求的总耗时
+func (s *churnStats) record(t *requestTrace, d time.Duration) {
+	us := &s.Reused
+	if !t.reusedConn() {
+		us = &s.New
+		p := t.phases(time.Time{})
+		s.Handshake += p.DNS + p.Connect + p.TLS
+	}
+	us.TotalRequests++
+	us.ResponseTimes = appendSample(us.ResponseTimes, &us.sampleCount, d)
+}
+
+// add 合并另一个 worker 的统计
+func (s *churnStats) add(other *churnStats) {
+	s.New.merge(&other.New)
+	s.Reused.merge(&other.Reused)
+	s.Handshake += other.Handshake
+}
+
+// churnSummary 为写入 JSON 汇总的连接重建统计
+type churnSummary struct {
+	CloseEvery     int     `json:"close_every"`
+	NewConnections int64   `json:"new_connections"`
+	Reused         int64   `json:"reused"`
+	HandshakeMs    float64 `json:"avg_handshake_ms"`
+	// HandshakeShare 为握手耗时占所有请求总耗时的百分比
+	HandshakeShare float64 `json:"handshake_share"`
+	NewP50Ms       float64 `json:"new_p50_ms"`
+	NewP99Ms       float64 `json:"new_p99_ms"`
+	ReusedP50Ms    float64 `json:"reused_p50_ms"`
+	ReusedP99Ms    float64 `json:"reused_p99_ms"`
+}
+
+// summarizeChurn 汇总连接重建的统计，会对样本原地排序；totalTime 为所有请求的总耗时。未设置 -close-every 时返回 nil
+func summarizeChurn(s *churnStats, totalTime time.Duration) *churnSummary {
+	if closeEvery <= 0 {
+		return nil
+	}
+	for _, us := range []*URLStats{&s.New, &s.Reused} {
+		times := us.ResponseTimes
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	}
+	summary := &churnSummary{
+		CloseEvery:     closeEvery,
+		NewConnections: s.New.TotalRequests,
+		Reused:         s.Reused.TotalRequests,
+		NewP50Ms:       durationMs(percentile(s.New.ResponseTimes, 50)),
+		NewP99Ms:       durationMs(percentile(s.New.ResponseTimes, 99)),
+		ReusedP50Ms:    durationMs(percentile(s.Reused.ResponseTimes, 50)),
+		ReusedP99Ms:    durationMs(percentile(s.Reused.ResponseTimes, 99)),
+	}
+	if s.New.TotalRequests > 0 {
+		summary.HandshakeMs = durationMs(s.Handshake / time.Duration(s.New.TotalRequests))
+	}
+	if totalTime > 0 {
+		summary.HandshakeShare = float64(s.Handshake) / float64(totalTime) * 100
+	}
+	return summary
+}
+
+// reportChurn 输出新连接与复用连接上的请求数与时延，以及握手开销
+func reportChurn(s *churnSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("\n🔁  Connection Churn (close every %d requests):\n", s.CloseEvery)
+	total := s.NewConnections + s.Reused
+	if total == 0 {
+		fmt.Println("  - no responses")
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Connection", "Requests", "Share", "P50", "P99"})
+	row := func(name string, n int64, p50, p99 float64) {
+		table.Append([]string{name, fmt.Sprintf("%d", n), fmt.Sprintf("%.2f%%", float64(n)/float64(total)*100),
+			fmt.Sprintf("%.1f ms", p50), fmt.Sprintf("%.1f ms", p99)})
+	}
+	row("new", s.NewConnections, s.NewP50Ms, s.NewP99Ms)
+	row("reused", s.Reused, s.ReusedP50Ms, s.ReusedP99Ms)
+	table.Render()
+	if s.NewConnections > 0 {
+		fmt.Printf("  - Handshake overhead: %.1f ms per new connection (DNS + connect + TLS), %.2f%% of all request time\n", s.HandshakeMs, s.HandshakeShare)
+	}
+	if s.NewConnections > 0 && s.Reused > 0 {
+		fmt.Printf("  - P50 on a new connection is %+.1f ms compared with a reused one\n", s.NewP50Ms-s.ReusedP50Ms)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// compareTarget 为 -targets-compare 中的一个被比较的实现
+type compareTarget struct {
+	URL    string
+	Weight float64
+	parsed *url.URL
+}
+
+// compareTargets 非空时每个请求按权重发往其中一个目标，并分别统计
+var compareTargets []compareTarget
+
+// parseCompareTargets 解析 urlA,urlB 或 urlA=3,urlB=1（权重默认为 1）
+func parseCompareTargets(value string) ([]compareTarget, error) {
+	var targets []compareTarget
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		target := compareTarget{URL: item, Weight: 1}
+		// URL 的查询参数中也可能有 =，因此只把最后一个 = 之后是数字的部分当作权重
+		if i := strings.LastIndex(item, "="); i > 0 {
+			if w, err := strconv.ParseFloat(item[i+1:], 64); err == nil {
+				target.URL, target.Weight = item[:i], w
+			}
+		}
+		u, err := url.Parse(target.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid compare target %q", target.URL)
+		}
+		if target.Weight < 0 {
+			return nil, fmt.Errorf("negative weight for compare target %q", target.URL)
+		}
+		target.parsed = u
+		targets = append(targets, target)
+	}
+	if len(targets) < 2 {
+		return nil, fmt.Errorf("-targets-compare needs at least two targets")
+	}
+	return targets, nil
+}
+
+// assignTarget 按权重为第 n 个请求选择目标，返回指向该目标的请求副本：
+// 未指定 URL 的请求直接使用目标 URL，指定了 URL 的请求保留路径与查询参数，只替换协议与主机
+func assignTarget(spec *requestSpec, n int64) *requestSpec {
+	var total float64
+	for _, t := range compareTargets {
+		total += t.Weight
+	}
+	pick := requestRand(n, randTarget).Float64() * total
+	target := compareTargets[len(compareTargets)-1]
+	for _, t := range compareTargets {
+		if pick < t.Weight {
+			target = t
+			break
+		}
+		pick -= t.Weight
+	}
+	return retarget(spec, target)
+}
+
+// retarget 返回发往 target 的请求副本
+func retarget(spec *requestSpec, target compareTarget) *requestSpec {
+	assigned := *spec
+	assigned.Target = target.URL
+	if spec.URL == "" {
+		assigned.URL = target.URL
+		return &assigned
+	}
+	u, err := url.Parse(spec.URL)
+	if err != nil {
+		return &assigned
+	}
+	u.Scheme = target.parsed.Scheme
+	u.Host = target.parsed.Host
+	if prefix := strings.TrimSuffix(target.parsed.Path, "/"); prefix != "" {
+		u.Path = prefix + u.Path
+	}
+	assigned.URL = u.String()
+	return &assigned
+}
+
+// reportTargetComparison 并排输出各目标的统计，最后一列为最后一个目标相对第一个的变化
+func reportTargetComparison(stats *Stats, elapsed time.Duration) {
+	if len(compareTargets) == 0 {
+		return
+	}
+	columns := make([]*URLStats, len(compareTargets))
+	header := []string{"Metric"}
+	for i, t := range compareTargets {
+		us := stats.Targets[t.URL]
+		if us == nil {
+			us = &URLStats{}
+		}
+		sort.Slice(us.ResponseTimes, func(a, b int) bool { return us.ResponseTimes[a] < us.ResponseTimes[b] })
+		columns[i] = us
+		header = append(header, t.URL)
+	}
+	header = append(header, "Δ last vs first")
+
+	rows := []struct {
+		name   string
+		value  func(us *URLStats) float64
+		format string
+	}{
+		{"Requests", func(us *URLStats) float64 { return float64(us.TotalRequests) }, "%.0f"},
+		{"Failed", func(us *URLStats) float64 { return float64(us.FailedRequests) }, "%.0f"},
+		{"Error Rate (%)", func(us *URLStats) float64 {
+			if us.TotalRequests == 0 {
+				return 0
+			}
+			return float64(us.FailedRequests) / float64(us.TotalRequests) * 100
+		}, "%.2f"},
+		{"QPS", func(us *URLStats) float64 {
+			if elapsed <= 0 {
+				return 0
+			}
+			return float64(us.TotalRequests) / elapsed.Seconds()
+		}, "%.2f"},
+		{"P50 (ms)", func(us *URLStats) float64 { return durationMs(percentile(us.ResponseTimes, 50)) }, "%.1f"},
+		{"P95 (ms)", func(us *URLStats) float64 { return durationMs(percentile(us.ResponseTimes, 95)) }, "%.1f"},
+		{"P99 (ms)", func(us *URLStats) float64 { return durationMs(percentile(us.ResponseTimes, 99)) }, "%.1f"},
+		{"Max (ms)", func(us *URLStats) float64 { return durationMs(percentile(us.ResponseTimes, 100)) }, "%.1f"},
+	}
+
+	fmt.Println("\n🆚  Target Comparison (latency is the full request time):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	for _, r := range rows {
+		line := []string{r.name}
+		for _, us := range columns {
+			line = append(line, fmt.Sprintf(r.format, r.value(us)))
+		}
+		line = append(line, percentDelta(r.value(columns[len(columns)-1]), r.value(columns[0])))
+		table.Append(line)
+	}
+	table.Render()
+}
+
+// durationMs 把时长转换为毫秒
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
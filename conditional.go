@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// conditionalRequests 为 -conditional：记下每个 URL 的 GET/HEAD 响应中的 ETag 与 Last-Modified，
+// 之后对该 URL 的请求带上 If-None-Match 与 If-Modified-Since，统计 304 的比例并对比 304 与完整响应的时延，
+// 用于压测缓存重新验证的路径；304 响应计为成功
+var conditionalRequests bool
+
+// validators 为一个 URL 最近一次响应中的缓存验证器
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// conditionalValidators 为各 URL 的缓存验证器，所有 worker 共享，第一个拿到验证器的请求即为该 URL 的初始请求
+var conditionalValidators sync.Map
+
+// addConditionalHeaders 为已有验证器的 GET/HEAD 请求加上条件请求 header，返回是否加上；
+// 请求本身（如 -H）已带条件 header 时不覆盖
+func addConditionalHeaders(req *http.Request, reqURL string) bool {
+	if !conditionalRequests || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return false
+	}
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return false
+	}
+	v, ok := conditionalValidators.Load(reqURL)
+	if !ok {
+		return false
+	}
+	vs := v.(validators)
+	if vs.etag != "" {
+		req.Header.Set("If-None-Match", vs.etag)
+	}
+	if vs.lastModified != "" {
+		req.Header.Set("If-Modified-Since", vs.lastModified)
+	}
+	return true
+}
+
+// storeValidators 记下 2xx 或 304 响应中的验证器；响应没有验证器时保留之前的
+func storeValidators(req *http.Request, reqURL string, resp *http.Response) {
+	if !conditionalRequests || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return
+	}
+	if resp.StatusCode != http.StatusNotModified && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return
+	}
+	vs := validators{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+	if vs.etag == "" && vs.lastModified == "" {
+		return
+	}
+	conditionalValidators.Store(reqURL, vs)
+}
+
+// revalidationStats 为 -conditional 下按结果区分的请求统计，时延为请求的总耗时
+type revalidationStats struct {
+	// Initial 为还没有验证器、以普通请求发出的请求
+	Initial URLStats
+	// NotModified 为得到 304 的条件请求，Full 为得到完整响应（或错误状态）的条件请求
+	NotModified URLStats
+	Full        URLStats
+}
+
+// record 记录一个 -conditional 下的请求，conditional 表示请求带了条件 header
+func (s *revalidationStats) record(conditional bool, status int, success bool, d time.Duration) {
+	us := &s.Initial
+	switch {
+	case conditional && status == http.StatusNotModified:
+		us = &s.NotModified
+	case conditional:
+		us = &s.Full
+	}
+	us.TotalRequests++
+	if !success {
+		us.FailedRequests++
+	}
+	us.ResponseTimes = appendSample(us.ResponseTimes, &us.sampleCount, d)
+}
+
+// add 合并另一个 worker 的统计
+func (s *revalidationStats) add(other *revalidationStats) {
+	s.Initial.merge(&other.Initial)
+	s.NotModified.merge(&other.NotModified)
+	s.Full.merge(&other.Full)
+}
+
+// revalidationSummary 为写入 JSON 汇总的条件请求统计
+type revalidationSummary struct {
+	ConditionalRequests int64 `json:"conditional_requests"`
+	NotModified         int64 `json:"not_modified"`
+	// NotModifiedRatio 为 304 占条件请求的百分比
+	NotModifiedRatio float64               `json:"not_modified_ratio"`
+	Outcomes         []revalidationOutcome `json:"outcomes"`
+}
+
+type revalidationOutcome struct {
+	Outcome  string  `json:"outcome"`
+	Requests int64   `json:"requests"`
+	Failed   int64   `json:"failed"`
+	P50Ms    float64 `json:"p50_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+}
+
+// summarizeRevalidation 汇总条件请求的统计，会对样本原地排序；未设置 -conditional 时返回 nil
+func summarizeRevalidation(s *revalidationStats) *revalidationSummary {
+	if !conditionalRequests {
+		return nil
+	}
+	summary := &revalidationSummary{
+		ConditionalRequests: s.NotModified.TotalRequests + s.Full.TotalRequests,
+		NotModified:         s.NotModified.TotalRequests,
+	}
+	if summary.ConditionalRequests > 0 {
+		summary.NotModifiedRatio = float64(summary.NotModified) / float64(summary.ConditionalRequests) * 100
+	}
+	for _, o := range []struct {
+		name string
+		us   *URLStats
+	}{{"initial", &s.Initial}, {"304 not modified", &s.NotModified}, {"full response", &s.Full}} {
+		times := o.us.ResponseTimes
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		summary.Outcomes = append(summary.Outcomes, revalidationOutcome{
+			Outcome:  o.name,
+			Requests: o.us.TotalRequests,
+			Failed:   o.us.FailedRequests,
+			P50Ms:    durationMs(percentile(times, 50)),
+			P99Ms:    durationMs(percentile(times, 99)),
+		})
+	}
+	return summary
+}
+
+// reportRevalidation 输出条件请求的 304 比例，以及 304 与完整响应的时延对比
+func reportRevalidation(s *revalidationSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Println("\n🗄️   Conditional Requests (ETag / Last-Modified):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Outcome", "Requests", "Failed", "P50", "P99"})
+	for _, o := range s.Outcomes {
+		table.Append([]string{o.Outcome, fmt.Sprintf("%d", o.Requests), fmt.Sprintf("%d", o.Failed),
+			fmt.Sprintf("%.1f ms", o.P50Ms), fmt.Sprintf("%.1f ms", o.P99Ms)})
+	}
+	table.Render()
+	if s.ConditionalRequests == 0 {
+		fmt.Println("  - No conditional request was sent: only GET and HEAD are revalidated, and the responses need an ETag or Last-Modified header")
+		return
+	}
+	fmt.Printf("  - 304 ratio: %.2f%% of %d conditional requests\n", s.NotModifiedRatio, s.ConditionalRequests)
+	// 没有条件请求得到完整响应时与初始请求对比
+	notModified, full := s.Outcomes[1], s.Outcomes[2]
+	if full.Requests == 0 {
+		full = s.Outcomes[0]
+	}
+	if notModified.Requests > 0 && full.Requests > 0 && notModified.P50Ms > 0 {
+		fmt.Printf("  - P50 latency: 304 %.1f ms vs %s %.1f ms (%.2fx)\n", notModified.P50Ms, full.Outcome, full.P50Ms, full.P50Ms/notModified.P50Ms)
+	}
+}
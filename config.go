@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// runConfig 为写入报告的实际生效配置：全部选项的取值、输入文件的哈希、工具版本与运行环境，
+// 便于事后复现与审阅结果
+type runConfig struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	GoVersion string `json:"go_version"`
+	// Args 为原始命令行参数，Flags 为解析后所有选项（含默认值）的取值，敏感内容已替换为 redacted
+	Args  []string          `json:"args"`
+	Flags map[string]string `json:"flags"`
+	Files []configFile      `json:"files,omitempty"`
+	Host  configHost        `json:"host"`
+}
+
+// configFile 为一个输入文件的大小与 SHA-256，内容改变后可据此发现
+type configFile struct {
+	Flag   string `json:"flag"`
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// configHost 为运行压测的机器
+type configHost struct {
+	Hostname   string `json:"hostname"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	NumCPU     int    `json:"num_cpu"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+}
+
+// configSecretFlags 为取值本身就是凭据的选项（如 Slack webhook 地址），写入报告时整体隐藏
+var configSecretFlags = map[string]bool{"notify-webhook": true, "notify-slack": true}
+
+// configSecretHeader 匹配取值需要隐藏的 header 名
+var configSecretHeader = regexp.MustCompile(`(?i)auth|token|secret|password|api-?key|cookie|session`)
+
+const redacted = "redacted"
+
+// effectiveConfig 收集当前进程的实际生效配置
+func effectiveConfig() runConfig {
+	cfg := runConfig{Version: "(devel)", GoVersion: runtime.Version(), Flags: make(map[string]string)}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Version != "" {
+			cfg.Version = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				cfg.Revision = s.Value
+			}
+		}
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		cfg.Flags[f.Name] = redactFlag(f.Name, f.Value.String())
+	})
+	if len(extraHeaders) > 0 {
+		parts := make([]string, len(extraHeaders))
+		for i, h := range extraHeaders {
+			value := h.value
+			if configSecretHeader.MatchString(h.name) {
+				value = redacted
+			}
+			parts[i] = h.name + ": " + value
+		}
+		cfg.Flags["H"] = strings.Join(parts, ", ")
+	}
+	cfg.Args = redactArgs(os.Args[1:])
+	cfg.Files = hashInputFiles()
+	cfg.Host = configHost{OS: runtime.GOOS, Arch: runtime.GOARCH, NumCPU: runtime.NumCPU(), GOMAXPROCS: runtime.GOMAXPROCS(0)}
+	cfg.Host.Hostname, _ = os.Hostname()
+	return cfg
+}
+
+// redactFlag 隐藏凭据类选项的取值与 URL 中的密码
+func redactFlag(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if configSecretFlags[name] {
+		return redacted
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return value
+}
+
+// redactArgs 按 redactFlag 与 header 规则处理原始命令行参数，支持 -name value 与 -name=value 两种写法
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	pending := ""
+	for i, arg := range args {
+		if pending != "" {
+			out[i] = redactArg(pending, arg)
+			pending = ""
+			continue
+		}
+		out[i] = arg
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if k, v, found := strings.Cut(name, "="); found {
+			out[i] = arg[:len(arg)-len(v)] + redactArg(k, v)
+		} else if f := flag.Lookup(name); f != nil {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				pending = name
+			}
+		}
+	}
+	return out
+}
+
+func redactArg(name, value string) string {
+	if name == "H" {
+		if k, _, found := strings.Cut(value, ":"); found && configSecretHeader.MatchString(k) {
+			return k + ": " + redacted
+		}
+		return value
+	}
+	return redactFlag(name, value)
+}
+
+// hashInputFiles 计算显式指定的输入文件（planInputFlags）的大小与 SHA-256，按选项名排序
+func hashInputFiles() []configFile {
+	inputs := make(map[string]bool)
+	for _, name := range planInputFlags {
+		inputs[name] = true
+	}
+	var files []configFile
+	flag.Visit(func(f *flag.Flag) {
+		path := f.Value.String()
+		if !inputs[f.Name] || path == "" {
+			return
+		}
+		file := configFile{Flag: f.Name, Path: path}
+		if err := hashFile(&file); err != nil {
+			file.Error = err.Error()
+		}
+		files = append(files, file)
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Flag < files[j].Flag })
+	return files
+}
+
+func hashFile(file *configFile) error {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	file.Bytes = n
+	file.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
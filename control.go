@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverAddr 为 -server，非空时以 REST 控制服务的方式运行，通过 HTTP 启动、停止与查询压测；
+// 没有给出主机（如 :8089）时只监听 127.0.0.1
+var serverAddr string
+
+// serverToken 为 -server-token（默认取环境变量 HTTP_TEST_GO_SERVER_TOKEN），非空时每个请求须带 Authorization: Bearer <token>；
+// 监听非回环地址时必须设置
+var serverToken string
+
+// controlArgs 为压测定义的 Args 中允许的选项，值表示该选项是否带参数。只允许影响负载与报告内容的选项：
+// 读写本机文件（-bodyfile、-request-log、-checkpoint-file 等）、打开监听端口（-pprof、-controller、-worker）、
+// 改变输出（-format、-o）或向目标以外的地址发请求（-notify-webhook、-resolver 等）的选项都不能通过 API 设置
+var controlArgs = map[string]bool{
+	"url": true, "X": true, "H": true, "c": true, "n": true, "rate": true, "soak": true, "iterations": true,
+	"max-duration": true, "max-errors": true, "max-inflight": true, "request-deadline": true,
+	"pattern": true, "burst": true, "burst-interval": true, "adaptive": false, "target-p99": true, "target-error-rate": true,
+	"method-mix": true, "keepalive-split": true, "targets-compare": true, "param": true, "tag": true, "seed": true,
+	"engine": true, "close-every": true, "preconnect": false, "dial-strategy": true, "fallback-delay": true,
+	"no-session-resumption": false, "expect-continue": false, "expect-continue-timeout": true,
+	"chunked": false, "chunk-size": true, "body-rate": true, "download-rate": true, "random-body": true, "range-size": true,
+	"body-mode": true, "conditional": false, "duplicate-ratio": true, "duplicate-expect": true,
+	"respect-retry-after": false, "retry-after-max": true, "requeue-failures": true,
+	"request-id-header": true, "idempotency-header": true, "capture-header": true, "backend-header": true,
+	"soap-action": true, "xpath": true, "expect-sha256": true, "schema-sample": true,
+	"cors-origin": true, "cors-preflight-ratio": true, "preflight": false, "preflight-origin": true,
+	"cert-check": false, "cert-warn-days": true,
+	"add-latency": true, "add-jitter": true, "bandwidth": true, "chaos-reset-rate": true, "chaos-stall": true,
+	"ws": false, "ws-duration": true, "ws-message": true, "ws-rate": true, "stream": false, "stream-duration": true,
+	"latency-metric": true, "apdex-t": true, "slo-target": true, "slo-buckets": true, "threshold": true,
+	"fairness-tolerance": true, "timezone": true, "interval": true, "trend-window": true, "heatmap": true,
+	"per-worker-stats": false, "slowest": true, "report-filter": true, "no-graphs": false, "graph-height": true, "graph-width": true,
+	"log-level": true, "log-format": true,
+}
+
+// runDefinition 为 POST /runs 的压测定义：常用参数直接给出，其余命令行参数放在 Args 中；
+// Scenarios 非空时写入临时文件作为 -scenarios
+type runDefinition struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Concurrency int               `json:"concurrency"`
+	Requests    int               `json:"requests"`
+	Duration    string            `json:"duration"`
+	Rate        float64           `json:"rate"`
+	Headers     map[string]string `json:"headers"`
+	Scenarios   json.RawMessage   `json:"scenarios"`
+	Args        []string          `json:"args"`
+}
+
+// args 返回执行该定义的命令行参数，scenariosFile 为 Scenarios 写入的文件
+func (d *runDefinition) args(scenariosFile string) ([]string, error) {
+	var args []string
+	if d.URL != "" {
+		args = append(args, "-url", d.URL)
+	}
+	if d.Method != "" {
+		args = append(args, "-X", d.Method)
+	}
+	if d.Concurrency > 0 {
+		args = append(args, "-c", strconv.Itoa(d.Concurrency))
+	}
+	if d.Requests > 0 {
+		args = append(args, "-n", strconv.Itoa(d.Requests))
+	}
+	if d.Duration != "" {
+		if _, err := time.ParseDuration(d.Duration); err != nil {
+			return nil, fmt.Errorf("invalid duration %q", d.Duration)
+		}
+		args = append(args, "-soak", d.Duration)
+	}
+	if d.Rate > 0 {
+		args = append(args, "-rate", strconv.FormatFloat(d.Rate, 'f', -1, 64))
+	}
+	for name, value := range d.Headers {
+		args = append(args, "-H", name+": "+value)
+	}
+	if scenariosFile != "" {
+		args = append(args, "-scenarios", scenariosFile)
+	}
+	// 支持 -name value 与 -name=value 两种写法；参数以外的位置参数以及 -- 都不允许，
+	// 否则其后追加的 -format json 会被当作位置参数
+	pending := ""
+	for _, arg := range d.Args {
+		if pending != "" {
+			pending = ""
+			continue
+		}
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("%q is not a flag; args must only contain flags and their values", arg)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesValue, ok := controlArgs[name]
+		if !ok {
+			return nil, fmt.Errorf("-%s cannot be set in a run definition", name)
+		}
+		if takesValue && !hasValue {
+			pending = name
+		}
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("-%s needs a value", pending)
+	}
+	// 汇总以 JSON 输出到 stdout，由控制服务解析
+	return append(append(args, d.Args...), "-format", "json"), nil
+}
+
+// controlRun 为控制服务启动的一次压测，在子进程中执行
+type controlRun struct {
+	ID       string          `json:"id"`
+	Status   string          `json:"status"`
+	Args     []string        `json:"args"`
+	Started  time.Time       `json:"started"`
+	Finished *time.Time      `json:"finished,omitempty"`
+	ExitCode *int            `json:"exit_code,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	// Output 为子进程 stderr（压测过程的输出）的最后一段
+	Output string `json:"output,omitempty"`
+
+	cmd     *exec.Cmd
+	stderr  *tailBuffer
+	stopped bool
+}
+
+// tailBuffer 只保留最后 limit 字节的输出，可并发读写
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   []byte
+	limit int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.limit {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// controlServer 管理压测：同一时刻只运行一个，保留所有压测的状态与结果
+type controlServer struct {
+	mu      sync.Mutex
+	runs    map[string]*controlRun
+	order   []string
+	current *controlRun
+	nextID  int
+}
+
+// controlListenAddr 返回控制服务实际监听的地址：没有给出主机时只监听 127.0.0.1；
+// 监听其他地址时要求设置 -server-token，因为压测定义可以让本机向任意地址发起压测
+func controlListenAddr(addr, token string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid -server address %q: %v", addr, err)
+	}
+	if host == "" {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+	if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && host != "localhost" && token == "" {
+		return "", fmt.Errorf("-server on %s requires -server-token (or HTTP_TEST_GO_SERVER_TOKEN)", addr)
+	}
+	return addr, nil
+}
+
+// requireToken 在 token 非空时要求请求带 Authorization: Bearer <token>
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// runControlServer 在 addr 上提供控制 API，直到进程退出：
+//   - POST /runs              以 JSON 的压测定义启动压测，已有压测在运行时返回 409
+//   - GET  /runs              列出所有压测
+//   - GET  /runs/{id}         查询压测的状态，结束后含 JSON 汇总
+//   - POST /runs/{id}/stop    中止压测，与 Ctrl+C 相同，子进程仍会输出已完成部分的汇总
+//   - GET  /status            当前（没有时为最近一次）压测的状态
+func runControlServer(addr string) {
+	s := &controlServer{runs: make(map[string]*controlRun)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.handleStart)
+	mux.HandleFunc("GET /runs", s.handleList)
+	mux.HandleFunc("GET /runs/{id}", s.handleGet)
+	mux.HandleFunc("POST /runs/{id}/stop", s.handleStop)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	if serverToken == "" {
+		serverToken = os.Getenv("HTTP_TEST_GO_SERVER_TOKEN")
+	}
+	listen, err := controlListenAddr(addr, serverToken)
+	if err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	fmt.Printf("🛰️   Control API listening on %s\n", listen)
+	if err := http.ListenAndServe(listen, requireToken(serverToken, mux)); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+}
+
+// writeJSON 以 JSON 写出响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError 以 {"error": "..."} 写出错误
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// snapshot 返回压测状态的副本，运行中时附带最新的输出；须持有 s.mu
+func (s *controlServer) snapshot(run *controlRun) controlRun {
+	copied := *run
+	copied.Output = run.stderr.String()
+	return copied
+}
+
+func (s *controlServer) handleStart(w http.ResponseWriter, r *http.Request) {
+	var def runDefinition
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run definition: %v", err))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("run %s is still running", s.current.ID))
+		return
+	}
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	var scenarios string
+	if len(def.Scenarios) > 0 {
+		// JSON 也是合法的 YAML，直接作为 -scenarios 文件
+		f, err := ioutil.TempFile("", "http-test-go-scenarios-*.json")
+		if err == nil {
+			_, err = f.Write(def.Scenarios)
+			f.Close()
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		scenarios = f.Name()
+	}
+	args, err := def.args(scenarios)
+	if err != nil {
+		os.Remove(scenarios)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		os.Remove(scenarios)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	run := &controlRun{ID: id, Status: "running", Args: args, Started: time.Now(), stderr: &tailBuffer{limit: 16 << 10}}
+	var stdout bytes.Buffer
+	run.cmd = exec.Command(exe, args...)
+	run.cmd.Stdout = &stdout
+	run.cmd.Stderr = run.stderr
+	if err := run.cmd.Start(); err != nil {
+		os.Remove(scenarios)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.runs[id] = run
+	s.order = append(s.order, id)
+	s.current = run
+	go s.wait(run, &stdout, scenarios)
+	writeJSON(w, http.StatusCreated, s.snapshot(run))
+}
+
+// wait 等待子进程结束并记录结果
+func (s *controlServer) wait(run *controlRun, stdout *bytes.Buffer, scenarios string) {
+	err := run.cmd.Wait()
+	if scenarios != "" {
+		os.Remove(scenarios)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	run.Finished = &now
+	code := run.cmd.ProcessState.ExitCode()
+	run.ExitCode = &code
+	if out := bytes.TrimSpace(stdout.Bytes()); json.Valid(out) && len(out) > 0 {
+		run.Result = out
+	}
+	switch {
+	case run.stopped:
+		run.Status = "stopped"
+	case err == nil:
+		run.Status = "completed"
+	default:
+		// 阈值未通过等情况下子进程以非 0 退出，但仍输出了汇总
+		run.Status = "failed"
+		run.Error = err.Error()
+	}
+	s.current = nil
+}
+
+func (s *controlServer) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := make([]controlRun, 0, len(s.order))
+	for _, id := range s.order {
+		runs = append(runs, s.snapshot(s.runs[id]))
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *controlServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(run))
+}
+
+func (s *controlServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", r.PathValue("id")))
+		return
+	}
+	if run.Finished == nil && !run.stopped {
+		run.stopped = true
+		if err := run.cmd.Process.Signal(os.Interrupt); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusAccepted, s.snapshot(run))
+}
+
+func (s *controlServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.current
+	if run == nil && len(s.order) > 0 {
+		run = s.runs[s.order[len(s.order)-1]]
+	}
+	if run == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"running": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"running": s.current != nil, "run": s.snapshot(run)})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRunDefinitionArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "value flags", args: []string{"-apdex-t", "100ms", "--latency-metric=ttfb"}, want: []string{"-url", "http://api/", "-apdex-t", "100ms", "--latency-metric=ttfb", "-format", "json"}},
+		{name: "bool flag", args: []string{"-chunked", "-seed", "7"}, want: []string{"-url", "http://api/", "-chunked", "-seed", "7", "-format", "json"}},
+		{name: "value that looks like a flag", args: []string{"-H", "-X: 1"}, want: []string{"-url", "http://api/", "-H", "-X: 1", "-format", "json"}},
+		{name: "file output", args: []string{"-request-log", "/etc/cron.d/x"}, wantErr: true},
+		{name: "file output with equals", args: []string{"-checkpoint-file=/tmp/x"}, wantErr: true},
+		{name: "file input", args: []string{"-bodyfile", "/etc/passwd"}, wantErr: true},
+		{name: "listener", args: []string{"-pprof", ":6060"}, wantErr: true},
+		{name: "worker", args: []string{"-worker", "-join", "evil:7000"}, wantErr: true},
+		{name: "format", args: []string{"-format", "text"}, wantErr: true},
+		{name: "end of flags", args: []string{"--", "-x"}, wantErr: true},
+		{name: "positional", args: []string{"-chunked", "false"}, wantErr: true},
+		{name: "missing value", args: []string{"-c"}, wantErr: true},
+		{name: "unknown flag", args: []string{"-nope"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &runDefinition{URL: "http://api/", Args: tt.args}
+			got, err := d.args("")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControlListenAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{addr: ":8089", want: "127.0.0.1:8089"},
+		{addr: "127.0.0.1:8089", want: "127.0.0.1:8089"},
+		{addr: "[::1]:8089", want: "[::1]:8089"},
+		{addr: "localhost:8089", want: "localhost:8089"},
+		{addr: "0.0.0.0:8089", wantErr: true},
+		{addr: "10.0.0.5:8089", wantErr: true},
+		{addr: "0.0.0.0:8089", token: "secret", want: "0.0.0.0:8089"},
+		{addr: "8089", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := controlListenAddr(tt.addr, tt.token)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("controlListenAddr(%q, %q) = %q, %v", tt.addr, tt.token, got, err)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	handler := requireToken("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range []struct {
+		auth string
+		want int
+	}{
+		{"Bearer secret", http.StatusOK},
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Authorization %q: status %d, want %d", tt.auth, rec.Code, tt.want)
+		}
+	}
+}
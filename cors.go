@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// corsOrigin、corsPreflightRatio 为 -cors-origin 与 -cors-preflight-ratio：模拟浏览器的跨域请求，实际请求带 Origin，
+// 需要预检的请求（非 GET/HEAD/POST 方法或带了非简单 header，如 Content-Type: application/json）先发送 OPTIONS 预检；
+// 按 corsPreflightRatio 的比例预检，模拟浏览器对预检结果的缓存。预检的时延与失败单独统计，不计入实际请求，
+// 预检未通过时实际请求照常发送
+var (
+	corsOrigin         string
+	corsPreflightRatio float64
+)
+
+// corsSafeContentTypes 为不需要预检的 Content-Type
+var corsSafeContentTypes = map[string]bool{
+	"application/x-www-form-urlencoded": true,
+	"multipart/form-data":               true,
+	"text/plain":                        true,
+}
+
+// corsIgnoredHeaders 为浏览器自行设置、不由页面脚本决定的 header，不影响是否需要预检
+var corsIgnoredHeaders = map[string]bool{
+	"User-Agent": true, "Origin": true, "Cookie": true, "Referer": true, "Host": true, "Connection": true,
+	"Accept-Encoding": true, "Content-Length": true, "Expect": true,
+}
+
+// preflightHeaders 返回 req 中需要在 Access-Control-Request-Headers 中声明的 header（小写、排序），
+// 以及 req 是否需要预检
+func preflightHeaders(req *http.Request) ([]string, bool) {
+	var names []string
+	for name := range req.Header {
+		switch {
+		case corsIgnoredHeaders[name] || strings.HasPrefix(name, "Sec-") || strings.HasPrefix(name, "Proxy-"):
+			continue
+		case name == "Accept" || name == "Accept-Language" || name == "Content-Language":
+			continue
+		case name == "Content-Type":
+			mediaType, _, _ := strings.Cut(req.Header.Get(name), ";")
+			if corsSafeContentTypes[strings.ToLower(strings.TrimSpace(mediaType))] {
+				continue
+			}
+		}
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	simple := req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodPost
+	return names, !simple || len(names) > 0
+}
+
+// sendPreflight 在实际请求 req 之前按比例发送 OPTIONS 预检并记录到 ws；不需要预检时不做任何事
+func sendPreflight(ws *WorkerStats, client *http.Client, req *http.Request) {
+	headers, needed := preflightHeaders(req)
+	if !needed || (corsPreflightRatio < 1 && sharedRand.Float64() >= corsPreflightRatio) {
+		return
+	}
+	preflight, err := http.NewRequestWithContext(runContext, http.MethodOptions, req.URL.String(), nil)
+	if err != nil {
+		return
+	}
+	preflight.Header.Set("User-Agent", req.Header.Get("User-Agent"))
+	preflight.Header.Set("Origin", corsOrigin)
+	preflight.Header.Set("Access-Control-Request-Method", req.Method)
+	if len(headers) > 0 {
+		preflight.Header.Set("Access-Control-Request-Headers", strings.Join(headers, ","))
+	}
+	start := time.Now()
+	resp, err := client.Do(preflight)
+	if err != nil {
+		ws.Preflight.record(time.Since(start), "request error")
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	ws.Preflight.record(time.Since(start), checkPreflight(resp, req.Method, headers))
+}
+
+// checkPreflight 按浏览器的规则检查预检响应是否允许该请求，返回不通过的原因，通过时返回空字符串
+func checkPreflight(resp *http.Response, method string, headers []string) string {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	if allow := resp.Header.Get("Access-Control-Allow-Origin"); allow != "*" && allow != corsOrigin {
+		return "origin not allowed"
+	}
+	if method != http.MethodGet && method != http.MethodHead && method != http.MethodPost &&
+		!corsListAllows(resp.Header.Get("Access-Control-Allow-Methods"), method, true) {
+		return "method " + method + " not allowed"
+	}
+	for _, name := range headers {
+		if !corsListAllows(resp.Header.Get("Access-Control-Allow-Headers"), name, false) {
+			return "header " + name + " not allowed"
+		}
+	}
+	return ""
+}
+
+// corsListAllows 判断逗号分隔的 Access-Control-Allow-* 列表是否包含 value 或 *；方法区分大小写，header 不区分
+func corsListAllows(list, value string, caseSensitive bool) bool {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || item == value || (!caseSensitive && strings.EqualFold(item, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// preflightStats 为 CORS 预检的统计，Reasons 为未通过的预检按原因的计数
+type preflightStats struct {
+	URLStats
+	Reasons map[string]int
+}
+
+// record 记录一个预检，reason 为空表示通过
+func (s *preflightStats) record(d time.Duration, reason string) {
+	s.TotalRequests++
+	s.ResponseTimes = appendSample(s.ResponseTimes, &s.sampleCount, d)
+	if reason == "" {
+		return
+	}
+	s.FailedRequests++
+	if s.Reasons == nil {
+		s.Reasons = make(map[string]int)
+	}
+	s.Reasons[reason]++
+}
+
+// add 合并另一个 worker 的统计
+func (s *preflightStats) add(other *preflightStats) {
+	s.URLStats.merge(&other.URLStats)
+	for reason, count := range other.Reasons {
+		if s.Reasons == nil {
+			s.Reasons = make(map[string]int)
+		}
+		s.Reasons[reason] += count
+	}
+}
+
+// preflightSummary 为写入 JSON 汇总的 CORS 预检统计
+type preflightSummary struct {
+	Origin    string         `json:"origin"`
+	Requests  int64          `json:"requests"`
+	Failed    int64          `json:"failed"`
+	ErrorRate float64        `json:"error_rate"`
+	P50Ms     float64        `json:"p50_ms"`
+	P95Ms     float64        `json:"p95_ms"`
+	P99Ms     float64        `json:"p99_ms"`
+	Failures  map[string]int `json:"failures,omitempty"`
+}
+
+// summarizePreflight 汇总 CORS 预检的统计，会对样本原地排序；未设置 -cors-origin 时返回 nil
+func summarizePreflight(s *preflightStats) *preflightSummary {
+	if corsOrigin == "" {
+		return nil
+	}
+	times := s.ResponseTimes
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	summary := &preflightSummary{
+		Origin:   corsOrigin,
+		Requests: s.TotalRequests,
+		Failed:   s.FailedRequests,
+		P50Ms:    durationMs(percentile(times, 50)),
+		P95Ms:    durationMs(percentile(times, 95)),
+		P99Ms:    durationMs(percentile(times, 99)),
+		Failures: s.Reasons,
+	}
+	if s.TotalRequests > 0 {
+		summary.ErrorRate = float64(s.FailedRequests) / float64(s.TotalRequests) * 100
+	}
+	return summary
+}
+
+// reportPreflight 输出 CORS 预检的数量、失败率与时延，以及未通过的原因
+func reportPreflight(s *preflightSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("\n✈️   CORS Preflight (Origin: %s):\n", s.Origin)
+	if s.Requests == 0 {
+		fmt.Println("  - No request needed a preflight (simple method and headers only)")
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Preflights", "Failed", "Error Rate", "P50", "P95", "P99"})
+	table.Append([]string{fmt.Sprintf("%d", s.Requests), fmt.Sprintf("%d", s.Failed), fmt.Sprintf("%.2f%%", s.ErrorRate),
+		fmt.Sprintf("%.1f ms", s.P50Ms), fmt.Sprintf("%.1f ms", s.P95Ms), fmt.Sprintf("%.1f ms", s.P99Ms)})
+	table.Render()
+	reasons := make([]string, 0, len(s.Failures))
+	for reason := range s.Failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if s.Failures[reasons[i]] != s.Failures[reasons[j]] {
+			return s.Failures[reasons[i]] > s.Failures[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	for _, reason := range reasons {
+		fmt.Printf("  - %s: %d preflights (a browser would block the request)\n", reason, s.Failures[reason])
+	}
+}
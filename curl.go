@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseCurlCommand 将一条 curl 命令解析为 requestSpec，支持常用的 -X/-H/-d/-F/-T/-u/-A/-b/-e/-G/-I 等参数，
+// 与请求内容无关的参数（-s、-k、-L、--compressed 等）会被忽略
+func parseCurlCommand(command string) (*requestSpec, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, errors.New("command does not start with curl")
+	}
+
+	spec := &requestSpec{Headers: http.Header{}}
+	var data []string
+	var forms []curlFormField
+	var upload string
+	getWithData := false
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		// next 取当前参数的值，兼容 -XPOST / --request=POST 这种紧贴写法
+		next := func(short, long string) (string, bool) {
+			switch {
+			case arg == short || arg == long:
+				if i+1 >= len(args) {
+					return "", false
+				}
+				i++
+				return args[i], true
+			case short != "" && strings.HasPrefix(arg, short) && !strings.HasPrefix(arg, "--"):
+				return arg[len(short):], true
+			case strings.HasPrefix(arg, long+"="):
+				return arg[len(long)+1:], true
+			}
+			return "", false
+		}
+
+		if v, ok := next("-X", "--request"); ok {
+			spec.Method = strings.ToUpper(v)
+		} else if v, ok := next("-H", "--header"); ok {
+			name, value, found := strings.Cut(v, ":")
+			if found {
+				spec.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+			}
+		} else if v, ok := next("-d", "--data"); ok {
+			body, err := curlDataValue(v)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, body)
+		} else if v, ok := next("", "--data-raw"); ok {
+			data = append(data, v)
+		} else if v, ok := next("", "--data-binary"); ok {
+			body, err := curlDataValue(v)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, body)
+		} else if v, ok := next("", "--data-ascii"); ok {
+			data = append(data, v)
+		} else if v, ok := next("", "--data-urlencode"); ok {
+			if name, value, found := strings.Cut(v, "="); found {
+				data = append(data, name+"="+url.QueryEscape(value))
+			} else {
+				data = append(data, url.QueryEscape(v))
+			}
+		} else if v, ok := next("", "--json"); ok {
+			data = append(data, v)
+			spec.Headers.Set("Content-Type", "application/json")
+			spec.Headers.Set("Accept", "application/json")
+		} else if v, ok := next("-F", "--form"); ok {
+			forms = append(forms, curlFormField{spec: v})
+		} else if v, ok := next("", "--form-string"); ok {
+			forms = append(forms, curlFormField{spec: v, literal: true})
+		} else if v, ok := next("-T", "--upload-file"); ok {
+			upload = v
+		} else if v, ok := next("-r", "--range"); ok {
+			spec.Headers.Set("Range", "bytes="+v)
+		} else if v, ok := next("", "--oauth2-bearer"); ok {
+			spec.Headers.Set("Authorization", "Bearer "+v)
+		} else if v, ok := next("-u", "--user"); ok {
+			spec.Headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+		} else if v, ok := next("-A", "--user-agent"); ok {
+			spec.Headers.Set("User-Agent", v)
+		} else if v, ok := next("-b", "--cookie"); ok {
+			spec.Headers.Set("Cookie", v)
+		} else if v, ok := next("-e", "--referer"); ok {
+			spec.Headers.Set("Referer", v)
+		} else if v, ok := next("", "--url"); ok {
+			spec.URL = v
+		} else if arg == "-G" || arg == "--get" {
+			getWithData = true
+		} else if arg == "-I" || arg == "--head" {
+			spec.Method = http.MethodHead
+		} else if curlValueFlags[arg] {
+			// 带值但与请求内容无关的参数，跳过其取值
+			i++
+		} else if !strings.HasPrefix(arg, "-") && spec.URL == "" {
+			spec.URL = arg
+		}
+	}
+	if spec.URL == "" {
+		return nil, errors.New("no URL found in curl command")
+	}
+	if !strings.Contains(spec.URL, "://") {
+		// 与 curl 一致：未指定协议时默认使用 http
+		spec.URL = "http://" + spec.URL
+	}
+
+	bodies := 0
+	for _, set := range []bool{len(data) > 0 && !getWithData, len(forms) > 0, upload != ""} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return nil, errors.New("only one of -d, -F and -T can set the request body")
+	}
+	if len(data) > 0 {
+		joined := strings.Join(data, "&")
+		if getWithData {
+			sep := "?"
+			if strings.Contains(spec.URL, "?") {
+				sep = "&"
+			}
+			spec.URL += sep + joined
+		} else {
+			spec.Body = joined
+			if spec.Headers.Get("Content-Type") == "" {
+				// 与 curl 一致：-d 默认以表单方式提交
+				spec.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+	}
+	if len(forms) > 0 {
+		body, contentType, err := curlMultipartBody(forms)
+		if err != nil {
+			return nil, err
+		}
+		// 与 curl 一致：-F 以 multipart/form-data 提交，Content-Type 带上实际使用的 boundary
+		spec.Body = body
+		spec.Headers.Set("Content-Type", contentType)
+	}
+	if upload != "" {
+		body, err := curlDataValue("@" + upload)
+		if err != nil {
+			return nil, err
+		}
+		spec.Body = body
+		if spec.Method == "" {
+			// 与 curl 一致：-T 以 PUT 上传文件，URL 以 / 结尾时追加文件名
+			spec.Method = http.MethodPut
+		}
+		if strings.HasSuffix(spec.URL, "/") {
+			spec.URL += filepath.Base(upload)
+		}
+	}
+	if spec.Method == "" {
+		spec.Method = http.MethodGet
+		if spec.Body != "" {
+			spec.Method = http.MethodPost
+		}
+	}
+	return spec, nil
+}
+
+// curlValueFlags 为需要跳过取值的 curl 参数
+var curlValueFlags = map[string]bool{
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+	"-m": true, "--max-time": true, "--connect-timeout": true,
+	"-x": true, "--proxy": true, "--cacert": true, "--cert": true, "--key": true,
+	"--retry": true, "--resolve": true, "-c": true, "--cookie-jar": true,
+	"--max-redirs": true, "--limit-rate": true, "--proxy-user": true,
+}
+
+// curlFormField 为 -F 或 --form-string 指定的一个表单字段，literal 为 true（--form-string）时取值不解析 @ 与 < 前缀
+type curlFormField struct {
+	spec    string
+	literal bool
+}
+
+// curlMultipartBody 按 curl 的 -F 规则把表单字段编码为 multipart/form-data 请求体：name=value 为普通字段，
+// name=@file 上传文件，name=<file 以文件内容作为字段值；文件后可跟 ;type=... 与 ;filename=... 。
+// 返回请求体与带 boundary 的 Content-Type
+func curlMultipartBody(fields []curlFormField) (string, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, field := range fields {
+		name, value, found := strings.Cut(field.spec, "=")
+		if !found || name == "" {
+			return "", "", fmt.Errorf("invalid -F value %q, expected name=value", field.spec)
+		}
+		if field.literal || (!strings.HasPrefix(value, "@") && !strings.HasPrefix(value, "<")) {
+			if err := w.WriteField(name, value); err != nil {
+				return "", "", err
+			}
+			continue
+		}
+		params := strings.Split(value[1:], ";")
+		content, err := ioutil.ReadFile(params[0])
+		if err != nil {
+			return "", "", err
+		}
+		header := textproto.MIMEHeader{}
+		filename := filepath.Base(params[0])
+		for _, param := range params[1:] {
+			key, v, _ := strings.Cut(param, "=")
+			switch strings.TrimSpace(key) {
+			case "type":
+				header.Set("Content-Type", v)
+			case "filename":
+				filename = strings.Trim(v, `"`)
+			}
+		}
+		disposition := fmt.Sprintf(`form-data; name="%s"`, multipartEscape(name))
+		if value[0] == '@' {
+			disposition += fmt.Sprintf(`; filename="%s"`, multipartEscape(filename))
+			if header.Get("Content-Type") == "" {
+				contentType := mime.TypeByExtension(filepath.Ext(filename))
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				header.Set("Content-Type", contentType)
+			}
+		}
+		header.Set("Content-Disposition", disposition)
+		part, err := w.CreatePart(header)
+		if err != nil {
+			return "", "", err
+		}
+		part.Write(content)
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+	return buf.String(), w.FormDataContentType(), nil
+}
+
+// multipartEscape 转义 Content-Disposition 中带引号的取值，与 mime/multipart 的处理一致
+var multipartEscape = strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace
+
+// curlDataValue 处理 curl 的 @file 写法，从文件中读取 body
+func curlDataValue(v string) (string, error) {
+	if !strings.HasPrefix(v, "@") {
+		return v, nil
+	}
+	data, err := ioutil.ReadFile(v[1:])
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// splitShellWords 按 shell 规则切分命令行：支持单引号、双引号、$'...'（浏览器“Copy as cURL”常用）、反斜杠转义与行尾续行符
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			end, err := readANSIQuoted(s, i+2, &cur)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			inWord = true
+		case c == '\\' && i+1 < len(s):
+			i++
+			if s[i] != '\n' {
+				cur.WriteByte(s[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// ansiEscapes 为 $'...' 中单字符转义对应的字节
+var ansiEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'e': 0x1b, 'E': 0x1b, 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'\\': '\\', '\'': '\'', '"': '"', '?': '?',
+}
+
+// readANSIQuoted 按 bash 的 $'...' 规则解码从 s[i] 开始、到下一个未转义单引号为止的内容并写入 cur，
+// 支持 \n、\t 等单字符转义、\nnn 八进制、\xHH、\uHHHH、\UHHHHHHHH 与 \cX；返回结束单引号的位置
+func readANSIQuoted(s string, i int, cur *strings.Builder) (int, error) {
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' {
+			return i, nil
+		}
+		if c != '\\' || i+1 >= len(s) {
+			cur.WriteByte(c)
+			continue
+		}
+		i++
+		e := s[i]
+		if b, ok := ansiEscapes[e]; ok {
+			cur.WriteByte(b)
+			continue
+		}
+		// digits 读取最多 n 位八进制或十六进制数字，返回其值与位数
+		digits := func(from, n, base int) (uint64, int) {
+			valid := "01234567"
+			if base == 16 {
+				valid = "0123456789abcdefABCDEF"
+			}
+			end := from
+			for end < len(s) && end-from < n && strings.IndexByte(valid, s[end]) >= 0 {
+				end++
+			}
+			if end == from {
+				return 0, 0
+			}
+			v, _ := strconv.ParseUint(s[from:end], base, 64)
+			return v, end - from
+		}
+		switch {
+		case e >= '0' && e <= '7':
+			v, n := digits(i, 3, 8)
+			cur.WriteByte(byte(v))
+			i += n - 1
+		case e == 'x':
+			if v, n := digits(i+1, 2, 16); n > 0 {
+				cur.WriteByte(byte(v))
+				i += n
+				continue
+			}
+			cur.WriteString(`\x`)
+		case e == 'u' || e == 'U':
+			width := 4
+			if e == 'U' {
+				width = 8
+			}
+			if v, n := digits(i+1, width, 16); n > 0 && utf8.ValidRune(rune(v)) {
+				cur.WriteRune(rune(v))
+				i += n
+				continue
+			}
+			cur.WriteByte('\\')
+			cur.WriteByte(e)
+		case e == 'c' && i+1 < len(s):
+			i++
+			cur.WriteByte(s[i] & 0x1f)
+		default:
+			cur.WriteByte('\\')
+			cur.WriteByte(e)
+		}
+	}
+	return 0, errors.New("unterminated $'...' quote")
+}
+
+// loadCurlFile 读取包含多条 curl 命令的文件（以反斜杠续行，空行与 # 开头的行被忽略），
+// 解析后追加到 requestPool，返回加载的请求数
+func loadCurlFile(filename string) int {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		slog.Error("Unable to read curl file", "err", err)
+		return 0
+	}
+	loaded := 0
+	var command strings.Builder
+	flush := func() {
+		text := strings.TrimSpace(command.String())
+		command.Reset()
+		if text == "" {
+			return
+		}
+		spec, err := parseCurlCommand(text)
+		if err != nil {
+			slog.Error("Unable to parse curl command", "command", text, "err", err)
+			return
+		}
+		requestPool = append(requestPool, spec)
+		loaded++
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if command.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		if strings.HasSuffix(trimmed, "\\") {
+			command.WriteString(strings.TrimSuffix(trimmed, "\\") + " ")
+			continue
+		}
+		command.WriteString(trimmed)
+		flush()
+	}
+	flush()
+	return loaded
+}
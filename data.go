@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// dataRows 为 -datafile 加载的数据行，列名即模板中的字段名，如 {{.username}}
+var dataRows []map[string]string
+
+// dataCursor 为 shared 分配方式下所有 worker 共用的游标
+var dataCursor int64
+
+// dataDistribution 为数据行在 worker 之间的分配方式：
+//   - shared       所有 worker 共用一个游标按顺序取行，全部取完后才会重复
+//   - partition    每个 worker 只使用互不重叠的一段数据（例如不会有两个 worker 用同一个账号登录）
+//   - per-vu-copy  每个 worker 各自从头按顺序使用完整的数据
+var dataDistribution string
+
+// loadDataFile 读取 CSV（首行为列名）或 JSON（对象数组）数据文件，返回加载的行数
+func loadDataFile(filename string) int {
+	f, err := os.Open(filename)
+	if err != nil {
+		slog.Error("Unable to read data file", "err", err)
+		return 0
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		var objects []map[string]interface{}
+		if err := json.NewDecoder(f).Decode(&objects); err != nil {
+			slog.Error("Unable to parse data file", "err", err)
+			return 0
+		}
+		for _, obj := range objects {
+			row := make(map[string]string, len(obj))
+			for k, v := range obj {
+				if s, ok := v.(string); ok {
+					row[k] = s
+					continue
+				}
+				// 非字符串值按 JSON 原样写入模板，便于直接嵌入请求体
+				encoded, _ := json.Marshal(v)
+				row[k] = string(encoded)
+			}
+			dataRows = append(dataRows, row)
+		}
+		return len(dataRows)
+	}
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		slog.Error("Unable to parse data file", "err", err)
+		return 0
+	}
+	if len(records) < 2 {
+		return 0
+	}
+	header := records[0]
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(name)] = record[i]
+			}
+		}
+		dataRows = append(dataRows, row)
+	}
+	return len(dataRows)
+}
+
+// compileRequestTemplates 为 requestPool 与 -scenarios 中包含 {{ 的 URL、请求体与 header 编译模板；未指定 URL 的请求使用 defaultURL 作为模板。
+// strict 为 true（指定了 -datafile）时模板有误即返回错误，否则无法解析的文本（如 Postman 中未定义的 {{variable}}）按原文发送
+func compileRequestTemplates(defaultURL string, strict bool) error {
+	specs := append([]*requestSpec{emptyRequest}, requestPool...)
+	specs = append(specs, scenarioRequestSpecs()...)
+	for _, spec := range specs {
+		if spec.URL == "" && strings.Contains(defaultURL, "{{") {
+			spec.URL = defaultURL
+		}
+		var err error
+		if spec.urlTpl, err = compileTemplate(spec.URL); err != nil && strict {
+			return fmt.Errorf("invalid URL template %q: %v", spec.URL, err)
+		}
+		if spec.bodyTpl, err = compileTemplate(spec.Body); err != nil && strict {
+			return fmt.Errorf("invalid body template: %v", err)
+		}
+		if err := compileHeaderTemplates(spec, strict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderSpec 用数据行渲染第 n 个请求的 URL、请求体、header 以及 -param、-H，并按 -user-agents 为它选择 User-Agent，
+// 返回渲染后的副本；模板函数与 User-Agent 的随机数由 n 决定。不含模板的请求原样返回
+func renderSpec(spec *requestSpec, row map[string]string, n int64) *requestSpec {
+	if spec.urlTpl == nil && spec.bodyTpl == nil && spec.headerTpls == nil && len(queryParams) == 0 && len(extraHeaders) == 0 && len(userAgents) == 0 {
+		return spec
+	}
+	rendered := *spec
+	if len(userAgents) > 0 {
+		rendered.UserAgent = pickUserAgent(requestRand(n, randUserAgent))
+	}
+	rendered.URL = renderTemplate(spec.urlTpl, spec.URL, row, n)
+	if spec.urlTpl != nil {
+		rendered.URLTemplate = spec.URL
+	}
+	rendered.Body = renderTemplate(spec.bodyTpl, spec.Body, row, n)
+	rendered.urlTpl, rendered.bodyTpl = nil, nil
+	rendered.Query = queryParams.render(row, n)
+	if spec.headerTpls != nil || len(extraHeaders) > 0 {
+		rendered.Headers = renderHeaders(spec, row, n)
+		rendered.headerTpls = nil
+	}
+	return &rendered
+}
+
+// checkDataDistribution 校验分配方式；partition 要求每个 worker 至少分到一行
+func checkDataDistribution(workers int) error {
+	switch dataDistribution {
+	case "shared", "per-vu-copy":
+		return nil
+	case "partition":
+		if len(dataRows) < workers {
+			return fmt.Errorf("-data-distribution partition needs at least one row per worker (%d rows, %d workers)", len(dataRows), workers)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown -data-distribution %q (expected partition, shared or per-vu-copy)", dataDistribution)
+	}
+}
+
+// dataSource 为某个 worker 取用数据行的来源
+type dataSource struct {
+	rows []map[string]string
+	// next 为 worker 私有的游标，shared 分配方式下不使用
+	next int
+}
+
+// newDataSource 按 dataDistribution 为第 worker 个（共 workers 个）worker 创建数据来源；未加载数据时返回 nil
+func newDataSource(worker, workers int) *dataSource {
+	if len(dataRows) == 0 {
+		return nil
+	}
+	if dataDistribution == "partition" {
+		start := worker * len(dataRows) / workers
+		end := (worker + 1) * len(dataRows) / workers
+		return &dataSource{rows: dataRows[start:end]}
+	}
+	return &dataSource{rows: dataRows}
+}
+
+// row 返回下一行数据，用完后从头循环
+func (d *dataSource) row() map[string]string {
+	if d == nil {
+		return nil
+	}
+	if dataDistribution == "shared" {
+		i := atomic.AddInt64(&dataCursor, 1) - 1
+		return d.rows[i%int64(len(d.rows))]
+	}
+	row := d.rows[d.next%len(d.rows)]
+	d.next++
+	return row
+}
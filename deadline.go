@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// requestDeadline 为 -request-deadline：完整耗时超过它的成功响应记为“迟到的成功”，既不计入成功也不计入失败，
+// 模拟自身带超时的上游调用方看到的结果——它已放弃等待，但目标仍完成了这个请求。请求本身不会被中断
+var requestDeadline time.Duration
+
+// globalLateRequests 为迟到的成功请求数，与 globalSuccessRequests、globalFailedRequests 一起用于进度显示
+var globalLateRequests int64
+
+// isLate 返回完整耗时为 d 的成功响应是否超过了 -request-deadline
+func isLate(d time.Duration) bool {
+	return requestDeadline > 0 && d > requestDeadline
+}
+
+// lateSummary 为写入 JSON 汇总的迟到成功统计，时延为完整请求耗时
+type lateSummary struct {
+	DeadlineMs float64 `json:"deadline_ms"`
+	Late       int64   `json:"late_successes"`
+	// Share 为迟到的成功占全部请求的百分比
+	Share float64 `json:"share"`
+	P50Ms float64 `json:"p50_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// summarizeLate 汇总迟到的成功请求，会对样本原地排序；total 为全部请求数。未设置 -request-deadline 时返回 nil
+func summarizeLate(s *URLStats, total int64) *lateSummary {
+	if requestDeadline <= 0 {
+		return nil
+	}
+	times := s.ResponseTimes
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	summary := &lateSummary{
+		DeadlineMs: durationMs(requestDeadline),
+		Late:       s.TotalRequests,
+		P50Ms:      durationMs(percentile(times, 50)),
+		P99Ms:      durationMs(percentile(times, 99)),
+		MaxMs:      durationMs(percentile(times, 100)),
+	}
+	if total > 0 {
+		summary.Share = float64(s.TotalRequests) / float64(total) * 100
+	}
+	return summary
+}
+
+// reportLate 输出迟到的成功请求数及其完整耗时
+func reportLate(s *lateSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("\n⏰  Request Deadline (%.0f ms): %d late successes (%.2f%% of all requests)\n", s.DeadlineMs, s.Late, s.Share)
+	if s.Late > 0 {
+		fmt.Printf("  - Total time of late responses: P50 %.1f ms, P99 %.1f ms, max %.1f ms\n", s.P50Ms, s.P99Ms, s.MaxMs)
+		fmt.Println("  - Late successes count neither as success nor as failure, as an upstream with this timeout would already have given up on them")
+	}
+}
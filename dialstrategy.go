@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// dialStrategy、fallbackDelay 为 -dial-strategy 与 -fallback-delay：parallel 为默认的 Happy Eyeballs（RFC 6555），
+// 首选地址族（通常是 IPv6）的连接 fallbackDelay 内未建立时并行尝试另一地址族；sequential 关闭 Happy Eyeballs，
+// 按解析结果的顺序逐个尝试地址，前一个失败或超时才尝试下一个，使 IPv6 路径的问题体现在建连耗时与失败中，而不被回退掩盖
+var (
+	dialStrategy  string
+	fallbackDelay time.Duration
+	// dialStrategySet 为是否设置了 -dial-strategy 或 -fallback-delay，此时统计新连接的地址族
+	dialStrategySet bool
+)
+
+// dialIPv4、dialIPv6、dialFailures 为设置 -dial-strategy 或 -fallback-delay 时经 IPv4、IPv6 建立的连接数与建连失败数；
+// attemptsIPv4、attemptsIPv6 为对各地址族的地址发起的连接尝试数，多出连接数的部分为失败、超时或在 Happy Eyeballs 中被放弃的尝试
+var dialIPv4, dialIPv6, dialFailures, attemptsIPv4, attemptsIPv6 int64
+
+// checkDialStrategy 检查 -dial-strategy 与 -fallback-delay
+func checkDialStrategy() error {
+	switch dialStrategy {
+	case "parallel", "sequential":
+	default:
+		return fmt.Errorf("unknown -dial-strategy %q (expected parallel or sequential)", dialStrategy)
+	}
+	if fallbackDelay < 0 {
+		return fmt.Errorf("-fallback-delay must not be negative")
+	}
+	if fallbackDelay > 0 && dialStrategy == "sequential" {
+		return fmt.Errorf("-fallback-delay only applies to -dial-strategy parallel")
+	}
+	dialStrategySet = isFlagSet("dial-strategy") || isFlagSet("fallback-delay")
+	return nil
+}
+
+// dialFallbackDelay 返回 net.Dialer 的 FallbackDelay：负数关闭 Happy Eyeballs，0 为 Go 的默认值 300ms
+func dialFallbackDelay() time.Duration {
+	if dialStrategy == "sequential" {
+		return -1
+	}
+	return fallbackDelay
+}
+
+// recordDial 统计一次建连使用的地址族或失败；未设置 -dial-strategy 与 -fallback-delay 时不做任何事
+func recordDial(conn net.Conn, err error) {
+	if !dialStrategySet {
+		return
+	}
+	if err != nil {
+		atomic.AddInt64(&dialFailures, 1)
+		return
+	}
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok && addr.IP.To4() == nil {
+		atomic.AddInt64(&dialIPv6, 1)
+	} else {
+		atomic.AddInt64(&dialIPv4, 1)
+	}
+}
+
+// recordDialAttempt 统计一次对 network（tcp4 或 tcp6）地址的连接尝试
+func recordDialAttempt(network string) {
+	if !dialStrategySet {
+		return
+	}
+	if network == "tcp6" {
+		atomic.AddInt64(&attemptsIPv6, 1)
+	} else {
+		atomic.AddInt64(&attemptsIPv4, 1)
+	}
+}
+
+// describeDialStrategy 返回启动时输出的建连策略说明
+func describeDialStrategy() string {
+	if dialStrategy == "sequential" {
+		return "sequential (Happy Eyeballs disabled, addresses tried one at a time)"
+	}
+	delay := fallbackDelay
+	if delay == 0 {
+		delay = 300 * time.Millisecond
+	}
+	return fmt.Sprintf("parallel (Happy Eyeballs, fallback to the other address family after %s)", delay)
+}
+
+// reportDialStrategy 输出各地址族的连接尝试与建立的连接数，以及建连失败数
+func reportDialStrategy() {
+	if !dialStrategySet {
+		return
+	}
+	fmt.Printf("\n🛣️   Dial Strategy (%s):\n", dialStrategy)
+	for _, family := range []struct {
+		name               string
+		attempts, connects int64
+	}{
+		{"IPv6", atomic.LoadInt64(&attemptsIPv6), atomic.LoadInt64(&dialIPv6)},
+		{"IPv4", atomic.LoadInt64(&attemptsIPv4), atomic.LoadInt64(&dialIPv4)},
+	} {
+		if family.attempts == 0 {
+			continue
+		}
+		fmt.Printf("  - %s: %d attempts, %d connected", family.name, family.attempts, family.connects)
+		if lost := family.attempts - family.connects; lost > 0 {
+			fmt.Printf(", %d failed, timed out or abandoned", lost)
+		}
+		fmt.Println()
+	}
+	if failed := atomic.LoadInt64(&dialFailures); failed > 0 {
+		fmt.Printf("  - %d dials failed on every address\n", failed)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math/bits"
+	"time"
+)
+
+// latencyDigest 为可合并的对数-线性时延直方图（单位微秒）：128µs 以下每微秒一个桶，
+// 之上每个 2 的幂区间再均分为 digestSubBuckets 个桶，相对误差不超过 1/128。
+// 合并只需逐桶相加，周期输出的开销与运行时长无关
+type latencyDigest struct {
+	counts []int64
+	total  int64
+}
+
+const (
+	digestSubBuckets = 64
+	digestLinear     = 2 * digestSubBuckets
+	// digestBuckets 覆盖到 2^41µs（约 25 天）
+	digestBuckets = digestLinear + 34*digestSubBuckets
+)
+
+// digestBucket 返回 us 微秒所在的桶
+func digestBucket(us int64) int {
+	if us < digestLinear {
+		if us < 0 {
+			return 0
+		}
+		return int(us)
+	}
+	shift := bits.Len64(uint64(us)) - 7
+	idx := digestLinear + (shift-1)*digestSubBuckets + int(us>>shift) - digestSubBuckets
+	if idx >= digestBuckets {
+		return digestBuckets - 1
+	}
+	return idx
+}
+
+// digestValue 返回桶的代表值（区间中点）
+func digestValue(idx int) time.Duration {
+	if idx < digestLinear {
+		return time.Duration(idx) * time.Microsecond
+	}
+	shift := (idx-digestLinear)/digestSubBuckets + 1
+	low := int64((idx-digestLinear)%digestSubBuckets+digestSubBuckets) << shift
+	return time.Duration(low+(int64(1)<<shift)/2) * time.Microsecond
+}
+
+// record 记录一个时延
+func (d *latencyDigest) record(v time.Duration) {
+	if d.counts == nil {
+		d.counts = make([]int64, digestBuckets)
+	}
+	d.counts[digestBucket(v.Microseconds())]++
+	d.total++
+}
+
+// add 合并另一份直方图
+func (d *latencyDigest) add(other latencyDigest) {
+	if other.total == 0 {
+		return
+	}
+	if d.counts == nil {
+		d.counts = make([]int64, digestBuckets)
+	}
+	for i, n := range other.counts {
+		d.counts[i] += n
+	}
+	d.total += other.total
+}
+
+// percentile 按与 percentile 相同的取整规则返回近似的百分位
+func (d *latencyDigest) percentile(percent float64) time.Duration {
+	if d.total == 0 {
+		return 0
+	}
+	rank := int64(float64(d.total) * percent / 100)
+	if rank >= d.total {
+		rank = d.total - 1
+	}
+	var seen int64
+	for i, n := range d.counts {
+		seen += n
+		if seen > rank {
+			return digestValue(i)
+		}
+	}
+	return digestValue(digestBuckets - 1)
+}
+
+// sparseDigest 为直方图的紧凑编码：只保留非空桶的 [下标, 次数]，用于在进程之间传输
+type sparseDigest struct {
+	Total   int64      `json:"total"`
+	Buckets [][2]int64 `json:"buckets"`
+}
+
+// sparse 返回直方图的紧凑编码
+func (d *latencyDigest) sparse() sparseDigest {
+	s := sparseDigest{Total: d.total, Buckets: [][2]int64{}}
+	for i, n := range d.counts {
+		if n != 0 {
+			s.Buckets = append(s.Buckets, [2]int64{int64(i), n})
+		}
+	}
+	return s
+}
+
+// digest 还原为直方图，越界的下标计入最后一个桶
+func (s sparseDigest) digest() latencyDigest {
+	var d latencyDigest
+	for _, b := range s.Buckets {
+		if d.counts == nil {
+			d.counts = make([]int64, digestBuckets)
+		}
+		idx := int(b[0])
+		if idx < 0 {
+			idx = 0
+		} else if idx >= digestBuckets {
+			idx = digestBuckets - 1
+		}
+		d.counts[idx] += b[1]
+		d.total += b[1]
+	}
+	return d
+}
@@ -0,0 +1,703 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// 分布式模式：一个 controller 与任意多个 worker（如 Kubernetes Deployment 的各个副本），worker 主动连接 controller，
+// 因此扩缩副本数即可改变压测的规模，不需要维护 worker 地址列表
+var (
+	// controllerAddr 为 -controller，非空时作为 controller 在该地址上等待 worker 注册并分发压测
+	controllerAddr string
+	// expectWorkers 为 -expect-workers，controller 在注册的 worker 达到该数量后开始压测
+	expectWorkers int
+	// workerMode、joinAddr 为 -worker 与 -join，以 worker 身份连接 controller 并执行分到的压测
+	workerMode bool
+	joinAddr   string
+	// workerLabels 为 -label 指定的 worker 标签（如 region=eu-west-1），controller 按标签分组汇总
+	workerLabels = map[string]string{}
+)
+
+// labelFlags 实现 flag.Value，允许多次指定 -label key=value，写入 workerLabels
+type labelFlags struct{}
+
+func (labelFlags) String() string {
+	return formatTags(workerLabels)
+}
+
+func (labelFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	key := strings.TrimSpace(kv[0])
+	if len(kv) != 2 || key == "" {
+		return fmt.Errorf("invalid label %q, expected key=value", value)
+	}
+	workerLabels[key] = strings.TrimSpace(kv[1])
+	return nil
+}
+
+const (
+	// workerPollInterval 为 worker 轮询任务的间隔，轮询同时作为心跳
+	workerPollInterval = time.Second
+	// workerTimeout 为 controller 认为 worker 已离开的心跳超时
+	workerTimeout = 10 * time.Second
+	// maxPendingIntervals 为 worker 在 controller 不可用时最多缓存的窗口数，超出后丢弃最早的窗口
+	maxPendingIntervals = 3600
+	// controllerProgressEvery 为 controller 输出合并进度的间隔（轮询次数）
+	controllerProgressEvery = 5
+)
+
+// distAssignment 为 controller 分给一个 worker 的压测
+type distAssignment struct {
+	Run  int      `json:"run"`
+	Args []string `json:"args"`
+}
+
+// distResult 为 worker 上报的压测结果，Result 为子进程 -format json 的输出，Intervals 为子进程输出的窗口数
+type distResult struct {
+	Run       int             `json:"run"`
+	ExitCode  int             `json:"exit_code"`
+	Result    json.RawMessage `json:"result"`
+	Intervals int             `json:"intervals"`
+}
+
+// distIntervals 为 worker 上报的一批窗口记录（gzip 压缩），First 为第一条的序号，重发的窗口由 controller 去重
+type distIntervals struct {
+	Run       int               `json:"run"`
+	First     int               `json:"first"`
+	Intervals []json.RawMessage `json:"intervals"`
+}
+
+// distRegistration 为 worker 注册时发送的信息
+type distRegistration struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// distWorker 为 controller 记录的一个 worker
+type distWorker struct {
+	ID         string
+	labels     map[string]string
+	lastSeen   time.Time
+	assignment *distAssignment
+	result     *distResult
+
+	// 以下为本次压测中收到的窗口：next 为期望的下一个序号，missing 为缺失（worker 缓存溢出或未送达）的窗口数
+	next, missing     int
+	digest            latencyDigest
+	requests, success int64
+	firstData         time.Time
+	lastData          time.Time
+}
+
+// distController 维护注册的 worker 与当前压测的分配情况
+type distController struct {
+	mu      sync.Mutex
+	workers map[string]*distWorker
+	run     int
+}
+
+// strippedFlags 为 controller 转发参数时去掉的选项，均带有取值；-n、-c、-rate 按 worker 数重新分配
+var strippedFlags = map[string]bool{"controller": true, "expect-workers": true, "n": true, "c": true, "rate": true, "format": true, "o": true, "interval-stream": true, "label": true}
+
+// stripFlags 从命令行参数中去掉 names 中的选项及其取值，支持 -name value、-name=value 与 --name 形式
+func stripFlags(args []string, names map[string]bool) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			kept = append(kept, arg)
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		hasValue := strings.Contains(name, "=")
+		name = strings.SplitN(name, "=", 2)[0]
+		if !names[name] {
+			kept = append(kept, arg)
+			continue
+		}
+		if !hasValue {
+			i++
+		}
+	}
+	return kept
+}
+
+// shardArgs 返回第 i 个（共 n 个）worker 的参数：-n 与 -c 平均分配（余数分给前面的 worker，-c 至少为 1），-rate 均分
+func shardArgs(base []string, i, n, concurrency, totalRequests int, rate float64) []string {
+	args := append([]string(nil), base...)
+	requests := totalRequests / n
+	if i < totalRequests%n {
+		requests++
+	}
+	workers := concurrency / n
+	if i < concurrency%n {
+		workers++
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	args = append(args, "-n", strconv.Itoa(requests), "-c", strconv.Itoa(workers))
+	if rate > 0 {
+		args = append(args, "-rate", strconv.FormatFloat(rate/float64(n), 'f', -1, 64))
+	}
+	return append(args, "-format", "json")
+}
+
+// live 返回心跳未超时的 worker，按 ID 排序；须持有 c.mu
+func (c *distController) live() []*distWorker {
+	var workers []*distWorker
+	for _, w := range c.workers {
+		if time.Since(w.lastSeen) < workerTimeout {
+			workers = append(workers, w)
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers
+}
+
+// runController 等待 expectWorkers 个 worker 注册后，把 -n、-c、-rate 按当时在线的 worker 数分配并下发，
+// 所有 worker 上报结果（或心跳超时）后输出汇总；本次未分到任务的 worker 不参与
+func runController(addr string, concurrency, totalRequests int, rate float64) {
+	c := &distController{workers: make(map[string]*distWorker)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /workers/{id}", c.handleRegister)
+	mux.HandleFunc("GET /workers/{id}/assignment", c.handleAssignment)
+	mux.HandleFunc("POST /workers/{id}/intervals", c.handleIntervals)
+	mux.HandleFunc("POST /workers/{id}/result", c.handleResult)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+	}()
+	fmt.Printf("🛰️   Controller listening on %s, waiting for %d workers\n", addr, expectWorkers)
+
+	var workers []*distWorker
+	for {
+		time.Sleep(workerPollInterval)
+		c.mu.Lock()
+		workers = c.live()
+		if len(workers) >= expectWorkers {
+			break
+		}
+		c.mu.Unlock()
+	}
+	base := stripFlags(os.Args[1:], strippedFlags)
+	c.run++
+	for i, w := range workers {
+		w.assignment = &distAssignment{Run: c.run, Args: shardArgs(base, i, len(workers), concurrency, totalRequests, rate)}
+		*w = distWorker{ID: w.ID, labels: w.labels, lastSeen: w.lastSeen, assignment: w.assignment}
+	}
+	c.mu.Unlock()
+	fmt.Printf("🚀  Started run on %d workers\n", len(workers))
+
+	for polls := 1; ; polls++ {
+		time.Sleep(workerPollInterval)
+		c.mu.Lock()
+		pending := 0
+		var merged latencyDigest
+		var requests int64
+		for _, w := range workers {
+			if w.result == nil && time.Since(w.lastSeen) < workerTimeout {
+				pending++
+			}
+			merged.add(w.digest)
+			requests += w.requests
+		}
+		c.mu.Unlock()
+		if pending == 0 {
+			break
+		}
+		if polls%controllerProgressEvery == 0 {
+			fmt.Printf("📈  %d requests, P50 %s, P95 %s, P99 %s, %d of %d workers running\n", requests,
+				merged.percentile(50), merged.percentile(95), merged.percentile(99), pending, len(workers))
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !reportDistributed(workers) {
+		exit(1)
+	}
+}
+
+func (c *distController) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var reg distRegistration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := r.PathValue("id")
+	worker, ok := c.workers[id]
+	if !ok {
+		worker = &distWorker{ID: id}
+		c.workers[id] = worker
+		if len(reg.Labels) > 0 {
+			fmt.Printf("➕  Worker %s joined (%s)\n", id, formatTags(reg.Labels))
+		} else {
+			fmt.Printf("➕  Worker %s joined\n", id)
+		}
+	}
+	worker.labels = reg.Labels
+	worker.lastSeen = time.Now()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAssignment 返回 worker 的当前任务，没有任务时返回 204；未注册的 worker 返回 404，须重新注册
+func (c *distController) handleAssignment(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	worker, ok := c.workers[r.PathValue("id")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	worker.lastSeen = time.Now()
+	if worker.assignment == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, worker.assignment)
+}
+
+// handleIntervals 合并 worker 上报的窗口直方图，序号跳过的窗口记为缺失
+func (c *distController) handleIntervals(w http.ResponseWriter, r *http.Request) {
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+	var batch distIntervals
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	worker, ok := c.workers[r.PathValue("id")]
+	if !ok || worker.assignment == nil || worker.assignment.Run != batch.Run {
+		writeError(w, http.StatusConflict, fmt.Errorf("no matching assignment"))
+		return
+	}
+	worker.lastSeen = time.Now()
+	for i, raw := range batch.Intervals {
+		seq := batch.First + i
+		if seq < worker.next {
+			continue
+		}
+		var rec intervalRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			continue
+		}
+		worker.missing += seq - worker.next
+		worker.next = seq + 1
+		worker.digest.add(rec.Digest.digest())
+		worker.requests += rec.Requests
+		worker.success += rec.Success
+		if worker.firstData.IsZero() {
+			worker.firstData = rec.Start
+		}
+		worker.lastData = rec.End
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *distController) handleResult(w http.ResponseWriter, r *http.Request) {
+	var result distResult
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	worker, ok := c.workers[r.PathValue("id")]
+	if !ok || worker.assignment == nil || worker.assignment.Run != result.Run {
+		writeError(w, http.StatusConflict, fmt.Errorf("no matching assignment"))
+		return
+	}
+	worker.lastSeen = time.Now()
+	worker.result = &result
+	worker.assignment = nil
+	if result.Intervals > worker.next {
+		worker.missing += result.Intervals - worker.next
+		worker.next = result.Intervals
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// distTotals 为一个或一组 worker 的合并结果
+type distTotals struct {
+	workers          int
+	requests, failed int64
+	tps              float64
+	digest           latencyDigest
+}
+
+// add 合并另一份结果
+func (t *distTotals) add(other *distTotals) {
+	t.workers += other.workers
+	t.requests += other.requests
+	t.failed += other.failed
+	t.tps += other.tps
+	t.digest.add(other.digest)
+}
+
+// row 返回表格中的计数、TPS 与百分位列
+func (t *distTotals) row() []string {
+	format := func(d time.Duration) string { return fmt.Sprintf("%.1f ms", float64(d.Microseconds())/1000) }
+	return []string{fmt.Sprintf("%d", t.requests), fmt.Sprintf("%d", t.failed), fmt.Sprintf("%.2f", t.tps),
+		format(t.digest.percentile(50)), format(t.digest.percentile(95)), format(t.digest.percentile(99))}
+}
+
+// reportDistributed 输出每个 worker 的结果、按 -label 分组与合并后的总数，返回是否所有 worker 都正常完成；
+// 百分位由各 worker 上报的窗口直方图逐桶合并后计算，与单机压测的统计方式相同。
+// 缺失窗口或中途失联的 worker 的数据只覆盖收到的部分，在 Data 列中注明
+func reportDistributed(workers []*distWorker) bool {
+	fmt.Println("\n======================================")
+	fmt.Printf("🛰️   Distributed Run: %d workers\n", len(workers))
+	labeled := false
+	for _, w := range workers {
+		labeled = labeled || len(w.labels) > 0
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	header := []string{"Worker", "Status", "Data", "Requests", "Failed", "TPS", "P50", "P95", "P99"}
+	if labeled {
+		header = append([]string{"Worker", "Labels"}, header[1:]...)
+	}
+	table.SetHeader(header)
+	var all distTotals
+	totals := make([]*distTotals, len(workers))
+	statusCodes := make(map[int]int)
+	ok, partial := true, false
+	for i, w := range workers {
+		status := "ok"
+		var s statsSummary
+		var parsed struct {
+			Summary statsSummary `json:"summary"`
+		}
+		summarized := false
+		switch {
+		case w.result == nil:
+			status, ok = "lost", false
+		case json.Unmarshal(w.result.Result, &parsed) != nil:
+			status, ok = fmt.Sprintf("exit %d, no summary", w.result.ExitCode), false
+		default:
+			s, summarized = parsed.Summary, true
+			if w.result.ExitCode != 0 {
+				status, ok = fmt.Sprintf("exit %d", w.result.ExitCode), false
+			}
+		}
+		data := "complete"
+		switch {
+		case w.next == 0:
+			data = "none"
+		case w.result == nil:
+			data = "partial, until " + w.lastData.Format("15:04:05")
+		case w.missing > 0:
+			data = fmt.Sprintf("partial, %d of %d windows", w.next-w.missing, w.next)
+		}
+		if data != "complete" {
+			partial = true
+		}
+		if !summarized {
+			// 没有汇总时以收到的窗口计算
+			s.TotalRequests = w.requests
+			s.FailedRequests = w.requests - w.success
+			if seconds := w.lastData.Sub(w.firstData).Seconds(); seconds > 0 {
+				s.TPS = float64(w.success) / seconds
+			}
+		}
+		for code, count := range s.StatusCodes {
+			statusCodes[code] += count
+		}
+		totals[i] = &distTotals{workers: 1, requests: s.TotalRequests, failed: s.FailedRequests, tps: s.TPS, digest: w.digest}
+		all.add(totals[i])
+		row := []string{w.ID, status, data}
+		if labeled {
+			row = []string{w.ID, formatTags(w.labels), status, data}
+		}
+		table.Append(append(row, totals[i].row()...))
+	}
+	row := []string{"All", "", ""}
+	if labeled {
+		row = append(row, "")
+	}
+	table.Append(append(row, all.row()...))
+	table.Render()
+	fmt.Printf("  Percentiles are merged from %d latency samples streamed as per-window histograms\n", all.digest.total)
+	if partial {
+		fmt.Println("  ⚠️  Partial data: some windows were not received, the merged figures cover only the data that arrived")
+	}
+	reportDistributedLabels(workers, totals)
+	codes := make([]int, 0, len(statusCodes))
+	for code := range statusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Println("\n📡  HTTP Status Code Statistics:")
+	for _, code := range codes {
+		fmt.Printf("  - %d: %d times\n", code, statusCodes[code])
+	}
+	return ok
+}
+
+// reportDistributedLabels 按每个标签 key 输出一张表，同一取值的 worker 合并为一行；没有该标签的 worker 记为 (none)
+func reportDistributedLabels(workers []*distWorker, totals []*distTotals) {
+	keys := make(map[string]bool)
+	for _, w := range workers {
+		for k := range w.labels {
+			keys[k] = true
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		groups := make(map[string]*distTotals)
+		for i, w := range workers {
+			value, ok := w.labels[key]
+			if !ok {
+				value = "(none)"
+			}
+			if groups[value] == nil {
+				groups[value] = &distTotals{}
+			}
+			groups[value].add(totals[i])
+		}
+		values := make([]string, 0, len(groups))
+		for v := range groups {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		fmt.Printf("\n🌍  By %s:\n", key)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{key, "Workers", "Requests", "Failed", "TPS", "P50", "P95", "P99"})
+		for _, v := range values {
+			table.Append(append([]string{v, fmt.Sprintf("%d", groups[v].workers)}, groups[v].row()...))
+		}
+		table.Render()
+	}
+}
+
+// runWorker 以 worker 身份连接 controller：注册后每隔 workerPollInterval 轮询任务（同时作为心跳），
+// 分到任务时在子进程中执行并上报结果，之后继续等待下一次压测；controller 不可用时持续重试
+func runWorker(controller string) {
+	if !strings.Contains(controller, "://") {
+		controller = "http://" + controller
+	}
+	host, _ := os.Hostname()
+	id := fmt.Sprintf("%s-%d", host, os.Getpid())
+	exe, err := os.Executable()
+	if err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	base := controller + "/workers/" + id
+	registration, _ := json.Marshal(distRegistration{Labels: workerLabels})
+	if len(workerLabels) > 0 {
+		fmt.Printf("🛰️   Worker %s (%s) joining %s\n", id, formatTags(workerLabels), controller)
+	} else {
+		fmt.Printf("🛰️   Worker %s joining %s\n", id, controller)
+	}
+
+	// running 为最近开始的任务编号；重新注册（如 controller 重启）后编号重新开始
+	running := 0
+	registered := false
+	for ; ; time.Sleep(workerPollInterval) {
+		if !registered {
+			resp, err := client.Post(base, "application/json", bytes.NewReader(registration))
+			if err != nil {
+				continue
+			}
+			resp.Body.Close()
+			registered = resp.StatusCode == http.StatusNoContent
+			running = 0
+			continue
+		}
+		resp, err := client.Get(base + "/assignment")
+		if err != nil {
+			registered = false
+			continue
+		}
+		var assignment distAssignment
+		status := resp.StatusCode
+		if status == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&assignment)
+		}
+		resp.Body.Close()
+		if status == http.StatusNotFound {
+			registered = false
+		}
+		if status != http.StatusOK || err != nil || assignment.Run == running {
+			continue
+		}
+		running = assignment.Run
+		fmt.Printf("🚀  Run %d: %s\n", assignment.Run, strings.Join(assignment.Args, " "))
+		go runAssignment(client, base, exe, assignment)
+	}
+}
+
+// runAssignment 在子进程中执行分到的压测：子进程以 -interval-stream 把每个窗口的直方图写入管道，
+// 边运行边转发给 controller；结束后发送剩余的窗口，再上报结果
+func runAssignment(client *http.Client, base, exe string, a distAssignment) {
+	var stdout bytes.Buffer
+	// 标签同时作为子进程的 -tag，出现在 worker 本地的汇总中
+	args := append([]string(nil), a.Args...)
+	for k, v := range workerLabels {
+		args = append(args, "-tag", k+"="+v)
+	}
+	forwarder := &intervalForwarder{}
+	r, w, err := os.Pipe()
+	if err == nil {
+		args = append(args, "-interval-stream", "/dev/fd/3")
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	sent := make(chan struct{})
+	if err == nil {
+		cmd.ExtraFiles = []*os.File{w}
+		go forwarder.read(r)
+		go func() {
+			forwarder.send(client, base+"/intervals", a.Run)
+			close(sent)
+		}()
+	} else {
+		slog.Error("Unable to stream intervals", "err", err)
+		close(sent)
+	}
+	result := distResult{Run: a.Run, ExitCode: -1}
+	if err := cmd.Start(); err != nil {
+		slog.Error(err.Error())
+	} else {
+		cmd.Wait()
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if w != nil {
+		// 关闭父进程持有的写端，子进程退出后管道即结束
+		w.Close()
+	}
+	<-sent
+	result.Result = bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(result.Result) {
+		result.Result = nil
+	}
+	result.Intervals = forwarder.count()
+	body, _ := json.Marshal(result)
+	// controller 暂时不可用时重试，直到它确认或明确拒绝
+	for {
+		resp, err := client.Post(base+"/result", "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(workerPollInterval)
+	}
+	fmt.Printf("✅  Run %d finished (exit %d)\n", a.Run, result.ExitCode)
+}
+
+// intervalForwarder 缓存子进程输出的窗口记录并批量转发给 controller；controller 不可用时继续缓存，
+// 最多 maxPendingIntervals 个，超出后丢弃最早的窗口，由 controller 按序号记为缺失
+type intervalForwarder struct {
+	mu      sync.Mutex
+	pending []json.RawMessage
+	// first 为 pending[0] 的序号，total 为读到的窗口数
+	first, total int
+	eof          bool
+}
+
+// read 逐行读取子进程的窗口记录，直到管道关闭
+func (f *intervalForwarder) read(r io.ReadCloser) {
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		line := append(json.RawMessage(nil), scanner.Bytes()...)
+		f.mu.Lock()
+		f.pending = append(f.pending, line)
+		f.total++
+		if len(f.pending) > maxPendingIntervals {
+			f.pending = f.pending[1:]
+			f.first++
+		}
+		f.mu.Unlock()
+	}
+	f.mu.Lock()
+	f.eof = true
+	f.mu.Unlock()
+}
+
+// count 返回读到的窗口数
+func (f *intervalForwarder) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.total
+}
+
+// send 每隔 workerPollInterval 把缓存的窗口以 gzip 压缩发送到 url，管道关闭且全部送达（或被 controller 拒绝）后返回
+func (f *intervalForwarder) send(client *http.Client, url string, run int) {
+	for {
+		f.mu.Lock()
+		batch := distIntervals{Run: run, First: f.first, Intervals: append([]json.RawMessage(nil), f.pending...)}
+		eof := f.eof
+		f.mu.Unlock()
+		if len(batch.Intervals) == 0 {
+			if eof {
+				return
+			}
+			time.Sleep(workerPollInterval)
+			continue
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		json.NewEncoder(zw).Encode(batch)
+		zw.Close()
+		req, _ := http.NewRequest(http.MethodPost, url, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		resp, err := client.Do(req)
+		if err != nil {
+			time.Sleep(workerPollInterval)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			// controller 已不认这次压测（如重启过），之后的窗口也无法送达
+			return
+		}
+		f.mu.Lock()
+		// 发送期间缓存可能溢出而丢弃了一部分已发送的窗口
+		if done := batch.First + len(batch.Intervals) - f.first; done > 0 {
+			f.pending = f.pending[done:]
+			f.first += done
+		}
+		f.mu.Unlock()
+		if !eof {
+			time.Sleep(workerPollInterval)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// downloadRate 大于 0 时按该速率（字节/秒）读取响应 body，模拟网络缓慢的移动端客户端
+var downloadRate float64
+
+// throttledReader 按 rate（字节/秒）读取 r；每次最多读取约 100ms 的数据，使读取节奏尽量平滑
+type throttledReader struct {
+	r     io.Reader
+	rate  float64
+	read  int64
+	start time.Time
+}
+
+// throttleBody 在设置了 -download-rate 时返回限速读取的 body，否则原样返回
+func throttleBody(body io.Reader) io.Reader {
+	if downloadRate <= 0 {
+		return body
+	}
+	return &throttledReader{r: body, rate: downloadRate}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+	// 已读取的字节按速率应耗费的时间，未到时等待
+	due := t.start.Add(time.Duration(float64(t.read) / t.rate * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(wait)
+	}
+	limit := int(t.rate / 10)
+	if limit < 1024 {
+		limit = 1024
+	}
+	if len(p) > limit {
+		p = p[:limit]
+	}
+	n, err := t.r.Read(p)
+	t.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// engine 为 -engine，发送请求使用的客户端实现：
+//   - net/http  标准库的 http.Transport（默认）
+//   - fasthttp  基于 valyala/fasthttp 的客户端：在调用方的 goroutine 上同步完成整个请求，每条连接没有额外的读写 goroutine，
+//     请求与响应对象池化复用，适合简单请求下追求极限 RPS；只支持 HTTP/1.1，不单独报告 DNS、建连、TLS 与写请求的耗时
+var engine string
+
+// fasthttpStreamThreshold 为 fasthttp 引擎随响应头一并读入内存的 body 上限，更大的 body 在读取 http.Response.Body 时才从连接上读出，
+// 使 TTFB 在收到响应头时记录；没有 Content-Length 也不是 chunked、靠关闭连接结束的 body 超过该大小时 fasthttp 无法流式读取，请求失败
+const fasthttpStreamThreshold = 4 << 10
+
+// checkEngine 校验 -engine 以及与之不兼容的选项，并为 fasthttp 引擎替换全局客户端的 Transport；
+// 须在全局客户端配置完成（如 -no-session-resumption）之后调用
+func checkEngine(connectionPolicy string, streamMode bool, downloadRate string) error {
+	switch engine {
+	case "net/http":
+		return nil
+	case "fasthttp":
+	default:
+		return fmt.Errorf("unknown -engine %q (expected net/http or fasthttp)", engine)
+	}
+	if connectionPolicy != "" {
+		return fmt.Errorf("-engine fasthttp cannot be combined with -connection-policy")
+	}
+	if expectContinue {
+		return fmt.Errorf("-engine fasthttp cannot be combined with -expect-continue")
+	}
+	// fasthttp 不报告请求使用的连接是否为新建，无法区分新旧连接上的时延
+	if closeEvery > 0 {
+		return fmt.Errorf("-engine fasthttp cannot be combined with -close-every")
+	}
+	// server 时延为 TTFB 去掉 DNS、建连与 TLS 握手，fasthttp 不报告这几个阶段
+	if latencyMetric == "server" {
+		return fmt.Errorf("-engine fasthttp cannot be combined with -latency-metric server")
+	}
+	// 以下选项按自己的节奏读取（或不读取）响应 body，而 fasthttp 在返回响应前已读入至多 fasthttpStreamThreshold 字节
+	if streamMode {
+		return fmt.Errorf("-engine fasthttp cannot be combined with -stream")
+	}
+	if downloadRate != "" {
+		return fmt.Errorf("-engine fasthttp cannot be combined with -download-rate")
+	}
+	if bodyMode == "ignore" {
+		return fmt.Errorf("-engine fasthttp cannot be combined with -body-mode ignore")
+	}
+	for _, client := range []*http.Client{clientKeepAlive, clientNoKeepAlive} {
+		client.Transport = newFasthttpTransport(client.Transport.(*http.Transport))
+	}
+	return nil
+}
+
+// fasthttpTransport 为 fasthttp 引擎的 http.RoundTripper，把 net/http 的请求转换为 fasthttp 的请求发出
+type fasthttpTransport struct {
+	keepAlive bool
+	client    *fasthttp.Client
+}
+
+// newFasthttpTransport 按 std 的 Keep-Alive、TLS、连接数与空闲超时配置创建 fasthttp 引擎，拨号同样经过 wanDial
+func newFasthttpTransport(std *http.Transport) *fasthttpTransport {
+	dial := wanDial(newDialer(30 * time.Second))
+	maxConns := std.MaxConnsPerHost
+	if maxConns <= 0 {
+		maxConns = math.MaxInt32
+	}
+	return &fasthttpTransport{
+		keepAlive: !std.DisableKeepAlives,
+		client: &fasthttp.Client{
+			Dial: func(addr string) (net.Conn, error) {
+				return dial(context.Background(), "tcp", addr)
+			},
+			TLSConfig:           std.TLSClientConfig,
+			MaxConnsPerHost:     maxConns,
+			MaxIdleConnDuration: std.IdleConnTimeout,
+			// 与 net/http 相同，复用的连接失效时只重试一次
+			MaxIdemponentCallAttempts: 2,
+			NoDefaultUserAgentHeader:  true,
+			DisablePathNormalizing:    true,
+			StreamResponseBody:        true,
+			MaxResponseBodySize:       fasthttpStreamThreshold,
+		},
+	}
+}
+
+// RoundTrip 同步发出请求并读完响应头；请求的 deadline（包括 http.Client.Timeout）作为 fasthttp 的超时，同样限制之后读取 body；
+// fasthttp 无法中途取消，没有 deadline 的 ctx 取消后要等请求自行结束
+func (t *fasthttpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		if hasBody {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("-engine fasthttp: unsupported scheme %q", req.URL.Scheme)
+	}
+	ctx := req.Context()
+	if err := ctx.Err(); err != nil {
+		if hasBody {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	freq := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(freq)
+	freq.Header.SetMethod(req.Method)
+	freq.SetRequestURI(req.URL.String())
+	if req.Host != "" && req.Host != req.URL.Host {
+		freq.UseHostHeader = true
+		freq.Header.SetHost(req.Host)
+	}
+	for key, values := range req.Header {
+		for _, value := range values {
+			freq.Header.Add(key, value)
+		}
+	}
+	if req.Close || !t.keepAlive {
+		freq.SetConnectionClose()
+	}
+	// 请求体由 fasthttp 在写出后关闭；长度未知时以 chunked 编码发送
+	if hasBody {
+		size := -1
+		if req.ContentLength > 0 {
+			size = int(req.ContentLength)
+		}
+		freq.SetBodyStream(req.Body, size)
+	}
+
+	// fasthttp 不报告连接的获取与请求的写出，这里在发出前一并标记，因此建连与写请求的耗时计入 TTFB
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil {
+		if trace.GetConn != nil {
+			trace.GetConn(req.URL.Host)
+		}
+		if trace.GotConn != nil {
+			trace.GotConn(httptrace.GotConnInfo{})
+		}
+		if trace.WroteRequest != nil {
+			trace.WroteRequest(httptrace.WroteRequestInfo{})
+		}
+	}
+
+	fresp := fasthttp.AcquireResponse()
+	var err error
+	if deadline, ok := ctx.Deadline(); ok {
+		err = t.client.DoDeadline(freq, fresp, deadline)
+	} else {
+		err = t.client.Do(freq, fresp)
+	}
+	if err != nil {
+		fasthttp.ReleaseResponse(fresp)
+		// 与 net/http 一致，超时以 context.DeadlineExceeded 报告，统计时归入超时错误
+		if errors.Is(err, fasthttp.ErrTimeout) {
+			return nil, context.DeadlineExceeded
+		}
+		if errors.Is(err, fasthttp.ErrBodyTooLarge) {
+			return nil, fmt.Errorf("-engine fasthttp: response body without Content-Length or chunked encoding is larger than %d bytes", fasthttpStreamThreshold)
+		}
+		return nil, err
+	}
+	// Do 在读完响应头（以及不超过 fasthttpStreamThreshold 的 body）后即返回
+	if trace != nil && trace.GotFirstResponseByte != nil {
+		trace.GotFirstResponseByte()
+	}
+	return newFasthttpResponse(req, fresp), nil
+}
+
+// newFasthttpResponse 把 fasthttp 的响应转换为 net/http 的响应，body 从 fresp 的 body 流读取，关闭时归还 fresp
+func newFasthttpResponse(req *http.Request, fresp *fasthttp.Response) *http.Response {
+	code := fresp.StatusCode()
+	contentLength := int64(fresp.Header.ContentLength())
+	if contentLength < 0 {
+		contentLength = -1
+	}
+	body := &fasthttpBody{resp: fresp, stream: fresp.BodyStream()}
+	if body.stream == nil {
+		// HEAD 请求以及 204、304 等没有 body 的响应
+		body.stream = bytes.NewReader(nil)
+		contentLength = 0
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		ContentLength: contentLength,
+		Close:         fresp.ConnectionClose(),
+		Request:       req,
+		Body:          body,
+	}
+	fresp.Header.VisitAll(func(key, value []byte) {
+		resp.Header.Add(string(key), string(value))
+	})
+	return resp
+}
+
+// errFasthttpBodyUnread 为 body 未读完就关闭时结束 body 流的错误，fasthttp 据此关闭连接而不是放回连接池
+var errFasthttpBodyUnread = errors.New("response body closed before EOF")
+
+// fasthttpBody 为 fasthttp 引擎的响应 body
+type fasthttpBody struct {
+	resp   *fasthttp.Response
+	stream io.Reader
+	eof    bool
+}
+
+func (b *fasthttpBody) Read(p []byte) (int, error) {
+	if b.resp == nil {
+		return 0, errors.New("read on closed response body")
+	}
+	n, err := b.stream.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+// Close 结束 body 流并归还 fresp：读到 EOF 的连接放回连接池；与 net/http 相同，未读完的连接直接关闭，不读出剩余的 body
+func (b *fasthttpBody) Close() error {
+	if b.resp == nil {
+		return nil
+	}
+	if b.eof {
+		b.resp.CloseBodyStream()
+	} else {
+		// fasthttp 只能经 BodyWriteTo 以错误结束 body 流，写入失败时它以该错误关闭流
+		b.resp.BodyWriteTo(failingWriter{})
+	}
+	fasthttp.ReleaseResponse(b.resp)
+	b.resp = nil
+	return nil
+}
+
+// failingWriter 的写入总是失败
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errFasthttpBodyUnread }
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFasthttpClient 返回使用 fasthttp 引擎、按 std 配置的客户端
+func newFasthttpClient(std *http.Transport, timeout time.Duration) *http.Client {
+	return &http.Client{Transport: newFasthttpTransport(std), Timeout: timeout}
+}
+
+func TestFasthttpRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Path", r.URL.RequestURI())
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-Echo", r.Header.Get("X-Test"))
+		w.Header().Set("X-Chunked", strings.Join(r.TransferEncoding, ","))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer server.Close()
+	client := newFasthttpClient(&http.Transport{}, 5*time.Second)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		host    string
+		body    io.Reader
+		chunked bool
+		want    string
+	}{
+		{name: "get", method: http.MethodGet, path: "/a/b?x=1&y=2"},
+		{name: "post with length", method: http.MethodPost, path: "/submit", body: strings.NewReader(`{"id":1}`), want: `{"id":1}`},
+		{name: "post chunked", method: http.MethodPost, path: "/stream", body: io.MultiReader(strings.NewReader("part1-"), strings.NewReader("part2")), chunked: true, want: "part1-part2"},
+		{name: "host override", method: http.MethodGet, path: "/", host: "example.test"},
+		{name: "unnormalized path", method: http.MethodGet, path: "/a//b/../c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Test", "hello")
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			got, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusCreated {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+			}
+			if string(got) != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if resp.ContentLength != int64(len(tt.want)) {
+				t.Errorf("ContentLength = %d, want %d", resp.ContentLength, len(tt.want))
+			}
+			wantHost := tt.host
+			if wantHost == "" {
+				wantHost = strings.TrimPrefix(server.URL, "http://")
+			}
+			for header, value := range map[string]string{
+				"X-Method": tt.method, "X-Path": tt.path, "X-Host": wantHost, "X-Echo": "hello",
+			} {
+				if got := resp.Header.Get(header); got != value {
+					t.Errorf("%s = %q, want %q", header, got, value)
+				}
+			}
+			if chunked := resp.Header.Get("X-Chunked") == "chunked"; chunked != tt.chunked {
+				t.Errorf("chunked request body = %v, want %v", chunked, tt.chunked)
+			}
+		})
+	}
+}
+
+func TestFasthttpKeepAlive(t *testing.T) {
+	var mu sync.Mutex
+	conns := map[string]bool{}
+	var closeHeaders int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		conns[r.RemoteAddr] = true
+		if r.Close {
+			closeHeaders++
+		}
+		mu.Unlock()
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name      string
+		std       *http.Transport
+		wantConns int
+		wantClose int
+	}{
+		{name: "keep-alive", std: &http.Transport{}, wantConns: 1, wantClose: 0},
+		{name: "no keep-alive", std: &http.Transport{DisableKeepAlives: true}, wantConns: 3, wantClose: 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			clear(conns)
+			closeHeaders = 0
+			mu.Unlock()
+			client := newFasthttpClient(tt.std, 5*time.Second)
+			if usesKeepAlive(client) == tt.std.DisableKeepAlives {
+				t.Errorf("usesKeepAlive = %v", !tt.std.DisableKeepAlives)
+			}
+			for i := 0; i < 3; i++ {
+				resp, err := client.Get(server.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(conns) != tt.wantConns {
+				t.Errorf("connections = %d, want %d", len(conns), tt.wantConns)
+			}
+			if closeHeaders != tt.wantClose {
+				t.Errorf("requests with Connection: close = %d, want %d", closeHeaders, tt.wantClose)
+			}
+		})
+	}
+}
+
+func TestFasthttpTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	defer server.Close()
+	client := newFasthttpClient(&http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, 5*time.Second)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HTTP/1.1" {
+		t.Errorf("protocol = %q, want HTTP/1.1", body)
+	}
+}
+
+func TestFasthttpTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	client := newFasthttpClient(&http.Transport{}, 50*time.Millisecond)
+	start := time.Now()
+	_, err := client.Get(server.URL)
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("error %v is not reported as a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timeout took %s", elapsed)
+	}
+}
+
+func TestFasthttpUnsupportedScheme(t *testing.T) {
+	client := newFasthttpClient(&http.Transport{}, time.Second)
+	if _, err := client.Get("ftp://localhost/file"); err == nil {
+		t.Fatal("expected an error for an ftp URL")
+	}
+}
+
+func TestFasthttpStreamsLargeBodies(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	large := strings.Repeat("x", 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			// 响应头先到，body 在 delay 之后才发出
+			w.Header().Set("Content-Length", fmt.Sprint(len(large)))
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			time.Sleep(delay)
+			io.WriteString(w, large)
+		case "/chunked":
+			io.WriteString(w, large[:fasthttpStreamThreshold])
+			w.(http.Flusher).Flush()
+			io.WriteString(w, large[fasthttpStreamThreshold:])
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	client := newFasthttpClient(&http.Transport{}, 5*time.Second)
+
+	var firstByte time.Time
+	trace := &httptrace.ClientTrace{GotFirstResponseByte: func() { firstByte = time.Now() }}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, server.URL+"/slow", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	end := time.Now()
+	if err != nil || len(body) != len(large) {
+		t.Fatalf("read %d bytes (%v), want %d", len(body), err, len(large))
+	}
+	if resp.ContentLength != int64(len(large)) {
+		t.Errorf("ContentLength = %d, want %d", resp.ContentLength, len(large))
+	}
+	if firstByte.IsZero() || end.Sub(firstByte) < delay/2 {
+		t.Errorf("first response byte reported %s before the body was read, want at least %s", end.Sub(firstByte), delay/2)
+	}
+
+	resp, err = client.Get(server.URL + "/chunked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != large {
+		t.Errorf("chunked body: read %d bytes (%v), want %d", len(body), err, len(large))
+	}
+	if resp.ContentLength != -1 {
+		t.Errorf("chunked ContentLength = %d, want -1", resp.ContentLength)
+	}
+
+	resp, err = client.Get(server.URL + "/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || len(body) != 0 || resp.StatusCode != http.StatusNoContent {
+		t.Errorf("empty body: status %d, read %q (%v)", resp.StatusCode, body, err)
+	}
+}
+
+func TestFasthttpUnreadBodyClosesConnection(t *testing.T) {
+	var mu sync.Mutex
+	conns := map[string]bool{}
+	large := strings.Repeat("y", 256<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		conns[r.RemoteAddr] = true
+		mu.Unlock()
+		io.WriteString(w, large)
+	}))
+	defer server.Close()
+	client := newFasthttpClient(&http.Transport{}, 5*time.Second)
+
+	get := func(readAll bool) {
+		t.Helper()
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if readAll {
+			if body, err := io.ReadAll(resp.Body); err != nil || len(body) != len(large) {
+				t.Fatalf("read %d bytes (%v), want %d", len(body), err, len(large))
+			}
+		} else {
+			resp.Body.Read(make([]byte, 10))
+		}
+		resp.Body.Close()
+	}
+	connCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(conns)
+	}
+
+	// 读完的连接被复用；未读完就关闭的连接不能复用，否则剩余的 body 会被当作下一个响应
+	get(true)
+	get(true)
+	if n := connCount(); n != 1 {
+		t.Errorf("connections after two full reads = %d, want 1", n)
+	}
+	get(false)
+	get(true)
+	if n := connCount(); n != 2 {
+		t.Errorf("connections after an unread body = %d, want 2", n)
+	}
+}
+
+func TestFasthttpIdentityBodyTooLarge(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Read(make([]byte, 4096))
+		// 没有 Content-Length 也不是 chunked，body 以关闭连接结束
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nConnection: close\r\n\r\n"+strings.Repeat("z", 2*fasthttpStreamThreshold))
+	}()
+	client := newFasthttpClient(&http.Transport{}, 5*time.Second)
+	_, err = client.Get("http://" + ln.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "without Content-Length") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCheckEngineRejectsOptions(t *testing.T) {
+	savedEngine, savedMetric, savedBodyMode := engine, latencyMetric, bodyMode
+	defer func() { engine, latencyMetric, bodyMode = savedEngine, savedMetric, savedBodyMode }()
+	engine = "fasthttp"
+	tests := []struct {
+		name             string
+		connectionPolicy string
+		streamMode       bool
+		downloadRate     string
+		latencyMetric    string
+		bodyMode         string
+	}{
+		{name: "connection policy", connectionPolicy: "policy.yaml"},
+		{name: "server latency", latencyMetric: "server"},
+		{name: "stream", streamMode: true},
+		{name: "download rate", downloadRate: "256kb/s"},
+		{name: "ignored body", bodyMode: "ignore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latencyMetric, bodyMode = "total", "discard"
+			if tt.latencyMetric != "" {
+				latencyMetric = tt.latencyMetric
+			}
+			if tt.bodyMode != "" {
+				bodyMode = tt.bodyMode
+			}
+			if err := checkEngine(tt.connectionPolicy, tt.streamMode, tt.downloadRate); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
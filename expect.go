@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// expectContinue 为 true 时带 body 的请求发送 Expect: 100-continue，先等服务端确认再上传 body
+var expectContinue bool
+
+// setExpectContinueTimeout 设置两个客户端等待 100 Continue 的时长，超时后不再等待直接发送 body
+func setExpectContinueTimeout(timeout time.Duration) {
+	for _, client := range []*http.Client{clientKeepAlive, clientNoKeepAlive} {
+		client.Transport.(*http.Transport).ExpectContinueTimeout = timeout
+	}
+}
+
+// continueStats 为 100-continue 握手的统计
+type continueStats struct {
+	// Expected 为发送了 Expect 且得到最终响应的请求数
+	Expected int64
+	// Continued 为收到 100 Continue 后才上传 body 的请求数，Wait 为发出 header 到收到 100 Continue 的耗时
+	Continued int64
+	Wait      phaseStats
+	// Rejected 为未收到 100 Continue 就直接得到最终响应的请求，按状态码计数；服务端借此提前拒绝大 body
+	Rejected map[int]int
+	// TimedOut 为等待超时后未经确认就上传了 body 的请求数
+	TimedOut int64
+}
+
+// record 记录一个发送了 Expect 的请求，status 为最终响应的状态码
+func (s *continueStats) record(t *requestTrace, status int) {
+	t.mu.Lock()
+	wroteHeaders, got100, wroteRequest := t.wroteHeaders, t.got100, t.wroteRequest
+	t.mu.Unlock()
+	s.Expected++
+	switch {
+	case !got100.IsZero():
+		s.Continued++
+		s.Wait.record(got100.Sub(wroteHeaders))
+	case wroteRequest.IsZero() || status >= 400:
+		// body 没有发出（或服务端不等 body 即返回错误）即视为提前拒绝
+		if s.Rejected == nil {
+			s.Rejected = make(map[int]int)
+		}
+		s.Rejected[status]++
+	default:
+		s.TimedOut++
+	}
+}
+
+// add 合并另一个 worker 的统计
+func (s *continueStats) add(other continueStats) {
+	s.Expected += other.Expected
+	s.Continued += other.Continued
+	s.Wait.add(other.Wait)
+	s.TimedOut += other.TimedOut
+	for code, count := range other.Rejected {
+		if s.Rejected == nil {
+			s.Rejected = make(map[int]int)
+		}
+		s.Rejected[code] += count
+	}
+}
+
+// reportContinue 输出 100-continue 握手的统计
+func reportContinue(s continueStats) {
+	if !expectContinue || s.Expected == 0 {
+		return
+	}
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.1f ms", float64(d.Microseconds())/1000)
+	}
+	fmt.Println("\n✋  100-continue Handshake:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Metric", "Value"})
+	table.Append([]string{"Requests with Expect", fmt.Sprintf("%d", s.Expected)})
+	table.Append([]string{"Got 100 Continue", fmt.Sprintf("%d", s.Continued)})
+	if s.Wait.Count > 0 {
+		table.Append([]string{"100 Continue Wait (avg / max)", ms(s.Wait.avg()) + " / " + ms(s.Wait.Max)})
+	}
+	codes := make([]int, 0, len(s.Rejected))
+	for code := range s.Rejected {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		table.Append([]string{fmt.Sprintf("Rejected Before Body (%d)", code), fmt.Sprintf("%d", s.Rejected[code])})
+	}
+	table.Append([]string{"Body Sent After Timeout", fmt.Sprintf("%d", s.TimedOut)})
+	table.Render()
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// extractRule 为场景请求的一条提取规则：从 JSON body 中的路径（JSON，如 $.queue_depth、$.items[0].id）或响应头（Header）取值。
+//   - 按顺序执行的场景中取到的值作为变量 Name，后续步骤可用 {{.Name}} 引用
+//   - Metric 为 true 时取到的数值记为自定义指标，按趋势窗口绘制曲线并汇总
+type extractRule struct {
+	Name   string `yaml:"name"`
+	JSON   string `yaml:"json"`
+	Header string `yaml:"header"`
+	Metric bool   `yaml:"metric"`
+
+	path []jsonPathStep
+}
+
+// jsonPathStep 为 JSON 路径中的一级：对象字段或数组下标
+type jsonPathStep struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// parseJSONPath 解析 $.a.b[0].c 形式的路径，开头的 $ 可省略
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []jsonPathStep
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSON path %q", path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid array index in JSON path %q", path)
+			}
+			steps = append(steps, jsonPathStep{index: index, isIdx: true})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q", path)
+		}
+	}
+	return steps, nil
+}
+
+// compile 校验规则并解析 JSON 路径
+func (r *extractRule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("extract: name is required")
+	}
+	if (r.JSON == "") == (r.Header == "") {
+		return fmt.Errorf("extract %s: set exactly one of json and header", r.Name)
+	}
+	if r.JSON == "" {
+		return nil
+	}
+	path, err := parseJSONPath(r.JSON)
+	if err != nil {
+		return fmt.Errorf("extract %s: %v", r.Name, err)
+	}
+	r.path = path
+	return nil
+}
+
+// extract 从响应中取值；doc 为按需解析一次的 JSON body，可在多条规则之间共用
+func (r *extractRule) extract(header http.Header, body []byte, doc *interface{}) (string, bool) {
+	if r.Header != "" {
+		value := header.Get(r.Header)
+		return value, value != ""
+	}
+	if *doc == nil {
+		if err := json.Unmarshal(body, doc); err != nil || *doc == nil {
+			return "", false
+		}
+	}
+	node := *doc
+	for _, step := range r.path {
+		if step.isIdx {
+			arr, ok := node.([]interface{})
+			if !ok || step.index >= len(arr) {
+				return "", false
+			}
+			node = arr[step.index]
+			continue
+		}
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if node, ok = obj[step.key]; !ok {
+			return "", false
+		}
+	}
+	switch v := node.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "", false
+	default:
+		data, _ := json.Marshal(v)
+		return string(data), true
+	}
+}
+
+// extractValues 对响应执行 spec 的提取规则：数值指标记入 worker 当前趋势窗口，取到的值写入 values（为 nil 时不保存）
+func extractValues(ws *WorkerStats, spec *requestSpec, header http.Header, body []byte, values map[string]string) {
+	var doc interface{}
+	for _, rule := range spec.Extract {
+		value, ok := rule.extract(header, body, &doc)
+		if !ok {
+			continue
+		}
+		if values != nil {
+			values[rule.Name] = value
+		}
+		if rule.Metric {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				ws.Window.metric(rule.Name, n)
+			}
+		}
+	}
+}
+
+// metricStats 为一个自定义指标的样本数、总和与最值
+type metricStats struct {
+	Count    int64
+	Sum      float64
+	Min, Max float64
+}
+
+// record 记录一个样本
+func (m *metricStats) record(v float64) {
+	if m.Count == 0 || v < m.Min {
+		m.Min = v
+	}
+	if m.Count == 0 || v > m.Max {
+		m.Max = v
+	}
+	m.Count++
+	m.Sum += v
+}
+
+// add 合并另一份统计
+func (m *metricStats) add(other *metricStats) {
+	if other.Count == 0 {
+		return
+	}
+	if m.Count == 0 || other.Min < m.Min {
+		m.Min = other.Min
+	}
+	if m.Count == 0 || other.Max > m.Max {
+		m.Max = other.Max
+	}
+	m.Count += other.Count
+	m.Sum += other.Sum
+}
+
+// addMetrics 把 src 中的各指标合并进 dst，返回合并后的 dst（为 nil 时创建）
+func addMetrics(dst, src map[string]*metricStats) map[string]*metricStats {
+	for name, m := range src {
+		if dst == nil {
+			dst = make(map[string]*metricStats)
+		}
+		agg, ok := dst[name]
+		if !ok {
+			agg = &metricStats{}
+			dst[name] = agg
+		}
+		agg.add(m)
+	}
+	return dst
+}
+
+// metric 在当前趋势窗口内记录自定义指标的一个样本
+func (w *windowStats) metric(name string, v float64) {
+	if w.Metrics == nil {
+		w.Metrics = make(map[string]*metricStats)
+	}
+	m, ok := w.Metrics[name]
+	if !ok {
+		m = &metricStats{}
+		w.Metrics[name] = m
+	}
+	m.record(v)
+}
+
+// metricHistory 为各自定义指标每个趋势窗口内的均值，与 tpsHistory 一一对应；窗口内没有样本时沿用上一个窗口的值
+var metricHistory = make(map[string][]float64)
+
+// appendMetricPoints 为刚结束的趋势窗口追加各指标的趋势点，n 为加上这个点后趋势点的个数
+func appendMetricPoints(window map[string]*metricStats, n int) {
+	for name := range window {
+		if _, ok := metricHistory[name]; !ok {
+			// 中途才出现的指标之前的窗口记为 0
+			metricHistory[name] = make([]float64, n-1)
+		}
+	}
+	for name, series := range metricHistory {
+		if m, ok := window[name]; ok && m.Count > 0 {
+			series = append(series, m.Sum/float64(m.Count))
+		} else if len(series) > 0 {
+			series = append(series, series[len(series)-1])
+		} else {
+			series = append(series, 0)
+		}
+		metricHistory[name] = series
+	}
+}
+
+// metricSummary 为导出汇总中的一个自定义指标
+type metricSummary struct {
+	Name    string  `json:"name"`
+	Samples int64   `json:"samples"`
+	Min     float64 `json:"min"`
+	Mean    float64 `json:"mean"`
+	Max     float64 `json:"max"`
+}
+
+// summarizeMetrics 返回按名称排序的自定义指标汇总
+func summarizeMetrics(metrics map[string]*metricStats) []metricSummary {
+	var result []metricSummary
+	for name, m := range metrics {
+		if m.Count == 0 {
+			continue
+		}
+		result = append(result, metricSummary{Name: name, Samples: m.Count, Min: m.Min, Mean: m.Sum / float64(m.Count), Max: m.Max})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// reportMetrics 输出自定义指标的汇总
+func reportMetrics(metrics []metricSummary) {
+	if len(metrics) == 0 {
+		return
+	}
+	fmt.Println("\n📟  Custom Metrics (extracted from responses):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Metric", "Samples", "Min", "Mean", "Max"})
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, m := range metrics {
+		table.Append([]string{m.Name, fmt.Sprintf("%d", m.Samples), format(m.Min), format(math.Round(m.Mean*100) / 100), format(m.Max)})
+	}
+	table.Render()
+}
+
+// plotMetricTrends 在 TPS 等趋势图之后绘制各自定义指标每个窗口的均值
+func plotMetricTrends() {
+	names := make([]string, 0, len(metricHistory))
+	for name := range metricHistory {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("\n📟  Metric Trend: %s (mean per window):\n", name)
+		fmt.Println(plotTrend(metricHistory[name], graphOptions(graphHeight/2)...))
+	}
+}
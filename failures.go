@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// replayFailures 为 true 时记录失败的请求，压测结束后逐个重新发送
+var replayFailures bool
+
+// replayRate 为重放失败请求的速率（请求/秒），0 表示上一个完成后立即发送下一个
+var replayRate float64
+
+// maxFailuresPerWorker 为每个 worker 最多记录的失败请求数，超出部分只计数
+const maxFailuresPerWorker = 1000
+
+// failedRequest 为一个失败的请求及其失败原因
+type failedRequest struct {
+	spec   *requestSpec
+	Method string
+	URL    string
+	Status int
+	Error  string
+}
+
+// outcome 返回失败原因的简短描述
+func (f failedRequest) outcome() string {
+	if f.Error != "" {
+		return f.Error
+	}
+	return fmt.Sprintf("HTTP %d", f.Status)
+}
+
+// failureLog 为 worker 记录的失败请求
+type failureLog struct {
+	Items   []failedRequest
+	Dropped int64
+}
+
+// record 记录一个失败的请求；未开启 -replay-failures 时不记录
+func (l *failureLog) record(f failedRequest) {
+	if !replayFailures {
+		return
+	}
+	if len(l.Items) >= maxFailuresPerWorker {
+		l.Dropped++
+		return
+	}
+	l.Items = append(l.Items, f)
+}
+
+// add 合并另一个 worker 的失败请求
+func (l *failureLog) add(other failureLog) {
+	l.Items = append(l.Items, other.Items...)
+	l.Dropped += other.Dropped
+}
+
+// maxReplayRows 为重放后仍失败的请求表格的最大行数
+const maxReplayRows = 20
+
+// replayFailedRequests 在压测结束、服务端不再承压后串行（或按 -replay-rate）重新发送失败的请求：
+// 重放成功的多半是过载导致的失败，重放仍失败的则多半是请求本身有问题
+func replayFailedRequests(failures failureLog, defaultURL, defaultMethod string) {
+	if len(failures.Items) == 0 {
+		return
+	}
+	if atomic.LoadInt32(&interrupted) == 1 {
+		fmt.Printf("\n🔁  Skipping replay of %d failed requests: the run was interrupted\n", len(failures.Items))
+		return
+	}
+	pace := "serially"
+	var interval time.Duration
+	if replayRate > 0 {
+		pace = fmt.Sprintf("at %.2f req/s", replayRate)
+		interval = time.Duration(float64(time.Second) / replayRate)
+	}
+	fmt.Printf("\n🔁  Replaying %d failed requests %s...\n", len(failures.Items), pace)
+
+	var recovered int
+	var still []failedRequest
+	var replayed []failedRequest
+	next := time.Now()
+	for _, f := range failures.Items {
+		if atomic.LoadInt32(&interrupted) == 1 {
+			break
+		}
+		if interval > 0 {
+			time.Sleep(time.Until(next))
+			next = next.Add(interval)
+		}
+		result := replayRequest(f.spec, defaultURL, defaultMethod)
+		if result.Error == "" && result.Status >= 200 && result.Status < 300 {
+			recovered++
+			continue
+		}
+		still = append(still, f)
+		replayed = append(replayed, result)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Result", "Requests"})
+	table.Append([]string{"Recovered on Replay", fmt.Sprintf("%d", recovered)})
+	table.Append([]string{"Still Failing", fmt.Sprintf("%d", len(still))})
+	if notReplayed := len(failures.Items) - recovered - len(still); notReplayed > 0 {
+		table.Append([]string{"Not Replayed (interrupted)", fmt.Sprintf("%d", notReplayed)})
+	}
+	if failures.Dropped > 0 {
+		table.Append([]string{"Not Recorded (over limit)", fmt.Sprintf("%d", failures.Dropped)})
+	}
+	table.Render()
+	fmt.Println("  Recovered requests most likely failed because of the load; requests that still fail are likely broken.")
+	if len(still) == 0 {
+		return
+	}
+
+	fmt.Println("\n❗  Requests Still Failing on Replay:")
+	detail := tablewriter.NewWriter(os.Stdout)
+	detail.SetHeader([]string{"Request", "During Run", "On Replay"})
+	for i, f := range still {
+		if i == maxReplayRows {
+			break
+		}
+		detail.Append([]string{f.Method + " " + f.URL, f.outcome(), replayed[i].outcome()})
+	}
+	detail.Render()
+	if len(still) > maxReplayRows {
+		fmt.Printf("  ... and %d more\n", len(still)-maxReplayRows)
+	}
+}
+
+// replayRequest 用 Keep-Alive 客户端重新发送一个请求，返回其结果
+func replayRequest(spec *requestSpec, defaultURL, defaultMethod string) failedRequest {
+	req, err := newHTTPRequest(spec, defaultURL, defaultMethod)
+	if err != nil {
+		return failedRequest{Error: err.Error()}
+	}
+	resp, err := clientKeepAlive.Do(req)
+	if err != nil {
+		return failedRequest{Error: err.Error()}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return failedRequest{Status: resp.StatusCode}
+}
+
+// failedRequestFor 构造 sendRequest 中失败请求的记录；req 为 nil 表示请求未能构造
+func failedRequestFor(spec *requestSpec, req *http.Request, status int, err error) failedRequest {
+	f := failedRequest{spec: spec, Method: spec.Method, URL: spec.URL, Status: status}
+	if req != nil {
+		f.Method, f.URL = req.Method, req.URL.String()
+	}
+	if err != nil {
+		f.Error = err.Error()
+	}
+	return f
+}
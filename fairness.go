@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// backendHeader、fairnessTolerance 为 -backend-header 与 -fairness-tolerance：按响应中标识后端实例的 header
+// （或 Set-Cookie:NAME 形式的粘性 cookie）统计各实例的请求数与时延，计算公平性指数，
+// 某个实例的份额超过平均份额的 1+fairnessTolerance 倍时给出警告
+var (
+	backendHeader     string
+	fairnessTolerance float64
+)
+
+// backendUnknown 为响应中没有后端标识时的实例名，不参与公平性计算
+const backendUnknown = "(unknown)"
+
+// backendID 返回响应所属的后端实例
+func backendID(resp *http.Response) string {
+	if name, cookie, found := strings.Cut(backendHeader, ":"); found && strings.EqualFold(name, "Set-Cookie") {
+		for _, c := range resp.Cookies() {
+			if c.Name == cookie && c.Value != "" {
+				return c.Value
+			}
+		}
+		return backendUnknown
+	}
+	if id := resp.Header.Get(backendHeader); id != "" {
+		return id
+	}
+	return backendUnknown
+}
+
+// backendStats 返回后端实例的统计数据，不存在时创建
+func (ws *WorkerStats) backendStats(id string) *URLStats {
+	bs, ok := ws.Backends[id]
+	if !ok {
+		bs = &URLStats{}
+		ws.Backends[id] = bs
+	}
+	return bs
+}
+
+// backendSummary 为按后端实例的统计与公平性
+type backendSummary struct {
+	Header string `json:"header"`
+	// Fairness 为 Jain 公平性指数 (Σx)²/(n·Σx²)：1 表示完全均匀，1/n 表示所有请求都落在一个实例上
+	Fairness float64             `json:"fairness"`
+	Backends []backendSummaryRow `json:"backends"`
+}
+
+type backendSummaryRow struct {
+	Backend  string  `json:"backend"`
+	Requests int64   `json:"requests"`
+	Failed   int64   `json:"failed"`
+	Share    float64 `json:"share"`
+	P50Ms    float64 `json:"p50_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+	// Overloaded 表示该实例的份额超过平均份额的 1+fairnessTolerance 倍
+	Overloaded bool `json:"overloaded,omitempty"`
+}
+
+// summarizeBackends 计算各后端实例的份额、时延与公平性指数，会对样本原地排序；未设置 -backend-header 时返回 nil
+func summarizeBackends(backends map[string]*URLStats) *backendSummary {
+	if backendHeader == "" {
+		return nil
+	}
+	summary := &backendSummary{Header: backendHeader}
+	var total, known int64
+	var sumSquares float64
+	var n int
+	for id, bs := range backends {
+		total += bs.TotalRequests
+		if id != backendUnknown {
+			known += bs.TotalRequests
+			sumSquares += float64(bs.TotalRequests) * float64(bs.TotalRequests)
+			n++
+		}
+	}
+	if sumSquares > 0 {
+		summary.Fairness = float64(known) * float64(known) / (float64(n) * sumSquares)
+	}
+	for id, bs := range backends {
+		sort.Slice(bs.ResponseTimes, func(i, j int) bool { return bs.ResponseTimes[i] < bs.ResponseTimes[j] })
+		row := backendSummaryRow{
+			Backend:  id,
+			Requests: bs.TotalRequests,
+			Failed:   bs.FailedRequests,
+			P50Ms:    durationMs(percentile(bs.ResponseTimes, 50)),
+			P99Ms:    durationMs(percentile(bs.ResponseTimes, 99)),
+		}
+		if total > 0 {
+			row.Share = float64(bs.TotalRequests) / float64(total) * 100
+		}
+		if id != backendUnknown && n > 1 && known > 0 {
+			row.Overloaded = float64(bs.TotalRequests)/float64(known) > (1+fairnessTolerance)/float64(n)
+		}
+		summary.Backends = append(summary.Backends, row)
+	}
+	sort.Slice(summary.Backends, func(i, j int) bool {
+		a, b := summary.Backends[i], summary.Backends[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Backend < b.Backend
+	})
+	return summary
+}
+
+// reportBackends 输出按后端实例的请求数、份额与时延，以及公平性指数和份额过高的实例
+func reportBackends(s *backendSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("\n⚖️   Load Balancer Fairness (by %s):\n", s.Header)
+	if len(s.Backends) == 0 {
+		fmt.Println("  - no responses")
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Backend", "Requests", "Share", "Failed", "P50", "P99"})
+	var known int
+	var knownRequests int64
+	for _, b := range s.Backends {
+		if b.Backend != backendUnknown {
+			known++
+			knownRequests += b.Requests
+		}
+		table.Append([]string{b.Backend, fmt.Sprintf("%d", b.Requests), fmt.Sprintf("%.2f%%", b.Share), fmt.Sprintf("%d", b.Failed),
+			fmt.Sprintf("%.1f ms", b.P50Ms), fmt.Sprintf("%.1f ms", b.P99Ms)})
+	}
+	table.Render()
+	switch known {
+	case 0:
+		fmt.Printf("  - No response carried %s, check the header name\n", s.Header)
+		return
+	case 1:
+		fmt.Println("  - Only one backend answered, the load was not balanced at all")
+		return
+	}
+	fmt.Printf("  - Jain's fairness index: %.3f across %d backends (1 = perfectly even, %.3f = all traffic on one backend)\n", s.Fairness, known, 1/float64(known))
+	for _, b := range s.Backends {
+		if b.Overloaded {
+			fmt.Printf("🚨  %s received %.1fx its fair share of the requests (tolerance %.0f%%)\n", b.Backend,
+				float64(b.Requests)/float64(knownRequests)*float64(known), fairnessTolerance*100)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// faker 为模板函数 fake 的返回值，模板中以 {{fake.Name}}、{{fake.Email}} 的形式生成随机的仿真数据，
+// 无需预先生成大量数据行。数据由 gofakeit 生成，随机数取自该请求的生成器，指定 -seed 时可复现；
+// 下面的方法覆盖 gofakeit 中同名的方法，gofakeit 的其余方法（如 {{fake.Color}}、{{fake.HackerPhrase}}）同样可用
+type faker struct {
+	*gofakeit.Faker
+}
+
+// fakeDomains 只使用为文档保留的域名，避免压测数据中的邮箱与网址指向真实的第三方
+var fakeDomains = []string{"example.com", "example.org", "example.net"}
+
+func (f faker) pick(list []string) string {
+	return list[f.IntN(len(list))]
+}
+
+// Username 形如 mary.smith42
+func (f faker) Username() string {
+	return strings.ToLower(f.FirstName()+"."+f.LastName()) + fmt.Sprint(f.IntN(100))
+}
+
+// Email 形如 mary.smith42@example.com
+func (f faker) Email() string { return f.Username() + "@" + f.pick(fakeDomains) }
+
+// Phone 形如 +1-555-013-4567（555-01xx 为虚构号码段）
+func (f faker) Phone() string {
+	return fmt.Sprintf("+1-555-01%d-%04d", f.IntN(10), f.IntN(10000))
+}
+
+func (f faker) ZipCode() string { return f.Zip() }
+
+// Address 形如 742 Maple Ave, Springfield 01234
+func (f faker) Address() string {
+	return fmt.Sprintf("%s, %s %s", f.Street(), f.City(), f.Zip())
+}
+
+// IPv4 返回公网范围内的随机地址（首段避开 0、10、127 与 224 以上）
+func (f faker) IPv4() string {
+	first := f.IntRange(1, 222)
+	for first == 10 || first == 127 {
+		first = f.IntRange(1, 222)
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", first, f.IntN(256), f.IntN(256), f.IntRange(1, 254))
+}
+
+// IPv6 返回 2001:db8::/32 文档地址段内的随机地址
+func (f faker) IPv6() string {
+	return fmt.Sprintf("2001:db8:%x:%x:%x:%x:%x:%x", f.IntN(1<<16), f.IntN(1<<16), f.IntN(1<<16),
+		f.IntN(1<<16), f.IntN(1<<16), f.IntN(1<<16))
+}
+
+func (f faker) MAC() string { return f.MacAddress() }
+
+// UUID 与 {{uuid}} 相同，取自密码学随机源，不受 -seed 影响
+func (f faker) UUID() string { return newUUID() }
+
+func (f faker) URL() string {
+	return "https://www." + f.pick(fakeDomains) + "/" + f.Word() + "/" + f.Word()
+}
+
+// Int 返回 [min, max] 区间内的随机整数，如 {{fake.Int 18 90}}
+func (f faker) Int(min, max int) int { return f.IntRange(min, max) }
+
+// Price 返回 0.99 到 999.99 之间保留两位小数的价格
+func (f faker) Price() string {
+	cents := f.IntRange(99, 99999)
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// Date 返回过去 30 年内的随机日期，格式为 2006-01-02
+func (f faker) Date() string { return f.past().Format("2006-01-02") }
+
+// DateTime 返回过去 30 年内的随机时间，格式为 RFC 3339
+func (f faker) DateTime() string { return f.past().Format(time.RFC3339) }
+
+func (f faker) past() time.Time {
+	now := time.Now().UTC()
+	return f.DateRange(now.AddDate(-30, 0, 0), now).Truncate(time.Second)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// newRequestFaker 返回第 n 个请求的模板中 fake 得到的 faker
+func newRequestFaker(n int64) faker {
+	return faker{gofakeit.NewFaker(requestRand(n, randTemplate), false)}
+}
+
+func TestFakeTemplate(t *testing.T) {
+	tpl, err := compileTemplate(`{{fake.Name}}|{{fake.Email}}|{{fake.Company}}|{{fake.Address}}|{{fake.Sentence}}|{{fake.Int 18 90}}|{{fake.Color}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同一请求序号渲染出相同的数据，不同序号的数据不同
+	first := renderTemplate(tpl, "", nil, 1)
+	if again := renderTemplate(tpl, "", nil, 1); again != first {
+		t.Errorf("request 1 rendered %q, then %q", first, again)
+	}
+	if other := renderTemplate(tpl, "", nil, 2); other == first {
+		t.Errorf("requests 1 and 2 both rendered %q", first)
+	}
+	if fields := strings.Split(first, "|"); len(fields) != 7 || strings.Contains(first, "||") {
+		t.Errorf("unexpected output %q", first)
+	}
+}
+
+func TestFakeFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		field   func(f faker) string
+	}{
+		{"Email", `^[a-z]+\.[a-z]+\d{1,2}@example\.(com|org|net)$`, func(f faker) string { return f.Email() }},
+		{"Phone", `^\+1-555-01\d-\d{4}$`, func(f faker) string { return f.Phone() }},
+		{"URL", `^https://www\.example\.(com|org|net)/\S+/\S+$`, func(f faker) string { return f.URL() }},
+		{"ZipCode", `^\d{5}$`, func(f faker) string { return f.ZipCode() }},
+		{"IPv6", `^2001:db8(:[0-9a-f]{1,4}){6}$`, func(f faker) string { return f.IPv6() }},
+		{"MAC", `^([0-9a-f]{2}:){5}[0-9a-f]{2}$`, func(f faker) string { return f.MAC() }},
+		{"UUID", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, func(f faker) string { return f.UUID() }},
+		{"Price", `^\d{1,3}\.\d{2}$`, func(f faker) string { return f.Price() }},
+		{"Date", `^\d{4}-\d{2}-\d{2}$`, func(f faker) string { return f.Date() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := regexp.MustCompile(tt.pattern)
+			for n := int64(0); n < 200; n++ {
+				if got := tt.field(newRequestFaker(n)); !re.MatchString(got) {
+					t.Fatalf("%s = %q, want a match for %s", tt.name, got, tt.pattern)
+				}
+			}
+		})
+	}
+}
+
+func TestFakeRanges(t *testing.T) {
+	now := time.Now().UTC()
+	for n := int64(0); n < 500; n++ {
+		f := newRequestFaker(n)
+		ip := net.ParseIP(f.IPv4()).To4()
+		if ip == nil || ip[0] == 0 || ip[0] == 10 || ip[0] == 127 || ip[0] >= 224 || ip[3] == 0 || ip[3] == 255 {
+			t.Fatalf("IPv4 %v is outside the public range", ip)
+		}
+		if v := f.Int(18, 90); v < 18 || v > 90 {
+			t.Fatalf("Int(18, 90) = %d", v)
+		}
+		d, err := time.Parse(time.RFC3339, f.DateTime())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.After(now) || d.Before(now.AddDate(-30, 0, -1)) {
+			t.Fatalf("DateTime %s is not within the past 30 years", d)
+		}
+	}
+}
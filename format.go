@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// outputFormat 为最终汇总的输出格式：text（默认）、wrk、hey、markdown 或 json
+var outputFormat string
+
+// socketErrors 按 wrk 的分类统计未得到响应的请求
+type socketErrors struct {
+	Connect int64
+	Read    int64
+	Write   int64
+	Timeout int64
+}
+
+func (s *socketErrors) add(other socketErrors) {
+	s.Connect += other.Connect
+	s.Read += other.Read
+	s.Write += other.Write
+	s.Timeout += other.Timeout
+}
+
+// recordError 把一次请求错误计入 Errors 与 SocketErrors
+func (ws *WorkerStats) recordError(err error) {
+	// 去掉 *url.Error 中带有完整 URL 的前缀，避免随机 URL 让错误分布无限增长
+	var urlErr *neturl.Error
+	msg := err.Error()
+	if errors.As(err, &urlErr) {
+		msg = urlErr.Err.Error()
+	}
+	ws.Errors[msg]++
+
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		ws.SocketErrors.Timeout++
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		ws.SocketErrors.Connect++
+	case errors.As(err, &opErr) && opErr.Op == "write":
+		ws.SocketErrors.Write++
+	default:
+		ws.SocketErrors.Read++
+	}
+}
+
+// reportFormatted 按 outputFormat 把最终汇总写到 w，text 格式时不做任何事
+func reportFormatted(w io.Writer, stats *Stats, summary statsSummary, results []thresholdResult, baseline *statsSummary,
+	target, method string, concurrency int, elapsed time.Duration) {
+	// 与 wrk/hey 一致，时延取发出请求到读完响应的完整耗时
+	sort.Slice(stats.TotalTimes, func(i, j int) bool {
+		return stats.TotalTimes[i] < stats.TotalTimes[j]
+	})
+	switch outputFormat {
+	case "wrk":
+		reportWrk(w, stats, target, concurrency, elapsed)
+	case "hey":
+		reportHey(w, stats, elapsed)
+	case "markdown":
+		reportMarkdown(w, summary, results, baseline, target, method, concurrency)
+	case "json":
+		reportJSON(w, summary, results)
+	}
+}
+
+// reportWrk 模仿 wrk 的汇总输出。每个 worker 对应 wrk 的一个线程与一条连接
+func reportWrk(w io.Writer, stats *Stats, target string, concurrency int, elapsed time.Duration) {
+	fmt.Fprintf(w, "Running %s test @ %s\n", wrkTime(elapsed, "%.0f"), target)
+	fmt.Fprintf(w, "  %d threads and %d connections\n", concurrency, concurrency)
+	fmt.Fprintf(w, "  Thread Stats%6s%11s%8s%12s\n", "Avg", "Stdev", "Max", "+/- Stdev")
+
+	latencies := make([]float64, len(stats.TotalTimes))
+	for i, d := range stats.TotalTimes {
+		latencies[i] = float64(d)
+	}
+	mean, stdev, max, within := describe(latencies)
+	fmt.Fprintf(w, "    %-10s%8s%10s%9s%8.2f%%\n", "Latency",
+		wrkTime(time.Duration(mean), "%.2f"), wrkTime(time.Duration(stdev), "%.2f"), wrkTime(time.Duration(max), "%.2f"), within)
+
+	rates := make([]float64, len(stats.WorkerRequests))
+	for i, n := range stats.WorkerRequests {
+		if elapsed > 0 {
+			rates[i] = float64(n) / elapsed.Seconds()
+		}
+	}
+	mean, stdev, max, within = describe(rates)
+	fmt.Fprintf(w, "    %-10s%8s%10s%9s%8.2f%%\n", "Req/Sec", wrkMetric(mean), wrkMetric(stdev), wrkMetric(max), within)
+
+	fmt.Fprintln(w, "  Latency Distribution")
+	for _, p := range []float64{50, 75, 90, 99} {
+		fmt.Fprintf(w, "%7.0f%%%10s\n", p, wrkTime(percentile(stats.TotalTimes, p), "%.2f"))
+	}
+
+	fmt.Fprintf(w, "  %d requests in %s, %sB read\n", stats.TotalRequests, wrkTime(elapsed, "%.2f"), wrkBinary(float64(stats.BytesRead)))
+	se := stats.SocketErrors
+	if se.Connect+se.Read+se.Write+se.Timeout > 0 {
+		fmt.Fprintf(w, "  Socket errors: connect %d, read %d, write %d, timeout %d\n", se.Connect, se.Read, se.Write, se.Timeout)
+	}
+	var non2xx3xx int
+	for code, count := range stats.StatusCodes {
+		if code < 200 || code > 399 {
+			non2xx3xx += count
+		}
+	}
+	if non2xx3xx > 0 {
+		fmt.Fprintf(w, "  Non-2xx or 3xx responses: %d\n", non2xx3xx)
+	}
+	var rps, bps float64
+	if elapsed > 0 {
+		rps = float64(stats.TotalRequests) / elapsed.Seconds()
+		bps = float64(stats.BytesRead) / elapsed.Seconds()
+	}
+	fmt.Fprintf(w, "Requests/sec: %9.2f\n", rps)
+	fmt.Fprintf(w, "Transfer/sec: %10sB\n", wrkBinary(bps))
+}
+
+// reportHey 模仿 hey 的汇总输出
+func reportHey(w io.Writer, stats *Stats, elapsed time.Duration) {
+	secs := func(d time.Duration) string { return fmt.Sprintf("%4.4f", d.Seconds()) }
+	latencies := stats.TotalTimes
+	var fastest, slowest, average time.Duration
+	if len(latencies) > 0 {
+		fastest, slowest = latencies[0], latencies[len(latencies)-1]
+		var sum time.Duration
+		for _, d := range latencies {
+			sum += d
+		}
+		average = sum / time.Duration(len(latencies))
+	}
+	var rps float64
+	if elapsed > 0 {
+		rps = float64(stats.TotalRequests) / elapsed.Seconds()
+	}
+
+	fmt.Fprintln(w, "\nSummary:")
+	fmt.Fprintf(w, "  Total:\t%s secs\n", secs(elapsed))
+	fmt.Fprintf(w, "  Slowest:\t%s secs\n", secs(slowest))
+	fmt.Fprintf(w, "  Fastest:\t%s secs\n", secs(fastest))
+	fmt.Fprintf(w, "  Average:\t%s secs\n", secs(average))
+	fmt.Fprintf(w, "  Requests/sec:\t%4.4f\n", rps)
+	if stats.BytesRead > 0 {
+		responses := stats.TotalRequests - stats.SocketErrors.Connect - stats.SocketErrors.Read - stats.SocketErrors.Write - stats.SocketErrors.Timeout
+		fmt.Fprintf(w, "  \n  Total data:\t%d bytes\n", stats.BytesRead)
+		if responses > 0 {
+			fmt.Fprintf(w, "  Size/request:\t%d bytes\n", stats.BytesRead/responses)
+		}
+	}
+
+	fmt.Fprintln(w, "\nResponse time histogram:")
+	if len(latencies) > 0 {
+		const buckets = 10
+		const barWidth = 40
+		marks := make([]time.Duration, buckets+1)
+		step := (slowest - fastest) / buckets
+		for i := range marks {
+			marks[i] = fastest + step*time.Duration(i)
+		}
+		marks[buckets] = slowest
+		counts := make([]int, buckets+1)
+		bi, maxCount := 0, 0
+		for _, d := range latencies {
+			for bi < buckets && d > marks[bi] {
+				bi++
+			}
+			counts[bi]++
+			if counts[bi] > maxCount {
+				maxCount = counts[bi]
+			}
+		}
+		for i, mark := range marks {
+			bar := (counts[i]*barWidth + maxCount/2) / maxCount
+			fmt.Fprintf(w, "  %4.3f [%d]\t|%s\n", mark.Seconds(), counts[i], strings.Repeat("■", bar))
+		}
+	}
+
+	fmt.Fprintln(w, "\n\nLatency distribution:")
+	if len(latencies) > 0 {
+		for _, p := range []float64{10, 25, 50, 75, 90, 95, 99} {
+			fmt.Fprintf(w, "  %.0f%% in %s secs\n", p, secs(percentile(latencies, p)))
+		}
+	}
+
+	// 与 hey 一致，标题写的是 fastest, slowest，实际依次输出最大值、最小值
+	fmt.Fprintln(w, "\nDetails (average, fastest, slowest):")
+	for _, phase := range []struct {
+		name  string
+		stats phaseStats
+	}{
+		{"DNS+dialup", stats.Phases.Dial},
+		{"DNS-lookup", stats.Phases.DNS},
+		{"req write", stats.Phases.Write},
+		{"resp wait", stats.Phases.Server},
+		{"resp read", stats.Phases.Transfer},
+	} {
+		fmt.Fprintf(w, "  %s:\t%s secs, %s secs, %s secs\n", phase.name, secs(phase.stats.avg()), secs(phase.stats.Max), secs(phase.stats.Min))
+	}
+
+	fmt.Fprintln(w, "\nStatus code distribution:")
+	codes := make([]int, 0, len(stats.StatusCodes))
+	for code := range stats.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  [%d]\t%d responses\n", code, stats.StatusCodes[code])
+	}
+
+	if len(stats.Errors) > 0 {
+		fmt.Fprintln(w, "\nError distribution:")
+		messages := make([]string, 0, len(stats.Errors))
+		for msg := range stats.Errors {
+			messages = append(messages, msg)
+		}
+		sort.Strings(messages)
+		for _, msg := range messages {
+			fmt.Fprintf(w, "  [%d]\t%s\n", stats.Errors[msg], msg)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// describe 返回均值、标准差、最大值以及落在均值 ±1 个标准差内的样本百分比（wrk 的 +/- Stdev）
+func describe(values []float64) (mean, stdev, max, within float64) {
+	if len(values) == 0 {
+		return 0, 0, 0, 0
+	}
+	for _, v := range values {
+		mean += v
+		if v > max {
+			max = v
+		}
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		stdev += (v - mean) * (v - mean)
+	}
+	if len(values) > 1 {
+		stdev = math.Sqrt(stdev / float64(len(values)-1))
+	}
+	var n int
+	for _, v := range values {
+		if v >= mean-stdev && v <= mean+stdev {
+			n++
+		}
+	}
+	return mean, stdev, max, float64(n) / float64(len(values)) * 100
+}
+
+// wrkTime 按 wrk 的方式把时长格式化为 us、ms、s 或 m
+func wrkTime(d time.Duration, format string) string {
+	us := float64(d) / float64(time.Microsecond)
+	switch {
+	case us >= 60e6:
+		return fmt.Sprintf(format+"m", us/60e6)
+	case us >= 1e6:
+		return fmt.Sprintf(format+"s", us/1e6)
+	case us >= 1e3:
+		return fmt.Sprintf(format+"ms", us/1e3)
+	default:
+		return fmt.Sprintf(format+"us", us)
+	}
+}
+
+// wrkMetric 按 wrk 的方式用 k、M、G 缩写数值
+func wrkMetric(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.2fG", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.2fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.2fk", v/1e3)
+	default:
+		return fmt.Sprintf("%.2f", v)
+	}
+}
+
+// wrkBinary 按 wrk 的方式用 K、M、G（1024 进制）缩写字节数
+func wrkBinary(v float64) string {
+	switch {
+	case v >= 1<<30:
+		return fmt.Sprintf("%.2fG", v/(1<<30))
+	case v >= 1<<20:
+		return fmt.Sprintf("%.2fM", v/(1<<20))
+	case v >= 1<<10:
+		return fmt.Sprintf("%.2fK", v/(1<<10))
+	default:
+		return fmt.Sprintf("%.2f", v)
+	}
+}
@@ -1,17 +1,27 @@
 module github.com/whiteCcinn/http-test-go
 
-go 1.17
+go 1.22
 
 require (
+	github.com/brianvoe/gofakeit/v7 v7.17.1
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/guptarohit/asciigraph v0.7.3
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/schollz/progressbar/v3 v3.18.0
+	github.com/valyala/fasthttp v1.59.0
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/klauspost/compress v1.17.11 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	golang.org/x/sys v0.29.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/term v0.28.0 // indirect
 )
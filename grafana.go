@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// grafanaExport 为 -grafana-export，压测结束后写出可直接导入 Grafana 的仪表盘 JSON
+var grafanaExport string
+
+// grafanaDatasource 为仪表盘面板使用的数据源：Grafana 内置的 TestData，数据以 CSV 内嵌在面板中，
+// 导入时在 __inputs 中选择 TestData 数据源即可，不需要额外的时序数据库
+var grafanaDatasource = map[string]string{"type": "grafana-testdata-datasource", "uid": "${DS_TESTDATA}"}
+
+// grafanaSeriesCSV 把趋势点转为 CSV：第一列为毫秒时间戳，其余列为各序列
+func grafanaSeriesCSV(times []time.Time, names []string, series ...[]float64) string {
+	var b strings.Builder
+	b.WriteString("time," + strings.Join(names, ",") + "\n")
+	for i, t := range times {
+		b.WriteString(strconv.FormatInt(t.UnixMilli(), 10))
+		for _, s := range series {
+			b.WriteByte(',')
+			if i < len(s) {
+				b.WriteString(strconv.FormatFloat(s[i], 'f', 2, 64))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// grafanaPanel 返回一个以 CSV 为数据的面板，kind 为 timeseries 或 stat
+func grafanaPanel(id int, kind, title, unit, csv string, x, y, w, h int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         id,
+		"type":       kind,
+		"title":      title,
+		"datasource": grafanaDatasource,
+		"gridPos":    map[string]int{"x": x, "y": y, "w": w, "h": h},
+		"fieldConfig": map[string]interface{}{
+			"defaults":  map[string]interface{}{"unit": unit},
+			"overrides": []interface{}{},
+		},
+		"options": map[string]interface{}{
+			"reduceOptions": map[string]interface{}{"calcs": []string{"lastNotNull"}, "fields": "", "values": false},
+		},
+		"targets": []map[string]interface{}{{
+			"refId":      "A",
+			"datasource": grafanaDatasource,
+			"scenarioId": "csv_content",
+			"csvContent": csv,
+		}},
+	}
+}
+
+// buildGrafanaDashboard 用最终汇总与趋势数组生成仪表盘：顶部为汇总数值，其下为 TPS/QPS、时延百分位、
+// Apdex 与自定义指标的趋势，时间范围为压测的起止时间
+func buildGrafanaDashboard(summary statsSummary, target string, start, end time.Time) map[string]interface{} {
+	trend := currentTrend()
+	var panels []map[string]interface{}
+	add := func(kind, title, unit, csv string, w, h int) {
+		// 按 24 列的网格依次排布
+		x, y := 0, 0
+		if n := len(panels); n > 0 {
+			last := panels[n-1]["gridPos"].(map[string]int)
+			x, y = last["x"]+last["w"], last["y"]
+			if x+w > 24 {
+				x, y = 0, last["y"]+last["h"]
+			}
+		}
+		panels = append(panels, grafanaPanel(len(panels)+1, kind, title, unit, csv, x, y, w, h))
+	}
+
+	totals := fmt.Sprintf("total_requests,failed_requests,tps,p50_ms,p95_ms,p99_ms\n%d,%d,%.2f,%.0f,%.0f,%.0f\n",
+		summary.TotalRequests, summary.FailedRequests, summary.TPS, summary.P50Ms, summary.P95Ms, summary.P99Ms)
+	add("stat", "Summary", "none", totals, 24, 4)
+	add("timeseries", "TPS / QPS", "reqps", grafanaSeriesCSV(trend.Time, []string{"tps", "qps"}, trend.TPS, trend.QPS), 12, 8)
+	add("timeseries", "Response Time", "ms", grafanaSeriesCSV(trend.Time, []string{"p50", "p95", "p99"}, trend.P50Ms, trend.P95Ms, trend.P99Ms), 12, 8)
+	if apdexThreshold > 0 {
+		add("timeseries", "Apdex", "none", grafanaSeriesCSV(trend.Time, []string{"apdex"}, trend.Apdex), 12, 8)
+	}
+	names := make([]string, 0, len(trend.Metrics))
+	for name := range trend.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add("timeseries", "Metric: "+name, "none", grafanaSeriesCSV(trend.Time, []string{name}, trend.Metrics[name]), 12, 8)
+	}
+
+	tags := []string{"http-test-go"}
+	for _, tag := range strings.Split(formatTags(runTags), ", ") {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return map[string]interface{}{
+		"__inputs": []map[string]string{{
+			"name":       "DS_TESTDATA",
+			"label":      "TestData",
+			"type":       "datasource",
+			"pluginId":   "grafana-testdata-datasource",
+			"pluginName": "TestData",
+		}},
+		"title":         fmt.Sprintf("http-test-go %s (%s)", target, start.Format("2006-01-02 15:04:05")),
+		"description":   fmt.Sprintf("Load test of %s, %d requests, engine %s", target, summary.TotalRequests, summary.Engine),
+		"tags":          tags,
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"editable":      true,
+		"time": map[string]string{
+			"from": start.UTC().Format(time.RFC3339),
+			"to":   end.UTC().Format(time.RFC3339),
+		},
+		"panels": panels,
+	}
+}
+
+// writeGrafanaDashboard 写出仪表盘 JSON
+func writeGrafanaDashboard(filename string, summary statsSummary, target string, start, end time.Time) error {
+	data, err := json.MarshalIndent(buildGrafanaDashboard(summary, target, start, end), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
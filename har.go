@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// harFile 为浏览器开发者工具导出的 HAR 文件中回放所需的部分
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime string `json:"startedDateTime"`
+			Request         struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// harSkippedHeaders 为回放时不应原样发送的 header，由 net/http 自行处理
+var harSkippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"keep-alive":        true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"proxy-connection":  true,
+}
+
+// loadHARFile 读取 HAR 文件，按请求开始时间排序后追加到 requestPool，返回加载的请求数
+func loadHARFile(filename string) int {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		slog.Error("Unable to read HAR file", "err", err)
+		return 0
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		slog.Error("Unable to parse HAR file", "err", err)
+		return 0
+	}
+
+	type timedSpec struct {
+		started time.Time
+		spec    *requestSpec
+	}
+	var entries []timedSpec
+	for _, entry := range har.Log.Entries {
+		spec := &requestSpec{
+			Method:  entry.Request.Method,
+			URL:     entry.Request.URL,
+			Headers: http.Header{},
+		}
+		for _, h := range entry.Request.Headers {
+			// HTTP/2 的伪 header（:authority 等）无法作为普通 header 发送
+			if strings.HasPrefix(h.Name, ":") || harSkippedHeaders[strings.ToLower(h.Name)] {
+				continue
+			}
+			spec.Headers.Add(h.Name, h.Value)
+		}
+		if entry.Request.PostData != nil {
+			spec.Body = entry.Request.PostData.Text
+			if entry.Request.PostData.MimeType != "" && spec.Headers.Get("Content-Type") == "" {
+				spec.Headers.Set("Content-Type", entry.Request.PostData.MimeType)
+			}
+		}
+		started, _ := time.Parse(time.RFC3339Nano, entry.StartedDateTime)
+		entries = append(entries, timedSpec{started: started, spec: spec})
+	}
+	if len(entries) == 0 {
+		return 0
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].started.Before(entries[j].started) })
+	first := entries[0].started
+	for _, e := range entries {
+		if !e.started.IsZero() && !first.IsZero() {
+			e.spec.Offset = e.started.Sub(first)
+		}
+		requestPool = append(requestPool, e.spec)
+	}
+	return len(entries)
+}
+
+// feedTimedRequests 按录制时的相对时间偏移依次投递请求，speed 大于 1 时压缩时间轴、小于 1 时拉长；
+// 请求数超过录制数量时循环回放，每一轮的间隔为录制时长加上平均请求间隔
+func feedTimedRequests(specs []*requestSpec, total int, speed float64, jobs chan<- *requestSpec) {
+	defer close(jobs)
+	if len(specs) == 0 {
+		return
+	}
+	span := specs[len(specs)-1].Offset
+	if len(specs) > 1 {
+		span += span / time.Duration(len(specs)-1)
+	}
+	start := time.Now()
+	for i := 0; i < total && !shouldStop(); i++ {
+		spec := specs[i%len(specs)]
+		offset := time.Duration(i/len(specs))*span + spec.Offset
+		at := start.Add(time.Duration(float64(offset) / speed))
+		if wait := time.Until(at); wait > 0 {
+			time.Sleep(wait)
+		}
+		scheduled := *spec
+		scheduled.Scheduled = at
+		jobs <- &scheduled
+	}
+}
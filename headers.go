@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// extraHeaders 为 -H 指定的 header，每个请求发送前渲染，覆盖请求自带的同名 header
+var extraHeaders headerFlags
+
+// headerValue 为一个 -H header：值可以是模板（如 {{uuid}}），也可以是 @file，按顺序轮流使用文件中的每一行
+type headerValue struct {
+	name  string
+	value string
+	tpl   *requestTemplate
+	// rotation 为 @file 加载的值，cursor 为下一个要使用的位置
+	rotation []string
+	cursor   *int64
+}
+
+// headerFlags 实现 flag.Value，支持多次指定 -H "Name: value"
+type headerFlags []headerValue
+
+func (f *headerFlags) String() string {
+	parts := make([]string, len(*f))
+	for i, h := range *f {
+		parts[i] = h.name + ": " + h.value
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *headerFlags) Set(value string) error {
+	kv := strings.SplitN(value, ":", 2)
+	name := strings.TrimSpace(kv[0])
+	if len(kv) != 2 || name == "" {
+		return fmt.Errorf("invalid header %q, expected \"Name: value\"", value)
+	}
+	h := headerValue{name: http.CanonicalHeaderKey(name), value: strings.TrimSpace(kv[1])}
+	if strings.HasPrefix(h.value, "@") {
+		data, err := ioutil.ReadFile(h.value[1:])
+		if err != nil {
+			return fmt.Errorf("unable to read header values: %v", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				h.rotation = append(h.rotation, line)
+			}
+		}
+		if len(h.rotation) == 0 {
+			return fmt.Errorf("no header values in %s", h.value[1:])
+		}
+		h.cursor = new(int64)
+	} else {
+		tpl, err := compileTemplate(h.value)
+		if err != nil {
+			return fmt.Errorf("invalid header template %q: %v", h.value, err)
+		}
+		h.tpl = tpl
+	}
+	*f = append(*f, h)
+	return nil
+}
+
+// render 返回第 n 个请求使用的值
+func (h headerValue) render(row map[string]string, n int64) string {
+	if h.rotation != nil {
+		i := atomic.AddInt64(h.cursor, 1) - 1
+		return h.rotation[i%int64(len(h.rotation))]
+	}
+	return renderTemplate(h.tpl, h.value, row, n)
+}
+
+// compileHeaderTemplates 为请求自带的、包含 {{ 的 header 值编译模板，与 URL、请求体模板一样每个请求渲染一次；
+// strict 的含义与 compileRequestTemplates 相同
+func compileHeaderTemplates(spec *requestSpec, strict bool) error {
+	for name, values := range spec.Headers {
+		for i, v := range values {
+			tpl, err := compileTemplate(v)
+			if err != nil && strict {
+				return fmt.Errorf("invalid %s header template: %v", name, err)
+			}
+			if tpl == nil {
+				continue
+			}
+			if spec.headerTpls == nil {
+				spec.headerTpls = make(map[string][]*requestTemplate)
+			}
+			if spec.headerTpls[name] == nil {
+				spec.headerTpls[name] = make([]*requestTemplate, len(values))
+			}
+			spec.headerTpls[name][i] = tpl
+		}
+	}
+	return nil
+}
+
+// renderHeaders 为第 n 个请求渲染请求自带的 header 模板并加上 -H 指定的 header，返回新的 header
+func renderHeaders(spec *requestSpec, row map[string]string, n int64) http.Header {
+	headers := spec.Headers.Clone()
+	for name, tpls := range spec.headerTpls {
+		for i, tpl := range tpls {
+			if tpl != nil {
+				headers[name][i] = renderTemplate(tpl, spec.Headers[name][i], row, n)
+			}
+		}
+	}
+	if len(extraHeaders) > 0 && headers == nil {
+		headers = make(http.Header, len(extraHeaders))
+	}
+	// 同名的 -H 第一次出现时替换请求自带的值，之后追加
+	seen := make(map[string]bool, len(extraHeaders))
+	for _, h := range extraHeaders {
+		if seen[h.name] {
+			headers.Add(h.name, h.render(row, n))
+		} else {
+			headers.Set(h.name, h.render(row, n))
+			seen[h.name] = true
+		}
+	}
+	return headers
+}
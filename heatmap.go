@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+)
+
+// heatmapBuckets 为时延分桶数：第 i 桶的上界为 100µs * 2^(i/2)，最后一桶约 74s，更慢的计入最后一桶
+const heatmapBuckets = 40
+
+// heatmapMaxColumns 为 ASCII 热力图的最大列数，时间窗口更多时合并相邻窗口
+const heatmapMaxColumns = 100
+
+// heatmapShades 由浅到深表示每个格子中的请求数
+const heatmapShades = " .:-=+*#%@"
+
+// heatmapWindow 为热力图的时间窗口，0 表示不记录
+var heatmapWindow time.Duration
+
+// heatmapStart 为热力图时间轴的起点
+var heatmapStart time.Time
+
+// heatmapCounts 以时间窗口序号为 key，记录每个窗口内各时延分桶的请求数
+type heatmapCounts map[int64]*[heatmapBuckets]int64
+
+// heatmapBucket 返回时延 d 所在的分桶
+func heatmapBucket(d time.Duration) int {
+	if d <= 100*time.Microsecond {
+		return 0
+	}
+	i := int(math.Ceil(2 * math.Log2(float64(d)/float64(100*time.Microsecond))))
+	if i >= heatmapBuckets {
+		return heatmapBuckets - 1
+	}
+	return i
+}
+
+// heatmapBucketBound 返回第 i 个分桶的上界
+func heatmapBucketBound(i int) time.Duration {
+	return time.Duration(float64(100*time.Microsecond) * math.Pow(2, float64(i)/2))
+}
+
+// record 把 at 时刻发出、耗时 d 的请求计入热力图
+func (h *heatmapCounts) record(at time.Time, d time.Duration) {
+	if heatmapWindow <= 0 {
+		return
+	}
+	if *h == nil {
+		*h = make(heatmapCounts)
+	}
+	window := int64(at.Sub(heatmapStart) / heatmapWindow)
+	row, ok := (*h)[window]
+	if !ok {
+		row = &[heatmapBuckets]int64{}
+		(*h)[window] = row
+	}
+	row[heatmapBucket(d)]++
+}
+
+// add 合并另一份热力图
+func (h *heatmapCounts) add(other heatmapCounts) {
+	for window, row := range other {
+		if *h == nil {
+			*h = make(heatmapCounts)
+		}
+		agg, ok := (*h)[window]
+		if !ok {
+			agg = &[heatmapBuckets]int64{}
+			(*h)[window] = agg
+		}
+		for i, n := range row {
+			agg[i] += n
+		}
+	}
+}
+
+// grid 把热力图展开为按时间顺序排列的二维数组，并返回实际出现过的最小、最大分桶
+func (h heatmapCounts) grid() (columns [][heatmapBuckets]int64, low, high int) {
+	var last int64 = -1
+	for window := range h {
+		if window > last {
+			last = window
+		}
+	}
+	columns = make([][heatmapBuckets]int64, last+1)
+	low, high = heatmapBuckets, -1
+	for window, row := range h {
+		columns[window] = *row
+		for i, n := range row {
+			if n == 0 {
+				continue
+			}
+			if i < low {
+				low = i
+			}
+			if i > high {
+				high = i
+			}
+		}
+	}
+	return columns, low, high
+}
+
+// reportHeatmap 输出时延-时间 ASCII 热力图：横轴为时间，纵轴为时延（对数刻度），字符越深请求越多
+func reportHeatmap(h heatmapCounts) {
+	columns, low, high := h.grid()
+	if high < 0 {
+		return
+	}
+	// 窗口过多时把相邻窗口合并为一列
+	group := (len(columns) + heatmapMaxColumns - 1) / heatmapMaxColumns
+	merged := make([][heatmapBuckets]int64, (len(columns)+group-1)/group)
+	var max int64
+	for i, col := range columns {
+		for b, n := range col {
+			merged[i/group][b] += n
+			if merged[i/group][b] > max {
+				max = merged[i/group][b]
+			}
+		}
+	}
+
+	fmt.Printf("\n🌡️   Latency Heatmap (each column = %s, darker = more requests, max %d per cell):\n", heatmapWindow*time.Duration(group), max)
+	for b := high; b >= low; b-- {
+		var line strings.Builder
+		for _, col := range merged {
+			line.WriteByte(heatmapShade(col[b], max))
+		}
+		fmt.Printf("%10s ┤%s\n", "<="+formatBound(heatmapBucketBound(b)), line.String())
+	}
+	fmt.Printf("%10s └%s\n", "", strings.Repeat("─", len(merged)))
+	elapsed := heatmapWindow * time.Duration(len(columns))
+	fmt.Printf("%10s  0s%*s\n", "", len(merged)-2, elapsed.String())
+}
+
+// heatmapShade 以对数比例把请求数映射为字符，避免少数高频格子让其余格子都显示为空白
+func heatmapShade(n, max int64) byte {
+	if n == 0 || max == 0 {
+		return heatmapShades[0]
+	}
+	level := 1 + int(math.Log(float64(n))/math.Log(float64(max)+1)*float64(len(heatmapShades)-1))
+	if level >= len(heatmapShades) {
+		level = len(heatmapShades) - 1
+	}
+	return heatmapShades[level]
+}
+
+// formatBound 把分桶上界格式化为简短字符串
+func formatBound(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	}
+}
+
+// writeHeatmapHTML 把热力图写为独立的 HTML 文件（canvas 绘制，鼠标悬停显示格子详情）
+func writeHeatmapHTML(filename string, h heatmapCounts) error {
+	columns, low, high := h.grid()
+	if high < 0 {
+		low, high = 0, 0
+	}
+	labels := make([]string, 0, high-low+1)
+	for b := low; b <= high; b++ {
+		labels = append(labels, formatBound(heatmapBucketBound(b)))
+	}
+	counts := make([][]int64, len(columns))
+	for i, col := range columns {
+		counts[i] = append([]int64(nil), col[low:high+1]...)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"windowSeconds": heatmapWindow.Seconds(),
+		"labels":        labels,
+		"counts":        counts,
+		"config":        effectiveConfig(),
+	})
+	if err != nil {
+		return err
+	}
+	page := strings.Replace(heatmapHTMLTemplate, "/*DATA*/null", string(data), 1)
+	return ioutil.WriteFile(filename, []byte(page), 0644)
+}
+
+const heatmapHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Latency Heatmap</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+#tip { position: fixed; background: #222; color: #fff; padding: 4px 8px; font-size: 12px; display: none; pointer-events: none; }
+</style>
+</head>
+<body>
+<h2>Latency Heatmap</h2>
+<p>X: time since start, Y: request latency (upper bound of bucket), color: number of requests (log scale).</p>
+<canvas id="heatmap"></canvas>
+<div id="tip"></div>
+<details><summary>Run configuration</summary><pre id="config"></pre></details>
+<script>
+const data = /*DATA*/null;
+document.getElementById("config").textContent = JSON.stringify(data.config, null, 2);
+const canvas = document.getElementById("heatmap");
+const ctx = canvas.getContext("2d");
+const left = 80, bottom = 30, rows = data.labels.length, cols = data.counts.length;
+const cellW = Math.max(2, Math.min(12, Math.floor(1200 / Math.max(cols, 1))));
+const cellH = 14;
+canvas.width = left + cols * cellW + 20;
+canvas.height = rows * cellH + bottom + 10;
+let max = 0;
+data.counts.forEach(col => col.forEach(n => { if (n > max) max = n; }));
+function color(n) {
+  if (n === 0) return "#f4f4f4";
+  const t = Math.log(n + 1) / Math.log(max + 1);
+  return "hsl(" + Math.round(60 - 60 * t) + ", 100%, " + Math.round(85 - 45 * t) + "%)";
+}
+ctx.font = "11px sans-serif";
+ctx.textAlign = "right";
+for (let r = 0; r < rows; r++) {
+  const y = (rows - 1 - r) * cellH;
+  ctx.fillStyle = "#333";
+  ctx.fillText("<=" + data.labels[r], left - 6, y + cellH - 3);
+  for (let c = 0; c < cols; c++) {
+    ctx.fillStyle = color(data.counts[c][r]);
+    ctx.fillRect(left + c * cellW, y, cellW, cellH);
+  }
+}
+ctx.textAlign = "left";
+ctx.fillStyle = "#333";
+const step = Math.max(1, Math.ceil(cols / 10));
+for (let c = 0; c < cols; c += step) {
+  ctx.fillText((c * data.windowSeconds).toFixed(0) + "s", left + c * cellW, rows * cellH + 15);
+}
+const tip = document.getElementById("tip");
+canvas.addEventListener("mousemove", e => {
+  const rect = canvas.getBoundingClientRect();
+  const c = Math.floor((e.clientX - rect.left - left) / cellW);
+  const r = rows - 1 - Math.floor((e.clientY - rect.top) / cellH);
+  if (c < 0 || c >= cols || r < 0 || r >= rows) { tip.style.display = "none"; return; }
+  tip.textContent = (c * data.windowSeconds).toFixed(1) + "s, <=" + data.labels[r] + ": " + data.counts[c][r] + " requests";
+  tip.style.left = (e.clientX + 12) + "px";
+  tip.style.top = (e.clientY + 12) + "px";
+  tip.style.display = "block";
+});
+</script>
+</body>
+</html>
+`
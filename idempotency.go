@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// idempotencyHeader、duplicateRatio、duplicateExpect 为 -idempotency-header、-duplicate-ratio 与 -duplicate-expect：
+// 每个请求带上唯一的幂等 key，其中 duplicateRatio 比例的请求改用之前已得到响应的 key 重复提交，
+// 并校验服务端对重复提交的处理——same 要求与首次提交的状态码和 body 完全相同，数字要求返回该状态码（如 409）。
+// 符合预期的重复提交计为成功，不符合的计为内容校验失败
+var (
+	idempotencyHeader string
+	duplicateRatio    float64
+	duplicateExpect   string
+	// duplicateStatus 为 -duplicate-expect 为状态码时的取值，same 时为 0
+	duplicateStatus int
+)
+
+// idempotencyKeys 为最近得到响应的幂等 key 及首次提交的结果，重复提交从中随机选取
+var idempotencyKeys = &submittedKeys{}
+
+// maxSubmittedKeys 为保留的最近 key 数
+const maxSubmittedKeys = 1024
+
+// submission 为一个幂等 key 首次提交得到的响应
+type submission struct {
+	key    string
+	status int
+	sum    string
+}
+
+// submittedKeys 为固定容量的环形缓冲，多个 worker 共享
+type submittedKeys struct {
+	mu    sync.Mutex
+	items []submission
+	next  int
+}
+
+func (s *submittedKeys) add(sub submission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) < maxSubmittedKeys {
+		s.items = append(s.items, sub)
+		return
+	}
+	s.items[s.next] = sub
+	s.next = (s.next + 1) % maxSubmittedKeys
+}
+
+// pick 随机返回一个已提交的 key，还没有时返回 false
+func (s *submittedKeys) pick() (submission, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) == 0 {
+		return submission{}, false
+	}
+	return s.items[sharedRand.Intn(len(s.items))], true
+}
+
+// parseDuplicateExpect 解析 -duplicate-expect：same 或一个状态码
+func parseDuplicateExpect(value string) error {
+	if value == "same" {
+		duplicateStatus = 0
+		return nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 100 || code > 599 {
+		return fmt.Errorf("invalid -duplicate-expect %q (expected same or a status code such as 409)", value)
+	}
+	duplicateStatus = code
+	return nil
+}
+
+// idempotentRequest 为一个请求使用的幂等 key；duplicate 非空时为重复提交，记录首次提交的结果
+type idempotentRequest struct {
+	key       string
+	duplicate *submission
+}
+
+// setIdempotencyKey 为请求加上幂等 key，按 duplicateRatio 决定是否重复使用之前的 key；未启用时返回 nil
+func setIdempotencyKey(req *http.Request) *idempotentRequest {
+	if idempotencyHeader == "" {
+		return nil
+	}
+	r := &idempotentRequest{}
+	if duplicateRatio > 0 && sharedRand.Float64() < duplicateRatio {
+		if sub, ok := idempotencyKeys.pick(); ok {
+			r.key, r.duplicate = sub.key, &sub
+		}
+	}
+	if r.duplicate == nil {
+		r.key = newUUID()
+	}
+	req.Header.Set(idempotencyHeader, r.key)
+	return r
+}
+
+// check 记录首次提交的结果，或校验重复提交的响应，返回不符合预期的原因，符合时返回空字符串；
+// 首次提交不做校验，总是返回空字符串
+func (r *idempotentRequest) check(ws *WorkerStats, status int, sum string) string {
+	if r.duplicate == nil {
+		ws.Idempotency.Keys++
+		idempotencyKeys.add(submission{key: r.key, status: status, sum: sum})
+		return ""
+	}
+	ws.Idempotency.Duplicates++
+	var reason string
+	switch {
+	case duplicateStatus != 0 && status != duplicateStatus:
+		reason = fmt.Sprintf("idempotency: duplicate got %d, expected %d", status, duplicateStatus)
+	case duplicateStatus == 0 && status != r.duplicate.status:
+		reason = fmt.Sprintf("idempotency: duplicate got %d, original got %d", status, r.duplicate.status)
+	case duplicateStatus == 0 && sum != r.duplicate.sum:
+		reason = "idempotency: duplicate body differs from the original"
+	default:
+		ws.Idempotency.Honoured++
+	}
+	return reason
+}
+
+// idempotencyStats 为幂等 key 的统计
+type idempotencyStats struct {
+	// Keys 为以新 key 首次提交的请求数，Duplicates 为重复提交数，Honoured 为其中得到预期响应的
+	Keys       int64 `json:"keys"`
+	Duplicates int64 `json:"duplicates"`
+	Honoured   int64 `json:"honoured"`
+}
+
+// add 合并另一个 worker 的统计
+func (s *idempotencyStats) add(other idempotencyStats) {
+	s.Keys += other.Keys
+	s.Duplicates += other.Duplicates
+	s.Honoured += other.Honoured
+}
+
+// summarizeIdempotency 返回写入 JSON 汇总的统计，未设置 -idempotency-header 时返回 nil
+func summarizeIdempotency(s idempotencyStats) *idempotencyStats {
+	if idempotencyHeader == "" {
+		return nil
+	}
+	return &s
+}
+
+// reportIdempotency 输出重复提交的数量与得到预期响应的比例，不符合预期的原因见内容校验失败的统计
+func reportIdempotency(s *idempotencyStats) {
+	if s == nil {
+		return
+	}
+	expect := "an identical response"
+	if duplicateStatus != 0 {
+		expect = fmt.Sprintf("status %d", duplicateStatus)
+	}
+	fmt.Printf("\n🔑  Idempotency (%s): %d new keys, %d duplicate submissions\n", idempotencyHeader, s.Keys, s.Duplicates)
+	if s.Duplicates == 0 {
+		return
+	}
+	mark := "✅"
+	if s.Honoured < s.Duplicates {
+		mark = "❌"
+	}
+	fmt.Printf("%s  %d of %d duplicates (%.2f%%) got %s\n", mark, s.Honoured, s.Duplicates, float64(s.Honoured)/float64(s.Duplicates)*100, expect)
+}
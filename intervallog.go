@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// intervalLogFile、rotateSizeValue、intervalLogStdout 为 -interval-log、-rotate-size 与 -interval-log-stdout：
+// 周期统计追加到该文件而不再输出到终端（-interval-log-stdout 时两者都输出），文件超过 -rotate-size 后轮转
+var (
+	intervalLogFile   string
+	rotateSizeValue   string
+	intervalLogStdout bool
+)
+
+// intervalLogger 把周期统计追加到文件并按大小轮转：当前文件始终为 -interval-log，写满后依次改名为 .1、.2……，
+// 编号越大越新，所有文件按编号连起来即完整的时间线；只在 ticker goroutine 中使用
+type intervalLogger struct {
+	path    string
+	limit   int64
+	f       *os.File
+	size    int64
+	rotated int
+}
+
+// intervalLog 为 -interval-log 打开的日志，未设置时为 nil
+var intervalLog *intervalLogger
+
+// openIntervalLog 创建 path，limit 为轮转的字节数，0 表示不轮转
+func openIntervalLog(path string, limit int64) (*intervalLogger, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &intervalLogger{path: path, limit: limit, f: f}, nil
+}
+
+// write 追加一次周期统计，前面加上墙钟时间与已运行时长；一次的内容总是写在同一个文件中。l 为 nil 时不做任何事
+func (l *intervalLogger) write(stats *Stats, startTime, now time.Time) {
+	if l == nil {
+		return
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n🕒  %s (+%s)\n", wallTime(now).Format(time.RFC3339), now.Sub(startTime).Round(time.Second))
+	writeStats(&buf, stats, startTime, now)
+	if l.limit > 0 && l.size > 0 && l.size+int64(buf.Len()) > l.limit {
+		if err := l.rotate(); err != nil {
+			slog.Error("Unable to rotate interval log", "err", err)
+		} else {
+			slog.Debug("Interval log rotated", "file", fmt.Sprintf("%s.%d", l.path, l.rotated))
+		}
+	}
+	n, _ := l.f.Write(buf.Bytes())
+	l.size += int64(n)
+}
+
+// rotate 把当前文件改名为下一个编号并重新创建
+func (l *intervalLogger) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(l.path, fmt.Sprintf("%s.%d", l.path, l.rotated+1)); err != nil {
+		// 改名失败时继续写原文件
+		f, openErr := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0644)
+		if openErr == nil {
+			l.f = f
+		}
+		return err
+	}
+	l.rotated++
+	f, err := os.Create(l.path)
+	if err != nil {
+		return err
+	}
+	l.f, l.size = f, 0
+	return nil
+}
+
+// Close 关闭当前文件
+func (l *intervalLogger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// describe 返回日志文件的说明，用于运行结束时的提示
+func (l *intervalLogger) describe() string {
+	if l.rotated == 0 {
+		return l.path
+	}
+	return fmt.Sprintf("%s (earlier parts in %s.1 to %s.%d, oldest first)", l.path, l.path, l.path, l.rotated)
+}
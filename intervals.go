@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// intervalStreamFile 为 -interval-stream，每个趋势窗口结束时向该文件追加一行 JSON：窗口内的请求数与时延直方图。
+// 分布式模式下 worker 以此把各窗口的直方图流式上报给 controller
+var intervalStreamFile string
+
+// intervalRecord 为一个趋势窗口的计数与时延直方图（而非原始样本），不同进程的窗口可逐桶精确合并；
+// Start、End 为墙钟时间（按 -timezone），StartOffsetMs、EndOffsetMs 为相对运行开始的单调时钟偏移
+type intervalRecord struct {
+	Seq           int          `json:"seq"`
+	Start         time.Time    `json:"start"`
+	End           time.Time    `json:"end"`
+	StartOffsetMs float64      `json:"start_offset_ms"`
+	EndOffsetMs   float64      `json:"end_offset_ms"`
+	Requests      int64        `json:"requests"`
+	Success       int64        `json:"success"`
+	Digest        sparseDigest `json:"digest"`
+}
+
+// intervalStream 按累计统计的差值生成各窗口的记录，只在 ticker goroutine 中（或 worker 全部结束后）使用
+type intervalStream struct {
+	f   *os.File
+	enc *json.Encoder
+	seq int
+	// prev 为上一个窗口结束时的累计值
+	prev                   latencyDigest
+	prevTotal, prevSuccess int64
+}
+
+// intervals 为 -interval-stream 打开的输出，未设置时为 nil
+var intervals *intervalStream
+
+// openIntervalStream 打开 -interval-stream 指定的文件，可以是 /dev/fd/N 形式的管道
+func openIntervalStream(filename string) (*intervalStream, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &intervalStream{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// emit 写出 [start, end) 窗口的记录：请求数与直方图均为各 worker 累计值与上一个窗口的差
+func (s *intervalStream) emit(workers []*WorkerStats, start, end time.Time) {
+	if s == nil {
+		return
+	}
+	var cur latencyDigest
+	var total, success int64
+	for _, ws := range workers {
+		cur.add(ws.Digest)
+		total += ws.TotalRequests
+		success += ws.SuccessRequests
+	}
+	window := latencyDigest{total: cur.total - s.prev.total}
+	if window.total > 0 {
+		window.counts = make([]int64, digestBuckets)
+		for i, n := range cur.counts {
+			if s.prev.counts != nil {
+				n -= s.prev.counts[i]
+			}
+			window.counts[i] = n
+		}
+	}
+	s.enc.Encode(intervalRecord{
+		Seq:           s.seq,
+		Start:         wallTime(start),
+		End:           wallTime(end),
+		StartOffsetMs: offsetMs(start),
+		EndOffsetMs:   offsetMs(end),
+		Requests:      total - s.prevTotal,
+		Success:       success - s.prevSuccess,
+		Digest:        window.sparse(),
+	})
+	s.seq++
+	s.prev, s.prevTotal, s.prevSuccess = cur, total, success
+}
+
+// skip 把当前累计值作为下一个窗口的起点而不写出记录，用于 -resume 恢复的计数
+func (s *intervalStream) skip(workers []*WorkerStats) {
+	if s == nil {
+		return
+	}
+	for _, ws := range workers {
+		s.prev.add(ws.Digest)
+		s.prevTotal += ws.TotalRequests
+		s.prevSuccess += ws.SuccessRequests
+	}
+}
+
+// Close 关闭输出
+func (s *intervalStream) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.f.Close()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// keepAliveSplit 决定 -keepalive_ratio 的分配方式：
+//   - random  每个请求按比例随机选择是否使用 Keep-Alive（默认）
+//   - exact   按比例把整个 worker 分给 Keep-Alive 与非 Keep-Alive 客户端，
+//     每个 worker 只使用其中一种，请求数少时比例也不会随机波动
+var keepAliveSplit string
+
+// checkKeepAliveSplit 校验 -keepalive-split
+func checkKeepAliveSplit() error {
+	if keepAliveSplit != "random" && keepAliveSplit != "exact" {
+		return fmt.Errorf("unknown -keepalive-split %q (expected random or exact)", keepAliveSplit)
+	}
+	return nil
+}
+
+// keepAliveWorkers 返回 exact 分配下使用 Keep-Alive 的 worker 数
+func keepAliveWorkers(ratio float64, workers int) int {
+	return int(math.Round(ratio * float64(workers)))
+}
+
+// workerKeepAliveRatio 返回第 worker 个 worker 实际使用的比例：exact 分配下为 1 或 0
+func workerKeepAliveRatio(ratio float64, worker, workers int) float64 {
+	if keepAliveSplit != "exact" {
+		return ratio
+	}
+	if worker < keepAliveWorkers(ratio, workers) {
+		return 1
+	}
+	return 0
+}
+
+// usesKeepAlive 判断客户端是否复用连接
+func usesKeepAlive(client *http.Client) bool {
+	switch transport := client.Transport.(type) {
+	case *http.Transport:
+		return !transport.DisableKeepAlives
+	case *fasthttpTransport:
+		return transport.keepAlive
+	}
+	return false
+}
+
+// reportKeepAliveSplit 输出 Keep-Alive 与非 Keep-Alive 请求的实际数量，只在两者混用时输出
+func reportKeepAliveSplit(stats *Stats, ratio float64, workers int) {
+	if (ratio <= 0 || ratio >= 1) && len(connectionPolicies) == 0 || stats.TotalRequests == 0 {
+		return
+	}
+	without := stats.TotalRequests - stats.KeepAliveRequests
+	fmt.Printf("\n🔁  Keep-Alive Split (%s): %d requests with keep-alive (%.1f%%), %d without (%.1f%%)",
+		keepAliveSplit,
+		stats.KeepAliveRequests, float64(stats.KeepAliveRequests)/float64(stats.TotalRequests)*100,
+		without, float64(without)/float64(stats.TotalRequests)*100)
+	if keepAliveSplit == "exact" {
+		fmt.Printf(", %d of %d workers on keep-alive", keepAliveWorkers(ratio, workers), workers)
+	}
+	fmt.Println()
+}
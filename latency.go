@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// latencyMetric 为 -latency-metric，选择计入百分位、趋势、Apdex 等统计的请求时延：
+// total 为发出请求到读完响应 body，ttfb 为发出请求到收到响应首字节，server 为 TTFB 去掉 DNS、建连与 TLS 握手的部分
+var latencyMetric string
+
+// checkLatencyMetric 检查 -latency-metric
+func checkLatencyMetric() error {
+	switch latencyMetric {
+	case "total", "ttfb", "server":
+		return nil
+	}
+	return fmt.Errorf("unknown -latency-metric %q (expected total, ttfb or server)", latencyMetric)
+}
+
+// measuredLatency 按 -latency-metric 从一个请求的三种时延中选出计入统计的一种
+func measuredLatency(total, ttfb, server time.Duration) time.Duration {
+	switch latencyMetric {
+	case "ttfb":
+		return ttfb
+	case "server":
+		return server
+	}
+	return total
+}
+
+// latencyPercentiles 为一种时延的百分位，单位为 ms
+type latencyPercentiles struct {
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// latencySummary 为写入 JSON 汇总的各请求时延：Total 为发出请求到读完响应 body，TTFB 为发出请求到收到响应首字节，
+// Server 为 TTFB 去掉 DNS、建连与 TLS 握手；Metric 为计入 P50/P95/P99 的一种
+type latencySummary struct {
+	Metric string             `json:"metric"`
+	Total  latencyPercentiles `json:"total"`
+	TTFB   latencyPercentiles `json:"ttfb"`
+	Server latencyPercentiles `json:"server"`
+}
+
+// summarizeLatency 计算三种时延的百分位，会对样本原地排序
+func summarizeLatency(total, ttfb, server []time.Duration) *latencySummary {
+	return &latencySummary{
+		Metric: latencyMetric,
+		Total:  latencyPercentilesOf(total),
+		TTFB:   latencyPercentilesOf(ttfb),
+		Server: latencyPercentilesOf(server),
+	}
+}
+
+// latencyPercentilesOf 对 times 原地排序并计算百分位
+func latencyPercentilesOf(times []time.Duration) latencyPercentiles {
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return latencyPercentiles{
+		P50Ms: durationMs(percentile(times, 50)),
+		P95Ms: durationMs(percentile(times, 95)),
+		P99Ms: durationMs(percentile(times, 99)),
+		MaxMs: durationMs(percentile(times, 100)),
+	}
+}
+
+// reportLatency 并列输出三种时延的百分位，标出 -latency-metric 选中的一种
+func reportLatency(s *latencySummary) {
+	if s == nil {
+		return
+	}
+	fmt.Println("\n⏳  Latency (total vs. time to first byte vs. server time):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Measure", "P50", "P95", "P99", "Max"})
+	row := func(name string, p latencyPercentiles) {
+		if name == s.Metric {
+			name += " *"
+		}
+		table.Append([]string{name, fmt.Sprintf("%.1f ms", p.P50Ms), fmt.Sprintf("%.1f ms", p.P95Ms),
+			fmt.Sprintf("%.1f ms", p.P99Ms), fmt.Sprintf("%.1f ms", p.MaxMs)})
+	}
+	row("total", s.Total)
+	row("ttfb", s.TTFB)
+	row("server", s.Server)
+	table.Render()
+	fmt.Println("  - total runs from sending the request until the whole body is read, ttfb until the first response byte, server is ttfb without DNS, connect and TLS")
+	fmt.Printf("  - * -latency-metric: the P50/P95/P99 above, per-endpoint and per-worker statistics, trends, Apdex and thresholds use %s\n", s.Metric)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// 拐点判定：趋势窗口的 P95 超过低负载窗口 P95 的 kneeLatencyFactor 倍且至少高出 kneeMinRiseMs 即认为越过了拐点，
+// 后者排除趋势按毫秒取整带来的抖动；各窗口吞吐量的最大值不到最小值的 kneeMinSpread 倍时负载变化太小，不做判定
+const (
+	kneeLatencyFactor = 2
+	kneeMinRiseMs     = 5
+	kneeMinSpread     = 1.2
+	kneeMinWindows    = 4
+)
+
+// kneeSummary 为按趋势窗口估计的吞吐量拐点：Rate 为 P95 开始明显上升之前的最高吞吐量，
+// InFlight 为按 Little 定律在该吞吐量下同时处理中的请求数
+type kneeSummary struct {
+	Rate          float64 `json:"rate"`
+	P95Ms         float64 `json:"p95_ms"`
+	BaselineP95Ms float64 `json:"baseline_p95_ms"`
+	InFlight      float64 `json:"in_flight"`
+}
+
+// littlesLawSummary 为按 Little 定律（L = λW）分析的并发与吞吐量：AvgInFlight 为实际达到的平均并发，
+// 即得到响应的请求的总耗时除以运行时长；OfferedRate 为 -rate 与 -pattern 计划的平均到达速率，闭环模型下为 0
+type littlesLawSummary struct {
+	Workers      int          `json:"workers"`
+	AvgInFlight  float64      `json:"avg_in_flight"`
+	Utilization  float64      `json:"utilization"`
+	AvgLatencyMs float64      `json:"avg_latency_ms"`
+	OfferedRate  float64      `json:"offered_rate,omitempty"`
+	AchievedRate float64      `json:"achieved_rate"`
+	PeakRate     float64      `json:"peak_rate"`
+	Knee         *kneeSummary `json:"knee,omitempty"`
+	// KneeNote 为未能估计拐点的原因
+	KneeNote string `json:"knee_note,omitempty"`
+}
+
+// plannedRate 返回 -rate 与 -pattern 计划的平均到达速率，即按 planStages 推算的计划请求数除以计划时长
+func plannedRate(rate float64, patterns []ratePattern, total int, soak time.Duration) float64 {
+	if rate <= 0 {
+		return 0
+	}
+	stages, duration := planStages(rate, patterns, total, soak)
+	if duration <= 0 {
+		return 0
+	}
+	var sent float64
+	for _, stage := range stages {
+		sent += stage.rate * (stage.to - stage.from).Seconds()
+	}
+	return sent / duration.Seconds()
+}
+
+// summarizeLittlesLaw 计算实际达到的平均并发、计划与实际的速率，并从趋势窗口估计吞吐量拐点
+func summarizeLittlesLaw(stats *Stats, workers int, offered float64, elapsed time.Duration) *littlesLawSummary {
+	if elapsed <= 0 {
+		return nil
+	}
+	var responses int
+	for _, count := range stats.StatusCodes {
+		responses += count
+	}
+	s := &littlesLawSummary{
+		Workers:      workers,
+		AvgInFlight:  stats.TotalTime.Seconds() / elapsed.Seconds(),
+		OfferedRate:  offered,
+		AchievedRate: float64(stats.TotalRequests) / elapsed.Seconds(),
+	}
+	if workers > 0 {
+		s.Utilization = s.AvgInFlight / float64(workers) * 100
+	}
+	if responses > 0 {
+		s.AvgLatencyMs = durationMs(stats.TotalTime / time.Duration(responses))
+	}
+	for _, qps := range qpsHistory {
+		s.PeakRate = max(s.PeakRate, qps)
+	}
+	// 趋势窗口按吞吐量从低到高作为负载的顺序
+	order := make([]int, len(qpsHistory))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return qpsHistory[order[i]] < qpsHistory[order[j]] })
+	var qps, p50, p95 []float64
+	for _, i := range order {
+		if i < len(p50History) && i < len(p95History) {
+			qps, p50, p95 = append(qps, qpsHistory[i]), append(p50, p50History[i]), append(p95, p95History[i])
+		}
+	}
+	s.Knee, s.KneeNote = detectKnee(qps, p50, p95)
+	return s
+}
+
+// detectKnee 从按负载从低到高排列的各点（按吞吐量排序的趋势窗口，或 capacity 子命令的各并发级别）估计拐点：
+// 以负载最低的四分之一的 P95 中位数为基线，返回 P95 首次超过基线 kneeLatencyFactor 倍之前一点的吞吐量，
+// 其并发按该点的 P50 估计；无法判定时返回原因
+func detectKnee(qps, p50, p95 []float64) (*kneeSummary, string) {
+	type point struct{ rate, p50, p95 float64 }
+	var points []point
+	lowest, highest := math.Inf(1), 0.0
+	for i := range qps {
+		if qps[i] > 0 {
+			points = append(points, point{qps[i], p50[i], p95[i]})
+			lowest, highest = min(lowest, qps[i]), max(highest, qps[i])
+		}
+	}
+	if len(points) < kneeMinWindows {
+		return nil, fmt.Sprintf("needs at least %d load levels with requests", kneeMinWindows)
+	}
+	if highest < lowest*kneeMinSpread {
+		return nil, "throughput did not vary enough between load levels"
+	}
+	low := make([]float64, 0, len(points)/4+1)
+	for _, p := range points[:len(points)/4+1] {
+		low = append(low, p.p95)
+	}
+	sort.Float64s(low)
+	// 趋势中的 P95 取整到毫秒，基线至少按 1ms 计
+	baseline := max(low[(len(low)-1)/2], 1)
+	for i, p := range points {
+		if p.p95 <= baseline*kneeLatencyFactor || p.p95-baseline < kneeMinRiseMs {
+			continue
+		}
+		if i == 0 {
+			return nil, "P95 was already high at the lowest load"
+		}
+		knee := points[i-1]
+		return &kneeSummary{Rate: knee.rate, P95Ms: knee.p95, BaselineP95Ms: baseline, InFlight: knee.rate * knee.p50 / 1000}, ""
+	}
+	return nil, fmt.Sprintf("P95 did not rise sharply above its %.0f ms baseline up to %.1f req/s; throughput saturates above the tested load",
+		baseline, highest)
+}
+
+// reportLittlesLaw 输出实际达到的并发、计划与实际速率的对比及吞吐量拐点，作为容量规划的参考
+func reportLittlesLaw(s *littlesLawSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Println("\n🧮  Capacity Analysis (Little's Law):")
+	fmt.Printf("  - Average concurrency achieved: %.2f of %d workers (%.1f%%) = %.2f req/s x %.1f ms average latency\n",
+		s.AvgInFlight, s.Workers, s.Utilization, s.AchievedRate, s.AvgLatencyMs)
+	if s.Utilization < 50 && s.OfferedRate == 0 {
+		fmt.Println("  - Workers spent most of the time outside answered requests (failed requests, pacing or client overhead), so the target was not the only limit")
+	}
+	if s.OfferedRate > 0 {
+		fmt.Printf("  - Offered vs achieved rate: %.2f vs %.2f req/s (%.1f%%)\n", s.OfferedRate, s.AchievedRate, s.AchievedRate/s.OfferedRate*100)
+		if s.AchievedRate < s.OfferedRate*0.9 {
+			fmt.Println("  - The target (or -c / -max-inflight) could not keep up with the offered load: it is saturated at this rate")
+		}
+	} else {
+		fmt.Printf("  - Closed model: each of the %d workers sends its next request when the previous one finishes, so offered equals achieved load\n", s.Workers)
+	}
+	fmt.Printf("  - Peak trend window throughput: %.2f req/s\n", s.PeakRate)
+	if s.Knee == nil {
+		fmt.Printf("  - Knee: not found from the trend windows (%s)\n", s.KneeNote)
+		fmt.Println("  - To find it, ramp the load, e.g. -rate 100 -pattern step:1x,2x,4x,8x:30s, or sweep concurrency with the capacity subcommand")
+		return
+	}
+	fmt.Printf("  - Knee: throughput saturates around %.1f req/s, where P95 was %.0f ms (baseline %.0f ms); beyond it P95 more than doubles\n",
+		s.Knee.Rate, s.Knee.P95Ms, s.Knee.BaselineP95Ms)
+	fmt.Printf("  - Capacity guidance: plan for at most %.1f req/s per target, with about %.1f requests in flight (connection pools, -c) at that rate\n",
+		s.Knee.Rate, s.Knee.InFlight)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// logLevel、logFormat 为 -log-level 与 -log-format：诊断信息（错误、警告、调试信息）经 slog 输出，
+// console 为默认的终端格式，text、json 为写到 stderr 的结构化格式，便于日志采集
+var (
+	logLevel  string
+	logFormat string
+)
+
+// logMidRun 为 1 时压测已开始，console 格式先换行，避免与进度条挤在同一行
+var logMidRun int32
+
+// logLevelVar 为当前的日志级别，-log-level 解析前为 info
+var logLevelVar = new(slog.LevelVar)
+
+func init() {
+	slog.SetDefault(slog.New(&consoleHandler{mu: new(sync.Mutex)}))
+}
+
+// setupLogging 按 -log-level 与 -log-format 设置默认的 slog 日志
+func setupLogging(level, format string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid -log-level %q (expected debug, info, warn or error)", level)
+	}
+	logLevelVar.Set(l)
+	options := &slog.HandlerOptions{Level: logLevelVar}
+	switch format {
+	case "console":
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, options)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, options)))
+	default:
+		return fmt.Errorf("invalid -log-format %q (expected console, text or json)", format)
+	}
+	return nil
+}
+
+// consoleHandler 为默认的终端格式：按级别加上 ❌、⚠️ 等前缀，err 属性写为 ": 错误"，其余属性写为 key=value；
+// 写到当时的 os.Stdout，非 text 的 -format 下即 stderr
+type consoleHandler struct {
+	mu    *sync.Mutex
+	attrs []slog.Attr
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= logLevelVar.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if atomic.LoadInt32(&logMidRun) == 1 {
+		b.WriteString("\n")
+	}
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("❌ ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("⚠️  ")
+	case r.Level < slog.LevelInfo:
+		b.WriteString("🔍  ")
+	}
+	b.WriteString(r.Message)
+	write := func(a slog.Attr) bool {
+		if a.Key == "err" {
+			b.WriteString(": " + a.Value.String())
+		} else {
+			fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		write(a)
+	}
+	r.Attrs(write)
+	b.WriteString("\n")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := os.Stdout.WriteString(b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &consoleHandler{mu: h.mu, attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+// WithGroup 不区分分组，属性直接平铺输出
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -6,40 +6,160 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
+	"math"
 	"math/rand"
 	"net/http"
 	"net/http/httptrace"
 	"os"
+	"os/signal"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/guptarohit/asciigraph"
 	"github.com/olekukonko/tablewriter"
-	"github.com/schollz/progressbar/v3"
 )
 
-// WorkerStats 保存每个 worker 的局部统计数据，加锁确保并发安全
+// WorkerStats 保存每个 worker 的局部统计数据：worker 只写自己的增量，累计统计只由 ticker goroutine 合并（见 statsRecorder），均无需加锁
 type WorkerStats struct {
-	mu              sync.Mutex
 	TotalRequests   int64
 	SuccessRequests int64
 	FailedRequests  int64
 	TotalTime       time.Duration
 	ResponseTimes   []time.Duration
 	StatusCodes     map[int]int
+	URLStats        map[string]*URLStats
+	Apdex           apdexCounts
+	Slowest         slowHeap
+	BytesRead       int64
+	Phases          phaseTotals
+	Errors          map[string]int
+	SocketErrors    socketErrors
+	Heatmap         heatmapCounts
+	// Targets 为 -targets-compare 下按目标的统计数据，时延为完整请求耗时
+	Targets map[string]*URLStats
+	// ByTag 为按请求标签 key=value 的统计数据，Filtered 为满足 -report-filter 的请求的统计数据
+	ByTag    map[string]*URLStats
+	Filtered URLStats
+	// Window 为当前趋势窗口内的统计数据
+	Window windowStats
+	// TotalTimes 为发出请求到读完响应的完整耗时样本，TTFBTimes 为发出请求到收到响应首字节的耗时样本，
+	// ServerTimes 为 TTFB 去掉 DNS、建连与 TLS 握手的耗时样本；ResponseTimes 为其中 -latency-metric 选中的一种
+	TotalTimes        []time.Duration
+	totalSampleCount  int64
+	TTFBTimes         []time.Duration
+	ttfbSampleCount   int64
+	ServerTimes       []time.Duration
+	serverSampleCount int64
+	// Failures 为 -replay-failures 下记录的失败请求
+	Failures failureLog
+	// FailedIDs 为 -request-id-header 下前若干个失败请求的 ID
+	FailedIDs []string
+	// Throttle 为 -respect-retry-after 下因 Retry-After 暂停的统计
+	Throttle throttleCounts
+	// Continue 为 -expect-continue 下 100-continue 握手的统计
+	Continue continueStats
+	// BodyHashes 为 -body-mode hash 下各接口响应内容的哈希
+	BodyHashes bodyHashes
+	// HeaderValues 为 -capture-header 各取值出现的次数
+	HeaderValues headerValues
+	// Backends 为 -backend-header 下按后端实例的统计
+	Backends map[string]*URLStats
+	// Revalidation 为 -conditional 下条件请求的统计
+	Revalidation revalidationStats
+	// Preflight 为 -cors-origin 下 CORS 预检的统计
+	Preflight preflightStats
+	// Idempotency 为 -idempotency-header 下幂等 key 与重复提交的统计
+	Idempotency idempotencyStats
+	// Churn 为 -close-every 下新连接与复用连接的统计
+	Churn churnStats
+	// Late 为 -request-deadline 下迟到的成功请求，时延为完整请求耗时
+	Late URLStats
+	// SLO 为 -slo-buckets 下各时延上限内的请求数
+	SLO sloCounts
+	// Validation 为状态码为 2xx 但内容校验未通过的请求数，按原因分类
+	Validation map[string]int
+	// SchemaChecked 为按 -response-schema 校验过的响应数
+	SchemaChecked int64
+	// RangeRequests 为 -range-size 下发送的 Range 请求数，RangeVerified 为其中通过校验的
+	RangeRequests int64
+	RangeVerified int64
+	// KeepAliveRequests 为通过 Keep-Alive 客户端发出的请求数
+	KeepAliveRequests int64
+	// TLS 为新建连接的 TLS 握手统计
+	TLS tlsStats
+	// Digest 为全部时延的直方图，只在累计统计中维护，供周期输出使用
+	Digest latencyDigest
+	// QueueWaits 为增量中各请求的排队时间，合并时计入累计统计的 QueueWait
+	QueueWaits []time.Duration
+	QueueWait  queueWaitStats
+	// Metrics 为从响应提取的自定义指标，只在累计统计中维护
+	Metrics map[string]*metricStats
+	// sampleCount 为已观测到的时延样本总数，用于蓄水池抽样
+	sampleCount int64
 }
 
 // Stats 用于聚合统计数据
 type Stats struct {
-	TotalRequests   int64
-	SuccessRequests int64
-	FailedRequests  int64
-	TotalTime       time.Duration
-	ResponseTimes   []time.Duration
-	StatusCodes     map[int]int
+	TotalRequests     int64
+	SuccessRequests   int64
+	FailedRequests    int64
+	TotalTime         time.Duration
+	ResponseTimes     []time.Duration
+	StatusCodes       map[int]int
+	URLStats          map[string]*URLStats
+	Apdex             apdexCounts
+	Slowest           []slowRequest
+	Failures          failureLog
+	FailedIDs         []string
+	Throttle          throttleCounts
+	Continue          continueStats
+	BodyHashes        bodyHashes
+	HeaderValues      headerValues
+	Backends          map[string]*URLStats
+	Revalidation      revalidationStats
+	Preflight         preflightStats
+	Idempotency       idempotencyStats
+	Churn             churnStats
+	Late              URLStats
+	SLO               sloCounts
+	Validation        map[string]int
+	SchemaChecked     int64
+	RangeRequests     int64
+	RangeVerified     int64
+	KeepAliveRequests int64
+	TLS               tlsStats
+	BytesRead         int64
+	Phases            phaseTotals
+	Errors            map[string]int
+	SocketErrors      socketErrors
+	Heatmap           heatmapCounts
+	Targets           map[string]*URLStats
+	ByTag             map[string]*URLStats
+	Filtered          URLStats
+	TotalTimes        []time.Duration
+	TTFBTimes         []time.Duration
+	ServerTimes       []time.Duration
+	// WorkerRequests 为每个 worker 各自发出的请求数
+	WorkerRequests []int64
+	// Digest 为全部时延的直方图；ResponseTimes 为空时按它计算百分位
+	Digest latencyDigest
+	// QueueWait 为调度器中请求的排队时间
+	QueueWait queueWaitStats
+	// Metrics 为从响应提取的自定义指标
+	Metrics map[string]*metricStats
+}
+
+// URLStats 保存单个 endpoint 的统计数据：命名请求按名称，其余按方法 + 不含查询参数的 URL
+type URLStats struct {
+	TotalRequests  int64
+	FailedRequests int64
+	ResponseTimes  []time.Duration
+	sampleCount    int64
 }
 
 // 全局趋势数组（TPS、QPS 为数值，响应时延单位为 ms）
@@ -51,11 +171,6 @@ var (
 	p99History []float64
 )
 
-// requestBodies 支持两种格式：
-// - 只有 body，则形式为 [["body"]]
-// - 有 URL 和 body，则形式为 [["url", "body"], ...]
-var requestBodies [][]string
-
 // 全局 HTTP 客户端复用
 var clientKeepAlive *http.Client
 var clientNoKeepAlive *http.Client
@@ -86,6 +201,31 @@ func init() {
 }
 
 func main() {
+	defer flushOutput()
+	if os.Getenv("NO_COLOR") != "" {
+		enablePlainOutput()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tcp" {
+		runTCPCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		runRecordCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		runTestServerCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "capacity" {
+		runCapacityCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		validateMode = true
+		os.Args = append([]string{os.Args[0]}, validateArgs(os.Args[2:])...)
+	}
+
 	var url string
 	var concurrency int
 	var totalRequests int
@@ -93,6 +233,55 @@ func main() {
 	var method string
 	var bodyFile string
 	var reportInterval int
+	var wsMode bool
+	var wsDuration time.Duration
+	var wsRate float64
+	var wsMessage string
+	var streamMode bool
+	var streamDuration time.Duration
+	var harFile string
+	var harTiming bool
+	var fromCurl string
+	var curlFile string
+	var postmanFile string
+	var postmanEnv string
+	var postmanWeights string
+	var openapiFile string
+	var openapiOperations string
+	var replayFile string
+	var replayFormat string
+	var replaySpeed string
+	var rate float64
+	var patterns patternFlags
+	var soakDuration time.Duration
+	var checkpointInterval time.Duration
+	var checkpointFile string
+	var thresholds thresholdFlags
+	var baselineFile string
+	var notifyWebhook string
+	var notifySlack string
+	var seedValue int64
+	var perWorkerStats bool
+	var heatmapHTML string
+	var noGraphs bool
+	var trendExport string
+	var targetsCompare string
+	var iterations int
+	var dataFile string
+	var oncePerEntry bool
+	var requeueFailures int
+	var requestLogFile string
+	var adaptive bool
+	var expectContinueTimeout time.Duration
+	var chunkSizeValue string
+	var bodyRateValue string
+	var downloadRateValue string
+	var responseSchemaFile string
+	var protoFile, protoMessageName, protoResponseName string
+	var xmlBodyFile string
+	var randomBodyValue string
+	var userAgentFile string
+	var connectionPolicyFile string
 
 	flag.StringVar(&url, "url", "http://localhost:8080", "Target URL")
 	flag.IntVar(&concurrency, "c", 10, "Number of concurrent workers")
@@ -102,214 +291,1737 @@ func main() {
 	flag.StringVar(&bodyFile, "bodyfile", "", "JSON file containing request bodies")
 	// reportInterval 表示每累计 N 个请求后输出一次统计
 	flag.IntVar(&reportInterval, "interval", 20, "Report stats every N requests")
+	flag.BoolVar(&wsMode, "ws", false, "WebSocket mode: hold -c connections open against a ws:// or wss:// URL")
+	flag.DurationVar(&wsDuration, "ws-duration", 10*time.Second, "How long to keep WebSocket connections open")
+	flag.Float64Var(&wsRate, "ws-rate", 0, "Messages per second per WebSocket connection (0 = send next message as soon as the reply arrives)")
+	flag.StringVar(&wsMessage, "ws-message", "", "WebSocket message template (defaults to bodies from -bodyfile)")
+	flag.BoolVar(&streamMode, "stream", false, "Streaming mode: consume long-lived SSE or line-delimited chunked responses")
+	flag.DurationVar(&streamDuration, "stream-duration", 30*time.Second, "How long to keep consuming streaming responses")
+	flag.StringVar(&harFile, "har", "", "Replay requests recorded in a HAR file (browser devtools export)")
+	flag.BoolVar(&harTiming, "har-timing", false, "Preserve the relative timing recorded in the HAR file")
+	flag.StringVar(&fromCurl, "from-curl", "", "Build the request from a curl command line")
+	flag.StringVar(&curlFile, "curl-file", "", "File with one curl command per line (backslash continuations allowed)")
+	flag.StringVar(&postmanFile, "postman", "", "Load requests from a Postman collection (v2.x)")
+	flag.StringVar(&postmanEnv, "postman-env", "", "Postman environment file used for {{variable}} substitution")
+	flag.StringVar(&postmanWeights, "postman-weights", "", "Relative weights per request name, e.g. \"Create user=3,List users=1\" (default weight is 1)")
+	flag.StringVar(&openapiFile, "openapi", "", "Generate requests from an OpenAPI 3.x / Swagger 2.0 spec (YAML or JSON)")
+	flag.StringVar(&openapiOperations, "operations", "", "Comma-separated operationIds to load test from -openapi (default: all operations)")
+	flag.StringVar(&replayFile, "replay", "", "Replay an access log with its original pacing (relative paths are resolved against -url)")
+	flag.StringVar(&replayFormat, "replay-format", "combined", "Access log format: combined (also accepts common) or json (one object per line)")
+	flag.StringVar(&replaySpeed, "speed", "1x", "Replay speed for -replay and -har-timing, e.g. 2x compresses the original timeline, 0.5x stretches it")
+	flag.Float64Var(&rate, "rate", 0, "Target arrival rate in requests per second, independent of response times (0 = every worker sends as fast as possible)")
+	flag.Var(&patterns, "pattern", "Rate modifier on top of -rate, repeatable: spike:10x:5s[:at=30s][:every=60s], step:1x,2x,4x:30s, sine:period=60s[:amplitude=0.5]")
+	flag.DurationVar(&soakDuration, "soak", 0, "Soak test: run for this long instead of -n requests, keeping memory bounded")
+	flag.DurationVar(&checkpointInterval, "checkpoint", 15*time.Minute, "Interval for writing soak checkpoints (0 disables)")
+	flag.StringVar(&checkpointFile, "checkpoint-file", "soak-checkpoint.json", "File the rolling soak summary and trend snapshot is written to")
+	flag.StringVar(&resumeFile, "resume", "", "Resume an interrupted soak test from this checkpoint file, keeping its counters, latency histogram and trend; runs for what is left of -soak")
+	flag.IntVar(&slowestLimit, "slowest", 0, "Number of slowest requests to keep and print with their phase breakdown (0 = disabled)")
+	flag.DurationVar(&apdexThreshold, "apdex-t", 0, "Apdex target time T, e.g. 100ms; enables the Apdex score in reports (0 = disabled)")
+	flag.StringVar(&outputFormat, "format", "text", "Final summary format: text, wrk, hey, markdown or json (non-text formats print only the summary to stdout)")
+	flag.StringVar(&outputFormat, "o", "text", "Shorthand for -format")
+	flag.Var(&thresholds, "threshold", "Pass/fail rule on the final summary, repeatable, e.g. p99<500ms, error_rate<1%, rps>=100; exits 1 if any fails")
+	flag.StringVar(&baselineFile, "baseline", "", "Previous -o json report (or soak checkpoint) to compare against in -o markdown")
+	flag.StringVar(&notifyWebhook, "notify-webhook", "", "POST the final summary and threshold results as JSON to this URL when the run finishes or is interrupted")
+	flag.StringVar(&notifySlack, "notify-slack", "", "Slack incoming webhook URL to post a formatted summary to when the run finishes or is interrupted")
+	flag.Var(tagFlags{}, "tag", "Run metadata key=value, repeatable (e.g. -tag env=staging -tag build=1234); attached to every exported summary")
+	flag.Int64Var(&seedValue, "seed", 0, "Seed all randomness (request selection, keep-alive choice, generated data) so runs produce the same request sequence")
+	flag.BoolVar(&perWorkerStats, "per-worker-stats", false, "Print request count, errors and latency percentiles for each worker to spot skew")
+	flag.DurationVar(&heatmapWindow, "heatmap", 0, "Print a latency-over-time heatmap with this time window per column, e.g. 1s (0 = disabled)")
+	flag.StringVar(&heatmapHTML, "heatmap-html", "", "Also write the latency heatmap to this HTML file (uses 1s windows unless -heatmap is set)")
+	flag.DurationVar(&trendWindow, "trend-window", 5*time.Second, "Length of the fixed, non-overlapping windows the trend graphs are computed over")
+	flag.IntVar(&graphWidth, "graph-width", 0, "Width of the trend graphs in columns (0 = one column per trend point)")
+	flag.IntVar(&graphHeight, "graph-height", 10, "Height of the TPS/QPS graphs in rows; latency and Apdex graphs use half of it")
+	flag.BoolVar(&noGraphs, "no-graphs", false, "Do not print the trend graphs")
+	flag.StringVar(&trendExport, "trend-export", "", "Write the trend history to this file, as CSV or JSON depending on the extension (.csv or .json)")
+	flag.StringVar(&intervalStreamFile, "interval-stream", "", "Append one JSON line per trend window with its request counts and compact latency histogram to this file")
+	flag.StringVar(&intervalLogFile, "interval-log", "", "Append the periodic statistics to this file instead of printing them, keeping the terminal clean on long runs")
+	flag.StringVar(&rotateSizeValue, "rotate-size", "50MB", "With -interval-log, start a new file once the current one reaches this size, e.g. 50MB (0 = never rotate)")
+	flag.BoolVar(&intervalLogStdout, "interval-log-stdout", false, "With -interval-log, also print the periodic statistics to the terminal")
+	flag.StringVar(&logLevel, "log-level", "info", "Level of diagnostic messages: debug (also every failed request), info, warn or error")
+	flag.StringVar(&logFormat, "log-format", "console", "Format of diagnostic messages: console (default), or text / json (slog key=value or JSON lines on stderr) for log pipelines")
+	flag.BoolVar(&plainOutput, "plain", false, "Plain ASCII output for CI consoles: no emoji, box-drawing characters or graphs, and a progress line every 10s instead of a progress bar (also enabled by the NO_COLOR environment variable)")
+	flag.StringVar(&timezoneName, "timezone", "", "Time zone for wall-clock timestamps in all outputs: UTC, Local or an IANA name such as Asia/Shanghai (default: local)")
+	flag.StringVar(&serverAddr, "server", "", "Run as a REST control server on this address, e.g. :8089 (127.0.0.1 only unless a host is given), starting, stopping and reporting runs defined as JSON over HTTP")
+	flag.StringVar(&serverToken, "server-token", "", "With -server, require Authorization: Bearer <token> on every request; required when listening on a non-loopback address (default $HTTP_TEST_GO_SERVER_TOKEN)")
+	flag.StringVar(&controllerAddr, "controller", "", "Distributed mode: listen on this address (e.g. :7000) for -worker replicas and shard -n, -c and -rate across them")
+	flag.IntVar(&expectWorkers, "expect-workers", 1, "With -controller, start once this many workers have joined")
+	flag.BoolVar(&workerMode, "worker", false, "Distributed mode: run as a worker that joins -join and executes the load the controller assigns")
+	flag.StringVar(&joinAddr, "join", "", "With -worker, the controller address, e.g. controller:7000")
+	flag.Var(labelFlags{}, "label", "With -worker, a key=value label sent to the controller, repeatable (e.g. -label region=eu-west-1); the controller also reports per-label breakdowns")
+	flag.BoolVar(&calibrate, "calibrate", false, "Measure the tool's own latency overhead and scheduling jitter against a built-in loopback server at -calibrate-levels, then exit")
+	flag.StringVar(&calibrateLevels, "calibrate-levels", "1,10,50,100,200", "Comma-separated concurrency levels for -calibrate; each level sends -n requests (2000 unless -n is set)")
+	flag.StringVar(&grafanaExport, "grafana-export", "", "Write a Grafana dashboard JSON with the trend graphs and final summary embedded, importable with the built-in TestData data source")
+	flag.StringVar(&latencyMetric, "latency-metric", "total", "Request latency used for percentiles, trends, Apdex and thresholds: total (until the body is read), ttfb (until the first response byte) or server (ttfb without DNS, connect and TLS); all three are in the JSON summary")
+	flag.DurationVar(&requestDeadline, "request-deadline", 0, "Count successful responses that take longer than this in total as late successes, neither success nor failure, as an upstream with this timeout would see them, e.g. 500ms (0 = disabled)")
+	flag.StringVar(&sloBucketsValue, "slo-buckets", "", "Report the percentage of requests completing within each of these latency bounds, e.g. 50ms,100ms,250ms,500ms, with the error budget burn against -slo-target")
+	flag.Float64Var(&sloTarget, "slo-target", 99, "With -slo-buckets, percentage of requests that should complete within a bound, e.g. 99.9")
+	flag.IntVar(&closeEvery, "close-every", 0, "Close and re-open connections so that each carries N keep-alive requests on average (one in N sends Connection: close); reports the handshake overhead")
+	flag.DurationVar(&addLatency, "add-latency", 0, "Add this round-trip time to every connection (connect, TLS handshake and each request) to simulate distant clients without tc/netem, e.g. 50ms")
+	flag.DurationVar(&addJitter, "add-jitter", 0, "With -add-latency, vary each injected round trip uniformly by up to this much, e.g. 10ms")
+	flag.StringVar(&bandwidthValue, "bandwidth", "", "Cap each connection's read and write rate to simulate constrained last-mile links, e.g. 5mbit, 512kbit or 1MB (bytes per second)")
+	flag.Float64Var(&chaosResetRate, "chaos-reset-rate", 0, "Fraction of requests whose connection is reset (TCP RST) halfway through sending, e.g. 0.01")
+	flag.StringVar(&chaosStallValue, "chaos-stall", "", "Fraction and duration of requests that stall halfway through sending, e.g. 0.005:2s")
+	flag.StringVar(&resolverAddr, "resolver", "", "Resolve target host names with this DNS server instead of the system resolver, e.g. 1.1.1.1:53; results are logged and summarized")
+	flag.StringVar(&dohURL, "doh", "", "Resolve target host names with this DNS-over-HTTPS endpoint, e.g. https://dns.google/dns-query; results are logged and summarized")
+	flag.StringVar(&dialStrategy, "dial-strategy", "parallel", "How to dial hosts with both IPv6 and IPv4 addresses: parallel (Happy Eyeballs) or sequential (one address at a time, no fallback race)")
+	flag.DurationVar(&fallbackDelay, "fallback-delay", 0, "With -dial-strategy parallel, how long to wait for the preferred address family before racing the other one (Go's default is 300ms)")
+	flag.BoolVar(&preconnect, "preconnect", false, "Open the whole keep-alive connection pool (TCP and TLS handshakes) to every target before the measured phase starts")
+	flag.IntVar(&burstSize, "burst", 0, "Fire requests in synchronized bursts of this size: all workers wait on a barrier and send at once, e.g. -burst 500 (raises -c to at least the burst size)")
+	flag.DurationVar(&burstInterval, "burst-interval", 10*time.Second, "Time between the starts of two -burst rounds")
+	flag.StringVar(&idempotencyHeader, "idempotency-header", "", "Send a unique key in this header with every request, e.g. Idempotency-Key, and re-submit -duplicate-ratio of them with a key already used")
+	flag.Float64Var(&duplicateRatio, "duplicate-ratio", 0.1, "With -idempotency-header, fraction of requests that re-use the key of an earlier request")
+	flag.StringVar(&duplicateExpect, "duplicate-expect", "same", "Expected answer to a duplicate submission: same (status and body identical to the original) or a status code such as 409")
+	flag.StringVar(&corsOrigin, "cors-origin", "", "Simulate browser CORS: send this Origin with every request and an OPTIONS preflight before each request that needs one; preflight latency and failures are reported separately")
+	flag.Float64Var(&corsPreflightRatio, "cors-preflight-ratio", 1, "With -cors-origin, fraction of the requests needing a preflight that actually send one (below 1 models the browser preflight cache)")
+	flag.StringVar(&methodMixValue, "method-mix", "", "Weighted mix of HTTP methods applied to every request, e.g. GET=90,HEAD=5,OPTIONS=5; OPTIONS is sent as a CORS preflight")
+	flag.StringVar(&preflightOrigin, "preflight-origin", "https://example.com", "Origin header of the OPTIONS preflight requests in -method-mix")
+	flag.StringVar(&targetsCompare, "targets-compare", "", "Split load across two or more implementations, e.g. http://stable,http://canary or http://a=3,http://b=1, and compare them side by side")
+	flag.StringVar(&scenariosFile, "scenarios", "", "YAML/JSON list of scenarios run at the same time, each with its own weight (share of -c and -n), concurrency, rate and request mix, reported separately")
+	flag.Var(reportFilterFlags{}, "report-filter", "Also report the requests carrying this scenario tag key=value, repeatable (all must match), e.g. -report-filter scenario=checkout")
+	flag.IntVar(&iterations, "iterations", 0, "Run the loaded requests in order this many times per virtual user (-c users), each with its own cookies and data row; replaces -n")
+	flag.StringVar(&dataFile, "datafile", "", "CSV (with header) or JSON array of objects; each row's fields can be used in URL and body templates, e.g. {{.username}}")
+	flag.StringVar(&dataDistribution, "data-distribution", "shared", "How -datafile rows are split across workers: shared (one cursor for all), partition (disjoint slice per worker) or per-vu-copy (full private copy per worker)")
+	flag.BoolVar(&oncePerEntry, "once-per-entry", false, "Treat the loaded requests (e.g. -bodyfile entries) as a work queue: send each exactly once across all workers, then stop; replaces -n")
+	flag.IntVar(&requeueFailures, "requeue-failures", 0, "With -once-per-entry, put a failed entry back at the end of the queue up to this many times")
+	flag.BoolVar(&replayFailures, "replay-failures", false, "After the run, re-send every failed request one by one and report which ones recover, separating overload-induced failures from broken requests")
+	flag.Float64Var(&replayRate, "replay-rate", 0, "Rate in requests per second for -replay-failures (0 = send each as soon as the previous one finishes)")
+	flag.StringVar(&requestIDHeader, "request-id-header", "", "Send a unique UUID per request in this header, e.g. X-Request-ID; IDs appear in -request-log and next to the slowest and failed requests")
+	flag.StringVar(&requestLogFile, "request-log", "", "Write one JSON object per request (time, request ID, URL, status, error, duration) to this NDJSON file")
+	flag.BoolVar(&respectRetryAfter, "respect-retry-after", false, "When a 429 or 503 response carries Retry-After, pause the worker that received it for that long and report the time spent throttled")
+	flag.DurationVar(&retryAfterMax, "retry-after-max", time.Minute, "Longest single pause for -respect-retry-after (0 = no limit)")
+	flag.BoolVar(&adaptive, "adaptive", false, "Closed-loop capacity probe: start at -rate and raise or lower it every -trend-window to hold -target-p99 and/or -target-error-rate")
+	flag.DurationVar(&targetP99, "target-p99", 0, "P99 latency the -adaptive controller holds, e.g. 200ms")
+	flag.Float64Var(&targetErrorRate, "target-error-rate", 0, "Error rate in percent the -adaptive controller stays under, e.g. 1")
+	flag.BoolVar(&expectContinue, "expect-continue", false, "Send Expect: 100-continue with request bodies and report the 100-continue handshake separately")
+	flag.DurationVar(&expectContinueTimeout, "expect-continue-timeout", time.Second, "How long to wait for 100 Continue before sending the body anyway (transport ExpectContinueTimeout)")
+	flag.BoolVar(&chunkedBody, "chunked", false, "Stream request bodies with Transfer-Encoding: chunked instead of a Content-Length")
+	flag.StringVar(&chunkSizeValue, "chunk-size", "64k", "Size of each chunk for -chunked, e.g. 16k or 1MB")
+	flag.StringVar(&bodyRateValue, "body-rate", "", "Upload rate per request for -chunked, e.g. 1MB/s or 64kb/s, to simulate slow clients (default: unlimited)")
+	flag.StringVar(&downloadRateValue, "download-rate", "", "Read each response body at most this fast, e.g. 256kb/s, to simulate slow clients (default: unlimited)")
+	flag.StringVar(&bodyMode, "body-mode", "discard", "How response bodies are consumed: discard (read fully, default), read (into memory), hash (SHA-256, report distinct bodies per endpoint) or ignore (headers only)")
+	flag.StringVar(&expectSHA256, "expect-sha256", "", "Expected SHA-256 (hex) of every 2xx response body; mismatches count as failures (a third element per -bodyfile entry overrides it)")
+	flag.StringVar(&responseSchemaFile, "response-schema", "", "JSON Schema file every 2xx response body is validated against; violations count as failures")
+	flag.Float64Var(&schemaSample, "schema-sample", 1, "Fraction of 2xx responses validated against -response-schema (0.0 - 1.0)")
+	flag.StringVar(&protoFile, "proto-file", "", ".proto file defining the request and response messages for protobuf over HTTP; imports are resolved from its directory and the current directory")
+	flag.StringVar(&protoMessageName, "proto-message", "", "Message type JSON request bodies are encoded to as binary protobuf, e.g. CreateUserRequest")
+	flag.StringVar(&protoResponseName, "proto-response", "", "Message type 2xx response bodies are decoded from, so -response-schema can assert on them as JSON")
+	flag.StringVar(&xmlBodyFile, "xml-body", "", "XML request body file, e.g. a SOAP envelope; with -datafile it may use templates like {{xml .username}}")
+	flag.StringVar(&soapAction, "soap-action", "", "SOAP action sent as the SOAPAction header (SOAP 1.1) or the Content-Type action parameter (SOAP 1.2)")
+	flag.Var(&xpathAssertions, "xpath", "XPath assertion every 2xx XML response must satisfy, repeatable: //Status, //Status=OK, //Code!=0 or !//soap:Fault")
+	flag.StringVar(&randomBodyValue, "random-body", "", "Replace each request body with random content whose size follows a distribution, e.g. size=1kb..64kb,dist=lognormal (dist: uniform or lognormal; format: json or text)")
+	flag.Var(&queryParams, "param", "Query parameter appended to every request URL, repeatable; the value may be a template, e.g. -param \"user_id={{randInt 1 100000}}\" -param \"ts={{now_unix}}\"")
+	flag.StringVar(&userAgentFile, "user-agents", "", "File with one User-Agent per line (optionally \"weight|agent\"), rotated per request instead of the default Go-HTTP-LoadTester")
+	flag.Var(&captureHeaders, "capture-header", "Response header whose values are tallied, repeatable, e.g. -capture-header X-Cache -capture-header X-Backend, to measure cache hit ratio or load balancing")
+	flag.StringVar(&rangeSizeValue, "range-size", "", "Send GET requests to -url with a random Range of this size, e.g. 1MB, and validate the 206 response, Content-Range and byte count")
+	flag.BoolVar(&conditionalRequests, "conditional", false, "Store ETag / Last-Modified from GET and HEAD responses and send later requests to the same URL with If-None-Match / If-Modified-Since; reports the 304 ratio and 304 vs full response latency")
+	flag.StringVar(&backendHeader, "backend-header", "", "Response header naming the backend instance (e.g. X-Backend), or Set-Cookie:NAME for a sticky-session cookie; reports requests, share and latency per backend and a fairness index")
+	flag.Float64Var(&fairnessTolerance, "fairness-tolerance", 0.25, "With -backend-header, flag a backend whose share exceeds the even share by more than this fraction")
+	flag.Var(&extraHeaders, "H", "Header added to every request, repeatable, e.g. -H \"X-Device-Id: {{uuid}}\"; the value may be a template or @file to rotate through the file's lines")
+	flag.StringVar(&connectionPolicyFile, "connection-policy", "", "YAML/JSON list of per-target connection settings (match URL prefix, keepalive_ratio, pool sizes, timeouts), each with its own connection pool")
+	flag.StringVar(&keepAliveSplit, "keepalive-split", "random", "How -keepalive_ratio is applied: random (per request) or exact (whole workers assigned to keep-alive in the exact ratio, reproducible at low -n)")
+	flag.BoolVar(&noSessionResumption, "no-session-resumption", false, "Disable TLS session resumption so every new connection performs a full handshake")
+	flag.BoolVar(&certCheck, "cert-check", false, "Before the run, connect once to each https target and print its certificate chain, expiry dates and stapled OCSP status")
+	flag.IntVar(&certWarnDays, "cert-warn-days", 14, "With -cert-check, warn when a certificate in the chain expires within this many days")
+	flag.BoolVar(&preflight, "preflight", false, "Before the run, send one request to each distinct endpoint, check DNS/TLS/status/assertions and estimate payload sizes; refuse to start if an endpoint is unreachable")
+	flag.StringVar(&startAt, "start-at", "", "Wait until this RFC 3339 time (e.g. 2024-06-01T02:00:00Z) before sending requests")
+	flag.StringVar(&rendezvousAddr, "rendezvous", "", "Join the coordinator at host:port and start when it says so")
+	flag.StringVar(&rendezvousListen, "rendezvous-listen", "", "Act as rendezvous coordinator on this address; starts all instances together once -rendezvous-peers have joined")
+	flag.IntVar(&rendezvousPeers, "rendezvous-peers", 0, "Number of other instances the coordinator waits for")
+	flag.DurationVar(&maxDuration, "max-duration", 0, "Hard cap on the run's wall-clock time: when reached, in-flight requests are cancelled, the report is printed and the exit status is 1 (0 means no limit)")
+	flag.Int64Var(&maxErrors, "max-errors", 0, "Abort the run (cancelling in-flight requests, exit status 1) once more than this many requests have failed (0 means no limit)")
+	flag.StringVar(&pprofAddr, "pprof", "", "Serve Go pprof profiles of the load generator itself on this address, e.g. localhost:6060")
+	flag.IntVar(&maxInFlight, "max-inflight", 0, "Cap the number of requests in flight across all workers; with -rate, requests beyond the cap queue and their wait is reported (0 means -c)")
+	flag.StringVar(&engine, "engine", "net/http", "HTTP client engine: net/http (default) or fasthttp, a synchronous HTTP/1.1 client built on valyala/fasthttp for maximum RPS on simple requests")
 	flag.Parse()
+	if plainOutput {
+		enablePlainOutput()
+		noGraphs = true
+	}
+	if err := setupLogging(logLevel, logFormat); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if err := loadOutputZone(timezoneName); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	// -c 决定 worker 数，并用于按 worker 均分 soak 模式的样本上限
+	if concurrency < 1 {
+		slog.Error("-c must be at least 1")
+		exit(1)
+	}
+	if validateMode {
+		if serverAddr != "" || workerMode {
+			slog.Error("validate checks a load test plan; -server and -worker take theirs at run time")
+			exit(1)
+		}
+		if flag.NArg() == 1 && scenariosFile == "" {
+			scenariosFile = flag.Arg(0)
+		}
+		validatePlanFiles()
+	}
+	if serverAddr != "" {
+		runControlServer(serverAddr)
+		return
+	}
+	if workerMode {
+		if joinAddr == "" {
+			slog.Error("-worker requires -join controller:port")
+			exit(1)
+		}
+		runWorker(joinAddr)
+		return
+	}
+	if controllerAddr != "" && !validateMode {
+		if expectWorkers < 1 {
+			slog.Error("-expect-workers must be at least 1")
+			exit(1)
+		}
+		runController(controllerAddr, concurrency, totalRequests, rate)
+		return
+	}
+	if calibrate {
+		levels, err := parseCalibrateLevels(calibrateLevels)
+		if err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		perLevel := 2000
+		if isFlagSet("n") {
+			perLevel = totalRequests
+		}
+		runCalibration(levels, perLevel)
+		return
+	}
+	if trendWindow <= 0 {
+		slog.Error("-trend-window must be positive")
+		exit(1)
+	}
+	if maxInFlight < 0 {
+		slog.Error("-max-inflight must not be negative")
+		exit(1)
+	}
+	if err := checkLatencyMetric(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if requestDeadline < 0 {
+		slog.Error("-request-deadline must not be negative")
+		exit(1)
+	}
+	if sloBucketsValue != "" {
+		var err error
+		if sloBuckets, err = parseSLOBuckets(sloBucketsValue); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+	}
+	if sloTarget <= 0 || sloTarget >= 100 {
+		slog.Error("-slo-target must be between 0 and 100 (exclusive)")
+		exit(1)
+	}
+	if heatmapHTML != "" && heatmapWindow <= 0 {
+		heatmapWindow = time.Second
+	}
+	if isFlagSet("seed") {
+		setSeed(seedValue)
+	}
+	setExpectContinueTimeout(expectContinueTimeout)
+	enableSessionResumption(!noSessionResumption)
+	if bandwidthValue != "" {
+		var err error
+		if bandwidth, err = parseBandwidth(bandwidthValue); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+	}
+	if chaosStallValue != "" {
+		if err := parseChaosStall(chaosStallValue); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+	}
+	if chaosResetRate < 0 || chaosResetRate+chaosStallRate > 1 {
+		slog.Error("-chaos-reset-rate must be between 0 and 1, and together with the -chaos-stall ratio at most 1")
+		exit(1)
+	}
+	if err := checkDialStrategy(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if err := setupResolver(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if err := setupWANLatency(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if err := checkEngine(connectionPolicyFile, streamMode, downloadRateValue); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if connectionPolicyFile != "" {
+		// 策略的客户端以全局客户端为基础，须在全局客户端配置完成之后创建
+		if err := loadConnectionPolicies(connectionPolicyFile); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+	}
+	if responseSchemaFile != "" {
+		var err error
+		if responseSchema, err = loadResponseSchema(responseSchemaFile); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+	}
+	if err := setupProto(protoFile, protoMessageName, protoResponseName); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if protoResponse != nil && responseSchema == nil {
+		slog.Error("-proto-response requires -response-schema")
+		exit(1)
+	}
+	if err := checkStartOptions(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if err := checkKeepAliveSplit(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if rangeSizeValue != "" {
+		if size, err := parseByteSize(rangeSizeValue); err != nil || size <= 0 {
+			slog.Error(fmt.Sprintf("Invalid -range-size %q", rangeSizeValue))
+			exit(1)
+		} else {
+			rangeSize = size
+		}
+		if bodyMode == "ignore" {
+			slog.Error("-range-size cannot be combined with -body-mode ignore, the body length must be checked")
+			exit(1)
+		}
+	}
+	if err := checkBodyMode(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if size, err := parseByteSize(chunkSizeValue); err != nil || size <= 0 {
+		slog.Error(fmt.Sprintf("Invalid -chunk-size %q", chunkSizeValue))
+		exit(1)
+	} else {
+		chunkSize = int(size)
+	}
+	if bodyRateValue != "" {
+		var err error
+		if bodyRate, err = parseByteRate(bodyRateValue); err != nil || bodyRate <= 0 {
+			slog.Error(fmt.Sprintf("Invalid -body-rate %q", bodyRateValue))
+			exit(1)
+		}
+		if !chunkedBody {
+			slog.Error("-body-rate requires -chunked")
+			exit(1)
+		}
+	}
+	if randomBodyValue != "" {
+		var err error
+		if randomBody, err = parseRandomBody(randomBodyValue); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		if protoRequest != nil {
+			slog.Error("-random-body cannot be combined with -proto-message")
+			exit(1)
+		}
+	}
+	if downloadRateValue != "" {
+		var err error
+		if downloadRate, err = parseByteRate(downloadRateValue); err != nil || downloadRate <= 0 {
+			slog.Error(fmt.Sprintf("Invalid -download-rate %q", downloadRateValue))
+			exit(1)
+		}
+	}
+
+	// 非 text 格式下 stdout 只输出该格式的汇总，其余输出改写到 stderr，便于下游脚本直接解析
+	summaryOut := os.Stdout
+	if plainStdout != nil && outputFormat == "json" {
+		// JSON 汇总按原样输出，不经 -plain 转换
+		summaryOut = plainStdout
+	}
+	switch outputFormat {
+	case "text":
+	case "wrk", "hey", "markdown", "json":
+		os.Stdout = os.Stderr
+	default:
+		slog.Error(fmt.Sprintf("Unknown -format %q (expected text, wrk, hey, markdown or json)", outputFormat))
+		exit(1)
+	}
+	var baseline *statsSummary
+	if baselineFile != "" {
+		var err error
+		if baseline, err = loadBaseline(baselineFile); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+	}
+
+	if requestLogFile != "" && !validateMode {
+		var err error
+		if requestLog, err = openRequestLog(requestLogFile); err != nil {
+			slog.Error("Unable to create request log", "err", err)
+			exit(1)
+		}
+	}
+
+	if intervalStreamFile != "" && !validateMode {
+		var err error
+		if intervals, err = openIntervalStream(intervalStreamFile); err != nil {
+			slog.Error("Unable to open interval stream", "err", err)
+			exit(1)
+		}
+	}
+
+	if rotateSize, err := parseByteSize(rotateSizeValue); err != nil || rotateSize < 0 {
+		slog.Error(fmt.Sprintf("Invalid -rotate-size %q", rotateSizeValue))
+		exit(1)
+	} else if intervalLogFile != "" && !validateMode {
+		if intervalLog, err = openIntervalLog(intervalLogFile, rotateSize); err != nil {
+			slog.Error("Unable to create interval log", "err", err)
+			exit(1)
+		}
+	}
+
+	if userAgentFile != "" {
+		loaded, err := loadUserAgents(userAgentFile)
+		if err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		if loaded == 0 {
+			slog.Error("No User-Agents loaded from file")
+			exit(1)
+		}
+		fmt.Printf("🕵️   Rotating %d User-Agents\n", loaded)
+	}
+
+	if validateMode && (wsMode || streamMode) {
+		if bodyFile != "" {
+			loadBodiesFromFile(bodyFile)
+		}
+		mode, duration := "WebSocket", wsDuration
+		if streamMode {
+			mode, duration = "Streaming", streamDuration
+		}
+		fmt.Printf("\n🧪  Test Plan (validated, nothing was sent):\n  - %s mode: %d connections to %s for %s\n", mode, concurrency, url, duration)
+		fmt.Println("\n✅  Plan is valid")
+		return
+	}
+	if wsMode {
+		if bodyFile != "" {
+			loadBodiesFromFile(bodyFile)
+		}
+		runWebSocket(url, concurrency, wsDuration, wsRate, wsMessage)
+		return
+	}
+	if streamMode {
+		if bodyFile != "" {
+			loadBodiesFromFile(bodyFile)
+		}
+		// 与普通压测相同，含 {{ 的 URL、请求体与 header 按模板渲染；没有 -datafile，无法解析的模板按原文发送
+		if err := compileRequestTemplates(url, false); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		runStream(url, method, concurrency, streamDuration)
+		return
+	}
+
+	if rangeSize > 0 && !validateMode {
+		if err := setupRanges(url); err != nil {
+			slog.Error("Unable to use -range-size", "err", err)
+			exit(1)
+		}
+	}
 
 	fmt.Printf("\n🌍  Target URL: %s\n", url)
 	fmt.Printf("🔄  Concurrency: %d, Total Requests: %d\n", concurrency, totalRequests)
 	fmt.Printf("⚡  Keep-Alive Ratio: %.2f\n", keepAliveRatio)
+	if keepAliveSplit == "exact" {
+		fmt.Printf("⚖️   Keep-Alive Split: %d of %d workers use keep-alive\n", keepAliveWorkers(keepAliveRatio, concurrency), concurrency)
+	}
 	fmt.Printf("📡  HTTP Method: %s\n", method)
+	fmt.Printf("🚂  Engine: %s\n", engine)
+	if requestDeadline > 0 {
+		fmt.Printf("⏰  Request Deadline: %s (slower successful responses count as late)\n", requestDeadline)
+	}
+	if addLatency > 0 {
+		fmt.Printf("🌐  WAN Simulation: %s\n", describeWANLatency())
+	}
+	if dialStrategySet {
+		fmt.Printf("🛣️   Dial Strategy: %s\n", describeDialStrategy())
+	}
+	if resolver := describeResolver(); resolver != "" {
+		fmt.Printf("🧭  Resolver: %s\n", resolver)
+		if resolved := resolveTarget(url); resolved != "" {
+			fmt.Printf("  - %s\n", resolved)
+		}
+	}
+	if chaosEnabled() {
+		fmt.Printf("🌪️   Chaos: %s\n", describeChaos())
+	}
+	if bandwidth > 0 {
+		fmt.Printf("🐢  Bandwidth: %s per connection in each direction\n", formatBandwidth(bandwidth))
+	}
+	if rangeObjectSize > 0 {
+		fmt.Printf("🎞️   Range Requests: %s ranges of a %s object\n", formatBytes(min(rangeSize, rangeObjectSize)), formatBytes(rangeObjectSize))
+	}
+	if maxInFlight > 0 {
+		fmt.Printf("🚧  Max In-Flight: %d\n", maxInFlight)
+	}
+	if len(runTags) > 0 {
+		fmt.Printf("🏷️   Tags: %s\n", formatTags(runTags))
+	}
+	if idempotencyHeader != "" {
+		if duplicateRatio < 0 || duplicateRatio > 1 {
+			slog.Error("-duplicate-ratio must be between 0 and 1")
+			exit(1)
+		}
+		if err := parseDuplicateExpect(duplicateExpect); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		if duplicateStatus == 0 && bodyMode == "ignore" {
+			slog.Error("-duplicate-expect same cannot be combined with -body-mode ignore, the bodies must be compared")
+			exit(1)
+		}
+		fmt.Printf("🔑  Idempotency: %s on every request, %.0f%% duplicate submissions expecting %s\n", idempotencyHeader, duplicateRatio*100, duplicateExpect)
+	}
+	if corsOrigin != "" {
+		if corsPreflightRatio < 0 || corsPreflightRatio > 1 {
+			slog.Error("-cors-preflight-ratio must be between 0 and 1")
+			exit(1)
+		}
+		fmt.Printf("✈️   CORS: Origin %s, preflight before %.0f%% of the requests that need one\n", corsOrigin, corsPreflightRatio*100)
+	}
+	if methodMixValue != "" {
+		var err error
+		if methodMix, err = parseMethodMix(methodMixValue); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		fmt.Printf("🔀  Method Mix: %s\n", describeMethodMix())
+	}
+	if targetsCompare != "" {
+		var err error
+		if compareTargets, err = parseCompareTargets(targetsCompare); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		parts := make([]string, len(compareTargets))
+		for i, t := range compareTargets {
+			parts[i] = fmt.Sprintf("%s (weight %g)", t.URL, t.Weight)
+		}
+		fmt.Printf("🆚  Comparing Targets: %s\n", strings.Join(parts, ", "))
+	}
 
 	if bodyFile != "" {
 		loadBodiesFromFile(bodyFile)
-		fmt.Printf("📂  Loaded %d request bodies\n", len(requestBodies))
+		if validateMode && len(requestPool) == 0 {
+			slog.Error("No request bodies loaded from -bodyfile")
+			exit(1)
+		}
+		fmt.Printf("📂  Loaded %d request bodies\n", len(requestPool))
+	}
+	if xmlBodyFile != "" {
+		if err := loadXMLBody(xmlBodyFile); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		fmt.Printf("🧼  Loaded XML body from %s\n", xmlBodyFile)
+	}
+	if fromCurl != "" {
+		spec, err := parseCurlCommand(fromCurl)
+		if err != nil {
+			slog.Error("Unable to parse curl command", "err", err)
+			exit(1)
+		}
+		requestPool = append(requestPool, spec)
+		fmt.Printf("🧾  Imported curl request: %s %s\n", spec.Method, spec.URL)
+	}
+	if curlFile != "" {
+		loaded := loadCurlFile(curlFile)
+		if loaded == 0 {
+			slog.Error("No requests loaded from curl file")
+			exit(1)
+		}
+		fmt.Printf("🧾  Imported %d curl requests\n", loaded)
+	}
+	if postmanFile != "" {
+		weights, err := parseWeights(postmanWeights)
+		if err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		loaded := loadPostmanCollection(postmanFile, postmanEnv, weights)
+		if loaded == 0 {
+			slog.Error("No requests loaded from Postman collection")
+			exit(1)
+		}
+		fmt.Printf("📮  Loaded %d requests from Postman collection\n", loaded)
+	}
+	if openapiFile != "" {
+		var operations []string
+		if openapiOperations != "" {
+			operations = strings.Split(openapiOperations, ",")
+		}
+		loaded := loadOpenAPISpec(openapiFile, operations, url)
+		if loaded == 0 {
+			slog.Error("No operations loaded from OpenAPI spec")
+			exit(1)
+		}
+		fmt.Printf("📘  Generated requests for %d OpenAPI operations\n", loaded)
+	}
+	buildRequestWeights()
+	if soakDuration > 0 {
+		// 按时长运行：-n 不再限制请求数，时延样本改为有界的蓄水池抽样
+		totalRequests = math.MaxInt32
 	}
-	fmt.Println("======================================")
 
-	bar := progressbar.Default(int64(totalRequests))
+	speed, err := parseSpeed(replaySpeed)
+	if err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	// jobs 非空时请求由 feedTimedRequests 按录制节奏投递，否则 worker 自行取用
+	var jobs chan *requestSpec
+	// sequential 为 true 时按录制顺序循环取用 requestPool，而不是随机选择
+	sequential := false
+	if harFile != "" {
+		loaded := loadHARFile(harFile)
+		if loaded == 0 {
+			slog.Error("No requests loaded from HAR file")
+			exit(1)
+		}
+		// 未显式指定 -n 时每条录制请求回放一次
+		if !isFlagSet("n") {
+			totalRequests = loaded
+		}
+		fmt.Printf("📼  Loaded %d HAR entries, replaying %d requests (timing preserved: %v)\n", loaded, totalRequests, harTiming)
+		sequential = true
+		if harTiming {
+			jobs = make(chan *requestSpec)
+			go feedTimedRequests(requestPool, totalRequests, speed, jobs)
+		}
+	}
+	if replayFile != "" {
+		loaded := loadAccessLog(replayFile, replayFormat, url)
+		if loaded == 0 {
+			slog.Error("No requests loaded from access log")
+			exit(1)
+		}
+		if !isFlagSet("n") {
+			totalRequests = loaded
+		}
+		fmt.Printf("📜  Loaded %d access log entries, replaying %d requests at %gx speed\n", loaded, totalRequests, speed)
+		jobs = make(chan *requestSpec)
+		go feedTimedRequests(requestPool, totalRequests, speed, jobs)
+	}
+	if len(reportFilter) > 0 && scenariosFile == "" {
+		slog.Error("-report-filter matches request tags, which are set in -scenarios")
+		exit(1)
+	}
+	if scenariosFile != "" {
+		if jobs != nil || len(requestPool) > 0 || rate > 0 || iterations > 0 || oncePerEntry || adaptive {
+			slog.Error("-scenarios defines its own requests and load; it cannot be combined with -bodyfile and other request sources, -rate, -iterations, -once-per-entry or -adaptive")
+			exit(1)
+		}
+		workers, err := loadScenarios(scenariosFile, concurrency, totalRequests)
+		if err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		concurrency = workers
+		totalRequests = 0
+		for _, sc := range scenarios {
+			totalRequests += sc.total
+		}
+		if soakDuration > 0 {
+			totalRequests = math.MaxInt32
+		}
+	}
+	if dataFile != "" {
+		loaded := loadDataFile(dataFile)
+		if loaded == 0 {
+			slog.Error("No rows loaded from data file")
+			exit(1)
+		}
+		if err := checkDataDistribution(concurrency); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		fmt.Printf("🗂️   Loaded %d data rows (distribution: %s)\n", loaded, dataDistribution)
+	}
+	// 含 {{ 的 URL 与请求体按模板发送；未指定 -datafile 时模板中只能使用 uuid、fake 等函数
+	if err := compileRequestTemplates(url, dataFile != ""); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if validateMode && dataFile == "" {
+		// 未指定 -datafile 时无法解析的模板按原文发送，validate 只提示
+		if err := compileRequestTemplates(url, true); err != nil {
+			slog.Warn(fmt.Sprintf("%v, sent as literal text", err))
+		}
+	}
+	if err := checkProtoBodies(); err != nil {
+		slog.Error("Invalid protobuf request body", "err", err)
+		exit(1)
+	}
+	// scenario 为 -iterations 下每个虚拟用户每次迭代按顺序发送的请求
+	var scenario []*requestSpec
+	if iterations > 0 {
+		if jobs != nil || rate > 0 || soakDuration > 0 {
+			slog.Error("-iterations cannot be combined with -rate, -soak, -replay or -har-timing")
+			exit(1)
+		}
+		scenario = requestPool
+		if len(scenario) == 0 {
+			scenario = []*requestSpec{emptyRequest}
+		}
+		totalRequests = concurrency * iterations * len(scenario)
+		fmt.Printf("🧑  Virtual Users: %d, Iterations: %d, Requests per Iteration: %d\n", concurrency, iterations, len(scenario))
+	}
+	// queue 非空时 worker 从工作队列中取请求，每个请求只成功发送一次
+	var queue *entryQueue
+	if oncePerEntry {
+		if jobs != nil || rate > 0 || soakDuration > 0 || scenario != nil {
+			slog.Error("-once-per-entry cannot be combined with -rate, -soak, -iterations, -replay or -har-timing")
+			exit(1)
+		}
+		if len(requestPool) == 0 {
+			slog.Error("-once-per-entry needs requests to send, e.g. from -bodyfile")
+			exit(1)
+		}
+		queue = newEntryQueue(requestPool, requeueFailures)
+		totalRequests = len(requestPool)
+		fmt.Printf("📦  Work Queue: %d entries, each sent once (failures re-queued up to %d times)\n", len(requestPool), requeueFailures)
+	}
+	// controller 非空时按 -adaptive 目标闭环调整 -rate
+	var controller *adaptiveController
+	if adaptive {
+		if rate <= 0 {
+			slog.Error("-adaptive requires a starting -rate")
+			exit(1)
+		}
+		if targetP99 <= 0 && targetErrorRate <= 0 {
+			slog.Error("-adaptive requires -target-p99 and/or -target-error-rate")
+			exit(1)
+		}
+		controller = newAdaptiveController(rate)
+		patterns = append(patterns, controller)
+		fmt.Printf("🎛️   Adaptive Load: holding %s, adjusting every %s\n", describeTargets(), trendWindow)
+	}
+	// nextRequest 返回第 i 个（从 0 开始）请求：回放录制内容时按录制顺序循环取用，否则随机选择
+	nextRequest := func(i int) *requestSpec {
+		if sequential {
+			return requestPool[i%len(requestPool)]
+		}
+		return getRandomRequest(requestRand(int64(i)+1, randBody))
+	}
+	if rate > 0 {
+		if jobs != nil {
+			slog.Error("-rate cannot be combined with -replay or -har-timing, which keep their recorded pacing")
+			exit(1)
+		}
+		fmt.Printf("⏱️   Arrival Rate: %.2f req/s, Patterns: %d\n", rate, len(patterns))
+		jobs = make(chan *requestSpec)
+		go feedRatedRequests(totalRequests, rate, patterns, nextRequest, jobs)
+	} else if len(patterns) > 0 {
+		slog.Error("-pattern requires a base -rate")
+		exit(1)
+	}
+	if burstSize > 0 {
+		if jobs != nil || scenarios != nil || scenario != nil || queue != nil {
+			slog.Error("-burst cannot be combined with -rate, -replay, -har-timing, -scenarios, -iterations or -once-per-entry")
+			exit(1)
+		}
+		if burstInterval <= 0 {
+			slog.Error("-burst-interval must be positive")
+			exit(1)
+		}
+		// 一轮的请求须同时就位，worker 数不少于 -burst
+		if concurrency < burstSize {
+			fmt.Printf("🔄  Concurrency raised from %d to %d to fire each burst at once\n", concurrency, burstSize)
+			concurrency = burstSize
+		}
+		fmt.Printf("💥  Burst: %s\n", describeBurst(totalRequests))
+		jobs = make(chan *requestSpec)
+		go feedBurstRequests(totalRequests, nextRequest, jobs)
+	}
+	if soakDuration > 0 {
+		// 样本预算按最终的 worker 数分摊，-scenarios 与 -burst 可能已改变 concurrency
+		sampleLimit = max(soakSampleBudget/concurrency, 1000)
+	}
+	if scenarios != nil {
+		reportScenarioSetup()
+	}
+	reportConnectionPolicies(keepAliveRatio)
+	if !validateMode {
+		startPprof(pprofAddr)
+	}
+	if randomBody != nil {
+		fmt.Printf("🎲  Random Bodies: %s\n", randomBody.describe())
+	}
+	if resumeFile != "" {
+		if resumedCheckpoint, err = loadCheckpoint(resumeFile, soakDuration); err != nil {
+			slog.Error(err.Error())
+			exit(1)
+		}
+		if !isFlagSet("checkpoint-file") {
+			checkpointFile = resumeFile
+		}
+	}
+	if soakDuration > 0 {
+		fmt.Printf("🛌  Soak Duration: %s, Checkpoint: every %s -> %s\n", soakDuration, checkpointInterval, checkpointFile)
+	}
+	if resumedCheckpoint != nil {
+		resumedCheckpoint.reportResume(resumeFile, url, soakDuration)
+	}
+	if validateMode {
+		reportPlan(url, method, concurrency, totalRequests, rate, patterns, soakDuration)
+		return
+	}
+	if certCheck {
+		urls := []string{url}
+		for _, spec := range requestPool {
+			urls = append(urls, spec.URL)
+		}
+		for _, t := range compareTargets {
+			urls = append(urls, t.URL)
+		}
+		targets := certTargets(urls)
+		if len(targets) == 0 {
+			fmt.Println("🔏  Certificate Check: no https targets")
+		} else if warnings := checkCertificates(targets); warnings > 0 {
+			fmt.Printf("🚨  Certificate Check: %d warning(s), see above\n", warnings)
+		}
+	}
+	if preflight {
+		estimate := totalRequests
+		if soakDuration > 0 {
+			estimate = 0
+		}
+		if !runPreflight(url, method, estimate) {
+			slog.Error("Preflight failed: some endpoints are unreachable, not starting the load test")
+			exit(1)
+		}
+	}
+	if preconnect && keepAliveRatio > 0 {
+		urls := []string{url}
+		for _, spec := range requestPool {
+			urls = append(urls, spec.URL)
+		}
+		for _, t := range compareTargets {
+			urls = append(urls, t.URL)
+		}
+		// random 分配下每个 worker 都可能使用 Keep-Alive
+		workers := concurrency
+		if keepAliveSplit == "exact" {
+			workers = keepAliveWorkers(keepAliveRatio, concurrency)
+		}
+		runPreconnect(preconnectOrigins(urls), url, workers)
+	}
+	fmt.Println("======================================")
 
-	// 初始化各个 worker 的统计数据
+	// 初始化各个 worker 的累计统计数据，由 ticker goroutine 合并 worker 交出的增量
 	workerStats := make([]*WorkerStats, concurrency)
 	for i := 0; i < concurrency; i++ {
-		workerStats[i] = &WorkerStats{
-			ResponseTimes: make([]time.Duration, 0),
-			StatusCodes:   make(map[int]int),
-		}
+		workerStats[i] = newWorkerStats(expectedWorkerSamples(totalRequests, concurrency))
+	}
+	deltas := make(chan statsDelta, concurrency*4)
+	var resumedElapsed time.Duration
+	if resumedCheckpoint != nil {
+		resumedElapsed = resumedCheckpoint.restore(workerStats[0])
+		atomic.StoreInt64(&globalTotalRequests, workerStats[0].TotalRequests)
+		intervals.skip(workerStats)
+	}
+
+	if err := waitForStart(); err != nil {
+		slog.Error(err.Error())
+		exit(1)
 	}
 
-	// 设置全局统计起始时间，用于累计统计
-	globalStartTime := time.Now()
+	// 设置全局统计起始时间，用于累计统计；续跑时起点前移检查点中已运行的时长，速率、截止时间与偏移量均按整个运行计算
+	globalStartTime := time.Now().Add(-resumedElapsed)
+	heatmapStart = globalStartTime
+	runStartTime = globalStartTime
+	monitor := newSelfMonitor(globalStartTime)
+	detector := newSaturationDetector(globalStartTime)
+	startMaxDuration(maxDuration)
+	if soakDuration > 0 {
+		runDeadline = globalStartTime.Add(soakDuration)
+	}
+	progressTotal := int64(totalRequests)
+	if soakDuration > 0 {
+		progressTotal = 0
+	}
+	bar := newProgressTracker(progressTotal, globalStartTime, runDeadline)
+	atomic.StoreInt32(&logMidRun, 1)
 	// 用于记录上次输出统计时的请求数量
 	var lastReportedRequests int64 = 0
+	lastCheckpoint := globalStartTime.Add(resumedElapsed)
+	// lastWindow 为当前趋势窗口的起始时间
+	lastWindow := globalStartTime.Add(resumedElapsed)
+	checkpoint := func(stats *Stats, now time.Time) {
+		if err := writeCheckpoint(checkpointFile, stats, globalStartTime, now); err != nil {
+			slog.Error("Unable to write checkpoint", "err", err)
+			return
+		}
+		fmt.Printf("\n💾  Checkpoint written to %s\n", checkpointFile)
+	}
 
-	// 启动 ticker，根据累计请求数达到 reportInterval 时输出统计
-	doneChan := make(chan struct{})
+	// 启动 ticker，合并各 worker 的统计增量，根据累计请求数达到 reportInterval 时输出统计；deltas 关闭且取完后退出
 	var tickerWg sync.WaitGroup
 	tickerWg.Add(1)
 	go func() {
 		defer tickerWg.Done()
 		ticker := time.NewTicker(1 * time.Second)
 		defer ticker.Stop()
+		trendTicker := time.NewTicker(trendWindow)
+		defer trendTicker.Stop()
 		for {
 			select {
-			case <-ticker.C:
+			case d, ok := <-deltas:
+				if !ok {
+					return
+				}
+				workerStats[d.worker].merge(d.stats)
+				putDelta(d.stats)
+			case now := <-trendTicker.C:
+				collectTrendWindow(workerStats, lastWindow, now)
+				lastWindow = now
+				if controller != nil {
+					controller.observe()
+				}
+			case tick := <-ticker.C:
+				bar.update(tick)
+				detector.sample(tick, monitor.sample(tick))
 				currentTotal := atomic.LoadInt64(&globalTotalRequests)
 				if currentTotal-lastReportedRequests >= int64(reportInterval) {
-					aggStats := aggregateWorkerStats(workerStats)
+					snapshot := snapshotWorkerStats(workerStats)
 					now := time.Now()
-					reportStats(&aggStats, globalStartTime, now)
+					intervalLog.write(&snapshot, globalStartTime, now)
+					if intervalLog == nil || intervalLogStdout {
+						reportStats(&snapshot, globalStartTime, now)
+					}
 					lastReportedRequests = currentTotal
 				}
-			case <-doneChan:
-				return
+				if soakDuration > 0 && checkpointInterval > 0 && time.Since(lastCheckpoint) >= checkpointInterval {
+					lastCheckpoint = time.Now()
+					snapshot := snapshotWorkerStats(workerStats)
+					checkpoint(&snapshot, lastCheckpoint)
+				}
 			}
 		}
 	}()
 
-	// 使用原子计数器分发请求，确保总请求数准确
+	// 收到中断信号时停止发出新请求，等待进行中的请求结束后照常输出汇总并发送通知；再次中断则立即退出
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		atomic.StoreInt32(&interrupted, 1)
+		slog.Warn("Interrupted, waiting for in-flight requests (press Ctrl+C again to quit immediately)")
+		<-signals
+		exit(130)
+	}()
+
+	// 由调度器分发请求，确保总请求数准确
+	sched := newScheduler(jobs, totalRequests, nextRequest)
+	startScenarios()
 	var wg sync.WaitGroup
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
-		go func(ws *WorkerStats) {
+		go func(worker int) {
 			defer wg.Done()
+			rec := newStatsRecorder(worker, deltas)
+			defer rec.flush()
+			data := newDataSource(worker, concurrency)
+			keepAliveRatio := workerKeepAliveRatio(keepAliveRatio, worker, concurrency)
+			if scenario != nil {
+				// 每个 worker 即一个虚拟用户：按顺序执行场景 iterations 次，每次迭代取一行数据
+				clients := newVUClients()
+				for it := 0; it < iterations && !shouldStop(); it++ {
+					clients.resetSession()
+					row := data.row()
+					for _, step := range scenario {
+						if shouldStop() {
+							return
+						}
+						wait, ok := sched.acquire()
+						if !ok {
+							return
+						}
+						reqNum := atomic.AddInt64(&globalTotalRequests, 1)
+						spec := renderSpec(step, row, reqNum)
+						if randomBody != nil {
+							spec = randomBody.apply(spec, reqNum)
+						}
+						if compareTargets != nil {
+							spec = assignTarget(spec, reqNum)
+						}
+						if methodMix != nil {
+							spec = assignMethod(spec, reqNum, method)
+						}
+						sched.record(rec.stats, wait)
+						sendRequest(rec.stats, clients.pick(requestRand(reqNum, randKeepAlive), keepAliveRatio, policyFor(spec, url)), spec, url, method, nil)
+						sched.release()
+						rec.done()
+						bar.Add(1)
+					}
+				}
+				return
+			}
+			if queue != nil {
+				for {
+					entry, ok := queue.next()
+					if !ok {
+						return
+					}
+					wait, ok := sched.acquire()
+					if !ok {
+						return
+					}
+					reqNum := atomic.AddInt64(&globalTotalRequests, 1)
+					spec := entry.spec
+					spec = renderSpec(spec, data.row(), reqNum)
+					if randomBody != nil {
+						spec = randomBody.apply(spec, reqNum)
+					}
+					if compareTargets != nil {
+						spec = assignTarget(spec, reqNum)
+					}
+					if methodMix != nil {
+						spec = assignMethod(spec, reqNum, method)
+					}
+					sched.record(rec.stats, wait)
+					success := sendRequest(rec.stats, pickClient(requestRand(reqNum, randKeepAlive), keepAliveRatio, policyFor(spec, url)), spec, url, method, nil)
+					sched.release()
+					rec.done()
+					// 进度按完成的项计算，被放回队列的失败尝试不计入
+					if queue.done(entry, success) {
+						bar.Add(1)
+					}
+				}
+			}
+			// -scenarios 下 worker 使用所属场景的调度器，按顺序执行的场景由 journey 换成自己的下一步
+			sched := sched
+			var journey *journeyRunner
+			if workerScenarios != nil {
+				sc := workerScenarios[worker]
+				sched = sc.sched
+				if sc.Journey {
+					journey = newJourneyRunner(sc, worker)
+					defer journey.finish()
+				}
+			}
 			for {
-				reqNum := int(atomic.AddInt64(&globalTotalRequests, 1))
-				if reqNum > totalRequests {
-					break
+				t, ok := sched.next()
+				if !ok {
+					return
 				}
-				startReq := time.Now()
-				reqURL, body := getRandomRequest(url)
-				var client *http.Client
-				if rand.Float64() < keepAliveRatio {
-					client = clientKeepAlive
+				var spec *requestSpec
+				var step *journeyStep
+				if journey != nil {
+					if step, spec = journey.step(t.spec, data); step == nil {
+						// 这一轮的步骤全部因条件不满足而跳过
+						sched.release()
+						bar.Add(1)
+						continue
+					}
+					spec = renderSpec(spec, journey.row, t.num)
 				} else {
-					client = clientNoKeepAlive
+					spec = renderSpec(t.spec, data.row(), t.num)
 				}
-				// 使用 HTTPTrace 捕获响应首字节时间
-				var startTrace time.Time
-				trace := &httptrace.ClientTrace{
-					GotFirstResponseByte: func() {
-						startTrace = time.Now()
-					},
+				if randomBody != nil {
+					spec = randomBody.apply(spec, t.num)
 				}
-				req, err := http.NewRequest(method, reqURL, strings.NewReader(body))
-				if err != nil {
-					ws.mu.Lock()
-					ws.FailedRequests++
-					ws.TotalRequests++
-					ws.mu.Unlock()
-					atomic.AddInt64(&globalFailedRequests, 1)
-					continue
+				if compareTargets != nil {
+					spec = assignTarget(spec, t.num)
 				}
-				req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-				req.Header.Set("User-Agent", "Go-HTTP-LoadTester")
-				req.Header.Set("Content-Type", "application/json")
-				resp, err := client.Do(req)
-				var duration time.Duration
-				if err != nil {
-					ws.mu.Lock()
-					ws.FailedRequests++
-					ws.TotalRequests++
-					ws.mu.Unlock()
-					atomic.AddInt64(&globalFailedRequests, 1)
-				} else {
-					_, _ = io.Copy(io.Discard, resp.Body)
-					resp.Body.Close()
-					if !startTrace.IsZero() {
-						duration = time.Since(startTrace)
-					} else {
-						duration = time.Since(startReq)
-					}
-					ws.mu.Lock()
-					if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-						ws.SuccessRequests++
-						atomic.AddInt64(&globalSuccessRequests, 1)
-					} else {
-						ws.FailedRequests++
-						atomic.AddInt64(&globalFailedRequests, 1)
+				if methodMix != nil {
+					spec = assignMethod(spec, t.num, method)
+				}
+				client := pickClient(requestRand(t.num, randKeepAlive), keepAliveRatio, policyFor(spec, url))
+				if journey != nil {
+					client = journey.clients.pick(requestRand(t.num, randKeepAlive), keepAliveRatio, policyFor(spec, url))
+				}
+				sched.record(rec.stats, t.wait)
+				for {
+					var result stepResult
+					sendRequest(rec.stats, client, spec, url, method, &result)
+					if step == nil || !journey.done(step, result) {
+						break
 					}
-					ws.StatusCodes[resp.StatusCode]++
-					ws.ResponseTimes = append(ws.ResponseTimes, duration)
-					ws.TotalRequests++
-					ws.TotalTime += time.Since(startReq)
-					ws.mu.Unlock()
 				}
+				sched.release()
+				rec.done()
 				bar.Add(1)
+				if step != nil {
+					journey.think(step)
+				}
 			}
-		}(workerStats[i])
+		}(i)
 	}
 
 	wg.Wait()
-	close(doneChan)
+	close(deltas)
 	tickerWg.Wait()
+	if requestLog != nil {
+		if err := requestLog.Close(); err != nil {
+			slog.Error("Unable to write request log", "err", err)
+		} else {
+			fmt.Printf("\n💾  Request log written to %s\n", requestLogFile)
+		}
+	}
+	if intervalLog != nil {
+		if err := intervalLog.Close(); err != nil {
+			slog.Error("Unable to write interval log", "err", err)
+		} else {
+			fmt.Printf("\n💾  Interval log written to %s\n", intervalLog.describe())
+		}
+	}
+	// 最后一个不完整的窗口按实际时长计算，保证短时间的压测也有趋势点
+	collectTrendWindow(workerStats, lastWindow, time.Now())
+	intervals.Close()
+	if trendExport != "" {
+		if err := writeTrendExport(trendExport); err != nil {
+			slog.Error("Unable to export trend", "err", err)
+		} else {
+			fmt.Printf("\n💾  Trend exported to %s\n", trendExport)
+		}
+	}
 
 	// 最终汇总所有 worker 的统计数据并输出累计统计结果
 	finalStats := aggregateWorkerStats(workerStats)
+	if resumedCheckpoint != nil {
+		// 检查点只保存了直方图，整个运行的百分位按直方图计算
+		finalStats.ResponseTimes = nil
+	}
 	endTime := time.Now()
+	finalSummary := summarizeStats(&finalStats, globalStartTime, endTime)
+	if scenarios != nil {
+		finalSummary.Scenarios = summarizeScenarios(workerStats, globalStartTime, endTime)
+		finalSummary.ByTag = summarizeTags(finalStats.ByTag)
+		finalSummary.Filter = summarizeFilter(&finalStats.Filtered, endTime.Sub(globalStartTime))
+	}
+	finalSummary.HeaderValues = summarizeHeaderValues(finalStats.HeaderValues)
+	finalSummary.Backends = summarizeBackends(finalStats.Backends)
+	finalSummary.Revalidation = summarizeRevalidation(&finalStats.Revalidation)
+	finalSummary.Preflight = summarizePreflight(&finalStats.Preflight)
+	finalSummary.Idempotency = summarizeIdempotency(finalStats.Idempotency)
+	finalSummary.Churn = summarizeChurn(&finalStats.Churn, finalStats.TotalTime)
+	finalSummary.Chaos = summarizeChaos()
+	finalSummary.Late = summarizeLate(&finalStats.Late, finalStats.TotalRequests)
+	finalSummary.SLO = summarizeSLO(finalStats.SLO)
+	var offered float64
+	if !adaptive {
+		offered = plannedRate(rate, patterns, totalRequests, min(soakDuration, endTime.Sub(globalStartTime)))
+	}
+	finalSummary.Capacity = summarizeLittlesLaw(&finalStats, concurrency, offered, endTime.Sub(globalStartTime))
+	finalSummary.Latency = summarizeLatency(finalStats.TotalTimes, finalStats.TTFBTimes, finalStats.ServerTimes)
+	thresholdResults := evaluateThresholds(thresholds, finalSummary)
+	finish := func() {
+		replayFailedRequests(finalStats.Failures, url, method)
+		if heatmapHTML != "" {
+			if err := writeHeatmapHTML(heatmapHTML, finalStats.Heatmap); err != nil {
+				slog.Error("Unable to write heatmap", "err", err)
+			} else {
+				fmt.Printf("🌡️   Heatmap written to %s\n", heatmapHTML)
+			}
+		}
+		if grafanaExport != "" {
+			if err := writeGrafanaDashboard(grafanaExport, finalSummary, url, globalStartTime, endTime); err != nil {
+				slog.Error("Unable to write Grafana dashboard", "err", err)
+			} else {
+				fmt.Printf("📊  Grafana dashboard written to %s\n", grafanaExport)
+			}
+		}
+		reportThresholds(thresholdResults)
+		status := "completed"
+		if atomic.LoadInt32(&interrupted) == 1 {
+			status = "aborted"
+		}
+		sendNotifications(notifyWebhook, notifySlack, notification{
+			Status:     status,
+			Target:     url,
+			Method:     method,
+			Passed:     thresholdsPassed(thresholdResults),
+			Summary:    finalSummary,
+			Thresholds: thresholdResults,
+		})
+		exitOnThresholdFailure(thresholdResults)
+		if abortedReason() != "" {
+			exit(1)
+		}
+	}
+	if outputFormat != "text" {
+		reportFormatted(summaryOut, &finalStats, finalSummary, thresholdResults, baseline,
+			url, method, concurrency, endTime.Sub(globalStartTime))
+		if queue != nil {
+			queue.report(len(requestPool))
+		}
+		if controller != nil {
+			controller.report()
+		}
+		finish()
+		return
+	}
 	fmt.Println("\n======================================")
-	fmt.Println("✅  Test completed! Final statistics:")
+	if reason := abortedReason(); reason != "" {
+		fmt.Printf("🛑  Test aborted (%s)! Final statistics:\n", reason)
+	} else {
+		fmt.Println("✅  Test completed! Final statistics:")
+	}
 	reportStats(&finalStats, globalStartTime, endTime)
+	reportLatency(finalSummary.Latency)
+	reportURLStats(&finalStats)
+	reportScenarios(finalSummary.Scenarios)
+	reportTagStats(finalSummary.ByTag)
+	reportFiltered(finalSummary.Filter, finalSummary.TotalRequests)
+	reportMetrics(finalSummary.Metrics)
+	reportLate(finalSummary.Late)
+	reportSLO(finalSummary.SLO)
+	reportThrottling(finalSummary.Throttled)
+	reportQueueWait(finalSummary.QueueWait)
+	reportKeepAliveSplit(&finalStats, keepAliveRatio, concurrency)
+	reportTLS(finalStats.TLS)
+	reportContinue(finalStats.Continue)
+	reportBodyHashes(finalStats.BodyHashes)
+	reportHeaderValues(finalStats.HeaderValues)
+	reportBackends(finalSummary.Backends)
+	reportRevalidation(finalSummary.Revalidation)
+	reportPreflight(finalSummary.Preflight)
+	reportChurn(finalSummary.Churn)
+	reportChaos(finalSummary.Chaos)
+	reportResolutions()
+	reportDialStrategy()
+	reportRanges(finalStats.RangeRequests, finalStats.RangeVerified)
+	reportIdempotency(finalSummary.Idempotency)
+	reportValidationFailures(finalStats.Validation, finalStats.SchemaChecked)
+	reportLittlesLaw(finalSummary.Capacity)
+	if controller != nil {
+		controller.report()
+	}
+	if queue != nil {
+		queue.report(len(requestPool))
+	}
+	reportSlowest(finalStats.Slowest)
+	reportFailedIDs(finalStats.FailedIDs, finalStats.FailedRequests)
+	monitor.report()
+	detector.report()
+	if perWorkerStats {
+		reportWorkerStats(workerStats)
+	}
+	reportTargetComparison(&finalStats, endTime.Sub(globalStartTime))
+	if isFlagSet("heatmap") {
+		reportHeatmap(finalStats.Heatmap)
+	}
+	if soakDuration > 0 && checkpointInterval > 0 {
+		checkpoint(&finalStats, endTime)
+	}
 
+	if noGraphs {
+		finish()
+		return
+	}
 	ensureNonEmptyHistory()
 
 	fmt.Println("\n📈  TPS Trend:")
-	fmt.Println(asciigraph.Plot(tpsHistory, asciigraph.Height(10)))
+	fmt.Println(plotTrend(tpsHistory, graphOptions(graphHeight)...))
 
 	fmt.Println("\n📊  QPS Trend:")
-	fmt.Println(asciigraph.Plot(qpsHistory, asciigraph.Height(10)))
+	fmt.Println(plotTrend(qpsHistory, graphOptions(graphHeight)...))
 
 	fmt.Println("\n📉  Response Time Trend (ms):")
 	fmt.Println("P50:")
-	fmt.Println(asciigraph.Plot(p50History, asciigraph.Height(5)))
+	fmt.Println(plotTrend(p50History, graphOptions(graphHeight/2)...))
 	fmt.Println("P95:")
-	fmt.Println(asciigraph.Plot(p95History, asciigraph.Height(5)))
+	fmt.Println(plotTrend(p95History, graphOptions(graphHeight/2)...))
 	fmt.Println("P99:")
-	fmt.Println(asciigraph.Plot(p99History, asciigraph.Height(5)))
+	fmt.Println(plotTrend(p99History, graphOptions(graphHeight/2)...))
+
+	if apdexThreshold > 0 {
+		fmt.Printf("\n🙂  Apdex Trend (T=%s):\n", apdexThreshold)
+		fmt.Println(plotTrend(apdexHistory, append(graphOptions(graphHeight/2), asciigraph.LowerBound(0), asciigraph.UpperBound(1))...))
+	}
+	plotMetricTrends()
+
+	finish()
+}
+
+// exitOnThresholdFailure 在有规则未通过时以退出码 1 结束，便于 CI 判定
+func exitOnThresholdFailure(results []thresholdResult) {
+	if !thresholdsPassed(results) {
+		fmt.Println("\n❌ Some thresholds failed")
+		exit(1)
+	}
 }
 
-// aggregateWorkerStats 将所有 worker 的统计数据合并为全局统计数据，读数据时加锁
+// aggregateWorkerStats 将所有 worker 的累计统计数据合并为全局统计数据，须在 ticker goroutine 中或 worker 全部结束后调用
 func aggregateWorkerStats(workers []*WorkerStats) Stats {
 	global := Stats{
 		StatusCodes:   make(map[int]int),
 		ResponseTimes: make([]time.Duration, 0),
+		URLStats:      make(map[string]*URLStats),
+		Errors:        make(map[string]int),
+		Targets:       make(map[string]*URLStats),
+		ByTag:         make(map[string]*URLStats),
+		BodyHashes:    make(bodyHashes),
+		HeaderValues:  make(headerValues),
+		Backends:      make(map[string]*URLStats),
+		Validation:    make(map[string]int),
 	}
 	for _, ws := range workers {
-		ws.mu.Lock()
 		global.TotalRequests += ws.TotalRequests
 		global.SuccessRequests += ws.SuccessRequests
 		global.FailedRequests += ws.FailedRequests
 		global.TotalTime += ws.TotalTime
+		global.Apdex.add(ws.Apdex)
+		global.Slowest = append(global.Slowest, ws.Slowest...)
+		global.Failures.add(ws.Failures)
+		global.Throttle.add(ws.Throttle)
+		global.Continue.add(ws.Continue)
+		global.Revalidation.add(&ws.Revalidation)
+		global.Preflight.add(&ws.Preflight)
+		global.Idempotency.add(ws.Idempotency)
+		global.Churn.add(&ws.Churn)
+		global.Late.TotalRequests += ws.Late.TotalRequests
+		global.Late.ResponseTimes = append(global.Late.ResponseTimes, ws.Late.ResponseTimes...)
+		global.SLO.add(ws.SLO)
+		global.BodyHashes.add(ws.BodyHashes)
+		global.HeaderValues.add(ws.HeaderValues)
+		global.SchemaChecked += ws.SchemaChecked
+		global.RangeRequests += ws.RangeRequests
+		global.RangeVerified += ws.RangeVerified
+		global.KeepAliveRequests += ws.KeepAliveRequests
+		global.TLS.add(ws.TLS)
+		global.QueueWait.add(ws.QueueWait)
+		global.Metrics = addMetrics(global.Metrics, ws.Metrics)
+		for reason, count := range ws.Validation {
+			global.Validation[reason] += count
+		}
+		for _, id := range ws.FailedIDs {
+			global.FailedIDs = appendFailedID(global.FailedIDs, id)
+		}
+		global.BytesRead += ws.BytesRead
+		global.TotalTimes = append(global.TotalTimes, ws.TotalTimes...)
+		global.TTFBTimes = append(global.TTFBTimes, ws.TTFBTimes...)
+		global.ServerTimes = append(global.ServerTimes, ws.ServerTimes...)
+		global.Phases.add(ws.Phases)
+		global.Heatmap.add(ws.Heatmap)
+		global.SocketErrors.add(ws.SocketErrors)
+		global.Digest.add(ws.Digest)
+		global.WorkerRequests = append(global.WorkerRequests, ws.TotalRequests)
+		for msg, count := range ws.Errors {
+			global.Errors[msg] += count
+		}
+		for target, ts := range ws.Targets {
+			agg, ok := global.Targets[target]
+			if !ok {
+				agg = &URLStats{}
+				global.Targets[target] = agg
+			}
+			agg.TotalRequests += ts.TotalRequests
+			agg.FailedRequests += ts.FailedRequests
+			agg.ResponseTimes = append(agg.ResponseTimes, ts.ResponseTimes...)
+		}
+		for id, bs := range ws.Backends {
+			agg, ok := global.Backends[id]
+			if !ok {
+				agg = &URLStats{}
+				global.Backends[id] = agg
+			}
+			agg.TotalRequests += bs.TotalRequests
+			agg.FailedRequests += bs.FailedRequests
+			agg.ResponseTimes = append(agg.ResponseTimes, bs.ResponseTimes...)
+		}
+		for tag, ts := range ws.ByTag {
+			agg, ok := global.ByTag[tag]
+			if !ok {
+				agg = &URLStats{}
+				global.ByTag[tag] = agg
+			}
+			agg.TotalRequests += ts.TotalRequests
+			agg.FailedRequests += ts.FailedRequests
+			agg.ResponseTimes = append(agg.ResponseTimes, ts.ResponseTimes...)
+		}
+		global.Filtered.TotalRequests += ws.Filtered.TotalRequests
+		global.Filtered.FailedRequests += ws.Filtered.FailedRequests
+		global.Filtered.ResponseTimes = append(global.Filtered.ResponseTimes, ws.Filtered.ResponseTimes...)
 		for code, count := range ws.StatusCodes {
 			global.StatusCodes[code] += count
 		}
 		global.ResponseTimes = append(global.ResponseTimes, ws.ResponseTimes...)
-		ws.mu.Unlock()
+		for key, us := range ws.URLStats {
+			agg, ok := global.URLStats[key]
+			if !ok {
+				agg = &URLStats{}
+				global.URLStats[key] = agg
+			}
+			agg.TotalRequests += us.TotalRequests
+			agg.FailedRequests += us.FailedRequests
+			agg.ResponseTimes = append(agg.ResponseTimes, us.ResponseTimes...)
+		}
 	}
+	global.Slowest = mergeSlowest(global.Slowest)
 	return global
 }
 
-// loadBodiesFromFile 读取 JSON 文件，支持两种格式
+// snapshotWorkerStats 为周期输出与检查点汇总计数与时延直方图，不复制、不排序时延样本，开销与运行时长无关；
+// 须在 ticker goroutine 中调用
+func snapshotWorkerStats(workers []*WorkerStats) Stats {
+	global := Stats{StatusCodes: make(map[int]int)}
+	for _, ws := range workers {
+		global.TotalRequests += ws.TotalRequests
+		global.SuccessRequests += ws.SuccessRequests
+		global.FailedRequests += ws.FailedRequests
+		global.Late.TotalRequests += ws.Late.TotalRequests
+		global.TotalTime += ws.TotalTime
+		global.Apdex.add(ws.Apdex)
+		global.Throttle.add(ws.Throttle)
+		global.WorkerRequests = append(global.WorkerRequests, ws.TotalRequests)
+		global.Digest.add(ws.Digest)
+		global.QueueWait.add(ws.QueueWait)
+		for code, count := range ws.StatusCodes {
+			global.StatusCodes[code] += count
+		}
+	}
+	return global
+}
+
+// loadBodiesFromFile 读取 JSON 文件，支持两种格式：
+// - 只有 body，则形式为 ["body", ...]
+// - 有 URL 和 body，则形式为 [["url", "body"], ...]，url 为空时使用默认 URL
+// - 第三个元素可选，为该请求响应 body 应有的 SHA-256，如 [["url", "body", "sha256"], ...]
 func loadBodiesFromFile(filename string) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		fmt.Printf("❌ Unable to read JSON file: %v\n", err)
+		slog.Error("Unable to read JSON file", "err", err)
 		return
 	}
 	var parsed [][]string
 	if err := json.Unmarshal(data, &parsed); err == nil {
-		requestBodies = parsed
+		for _, entry := range parsed {
+			switch len(entry) {
+			case 0:
+				continue
+			case 1:
+				requestPool = append(requestPool, &requestSpec{Body: entry[0]})
+			case 2:
+				requestPool = append(requestPool, &requestSpec{URL: entry[0], Body: entry[1]})
+			default:
+				requestPool = append(requestPool, &requestSpec{URL: entry[0], Body: entry[1], SHA256: entry[2]})
+			}
+		}
 		return
 	}
 	var singleParsed []string
 	if err := json.Unmarshal(data, &singleParsed); err == nil {
 		for _, body := range singleParsed {
-			requestBodies = append(requestBodies, []string{"", body})
+			requestPool = append(requestPool, &requestSpec{Body: body})
 		}
 	}
 }
 
-// getRandomRequest 随机返回一个请求的 URL 与 body
-func getRandomRequest(defaultURL string) (string, string) {
-	if len(requestBodies) == 0 {
-		return defaultURL, ""
+// emptyRequest 在未加载任何请求时使用，即使用默认 URL 且 body 为空
+var emptyRequest = &requestSpec{}
+
+// getRandomRequest 用 rng 随机返回一个请求，设置了权重时按权重选择
+func getRandomRequest(rng *rand.Rand) *requestSpec {
+	if len(requestPool) == 0 {
+		return emptyRequest
 	}
-	randomEntry := requestBodies[rand.Intn(len(requestBodies))]
-	if len(randomEntry) == 1 {
-		return defaultURL, randomEntry[0]
+	if requestWeights != nil {
+		return pickWeightedRequest(rng)
 	}
-	if randomEntry[0] == "" {
-		return defaultURL, randomEntry[1]
+	return requestPool[rng.Intn(len(requestPool))]
+}
+
+// isFlagSet 判断命令行中是否显式指定了某个参数
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// pickClient 用 rng 按 keepAliveRatio 随机选择是否使用 Keep-Alive 客户端；
+// policy 非空时改用该连接策略的客户端与 Keep-Alive 比例
+func pickClient(rng *rand.Rand, keepAliveRatio float64, policy *connectionPolicy) *http.Client {
+	if policy != nil {
+		if rng.Float64() < policy.keepAliveRatio(keepAliveRatio) {
+			return policy.keepAlive
+		}
+		return policy.noKeepAlive
+	}
+	if rng.Float64() < keepAliveRatio {
+		return clientKeepAlive
 	}
-	return randomEntry[0], randomEntry[1]
+	return clientNoKeepAlive
+}
+
+// sendRequest 发送一个请求并把结果记录到 worker 的统计数据中，返回是否得到 2xx 响应；
+// result 非空时记录响应状态，供按顺序执行的场景判断后续步骤
+func sendRequest(ws *WorkerStats, client *http.Client, spec *requestSpec, defaultURL, defaultMethod string, result *stepResult) bool {
+	startReq := time.Now()
+	// 使用 HTTPTrace 捕获响应首字节时间及各阶段耗时
+	trace := &requestTrace{}
+	req, err := newHTTPRequest(spec, defaultURL, defaultMethod)
+	if err != nil {
+		ws.FailedRequests++
+		ws.TotalRequests++
+		ws.Apdex.Frustrated++
+		ws.SLO.record(false, 0)
+		ws.Failures.record(failedRequestFor(spec, nil, 0, err))
+		countFailure()
+		slog.Debug("Unable to build request", "method", spec.Method, "url", spec.URL, "err", err)
+		requestLog.write(requestLogEntry{Time: startReq, Method: spec.Method, URL: spec.URL, Target: spec.Target, Tags: spec.Tags, Error: err.Error()})
+		return false
+	}
+	defer releaseRequest(client, req)
+	var requestID string
+	if requestIDHeader != "" {
+		requestID = newUUID()
+		req.Header.Set(requestIDHeader, requestID)
+	}
+	reqURL := req.URL.String()
+	conditional := addConditionalHeaders(req, reqURL)
+	ranged := setRange(req, reqURL)
+	idem := setIdempotencyKey(req)
+	churnClose(req, client)
+	key := spec.Name
+	if key == "" && spec.URLTemplate != "" {
+		key = templateStatsKey(req.Method, spec.URLTemplate)
+	} else if key == "" {
+		key = urlStatsKey(req.Method, reqURL)
+	}
+	if spec.Timeout > 0 {
+		withTimeout := *client
+		withTimeout.Timeout = spec.Timeout
+		client = &withTimeout
+	}
+	sentAt := startReq
+	if corsOrigin != "" {
+		req.Header.Set("Origin", corsOrigin)
+		sendPreflight(ws, client, req)
+		// 预检的耗时单独统计，实际请求从预检结束后开始计时
+		startReq = time.Now()
+	}
+	req = req.WithContext(httptrace.WithClientTrace(runContext, trace.clientTrace()))
+	resp, err := client.Do(req)
+	gotResponse := time.Now()
+	saturation.record(spec.Scheduled, sentAt, trace, err)
+	var duration time.Duration
+	if err != nil {
+		end := time.Now()
+		ws.FailedRequests++
+		ws.TotalRequests++
+		if usesKeepAlive(client) {
+			ws.KeepAliveRequests++
+		}
+		ws.TLS.record(trace)
+		us := ws.urlStats(key)
+		us.TotalRequests++
+		us.FailedRequests++
+		ws.Apdex.Frustrated++
+		ws.SLO.record(false, 0)
+		ws.Window.fail()
+		if spec.Target != "" {
+			ts := ws.targetStats(spec.Target)
+			ts.TotalRequests++
+			ts.FailedRequests++
+		}
+		ws.recordTags(spec, false, false, 0)
+		ws.recordError(err)
+		ws.Failures.record(failedRequestFor(spec, req, 0, err))
+		ws.FailedIDs = appendFailedID(ws.FailedIDs, requestID)
+		ws.Heatmap.record(startReq, end.Sub(startReq))
+		ws.Slowest.offer(slowRequest{Time: startReq, ID: requestID, Method: req.Method, URL: reqURL, Error: err.Error(),
+			Total: end.Sub(startReq), Phases: trace.phases(end)})
+		countFailure()
+		slog.Debug("Request failed", "method", req.Method, "url", reqURL, "err", err)
+		requestLog.write(requestLogEntry{Time: startReq, RequestID: requestID, Method: req.Method, URL: reqURL,
+			Target: spec.Target, Tags: spec.Tags, Error: err.Error(), DurationMs: durationMs(end.Sub(startReq))})
+		return false
+	}
+	checkSchema := sampleSchema()
+	bytesRead, body, bodySum, _ := readBody(resp.Body, expectedSHA256(spec) != "" || (idem != nil && duplicateStatus == 0), checkSchema || len(xpathAssertions) > 0 || len(spec.Extract) > 0)
+	resp.Body.Close()
+	end := time.Now()
+	phases := trace.phases(end)
+	// 收到 100 Continue 等无法得知最终响应首字节的情况下，以收到响应头的时间近似
+	ttfb := gotResponse.Sub(startReq)
+	if firstByte := trace.firstResponseByte(); !firstByte.IsZero() {
+		ttfb = firstByte.Sub(startReq)
+	}
+	server := max(ttfb-phases.DNS-phases.Connect-phases.TLS, 0)
+	duration = measuredLatency(end.Sub(startReq), ttfb, server)
+	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	// invalid 为 2xx 响应内容校验未通过的原因，此时请求同样记为失败
+	var invalid string
+	checkSchema = checkSchema && success
+	if success {
+		invalid = validateResponse(spec, bodySum, body, checkSchema)
+		if invalid == "" && ranged != nil {
+			invalid = ranged.check(resp, bytesRead)
+		}
+		success = invalid == ""
+	}
+	// 重复提交得到预期的响应（如 409）即计为成功
+	if idem != nil {
+		reason := idem.check(ws, resp.StatusCode, bodySum)
+		switch {
+		case invalid != "":
+		case reason != "":
+			invalid, success = reason, false
+		case idem.duplicate != nil:
+			success = true
+		}
+	}
+	if ranged != nil {
+		ws.RangeRequests++
+		if success {
+			ws.RangeVerified++
+		}
+	}
+	if checkSchema {
+		ws.SchemaChecked++
+	}
+	// -conditional 下条件请求得到的 304 即为期望的结果
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		success = true
+	}
+	if req.Header.Get("Expect") != "" {
+		ws.Continue.record(trace, resp.StatusCode)
+	}
+	us := ws.urlStats(key)
+	// late 为超过 -request-deadline 的成功响应，既不计为成功也不计为失败
+	late := success && isLate(end.Sub(startReq))
+	ws.Window.sample(success && !late, duration)
+	ws.SLO.record(success, end.Sub(startReq))
+	if spec.Target != "" {
+		ts := ws.targetStats(spec.Target)
+		ts.TotalRequests++
+		if !success {
+			ts.FailedRequests++
+		}
+		ts.ResponseTimes = appendSample(ts.ResponseTimes, &ts.sampleCount, end.Sub(startReq))
+	}
+	switch {
+	case late:
+		ws.Late.TotalRequests++
+		ws.Late.ResponseTimes = appendSample(ws.Late.ResponseTimes, &ws.Late.sampleCount, end.Sub(startReq))
+		ws.Apdex.Frustrated++
+		atomic.AddInt64(&globalLateRequests, 1)
+	case success:
+		ws.SuccessRequests++
+		ws.Apdex.record(duration)
+		atomic.AddInt64(&globalSuccessRequests, 1)
+	default:
+		ws.FailedRequests++
+		ws.Apdex.Frustrated++
+		us.FailedRequests++
+		if invalid != "" {
+			ws.Validation[invalid]++
+		}
+		ws.Failures.record(failedRequest{spec: spec, Method: req.Method, URL: reqURL, Status: resp.StatusCode, Error: invalid})
+		ws.FailedIDs = appendFailedID(ws.FailedIDs, requestID)
+		countFailure()
+	}
+	if result != nil {
+		result.Status = resp.StatusCode
+		result.Success = success
+	}
+	if len(spec.Extract) > 0 {
+		var values map[string]string
+		if result != nil {
+			values = make(map[string]string, len(spec.Extract))
+			result.Values = values
+		}
+		extractValues(ws, spec, resp.Header, body, values)
+	}
+	ws.StatusCodes[resp.StatusCode]++
+	ws.ResponseTimes = appendSample(ws.ResponseTimes, &ws.sampleCount, duration)
+	ws.TotalRequests++
+	if usesKeepAlive(client) {
+		ws.KeepAliveRequests++
+	}
+	ws.TotalTime += end.Sub(startReq)
+	us.TotalRequests++
+	us.ResponseTimes = appendSample(us.ResponseTimes, &us.sampleCount, duration)
+	ws.recordTags(spec, success, true, duration)
+	ws.BytesRead += bytesRead
+	if bodyMode == "hash" && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		ws.BodyHashes.record(key, bodySum)
+	}
+	if len(captureHeaders) > 0 {
+		ws.HeaderValues.record(resp.Header)
+	}
+	if conditionalRequests {
+		storeValidators(req, reqURL, resp)
+		ws.Revalidation.record(conditional, resp.StatusCode, success, end.Sub(startReq))
+	}
+	if backendHeader != "" {
+		bs := ws.backendStats(backendID(resp))
+		bs.TotalRequests++
+		if !success {
+			bs.FailedRequests++
+		}
+		bs.ResponseTimes = appendSample(bs.ResponseTimes, &bs.sampleCount, end.Sub(startReq))
+	}
+	ws.TotalTimes = appendSample(ws.TotalTimes, &ws.totalSampleCount, end.Sub(startReq))
+	ws.TTFBTimes = appendSample(ws.TTFBTimes, &ws.ttfbSampleCount, ttfb)
+	ws.ServerTimes = appendSample(ws.ServerTimes, &ws.serverSampleCount, server)
+	ws.Phases.record(phases)
+	ws.TLS.record(trace)
+	if closeEvery > 0 {
+		ws.Churn.record(trace, end.Sub(startReq))
+	}
+	ws.Heatmap.record(startReq, end.Sub(startReq))
+	ws.Slowest.offer(slowRequest{Time: startReq, ID: requestID, Method: req.Method, URL: reqURL, Status: resp.StatusCode,
+		Total: end.Sub(startReq), Phases: phases})
+	requestLog.write(requestLogEntry{Time: startReq, RequestID: requestID, Method: req.Method, URL: reqURL,
+		Target: spec.Target, Tags: spec.Tags, Status: resp.StatusCode, Error: invalid, DurationMs: durationMs(end.Sub(startReq)), Bytes: bytesRead})
+	if delay := retryAfterDelay(resp, end); delay > 0 {
+		throttled := backoff(delay)
+		ws.Throttle.Backoffs++
+		ws.Throttle.Time += throttled
+	}
+	return success
+}
+
+// targetStats 返回 target 对应的统计数据，不存在时创建
+func (ws *WorkerStats) targetStats(target string) *URLStats {
+	ts, ok := ws.Targets[target]
+	if !ok {
+		ts = &URLStats{}
+		ws.Targets[target] = ts
+	}
+	return ts
+}
+
+// urlStats 返回 key 对应的 URL 统计数据，不存在时创建
+func (ws *WorkerStats) urlStats(key string) *URLStats {
+	us, ok := ws.URLStats[key]
+	if !ok {
+		us = &URLStats{}
+		ws.URLStats[key] = us
+	}
+	return us
 }
 
 // ensureNonEmptyHistory 保证全局趋势数组不为空，防止 asciigraph.Plot 因为空切片而 panic
@@ -329,6 +2041,9 @@ func ensureNonEmptyHistory() {
 	if len(p99History) == 0 {
 		p99History = append(p99History, 0)
 	}
+	if len(apdexHistory) == 0 {
+		apdexHistory = append(apdexHistory, 0)
+	}
 }
 
 // percentile 计算 durations 切片中指定百分比的响应时延
@@ -343,46 +2058,132 @@ func percentile(durations []time.Duration, percent float64) time.Duration {
 	return durations[index]
 }
 
-// reportStats 输出当前累计统计数据，并更新全局趋势数组；统计周期为 startTime 到 now 的间隔
+// reportStats 输出当前累计统计数据；统计周期为 startTime 到 now 的间隔
 func reportStats(stats *Stats, startTime, now time.Time) {
-	totalDuration := now.Sub(startTime)
-	if totalDuration.Seconds() == 0 {
+	writeStats(os.Stdout, stats, startTime, now)
+}
+
+// writeStats 把 reportStats 的内容写到 w
+func writeStats(w io.Writer, stats *Stats, startTime, now time.Time) {
+	if now.Sub(startTime).Seconds() == 0 {
 		return
 	}
-	tps := float64(stats.SuccessRequests) / totalDuration.Seconds()
-	qps := float64(stats.TotalRequests) / totalDuration.Seconds()
+	if len(stats.ResponseTimes) == 0 && stats.Digest.total == 0 {
+		fmt.Fprintln(w, "\n⚠️  Not enough data for statistics")
+		return
+	}
+	summary := summarizeStats(stats, startTime, now)
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Metric", "Value"})
+	table.Append([]string{"Total Requests", fmt.Sprintf("%d", summary.TotalRequests)})
+	table.Append([]string{"Success Requests", fmt.Sprintf("%d", summary.SuccessRequests)})
+	table.Append([]string{"Failed Requests", fmt.Sprintf("%d", summary.FailedRequests)})
+	if requestDeadline > 0 {
+		table.Append([]string{"Late Requests", fmt.Sprintf("%d", summary.LateRequests)})
+	}
+	table.Append([]string{"TPS", fmt.Sprintf("%.2f", summary.TPS)})
+	table.Append([]string{"QPS", fmt.Sprintf("%.2f", summary.QPS)})
+	table.Append([]string{"P50", fmt.Sprintf("%.0f ms", summary.P50Ms)})
+	table.Append([]string{"P95", fmt.Sprintf("%.0f ms", summary.P95Ms)})
+	table.Append([]string{"P99", fmt.Sprintf("%.0f ms", summary.P99Ms)})
+	if summary.Apdex != nil {
+		table.Append([]string{fmt.Sprintf("Apdex (T=%s)", apdexThreshold), fmt.Sprintf("%.2f [S=%d T=%d F=%d]",
+			summary.Apdex.Score, summary.Apdex.Satisfied, summary.Apdex.Tolerating, summary.Apdex.Frustrated)})
+	}
+	table.Render()
+
+	fmt.Fprintln(w, "\n📡  HTTP Status Code Statistics:")
+	for code, count := range stats.StatusCodes {
+		fmt.Fprintf(w, "  - %d: %d times\n", code, count)
+	}
+}
+
+// maxURLStatsRows 为按 URL 统计表格的最大行数，超出部分只输出数量
+const maxURLStatsRows = 20
 
-	if len(stats.ResponseTimes) == 0 {
-		fmt.Println("\n⚠️  Not enough data for statistics")
+// reportURLStats 在请求涉及多个 URL（或命名请求）时按请求数降序输出每一项的统计数据
+func reportURLStats(stats *Stats) {
+	if len(stats.URLStats) < 2 {
 		return
 	}
-	sort.Slice(stats.ResponseTimes, func(i, j int) bool {
-		return stats.ResponseTimes[i] < stats.ResponseTimes[j]
+	keys := make([]string, 0, len(stats.URLStats))
+	for key := range stats.URLStats {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := stats.URLStats[keys[i]], stats.URLStats[keys[j]]
+		if a.TotalRequests != b.TotalRequests {
+			return a.TotalRequests > b.TotalRequests
+		}
+		return keys[i] < keys[j]
 	})
-	p50 := percentile(stats.ResponseTimes, 50)
-	p95 := percentile(stats.ResponseTimes, 95)
-	p99 := percentile(stats.ResponseTimes, 99)
-
-	tpsHistory = append(tpsHistory, tps)
-	qpsHistory = append(qpsHistory, qps)
-	p50History = append(p50History, float64(p50.Milliseconds()))
-	p95History = append(p95History, float64(p95.Milliseconds()))
-	p99History = append(p99History, float64(p99.Milliseconds()))
 
+	fmt.Println("\n🔗  Per-Endpoint Statistics:")
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Metric", "Value"})
-	table.Append([]string{"Total Requests", fmt.Sprintf("%d", stats.TotalRequests)})
-	table.Append([]string{"Success Requests", fmt.Sprintf("%d", stats.SuccessRequests)})
-	table.Append([]string{"Failed Requests", fmt.Sprintf("%d", stats.FailedRequests)})
-	table.Append([]string{"TPS", fmt.Sprintf("%.2f", tps)})
-	table.Append([]string{"QPS", fmt.Sprintf("%.2f", qps)})
-	table.Append([]string{"P50", fmt.Sprintf("%d ms", p50.Milliseconds())})
-	table.Append([]string{"P95", fmt.Sprintf("%d ms", p95.Milliseconds())})
-	table.Append([]string{"P99", fmt.Sprintf("%d ms", p99.Milliseconds())})
+	table.SetHeader([]string{"Endpoint", "Requests", "Failed", "P50", "P95", "P99"})
+	for i, key := range keys {
+		if i == maxURLStatsRows {
+			break
+		}
+		us := stats.URLStats[key]
+		sort.Slice(us.ResponseTimes, func(i, j int) bool { return us.ResponseTimes[i] < us.ResponseTimes[j] })
+		table.Append([]string{
+			key,
+			fmt.Sprintf("%d", us.TotalRequests),
+			fmt.Sprintf("%d", us.FailedRequests),
+			fmt.Sprintf("%d ms", percentile(us.ResponseTimes, 50).Milliseconds()),
+			fmt.Sprintf("%d ms", percentile(us.ResponseTimes, 95).Milliseconds()),
+			fmt.Sprintf("%d ms", percentile(us.ResponseTimes, 99).Milliseconds()),
+		})
+	}
 	table.Render()
+	if len(keys) > maxURLStatsRows {
+		fmt.Printf("  ... and %d more endpoints\n", len(keys)-maxURLStatsRows)
+	}
+}
 
-	fmt.Println("\n📡  HTTP Status Code Statistics:")
-	for code, count := range stats.StatusCodes {
-		fmt.Printf("  - %d: %d times\n", code, count)
+// reportWorkerStats 输出每个 worker 的请求数、失败数与时延百分位，用于发现个别 worker 被饿死或卡在慢连接上
+func reportWorkerStats(workers []*WorkerStats) {
+	var total, busiest, idlest int64
+	idlest = -1
+	for _, ws := range workers {
+		n := ws.TotalRequests
+		total += n
+		if n > busiest {
+			busiest = n
+		}
+		if idlest < 0 || n < idlest {
+			idlest = n
+		}
+	}
+
+	fmt.Println("\n👷  Per-Worker Statistics:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Worker", "Requests", "Share", "Failed", "P50", "P95", "P99", "Max"})
+	for i, ws := range workers {
+		times := append([]time.Duration(nil), ws.ResponseTimes...)
+		requests, failed := ws.TotalRequests, ws.FailedRequests
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		var share float64
+		if total > 0 {
+			share = float64(requests) / float64(total) * 100
+		}
+		table.Append([]string{
+			fmt.Sprintf("%d", i),
+			fmt.Sprintf("%d", requests),
+			fmt.Sprintf("%.1f%%", share),
+			fmt.Sprintf("%d", failed),
+			fmt.Sprintf("%d ms", percentile(times, 50).Milliseconds()),
+			fmt.Sprintf("%d ms", percentile(times, 95).Milliseconds()),
+			fmt.Sprintf("%d ms", percentile(times, 99).Milliseconds()),
+			fmt.Sprintf("%d ms", percentile(times, 100).Milliseconds()),
+		})
+	}
+	table.Render()
+	if idlest > 0 {
+		fmt.Printf("  Skew: busiest worker sent %.2fx the requests of the least busy one\n", float64(busiest)/float64(idlest))
+	} else if busiest > 0 {
+		fmt.Println("  Skew: at least one worker sent no requests")
 	}
 }
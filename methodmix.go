@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// methodMixValue、preflightOrigin 为 -method-mix 与 -preflight-origin：每个请求按权重换成其中一个方法，
+// 如 GET=90,HEAD=5,OPTIONS=5，使廉价方法与 CORS 预检的流量也体现在压测中；HEAD 与 OPTIONS 不带 body，
+// OPTIONS 以 CORS 预检的形式发出，带 Origin 与 Access-Control-Request-Method
+var (
+	methodMixValue  string
+	preflightOrigin string
+)
+
+// methodWeight 为 -method-mix 中的一项
+type methodWeight struct {
+	Method string
+	Weight float64
+}
+
+// methodMix 非空时每个请求按权重换成其中一个方法
+var methodMix []methodWeight
+
+// parseMethodMix 解析 GET=90,HEAD=5,OPTIONS=5 形式的方法与权重，省略权重时为 1
+func parseMethodMix(value string) ([]methodWeight, error) {
+	var mix []methodWeight
+	var total float64
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, weight, hasWeight := strings.Cut(item, "=")
+		m := methodWeight{Method: strings.ToUpper(strings.TrimSpace(name)), Weight: 1}
+		if m.Method == "" || strings.ContainsAny(m.Method, " \t()<>@,;:\\\"/[]?={}") {
+			return nil, fmt.Errorf("invalid method %q in -method-mix", name)
+		}
+		if hasWeight {
+			w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight %q for %s in -method-mix", weight, m.Method)
+			}
+			m.Weight = w
+		}
+		total += m.Weight
+		mix = append(mix, m)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("-method-mix needs at least one method with a positive weight")
+	}
+	return mix, nil
+}
+
+// assignMethod 按权重为第 n 个请求选择方法，返回换成该方法的请求副本；defaultMethod 为 -X，
+// 用作 OPTIONS 预检中 Access-Control-Request-Method 的取值（请求自身为 HEAD 或 OPTIONS 时为 GET）
+func assignMethod(spec *requestSpec, n int64, defaultMethod string) *requestSpec {
+	var total float64
+	for _, m := range methodMix {
+		total += m.Weight
+	}
+	pick := requestRand(n, randMethod).Float64() * total
+	chosen := methodMix[len(methodMix)-1].Method
+	for _, m := range methodMix {
+		if pick < m.Weight {
+			chosen = m.Method
+			break
+		}
+		pick -= m.Weight
+	}
+	original := spec.Method
+	if original == "" {
+		original = defaultMethod
+	}
+	if chosen == original {
+		return spec
+	}
+	assigned := *spec
+	assigned.Method = chosen
+	switch chosen {
+	case http.MethodHead:
+		assigned.Body = ""
+	case http.MethodOptions:
+		assigned.Body = ""
+		if original == http.MethodHead || original == http.MethodOptions {
+			original = http.MethodGet
+		}
+		assigned.Headers = spec.Headers.Clone()
+		if assigned.Headers == nil {
+			assigned.Headers = http.Header{}
+		}
+		assigned.Headers.Set("Origin", preflightOrigin)
+		assigned.Headers.Set("Access-Control-Request-Method", original)
+		assigned.Headers.Set("Access-Control-Request-Headers", "content-type")
+	}
+	return &assigned
+}
+
+// describeMethodMix 返回启动时输出的方法占比，如 GET 90%, HEAD 5%, OPTIONS 5%
+func describeMethodMix() string {
+	var total float64
+	for _, m := range methodMix {
+		total += m.Weight
+	}
+	parts := make([]string, 0, len(methodMix))
+	for _, m := range methodMix {
+		parts = append(parts, fmt.Sprintf("%s %.4g%%", m.Method, m.Weight/total*100))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// notifyClient 用于发送完成通知，与压测使用的 client 分开，避免影响连接池统计
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// notification 为 -notify-webhook 发送的 JSON 内容
+type notification struct {
+	// Status 为 completed 或 aborted（收到中断信号提前结束）
+	Status     string            `json:"status"`
+	Target     string            `json:"target"`
+	Method     string            `json:"method"`
+	Passed     bool              `json:"passed"`
+	Summary    statsSummary      `json:"summary"`
+	Thresholds []thresholdResult `json:"thresholds,omitempty"`
+}
+
+// sendNotifications 把最终汇总 POST 到通用 webhook 和/或 Slack incoming webhook，失败只打印错误
+func sendNotifications(webhookURL, slackURL string, n notification) {
+	if webhookURL != "" {
+		if err := postJSON(webhookURL, n); err != nil {
+			slog.Error("Unable to notify webhook", "err", err)
+		} else {
+			fmt.Println("📣  Notification sent to webhook")
+		}
+	}
+	if slackURL != "" {
+		if err := postJSON(slackURL, map[string]string{"text": slackMessage(n)}); err != nil {
+			slog.Error("Unable to notify Slack", "err", err)
+		} else {
+			fmt.Println("📣  Notification sent to Slack")
+		}
+	}
+}
+
+func postJSON(target string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := notifyClient.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// slackMessage 生成 Slack mrkdwn 格式的消息
+func slackMessage(n notification) string {
+	var b strings.Builder
+	icon := "✅"
+	if !n.Passed {
+		icon = "❌"
+	}
+	if n.Status == "aborted" {
+		icon = "⚠️"
+	}
+	s := n.Summary
+	fmt.Fprintf(&b, "%s *Load test %s* `%s %s`\n", icon, n.Status, n.Method, n.Target)
+	if len(s.Tags) > 0 {
+		fmt.Fprintf(&b, "• Tags: `%s`\n", formatTags(s.Tags))
+	}
+	fmt.Fprintf(&b, "• Requests: %d in %.1fs (%.2f req/s)\n", s.TotalRequests, s.ElapsedSeconds, s.QPS)
+	fmt.Fprintf(&b, "• Latency: P50 %.0f ms, P95 %.0f ms, P99 %.0f ms\n", s.P50Ms, s.P95Ms, s.P99Ms)
+	fmt.Fprintf(&b, "• Errors: %d (%.2f%%)\n", s.FailedRequests, errorRate(s))
+	if s.Apdex != nil {
+		fmt.Fprintf(&b, "• Apdex: %.2f\n", s.Apdex.Score)
+	}
+	for _, r := range n.Thresholds {
+		result := "✅"
+		if !r.Passed {
+			result = "❌"
+		}
+		fmt.Fprintf(&b, "%s `%s` (actual %.2f)\n", result, r.Threshold, r.Actual)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
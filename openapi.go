@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openapiVariants 为每个 operation 预先生成的请求数量，用于在压测中引入取值变化
+const openapiVariants = 10
+
+// openapiMaxDepth 为根据 schema 生成数据时的最大嵌套深度，防止循环引用导致无限递归
+const openapiMaxDepth = 6
+
+// openapiMethods 为 path item 中可能出现的 HTTP 方法
+var openapiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// openapiSpec 封装解析后的 OpenAPI 3.x / Swagger 2.0 文档（YAML 与 JSON 均可）
+type openapiSpec struct {
+	doc map[string]interface{}
+}
+
+// loadOpenAPISpec 读取 OpenAPI 文档，为 operations 中列出的 operationId（为空时表示全部）生成请求并追加到 requestPool，
+// 返回生成请求的 operation 数量
+func loadOpenAPISpec(filename string, operations []string, defaultURL string) int {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		slog.Error("Unable to read OpenAPI spec", "err", err)
+		return 0
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		slog.Error("Unable to parse OpenAPI spec", "err", err)
+		return 0
+	}
+	spec := &openapiSpec{doc: doc}
+
+	wanted := make(map[string]bool)
+	for _, op := range operations {
+		if op = strings.TrimSpace(op); op != "" {
+			wanted[op] = true
+		}
+	}
+
+	baseURL := spec.baseURL(defaultURL)
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathNames := make([]string, 0, len(paths))
+	for p := range paths {
+		pathNames = append(pathNames, p)
+	}
+	sort.Strings(pathNames)
+
+	loaded := 0
+	found := make(map[string]bool)
+	for _, path := range pathNames {
+		item, _ := paths[path].(map[string]interface{})
+		for _, method := range openapiMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := op["operationId"].(string)
+			if name == "" {
+				name = strings.ToUpper(method) + " " + path
+			}
+			if len(wanted) > 0 && !wanted[name] {
+				continue
+			}
+			found[name] = true
+			params := append(spec.asList(item["parameters"]), spec.asList(op["parameters"])...)
+			for i := 0; i < openapiVariants; i++ {
+				req := spec.buildRequest(strings.ToUpper(method), baseURL, path, params, op)
+				req.Name = name
+				requestPool = append(requestPool, req)
+			}
+			loaded++
+		}
+	}
+	for name := range wanted {
+		if !found[name] {
+			slog.Warn(fmt.Sprintf("Operation %q not found in OpenAPI spec", name))
+		}
+	}
+	return loaded
+}
+
+// baseURL 根据 servers（3.x）或 host/basePath（2.0）确定请求前缀，相对地址基于 -url 解析
+func (s *openapiSpec) baseURL(defaultURL string) string {
+	if servers, ok := s.doc["servers"].([]interface{}); ok && len(servers) > 0 {
+		server, _ := servers[0].(map[string]interface{})
+		raw, _ := server["url"].(string)
+		// 用变量默认值替换 {variable}
+		if vars, ok := server["variables"].(map[string]interface{}); ok {
+			for name, v := range vars {
+				if def, ok := v.(map[string]interface{})["default"]; ok {
+					raw = strings.ReplaceAll(raw, "{"+name+"}", fmt.Sprint(def))
+				}
+			}
+		}
+		if strings.Contains(raw, "://") {
+			return strings.TrimRight(raw, "/")
+		}
+		return strings.TrimRight(defaultURL, "/") + "/" + strings.Trim(raw, "/")
+	}
+	if host, ok := s.doc["host"].(string); ok {
+		scheme := "http"
+		if schemes, ok := s.doc["schemes"].([]interface{}); ok && len(schemes) > 0 {
+			scheme = fmt.Sprint(schemes[0])
+		}
+		basePath, _ := s.doc["basePath"].(string)
+		return scheme + "://" + host + strings.TrimRight(basePath, "/")
+	}
+	if basePath, ok := s.doc["basePath"].(string); ok {
+		return strings.TrimRight(defaultURL, "/") + strings.TrimRight(basePath, "/")
+	}
+	return strings.TrimRight(defaultURL, "/")
+}
+
+// buildRequest 为一个 operation 生成一个请求：填充 path/query/header 参数并按 schema 生成 body
+func (s *openapiSpec) buildRequest(method, baseURL, path string, params []interface{}, op map[string]interface{}) *requestSpec {
+	spec := &requestSpec{Method: method, Headers: http.Header{}}
+	query := url.Values{}
+	form := url.Values{}
+	var body interface{}
+	hasBody := false
+
+	for _, p := range params {
+		param := s.resolve(p)
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		required, _ := param["required"].(bool)
+		value, hasExample := param["example"]
+		if !hasExample {
+			schema := s.resolve(param["schema"])
+			if len(schema) == 0 {
+				// Swagger 2.0 的非 body 参数直接在参数上声明 type/format
+				schema = param
+			}
+			value = s.generate(schema, 0)
+		}
+		switch in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(fmt.Sprint(value)))
+		case "query":
+			if required || hasExample {
+				query.Set(name, fmt.Sprint(value))
+			}
+		case "header":
+			if required || hasExample {
+				spec.Headers.Set(name, fmt.Sprint(value))
+			}
+		case "body":
+			body = s.generate(s.resolve(param["schema"]), 0)
+			hasBody = true
+			spec.Headers.Set("Content-Type", "application/json")
+		case "formData":
+			if required || hasExample {
+				form.Set(name, fmt.Sprint(value))
+			}
+		}
+	}
+
+	if reqBody := s.resolve(op["requestBody"]); len(reqBody) > 0 {
+		content, _ := reqBody["content"].(map[string]interface{})
+		mediaType := ""
+		if _, ok := content["application/json"]; ok {
+			mediaType = "application/json"
+		} else {
+			for mt := range content {
+				if mediaType == "" || mt < mediaType {
+					mediaType = mt
+				}
+			}
+		}
+		if media, ok := content[mediaType].(map[string]interface{}); ok {
+			if example, ok := media["example"]; ok {
+				body = example
+			} else {
+				body = s.generate(s.resolve(media["schema"]), 0)
+			}
+			hasBody = true
+			spec.Headers.Set("Content-Type", mediaType)
+			if mediaType == "application/x-www-form-urlencoded" {
+				if fields, ok := body.(map[string]interface{}); ok {
+					for k, v := range fields {
+						form.Set(k, fmt.Sprint(v))
+					}
+				}
+				hasBody = false
+			}
+		}
+	}
+
+	if len(form) > 0 {
+		spec.Body = form.Encode()
+		spec.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else if hasBody {
+		if text, ok := body.(string); ok && !strings.Contains(spec.Headers.Get("Content-Type"), "json") {
+			spec.Body = text
+		} else if encoded, err := json.Marshal(body); err == nil {
+			spec.Body = string(encoded)
+		}
+	}
+
+	spec.URL = baseURL + path
+	if len(query) > 0 {
+		spec.URL += "?" + query.Encode()
+	}
+	return spec
+}
+
+// resolve 将节点转换为 map，并解析本文档内的 $ref（#/components/... 或 #/definitions/...）
+func (s *openapiSpec) resolve(node interface{}) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+	for i := 0; i < openapiMaxDepth; i++ {
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return m
+		}
+		var cur interface{} = s.doc
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			next, _ := cur.(map[string]interface{})
+			cur = next[part]
+		}
+		m, _ = cur.(map[string]interface{})
+	}
+	return m
+}
+
+// asList 将参数列表节点转换为 []interface{}
+func (s *openapiSpec) asList(node interface{}) []interface{} {
+	list, _ := node.([]interface{})
+	return list
+}
+
+// generate 根据 schema 生成一个合法的取值：优先使用 example/default/enum，否则按 type 与 format 随机生成
+func (s *openapiSpec) generate(schema map[string]interface{}, depth int) interface{} {
+	if example, ok := schema["example"]; ok {
+		return example
+	}
+	if def, ok := schema["default"]; ok {
+		return def
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[sharedRand.Intn(len(enum))]
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		merged := make(map[string]interface{})
+		for _, sub := range all {
+			if obj, ok := s.generate(s.resolve(sub), depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if options, ok := schema[key].([]interface{}); ok && len(options) > 0 {
+			return s.generate(s.resolve(options[0]), depth+1)
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	if typ == "" {
+		if _, ok := schema["properties"]; ok {
+			typ = "object"
+		}
+	}
+	format, _ := schema["format"].(string)
+	switch typ {
+	case "object":
+		obj := make(map[string]interface{})
+		if depth >= openapiMaxDepth {
+			return obj
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, prop := range props {
+			obj[name] = s.generate(s.resolve(prop), depth+1)
+		}
+		return obj
+	case "array":
+		if depth >= openapiMaxDepth {
+			return []interface{}{}
+		}
+		n := 1 + sharedRand.Intn(3)
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i] = s.generate(s.resolve(schema["items"]), depth+1)
+		}
+		return items
+	case "integer":
+		min, max := schemaBounds(schema, 1, 1000)
+		return int64(min) + sharedRand.Int63n(int64(max-min)+1)
+	case "number":
+		min, max := schemaBounds(schema, 0, 1000)
+		return min + sharedRand.Float64()*(max-min)
+	case "boolean":
+		return sharedRand.Intn(2) == 1
+	}
+
+	switch format {
+	case "email":
+		return fmt.Sprintf("user%d@example.com", sharedRand.Intn(100000))
+	case "uuid":
+		return newUUID()
+	case "date-time":
+		return time.Now().Add(-time.Duration(sharedRand.Intn(86400*30)) * time.Second).UTC().Format(time.RFC3339)
+	case "date":
+		return time.Now().AddDate(0, 0, -sharedRand.Intn(365)).Format("2006-01-02")
+	case "uri", "url":
+		return fmt.Sprintf("https://example.com/%d", sharedRand.Intn(100000))
+	case "ipv4":
+		return fmt.Sprintf("10.%d.%d.%d", sharedRand.Intn(256), sharedRand.Intn(256), 1+sharedRand.Intn(254))
+	case "hostname":
+		return fmt.Sprintf("host%d.example.com", sharedRand.Intn(1000))
+	}
+	minLen, maxLen := 5, 12
+	if v, ok := schema["maxLength"].(int); ok && v >= 0 {
+		maxLen = v
+		if minLen > maxLen {
+			minLen = maxLen
+		}
+	}
+	if v, ok := schema["minLength"].(int); ok && v > 0 {
+		minLen = v
+		if maxLen < minLen {
+			maxLen = minLen
+		}
+	}
+	return randomWord(minLen + sharedRand.Intn(maxLen-minLen+1))
+}
+
+// schemaBounds 读取 schema 的 minimum/maximum，不存在时使用默认值
+func schemaBounds(schema map[string]interface{}, defMin, defMax float64) (float64, float64) {
+	min, max := defMin, defMax
+	if v, ok := toFloat(schema["minimum"]); ok {
+		min = v
+		if max < min {
+			max = min + defMax
+		}
+	}
+	if v, ok := toFloat(schema["maximum"]); ok {
+		max = v
+		if min > max {
+			min = max
+		}
+	}
+	return min, max
+}
+
+// toFloat 将 YAML/JSON 中的数值转换为 float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// randomWord 生成指定长度的小写字母字符串
+func randomWord(n int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[sharedRand.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// queryParams 为 -param 指定的查询参数，每个请求发送前渲染并追加到 URL
+var queryParams paramFlags
+
+// queryParam 为一个查询参数，value 可以是模板，如 {{randInt 1 100000}}
+type queryParam struct {
+	name  string
+	value string
+	tpl   *requestTemplate
+}
+
+// paramFlags 实现 flag.Value，支持多次指定 -param name=value
+type paramFlags []queryParam
+
+func (f *paramFlags) String() string {
+	parts := make([]string, len(*f))
+	for i, p := range *f {
+		parts[i] = p.name + "=" + p.value
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *paramFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	name := strings.TrimSpace(kv[0])
+	if len(kv) != 2 || name == "" {
+		return fmt.Errorf("invalid param %q, expected name=value", value)
+	}
+	tpl, err := compileTemplate(kv[1])
+	if err != nil {
+		return fmt.Errorf("invalid param template %q: %v", kv[1], err)
+	}
+	*f = append(*f, queryParam{name: name, value: kv[1], tpl: tpl})
+	return nil
+}
+
+// render 用数据行为第 n 个请求渲染所有查询参数，未指定 -param 时返回 nil
+func (f paramFlags) render(row map[string]string, n int64) url.Values {
+	if len(f) == 0 {
+		return nil
+	}
+	values := make(url.Values, len(f))
+	for _, p := range f {
+		values.Add(p.name, renderTemplate(p.tpl, p.value, row, n))
+	}
+	return values
+}
+
+// appendQuery 把 values 追加到 u 已有的查询参数之后，已有参数的顺序保持不变
+func appendQuery(u *url.URL, values url.Values) {
+	if len(values) == 0 {
+		return
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += values.Encode()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"unicode"
+)
+
+// plainOutput 为 -plain（或设置了 NO_COLOR 环境变量）：stdout 与 stderr 只输出 ASCII——emoji 换成 [OK]、[X] 等标记或去掉，
+// 制表符与方块字符换成 -、|、+、#，不画趋势图，进度条改为每 plainProgressInterval 一行的文字，便于 CI 控制台与工单系统
+var plainOutput bool
+
+// plainFilter 为一个被过滤的输出：原文件被换成管道的写端，由 goroutine 转换后写到原文件
+type plainFilter struct {
+	w    *os.File
+	done chan struct{}
+}
+
+var (
+	plainFilters []*plainFilter
+	// plainStdout 为过滤前的 stdout，-format 的汇总写到这里，保证 JSON 等机器可读的输出原样不变
+	plainStdout *os.File
+	plainOnce   sync.Once
+	exitOnce    sync.Once
+)
+
+// plainMarkers 为有含义的 emoji 对应的 ASCII 标记，其余 emoji 直接去掉
+var plainMarkers = map[rune]string{
+	'✅': "[OK]", '❌': "[X]", '⚠': "[!]", '🚨': "[!]", '🛑': "[STOP]", '✔': "[OK]", '✖': "[X]",
+}
+
+// plainRunes 为常见符号对应的 ASCII
+var plainRunes = map[rune]string{
+	'→': "->", '←': "<-", '↑': "^", '↓': "v", '…': "...", '×': "x", 'µ': "u", '≤': "<=", '≥': ">=",
+	'±': "+/-", '•': "*", '·': ".", 'Δ': "delta", '░': ".", '▒': ":", '▓': "#", '—': "-", '–': "-",
+}
+
+// enablePlainOutput 开始过滤 stdout 与 stderr，只在第一次调用时生效
+func enablePlainOutput() {
+	plainOnce.Do(func() {
+		plainOutput = true
+		plainStdout = os.Stdout
+		os.Stdout = newPlainFilter(os.Stdout)
+		os.Stderr = newPlainFilter(os.Stderr)
+	})
+}
+
+// newPlainFilter 返回替代 out 的管道写端；管道创建失败时原样返回 out
+func newPlainFilter(out *os.File) *os.File {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return out
+	}
+	f := &plainFilter{w: w, done: make(chan struct{})}
+	plainFilters = append(plainFilters, f)
+	go func() {
+		defer close(f.done)
+		copyPlain(out, r)
+	}()
+	return w
+}
+
+// copyPlain 把 r 中的文本转为 ASCII 写到 out；读缓冲区取空时立即写出，输出不会滞后
+func copyPlain(out io.Writer, r io.Reader) {
+	in := bufio.NewReader(r)
+	buf := bufio.NewWriter(out)
+	// pending 为去掉 emoji 后其后的空格一并去掉（plainDropSpaces），或换成标记后只保留一个空格（plainOneSpace）
+	pending := plainNoSpace
+	for {
+		c, _, err := in.ReadRune()
+		if err != nil {
+			buf.Flush()
+			return
+		}
+		if c == ' ' && pending != plainNoSpace {
+			continue
+		}
+		switch marker := plainMarkers[c]; {
+		case marker != "":
+			buf.WriteString(marker)
+			pending = plainOneSpace
+		case plainEmoji(c):
+			if pending == plainNoSpace {
+				pending = plainDropSpaces
+			}
+		default:
+			if pending == plainOneSpace && c != '\n' && c != '\r' {
+				buf.WriteByte(' ')
+			}
+			pending = plainNoSpace
+			buf.WriteString(plainRune(c))
+		}
+		if in.Buffered() == 0 {
+			buf.Flush()
+		}
+	}
+}
+
+// copyPlain 中 emoji 之后的空格的处理方式
+const (
+	plainNoSpace = iota
+	plainDropSpaces
+	plainOneSpace
+)
+
+// plainEmoji 判断 c 是否为要去掉的 emoji（含变体选择符与连接符）；制表符、方块等有 ASCII 替代的符号除外
+func plainEmoji(c rune) bool {
+	if c <= unicode.MaxASCII || plainRunes[c] != "" || (c >= 0x2500 && c <= 0x259f) || (c >= 0x2800 && c <= 0x28ff) {
+		return false
+	}
+	return c == 0xfe0f || c == 0x200d || unicode.Is(unicode.So, c) || (c >= 0x1f000 && c <= 0x1faff)
+}
+
+// plainRune 返回 c 对应的 ASCII：制表符换成 -、| 或 +，方块换成 #，盲文（spinner）换成 *，无法转换的换成 ?
+func plainRune(c rune) string {
+	switch {
+	case c <= unicode.MaxASCII:
+		return string(c)
+	case plainRunes[c] != "":
+		return plainRunes[c]
+	case c >= 0x2500 && c <= 0x257f:
+		return string(boxRune(c))
+	case c >= 0x2580 && c <= 0x259f:
+		return "#"
+	case c >= 0x2800 && c <= 0x28ff:
+		return "*"
+	}
+	return "?"
+}
+
+// boxRune 把制表符换成水平线 -、竖线 | 或交点 +
+func boxRune(c rune) byte {
+	switch c {
+	case '─', '━', '┄', '┅', '┈', '┉', '╌', '╍', '═', '╴', '╶', '╸', '╺':
+		return '-'
+	case '│', '┃', '┆', '┇', '┊', '┋', '╎', '╏', '║', '╵', '╷', '╹', '╻':
+		return '|'
+	}
+	return '+'
+}
+
+// exit 写出被过滤的输出后以 code 退出；程序中所有的退出都经由它，避免 -plain 下丢失最后的输出
+func exit(code int) {
+	flushOutput()
+	os.Exit(code)
+}
+
+// flushOutput 关闭过滤用的管道并等待转换完成，只在第一次调用时生效；main 返回前也会调用
+func flushOutput() {
+	exitOnce.Do(func() {
+		for _, f := range plainFilters {
+			f.w.Close()
+			<-f.done
+		}
+	})
+}
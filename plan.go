@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// validateMode 为 validate 子命令：照常解析参数、加载并校验所有文件与模板，输出实际的压测计划后退出，不发送任何请求
+var validateMode bool
+
+// validateArgs 把 validate 子命令的参数转为普通的命令行参数：第一个参数不是选项时视为 -scenarios 文件
+func validateArgs(args []string) []string {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return append([]string{"-scenarios", args[0]}, args[1:]...)
+	}
+	return args
+}
+
+// planInputFlags、planOutputFlags 为引用输入文件与输出文件的选项；validate 检查输入文件存在、输出文件所在目录存在
+var (
+	planInputFlags = []string{"bodyfile", "datafile", "scenarios", "har", "curl-file", "postman", "postman-env", "openapi", "replay",
+		"baseline", "response-schema", "proto-file", "xml-body", "user-agents", "connection-policy", "resume"}
+	planOutputFlags = []string{"request-log", "trend-export", "heatmap-html", "grafana-export", "interval-stream", "interval-log", "checkpoint-file"}
+)
+
+// checkPlanFiles 返回显式指定的选项中不存在的输入文件与无法写入的输出位置
+func checkPlanFiles() []string {
+	inputs := make(map[string]bool)
+	for _, name := range planInputFlags {
+		inputs[name] = true
+	}
+	outputs := make(map[string]bool)
+	for _, name := range planOutputFlags {
+		outputs[name] = true
+	}
+	var problems []string
+	flag.Visit(func(f *flag.Flag) {
+		path := f.Value.String()
+		if path == "" {
+			return
+		}
+		switch {
+		case inputs[f.Name]:
+			if info, err := os.Stat(path); err != nil {
+				problems = append(problems, fmt.Sprintf("-%s: %v", f.Name, err))
+			} else if info.IsDir() {
+				problems = append(problems, fmt.Sprintf("-%s: %s is a directory", f.Name, path))
+			}
+		case outputs[f.Name]:
+			if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+				problems = append(problems, fmt.Sprintf("-%s: directory %s does not exist", f.Name, filepath.Dir(path)))
+			}
+		}
+	})
+	return problems
+}
+
+// planStage 为一段速率不变（取整后）的时间
+type planStage struct {
+	from, to time.Duration
+	rate     float64
+}
+
+// maxPlanStages 为输出的阶段数上限，maxPlanDuration 为推算的时长上限
+const (
+	maxPlanStages   = 20
+	maxPlanDuration = 24 * time.Hour
+)
+
+// planStages 按秒推算 -rate 与 -pattern 下的速率变化，合并取整后相同的相邻秒；
+// soak 大于 0 时推算到 soak，否则推算到发出 total 个请求，返回各阶段与预计时长
+func planStages(rate float64, patterns []ratePattern, total int, soak time.Duration) ([]planStage, time.Duration) {
+	var stages []planStage
+	var sent float64
+	elapsed := time.Duration(0)
+	for elapsed < maxPlanDuration {
+		if soak > 0 && elapsed >= soak {
+			break
+		}
+		if soak <= 0 && sent >= float64(total) {
+			break
+		}
+		r := rate
+		for _, p := range patterns {
+			r *= p.factor(elapsed)
+		}
+		next := elapsed + time.Second
+		if soak <= 0 && r > 0 && sent+r > float64(total) {
+			// 最后一秒只需发出剩余的请求
+			next = elapsed + time.Duration((float64(total)-sent)/r*float64(time.Second))
+		}
+		if n := len(stages); n > 0 && math.Round(stages[n-1].rate) == math.Round(r) {
+			stages[n-1].to = next
+		} else {
+			stages = append(stages, planStage{from: elapsed, to: next, rate: r})
+		}
+		sent += r
+		elapsed = next
+	}
+	return stages, elapsed
+}
+
+// planShares 把累计权重转为每个请求被选中的比例，cumulative 为空时均匀选择
+func planShares(cumulative []float64, n int) []float64 {
+	shares := make([]float64, n)
+	for i := range shares {
+		switch {
+		case len(cumulative) == 0:
+			shares[i] = 1 / float64(n)
+		case i == 0:
+			shares[i] = cumulative[0] / cumulative[n-1]
+		default:
+			shares[i] = (cumulative[i] - cumulative[i-1]) / cumulative[n-1]
+		}
+	}
+	return shares
+}
+
+// reportRequestMix 输出一组请求的方法、URL（或名称）与所占比例；ordered 为 true 时按顺序执行，不显示比例
+func reportRequestMix(title string, specs []*requestSpec, cumulative []float64, defaultURL, defaultMethod string, ordered bool) {
+	if len(specs) == 0 {
+		return
+	}
+	fmt.Printf("\n%s\n", title)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "Method", "URL / Name", "Share"})
+	shares := planShares(cumulative, len(specs))
+	for i, spec := range specs {
+		method, target := spec.Method, spec.URL
+		if method == "" {
+			method = defaultMethod
+		}
+		if spec.URLTemplate != "" {
+			target = spec.URLTemplate
+		}
+		if target == "" {
+			target = defaultURL
+		}
+		if spec.Name != "" {
+			target = spec.Name + " (" + target + ")"
+		}
+		share := fmt.Sprintf("%.1f%%", shares[i]*100)
+		if ordered {
+			share = "in order"
+		}
+		table.Append([]string{fmt.Sprintf("%d", i+1), method, target, share})
+	}
+	table.Render()
+}
+
+// reportPlan 输出 validate 得到的压测计划：负载模型、速率阶段与请求组合
+func reportPlan(defaultURL, defaultMethod string, concurrency, totalRequests int, rate float64, patterns []ratePattern, soak time.Duration) {
+	fmt.Println("\n🧪  Test Plan (validated, nothing was sent):")
+	load := fmt.Sprintf("%d requests", totalRequests)
+	if soak > 0 {
+		load = "for " + soak.String()
+	}
+	if rate > 0 {
+		fmt.Printf("  - Open model: %.2f req/s base rate, %s, up to %d workers\n", rate, load, concurrency)
+		stages, duration := planStages(rate, patterns, totalRequests, soak)
+		if soak <= 0 {
+			fmt.Printf("  - Estimated duration: %s\n", duration.Round(time.Second))
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Stage", "From", "To", "Rate"})
+		for i, s := range stages {
+			if i == maxPlanStages {
+				table.Append([]string{"...", s.from.Round(time.Second).String(), duration.Round(time.Second).String(), fmt.Sprintf("%d more stages", len(stages)-i)})
+				break
+			}
+			table.Append([]string{fmt.Sprintf("%d", i+1), s.from.Round(time.Second).String(), s.to.Round(time.Second).String(), fmt.Sprintf("%.1f req/s", s.rate)})
+		}
+		table.Render()
+	} else if scenarios == nil {
+		fmt.Printf("  - Closed model: %d workers, %s\n", concurrency, load)
+	}
+	if scenarios != nil {
+		for _, sc := range scenarios {
+			reportRequestMix(fmt.Sprintf("🎭  Scenario %s:", sc.Name), sc.specs, sc.weights, defaultURL, defaultMethod, sc.Journey)
+		}
+	} else if len(requestPool) > 0 {
+		reportRequestMix("🧩  Request Mix:", requestPool, requestWeights, defaultURL, defaultMethod, false)
+	} else {
+		fmt.Printf("  - Request: %s %s\n", defaultMethod, defaultURL)
+	}
+	fmt.Println("\n✅  Plan is valid")
+}
+
+// validatePlanFiles 在 validate 模式下检查文件，有问题时逐条输出并退出
+func validatePlanFiles() {
+	problems := checkPlanFiles()
+	if len(problems) == 0 {
+		return
+	}
+	sort.Strings(problems)
+	for _, p := range problems {
+		slog.Error(p)
+	}
+	exit(1)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+// connectionPolicies 为 -connection-policy 加载的按目标区分的连接策略，按 Match 从长到短排列
+var connectionPolicies []*connectionPolicy
+
+// connectionPolicy 为 URL 以 Match 开头的请求使用的连接参数，未设置的项沿用全局配置；
+// 每个策略有自己的连接池，不同目标的连接互不影响
+type connectionPolicy struct {
+	Match string `yaml:"match"`
+	// KeepAliveRatio 为 nil 时使用 -keepalive_ratio
+	KeepAliveRatio      *float64      `yaml:"keepalive_ratio"`
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+	IdleTimeout         time.Duration `yaml:"idle_timeout"`
+	Timeout             time.Duration `yaml:"timeout"`
+	DialTimeout         time.Duration `yaml:"dial_timeout"`
+	HeaderTimeout       time.Duration `yaml:"response_header_timeout"`
+
+	keepAlive   *http.Client
+	noKeepAlive *http.Client
+}
+
+// loadConnectionPolicies 读取 YAML（或 JSON）格式的策略列表，并为每个策略创建客户端
+func loadConnectionPolicies(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("unable to read connection policy: %v", err)
+	}
+	var policies []*connectionPolicy
+	if err := yaml.Unmarshal(data, &policies); err != nil {
+		return fmt.Errorf("unable to parse connection policy: %v", err)
+	}
+	for i, p := range policies {
+		if p.Match == "" {
+			return fmt.Errorf("connection policy %d: match is required", i+1)
+		}
+		if p.KeepAliveRatio != nil && (*p.KeepAliveRatio < 0 || *p.KeepAliveRatio > 1) {
+			return fmt.Errorf("connection policy %s: keepalive_ratio must be between 0 and 1", p.Match)
+		}
+		p.keepAlive = p.newClient(clientKeepAlive)
+		p.noKeepAlive = p.newClient(clientNoKeepAlive)
+	}
+	// 最长前缀优先，使 https://api/v2/ 的策略先于 https://api/ 匹配
+	sort.SliceStable(policies, func(i, j int) bool { return len(policies[i].Match) > len(policies[j].Match) })
+	connectionPolicies = policies
+	return nil
+}
+
+// newClient 以全局客户端为基础，按策略覆盖连接池与超时参数
+func (p *connectionPolicy) newClient(base *http.Client) *http.Client {
+	transport := base.Transport.(*http.Transport).Clone()
+	if p.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = p.MaxConnsPerHost
+	}
+	if p.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = p.MaxIdleConnsPerHost
+		if transport.MaxIdleConns < p.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = p.MaxIdleConnsPerHost
+		}
+	}
+	if p.IdleTimeout > 0 {
+		transport.IdleConnTimeout = p.IdleTimeout
+	}
+	if p.DialTimeout > 0 {
+		transport.DialContext = wanDial(newDialer(p.DialTimeout))
+	}
+	if p.HeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = p.HeaderTimeout
+	}
+	client := &http.Client{Transport: transport, Timeout: base.Timeout}
+	if p.Timeout > 0 {
+		client.Timeout = p.Timeout
+	}
+	return client
+}
+
+// policyFor 返回请求适用的连接策略，没有匹配时返回 nil
+func policyFor(spec *requestSpec, defaultURL string) *connectionPolicy {
+	if len(connectionPolicies) == 0 {
+		return nil
+	}
+	target := spec.URL
+	if target == "" {
+		target = defaultURL
+	}
+	for _, p := range connectionPolicies {
+		if strings.HasPrefix(target, p.Match) {
+			return p
+		}
+	}
+	return nil
+}
+
+// keepAliveRatio 返回策略的 Keep-Alive 比例，未设置时为 fallback
+func (p *connectionPolicy) keepAliveRatio(fallback float64) float64 {
+	if p.KeepAliveRatio == nil {
+		return fallback
+	}
+	return *p.KeepAliveRatio
+}
+
+// reportConnectionPolicies 在开始前输出各策略的生效参数
+func reportConnectionPolicies(keepAliveRatio float64) {
+	if len(connectionPolicies) == 0 {
+		return
+	}
+	orDefault := func(set bool, value string) string {
+		if !set {
+			return "-"
+		}
+		return value
+	}
+	fmt.Println("🔌  Connection Policies:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Match", "Keep-Alive", "Max Conns/Host", "Max Idle/Host", "Idle Timeout", "Timeout", "Dial Timeout", "Header Timeout"})
+	for _, p := range connectionPolicies {
+		table.Append([]string{
+			p.Match,
+			fmt.Sprintf("%.2f", p.keepAliveRatio(keepAliveRatio)),
+			orDefault(p.MaxConnsPerHost > 0, fmt.Sprintf("%d", p.MaxConnsPerHost)),
+			orDefault(p.MaxIdleConnsPerHost > 0, fmt.Sprintf("%d", p.MaxIdleConnsPerHost)),
+			orDefault(p.IdleTimeout > 0, p.IdleTimeout.String()),
+			p.keepAlive.Timeout.String(),
+			orDefault(p.DialTimeout > 0, p.DialTimeout.String()),
+			orDefault(p.HeaderTimeout > 0, p.HeaderTimeout.String()),
+		})
+	}
+	table.Render()
+}
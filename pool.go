@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// 请求体 reader 与请求头的复用。只有 fasthttp 引擎在 RoundTrip 返回前同步写完请求，Do 返回后即可回收；
+// net/http 的 Transport 在返回响应后仍可能在后台写请求体，因此不回收，池中取不到时照常分配
+var (
+	bodyReaders = sync.Pool{New: func() any { return new(pooledBody) }}
+	headerMaps  = sync.Pool{New: func() any { return make(http.Header, 8) }}
+)
+
+// pooledBody 为可复用的请求体
+type pooledBody struct {
+	strings.Reader
+}
+
+func (*pooledBody) Close() error { return nil }
+
+// setPooledBody 把 payload 设为 req 的请求体，效果与 http.NewRequest 传入 strings.Reader 相同
+func setPooledBody(req *http.Request, payload string) {
+	body := bodyReaders.Get().(*pooledBody)
+	body.Reset(payload)
+	req.Body = body
+	req.ContentLength = int64(len(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(payload)), nil
+	}
+}
+
+// pooledHeader 返回一个空的请求头
+func pooledHeader() http.Header {
+	return headerMaps.Get().(http.Header)
+}
+
+// releaseRequest 在请求结束后回收 newHTTPRequest 分配的请求体与请求头；client 不是 fasthttp 引擎时什么也不做
+func releaseRequest(client *http.Client, req *http.Request) {
+	if _, ok := client.Transport.(*fasthttpTransport); !ok {
+		return
+	}
+	if body, ok := req.Body.(*pooledBody); ok {
+		body.Reset("")
+		bodyReaders.Put(body)
+	}
+	clear(req.Header)
+	headerMaps.Put(req.Header)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// postmanCollection 为 Postman v2.x 集合格式中回放所需的部分
+type postmanCollection struct {
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+// postmanItem 可以是一个请求，也可以是包含子项的文件夹
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	Header []postmanKV     `json:"header"`
+	URL    json.RawMessage `json:"url"`
+	Auth   *postmanAuth    `json:"auth"`
+	Body   *struct {
+		Mode       string      `json:"mode"`
+		Raw        string      `json:"raw"`
+		URLEncoded []postmanKV `json:"urlencoded"`
+		Options    struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+}
+
+type postmanKV struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+type postmanVariable struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Enabled *bool  `json:"enabled"`
+}
+
+type postmanAuth struct {
+	Type   string      `json:"type"`
+	Bearer []postmanKV `json:"bearer"`
+	Basic  []postmanKV `json:"basic"`
+}
+
+// postmanVarPattern 匹配 Postman 的 {{variable}} 占位符
+var postmanVarPattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// postmanDynamicVars 将 Postman 的动态变量翻译为同义的模板函数，每个请求重新生成
+var postmanDynamicVars = map[string]string{
+	"$guid":              "{{uuid}}",
+	"$randomUUID":        "{{uuid}}",
+	"$timestamp":         "{{now_unix}}",
+	"$randomInt":         "{{randInt 0 1000}}",
+	"$randomBoolean":     "{{fake.Bool}}",
+	"$randomFirstName":   "{{fake.FirstName}}",
+	"$randomLastName":    "{{fake.LastName}}",
+	"$randomFullName":    "{{fake.Name}}",
+	"$randomUserName":    "{{fake.Username}}",
+	"$randomEmail":       "{{fake.Email}}",
+	"$randomPhoneNumber": "{{fake.Phone}}",
+	"$randomCompanyName": "{{fake.Company}}",
+	"$randomCity":        "{{fake.City}}",
+	"$randomCountry":     "{{fake.Country}}",
+	"$randomIP":          "{{fake.IPv4}}",
+	"$randomIPV6":        "{{fake.IPv6}}",
+	"$randomMACAddress":  "{{fake.MAC}}",
+	"$randomUrl":         "{{fake.URL}}",
+	"$randomPrice":       "{{fake.Price}}",
+	"$randomColor":       "{{fake.Color}}",
+	"$randomWord":        "{{fake.Word}}",
+}
+
+// postmanVars 为集合与环境文件中的变量，并记录替换时遇到的未定义变量
+type postmanVars struct {
+	values     map[string]string
+	unresolved map[string]bool
+}
+
+// template 替换文本中的变量，结果按模板发送：动态变量翻译为模板函数，
+// 未定义的变量转义为原文 {{name}}，避免被当作模板解析
+func (v *postmanVars) template(s string) string {
+	return v.replace(s, func(name, m string) string {
+		if action, ok := postmanDynamicVars[name]; ok {
+			return action
+		}
+		v.unresolved[name] = true
+		return `{{"{{"}}` + m[2:]
+	})
+}
+
+// text 替换文本中的变量，结果还会被编码（Basic 认证、表单），不能按模板渲染，未定义的变量与动态变量均保留原文
+func (v *postmanVars) text(s string) string {
+	return v.replace(s, func(name, m string) string {
+		v.unresolved[name] = true
+		return m
+	})
+}
+
+func (v *postmanVars) replace(s string, missing func(name, m string) string) string {
+	return postmanVarPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := postmanVarPattern.FindStringSubmatch(m)[1]
+		if value, ok := v.values[name]; ok {
+			return value
+		}
+		return missing(name, m)
+	})
+}
+
+// loadPostmanCollection 读取 Postman 集合（以及可选的环境文件），展开文件夹后把每个请求追加到 requestPool，
+// 变量优先级为 环境文件 > 集合变量，未定义的变量按原文发送并给出警告；权重按请求名称从 weights 中查找，返回加载的请求数
+func loadPostmanCollection(filename, envFile string, weights map[string]float64) int {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		slog.Error("Unable to read Postman collection", "err", err)
+		return 0
+	}
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		slog.Error("Unable to parse Postman collection", "err", err)
+		return 0
+	}
+
+	vars := &postmanVars{values: make(map[string]string), unresolved: make(map[string]bool)}
+	for _, v := range collection.Variable {
+		if v.Enabled == nil || *v.Enabled {
+			vars.values[v.Key] = v.Value
+		}
+	}
+	if envFile != "" {
+		envData, err := ioutil.ReadFile(envFile)
+		if err != nil {
+			slog.Error("Unable to read Postman environment", "err", err)
+			return 0
+		}
+		var env struct {
+			Values []postmanVariable `json:"values"`
+		}
+		if err := json.Unmarshal(envData, &env); err != nil {
+			slog.Error("Unable to parse Postman environment", "err", err)
+			return 0
+		}
+		for _, v := range env.Values {
+			if v.Enabled == nil || *v.Enabled {
+				vars.values[v.Key] = v.Value
+			}
+		}
+	}
+
+	loaded := 0
+	var walk func(items []postmanItem, prefix string, auth *postmanAuth)
+	walk = func(items []postmanItem, prefix string, auth *postmanAuth) {
+		for _, item := range items {
+			itemAuth := auth
+			if item.Auth != nil {
+				itemAuth = item.Auth
+			}
+			name := item.Name
+			if prefix != "" {
+				name = prefix + "/" + item.Name
+			}
+			if item.Request == nil {
+				walk(item.Item, name, itemAuth)
+				continue
+			}
+			spec := postmanRequestSpec(item.Request, itemAuth, vars)
+			spec.Name = name
+			// 未配置权重的请求按权重 1 参与混合
+			spec.Weight = 1
+			if w, ok := weights[item.Name]; ok {
+				spec.Weight = w
+			}
+			if w, ok := weights[name]; ok {
+				spec.Weight = w
+			}
+			requestPool = append(requestPool, spec)
+			loaded++
+		}
+	}
+	walk(collection.Item, "", collection.Auth)
+	if len(vars.unresolved) > 0 {
+		names := make([]string, 0, len(vars.unresolved))
+		for name := range vars.unresolved {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		slog.Warn("Undefined Postman variables are sent as literal {{name}} text", "variables", strings.Join(names, ", "))
+	}
+	return loaded
+}
+
+// postmanRequestSpec 将单个 Postman 请求转换为 requestSpec，并完成变量替换
+func postmanRequestSpec(r *postmanRequest, auth *postmanAuth, vars *postmanVars) *requestSpec {
+	spec := &requestSpec{
+		Method:  strings.ToUpper(r.Method),
+		Headers: http.Header{},
+	}
+	if spec.Method == "" {
+		spec.Method = http.MethodGet
+	}
+
+	// url 既可以是字符串，也可以是带 raw 字段的对象
+	var rawURL string
+	if err := json.Unmarshal(r.URL, &rawURL); err != nil {
+		var u struct {
+			Raw string `json:"raw"`
+		}
+		json.Unmarshal(r.URL, &u)
+		rawURL = u.Raw
+	}
+	spec.URL = vars.template(rawURL)
+	if !strings.Contains(spec.URL, "://") {
+		spec.URL = "http://" + spec.URL
+	}
+
+	for _, h := range r.Header {
+		if !h.Disabled {
+			spec.Headers.Add(h.Key, vars.template(h.Value))
+		}
+	}
+
+	if r.Auth != nil {
+		auth = r.Auth
+	}
+	if auth != nil {
+		switch auth.Type {
+		case "bearer":
+			for _, kv := range auth.Bearer {
+				if kv.Key == "token" {
+					spec.Headers.Set("Authorization", "Bearer "+vars.template(kv.Value))
+				}
+			}
+		case "basic":
+			var user, pass string
+			for _, kv := range auth.Basic {
+				switch kv.Key {
+				case "username":
+					user = vars.text(kv.Value)
+				case "password":
+					pass = vars.text(kv.Value)
+				}
+			}
+			spec.Headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+pass)))
+		}
+	}
+
+	if r.Body != nil {
+		switch r.Body.Mode {
+		case "raw":
+			spec.Body = vars.template(r.Body.Raw)
+			if spec.Headers.Get("Content-Type") == "" && r.Body.Options.Raw.Language == "xml" {
+				spec.Headers.Set("Content-Type", "application/xml")
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, kv := range r.Body.URLEncoded {
+				if !kv.Disabled {
+					form.Add(kv.Key, vars.text(kv.Value))
+				}
+			}
+			spec.Body = form.Encode()
+			if spec.Headers.Get("Content-Type") == "" {
+				spec.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+	}
+	return spec
+}
+
+// parseWeights 解析 "name=weight,name2=weight2" 形式的权重配置
+func parseWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	if s == "" {
+		return weights, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid weight %q, expected name=weight", part)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q", part)
+		}
+		weights[strings.TrimSpace(name)] = w
+	}
+	return weights, nil
+}
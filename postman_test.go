@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestPostmanVariables(t *testing.T) {
+	collection := `{
+  "variable": [{"key": "host", "value": "api.example.com"}],
+  "item": [{
+    "name": "Create order",
+    "request": {
+      "method": "POST",
+      "url": "https://{{host}}/orders?tenant={{tenant}}",
+      "header": [{"key": "X-Request-Id", "value": "{{$guid}}"}],
+      "auth": {"type": "basic", "basic": [{"key": "username", "value": "{{$randomUserName}}"}, {"key": "password", "value": "{{password}}"}]},
+      "body": {"mode": "raw", "raw": "{\"ts\":{{$timestamp}},\"note\":\"{{note}}\",\"qty\":{{$randomInt}}}"}
+    }
+  }]
+}`
+	file := filepath.Join(t.TempDir(), "collection.json")
+	if err := os.WriteFile(file, []byte(collection), 0644); err != nil {
+		t.Fatal(err)
+	}
+	savedPool := requestPool
+	defer func() { requestPool = savedPool }()
+	requestPool = nil
+	if loaded := loadPostmanCollection(file, "", nil); loaded != 1 {
+		t.Fatalf("loaded %d requests, want 1", loaded)
+	}
+	// 未定义的变量不能使 -datafile 下的严格模板编译失败
+	if err := compileRequestTemplates("", true); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := renderSpec(requestPool[0], nil, 1)
+	if want := "https://api.example.com/orders?tenant={{tenant}}"; spec.URL != want {
+		t.Errorf("URL = %q, want %q", spec.URL, want)
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id := spec.Headers.Get("X-Request-Id"); !uuid.MatchString(id) {
+		t.Errorf("X-Request-Id = %q, want a UUID", id)
+	}
+	if body := regexp.MustCompile(`^\{"ts":\d{10},"note":"\{\{note\}\}","qty":\d{1,4}\}$`); !body.MatchString(spec.Body) {
+		t.Errorf("body = %q", spec.Body)
+	}
+	// Basic 认证的值会被编码，无法按模板渲染，变量保留原文
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("{{$randomUserName}}:{{password}}"))
+	if got := spec.Headers.Get("Authorization"); got != auth {
+		t.Errorf("Authorization = %q, want %q", got, auth)
+	}
+}
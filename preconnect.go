@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// preconnect 为 -preconnect：压测开始前为每个目标建立好整个 Keep-Alive 连接池（TCP 与 TLS 握手），
+// 使统计只反映稳定状态下的请求时延，最初的百分位不被握手主导
+var preconnect bool
+
+// preconnectTimeout 为建立连接池的最长等待时间，超时后未建立的连接留给压测阶段
+const preconnectTimeout = 10 * time.Second
+
+// preconnectOrigins 返回需要预先建立连接的各目标的 scheme://host，跳过含模板的 URL
+func preconnectOrigins(urls []string) []string {
+	seen := make(map[string]bool)
+	var origins []string
+	for _, raw := range urls {
+		if raw == "" || strings.Contains(raw, "{{") {
+			continue
+		}
+		u, err := neturl.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// idleLimit 返回客户端为每个主机保留的空闲连接数上限
+func idleLimit(client *http.Client) int {
+	switch t := client.Transport.(type) {
+	case *http.Transport:
+		if t.MaxIdleConnsPerHost > 0 {
+			return t.MaxIdleConnsPerHost
+		}
+		return http.DefaultMaxIdleConnsPerHost
+	case *fasthttpTransport:
+		// fasthttp 不限制空闲连接数，连接总数的上限即空闲连接数的上限
+		return t.client.MaxConnsPerHost
+	}
+	return math.MaxInt
+}
+
+// runPreconnect 用 Keep-Alive 客户端向每个目标同时发出 HEAD 请求，每个请求在拿到连接后先等其余请求也拿到连接，
+// 因此各自新建一条连接，结束后全部留在连接池中；workers 为使用 Keep-Alive 的 worker 数，即每个目标的连接数
+func runPreconnect(origins []string, defaultURL string, workers int) {
+	start := time.Now()
+	var opened, failed int64
+	limited := false
+	for _, origin := range origins {
+		client := clientKeepAlive
+		if policy := policyFor(&requestSpec{URL: origin}, defaultURL); policy != nil {
+			client = policy.keepAlive
+		}
+		n := workers
+		if limit := idleLimit(client); n > limit {
+			n, limited = limit, true
+		}
+		o, f := preconnectOrigin(client, origin, n)
+		opened += o
+		failed += f
+	}
+	fmt.Printf("🔌  Preconnect: %d connections to %d host(s) in %s", opened, len(origins), time.Since(start).Round(time.Millisecond))
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+	if limited {
+		slog.Warn("More keep-alive workers than idle connections per host; the extra connections are opened during the test")
+	}
+}
+
+// preconnectOrigin 为 origin 建立 n 条连接，返回新建与失败的连接数
+func preconnectOrigin(client *http.Client, origin string, n int) (opened, failed int64) {
+	ctx, cancel := context.WithTimeout(runContext, preconnectTimeout)
+	defer cancel()
+	var ready sync.WaitGroup
+	ready.Add(n)
+	release := make(chan struct{})
+	var done sync.WaitGroup
+	for i := 0; i < n; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			var once sync.Once
+			arrive := func() { once.Do(ready.Done) }
+			defer arrive()
+			trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+				if !info.Reused {
+					atomic.AddInt64(&opened, 1)
+				}
+				arrive()
+				// 拿到连接后等其余请求，使每个请求占用各自的连接
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+			}}
+			req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodHead, origin+"/", nil)
+			if err != nil {
+				atomic.AddInt64(&failed, 1)
+				return
+			}
+			req.Header.Set("User-Agent", pickUserAgent(sharedRand))
+			resp, err := client.Do(req)
+			if err != nil {
+				atomic.AddInt64(&failed, 1)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	ready.Wait()
+	close(release)
+	done.Wait()
+	return opened, failed
+}
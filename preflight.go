@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// preflight 为 -preflight，开始压测前向每个不同的接口发送一个请求做连通性与正确性检查
+var preflight bool
+
+// preflightResult 为一个接口的检查结果
+type preflightResult struct {
+	Key      string
+	Phases   requestPhases
+	Status   int
+	Sent     int64
+	Received int64
+	// Unreachable 为 DNS、连接、TLS 等网络层失败，此时不开始压测
+	Unreachable bool
+	Problem     string
+}
+
+// preflightSpecs 返回检查用的请求：每个请求用第一行数据渲染，-targets-compare 下对每个目标各发一次，按统计 key 去重
+func preflightSpecs(defaultURL, defaultMethod string) []*requestSpec {
+	specs := requestPool
+	if scenarios != nil {
+		specs = scenarioRequestSpecs()
+	}
+	if len(specs) == 0 {
+		specs = []*requestSpec{emptyRequest}
+	}
+	var row map[string]string
+	if len(dataRows) > 0 {
+		row = dataRows[0]
+	}
+	seen := make(map[string]bool)
+	var result []*requestSpec
+	for _, spec := range specs {
+		spec = renderSpec(spec, row, 1)
+		if randomBody != nil {
+			spec = randomBody.apply(spec, 1)
+		}
+		variants := []*requestSpec{spec}
+		if compareTargets != nil {
+			variants = variants[:0]
+			for _, t := range compareTargets {
+				variants = append(variants, retarget(spec, t))
+			}
+		}
+		for _, v := range variants {
+			key := preflightKey(v, defaultURL, defaultMethod)
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// preflightKey 返回请求的统计 key，与 sendRequest 的规则相同
+func preflightKey(spec *requestSpec, defaultURL, defaultMethod string) string {
+	method := spec.Method
+	if method == "" {
+		method = defaultMethod
+	}
+	if spec.Name != "" {
+		return spec.Name
+	}
+	if spec.URLTemplate != "" {
+		return templateStatsKey(method, spec.URLTemplate)
+	}
+	target := spec.URL
+	if target == "" {
+		target = defaultURL
+	}
+	return urlStatsKey(method, target)
+}
+
+// preflightRequest 用新连接发送一个请求，按压测时相同的规则校验状态码与响应内容
+func preflightRequest(spec *requestSpec, defaultURL, defaultMethod string) preflightResult {
+	result := preflightResult{Key: preflightKey(spec, defaultURL, defaultMethod)}
+	req, err := newHTTPRequest(spec, defaultURL, defaultMethod)
+	if err != nil {
+		result.Unreachable, result.Problem = true, err.Error()
+		return result
+	}
+	result.Sent = int64(len(spec.Body))
+	trace := &requestTrace{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+	client := clientNoKeepAlive
+	if policy := policyFor(spec, defaultURL); policy != nil {
+		client = policy.noKeepAlive
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Phases = trace.phases(time.Now())
+		result.Unreachable, result.Problem = true, err.Error()
+		return result
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	result.Phases = trace.phases(time.Now())
+	result.Status = resp.StatusCode
+	result.Received = int64(len(body))
+	if err != nil {
+		result.Problem = "reading body: " + err.Error()
+		return result
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Problem = fmt.Sprintf("status %d", resp.StatusCode)
+		return result
+	}
+	sum := sha256.Sum256(body)
+	result.Problem = validateResponse(spec, hex.EncodeToString(sum[:]), body, responseSchema != nil)
+	return result
+}
+
+// runPreflight 检查每个接口并输出结果，有接口无法连通时返回 false；totalRequests 大于 0 时估算整次压测的传输量
+func runPreflight(defaultURL, defaultMethod string, totalRequests int) bool {
+	specs := preflightSpecs(defaultURL, defaultMethod)
+	fmt.Printf("🛫  Preflight: checking %d endpoint(s)\n", len(specs))
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.1f", float64(d.Microseconds())/1000)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Endpoint", "DNS (ms)", "Connect (ms)", "TLS (ms)", "Status", "Sent", "Received", "Result"})
+	reachable := true
+	var sent, received int64
+	for _, spec := range specs {
+		r := preflightRequest(spec, defaultURL, defaultMethod)
+		status, outcome := "-", "✅ OK"
+		if r.Status != 0 {
+			status = fmt.Sprintf("%d", r.Status)
+		}
+		if r.Unreachable {
+			outcome = "❌ " + r.Problem
+			reachable = false
+		} else if r.Problem != "" {
+			outcome = "⚠️  " + r.Problem
+		}
+		table.Append([]string{r.Key, ms(r.Phases.DNS), ms(r.Phases.Connect), ms(r.Phases.TLS), status,
+			formatBytes(r.Sent), formatBytes(r.Received), outcome})
+		sent += r.Sent
+		received += r.Received
+	}
+	table.Render()
+	if len(specs) > 0 && totalRequests > 0 {
+		// 按各接口的平均大小粗略估算，混合压测中接口的权重不同，实际大小会有偏差
+		n := int64(totalRequests)
+		fmt.Printf("📦  Estimated Transfer for %d requests: ~%s sent, ~%s received\n",
+			totalRequests, formatBytes(sent*n/int64(len(specs))), formatBytes(received*n/int64(len(specs))))
+	}
+	return reachable
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// progressTracker 每秒刷新进度条的描述：最近一秒的 RPS、错误数与错误率，
+// 以及按实际吞吐估算的 ETA（按时长运行时为已运行/剩余时间）
+type progressTracker struct {
+	bar   *progressbar.ProgressBar
+	total int64
+	// printed 为 -plain 下上次输出进度行的时间
+	printed  time.Time
+	deadline time.Time
+	start    time.Time
+	lastDone int64
+	lastTime time.Time
+}
+
+// newProgressTracker 创建进度条；total 为 0 时按时长运行，显示为 spinner
+func newProgressTracker(total int64, start, deadline time.Time) *progressTracker {
+	max := total
+	if total == 0 {
+		max = -1
+	}
+	bar := progressbar.NewOptions64(
+		max,
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionSetWidth(20),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionShowCount(),
+		// ETA 由描述中按最近吞吐估算的值给出
+		progressbar.OptionSetPredictTime(false),
+		progressbar.OptionShowDescriptionAtLineEnd(),
+		progressbar.OptionOnCompletion(func() {
+			fmt.Fprint(os.Stderr, "\n")
+		}),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionSetRenderBlankState(true),
+		progressbar.OptionSetVisibility(!plainOutput),
+	)
+	return &progressTracker{bar: bar, total: total, deadline: deadline, start: start, lastTime: start, printed: start}
+}
+
+// Add 记录完成的请求数
+func (p *progressTracker) Add(n int) {
+	p.bar.Add(n)
+}
+
+// update 根据全局计数器刷新描述，由每秒的 ticker 调用
+func (p *progressTracker) update(now time.Time) {
+	success := atomic.LoadInt64(&globalSuccessRequests)
+	failed := atomic.LoadInt64(&globalFailedRequests)
+	done := success + failed + atomic.LoadInt64(&globalLateRequests)
+	var rps float64
+	if seconds := now.Sub(p.lastTime).Seconds(); seconds > 0 {
+		rps = float64(done-p.lastDone) / seconds
+	}
+	p.lastDone, p.lastTime = done, now
+
+	var errorRate float64
+	if done > 0 {
+		errorRate = float64(failed) / float64(done) * 100
+	}
+	desc := fmt.Sprintf("%.1f req/s, errors %d (%.1f%%)", rps, failed, errorRate)
+	switch {
+	case !p.deadline.IsZero():
+		remaining := p.deadline.Sub(now)
+		if remaining < 0 {
+			remaining = 0
+		}
+		desc += fmt.Sprintf(", elapsed %s, remaining %s", now.Sub(p.start).Round(time.Second), remaining.Round(time.Second))
+	case rps > 0 && p.total > done:
+		desc += fmt.Sprintf(", ETA %s", time.Duration(float64(p.total-done)/rps*float64(time.Second)).Round(time.Second))
+	}
+	p.bar.Describe(desc)
+	if plainOutput && now.Sub(p.printed) >= plainProgressInterval {
+		p.printed = now
+		if p.total > 0 {
+			fmt.Fprintf(os.Stdout, "progress: %d/%d (%.0f%%), %s\n", done, p.total, float64(done)*100/float64(p.total), desc)
+		} else {
+			fmt.Fprintf(os.Stdout, "progress: %d, %s\n", done, desc)
+		}
+	}
+}
+
+// plainProgressInterval 为 -plain 下输出进度行的间隔
+const plainProgressInterval = 10 * time.Second
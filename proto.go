@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoRequest、protoResponse 为 -proto-message、-proto-response 指定的消息类型：
+// 非空时请求体（JSON）编码为二进制 protobuf 后发送，响应 body 解码为 JSON 后再做内容校验
+var (
+	protoRequest  protoreflect.MessageDescriptor
+	protoResponse protoreflect.MessageDescriptor
+)
+
+// setupProto 加载 -proto-file 并查找 -proto-message 与 -proto-response 指定的消息类型
+func setupProto(filename, requestMessage, responseMessage string) error {
+	if filename == "" {
+		if requestMessage != "" || responseMessage != "" {
+			return fmt.Errorf("-proto-message and -proto-response require -proto-file")
+		}
+		return nil
+	}
+	if requestMessage == "" && responseMessage == "" {
+		return fmt.Errorf("-proto-file requires -proto-message and/or -proto-response")
+	}
+	file, err := loadProtoFile(filename)
+	if err != nil {
+		return err
+	}
+	if requestMessage != "" {
+		if protoRequest, err = file.message(requestMessage); err != nil {
+			return err
+		}
+	}
+	if responseMessage != "" {
+		if protoResponse, err = file.message(responseMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkProtoBodies 在压测开始前编码一遍不含模板的请求体，使 JSON 与消息定义不符的问题尽早暴露；
+// 含模板的请求体在渲染后才能编码，编码失败时记为请求失败
+func checkProtoBodies() error {
+	if protoRequest == nil {
+		return nil
+	}
+	for i, spec := range requestPool {
+		if spec.Body == "" || spec.bodyTpl != nil {
+			continue
+		}
+		if _, err := encodeProtoJSON(protoRequest, spec.Body); err != nil {
+			return fmt.Errorf("request %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// protoFile 为编译后的 .proto 文件
+type protoFile struct {
+	fd protoreflect.FileDescriptor
+}
+
+// loadProtoFile 用 protocompile 编译 .proto 文件：import 依次在该文件所在目录与当前目录下查找，
+// google/protobuf/ 下的 well-known types 使用内置的定义
+func loadProtoFile(filename string) (*protoFile, error) {
+	return compileProtoFile(filepath.Base(filename), &protocompile.SourceResolver{
+		ImportPaths: []string{filepath.Dir(filename), "."},
+	})
+}
+
+// parseProtoFile 编译 .proto 源码，name 为描述符中的文件名；源码中只能 import well-known types
+func parseProtoFile(name, src string) (*protoFile, error) {
+	return compileProtoFile(name, &protocompile.SourceResolver{
+		Accessor: protocompile.SourceAccessorFromMap(map[string]string{name: src}),
+	})
+}
+
+func compileProtoFile(name string, resolver protocompile.Resolver) (*protoFile, error) {
+	compiler := protocompile.Compiler{Resolver: protocompile.WithStandardImports(resolver)}
+	files, err := compiler.Compile(context.Background(), name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proto file: %v", err)
+	}
+	return &protoFile{fd: files[0]}, nil
+}
+
+// message 按名称查找文件中定义的消息类型，名称可带 package 前缀，嵌套消息写作 Outer.Inner
+func (f *protoFile) message(name string) (protoreflect.MessageDescriptor, error) {
+	pkg := string(f.fd.Package())
+	name = strings.TrimPrefix(name, ".")
+	if pkg != "" {
+		name = strings.TrimPrefix(name, pkg+".")
+	}
+	var names []string
+	var found protoreflect.MessageDescriptor
+	var walk func(messages protoreflect.MessageDescriptors, scope string)
+	walk = func(messages protoreflect.MessageDescriptors, scope string) {
+		for i := 0; i < messages.Len(); i++ {
+			m := messages.Get(i)
+			// map 字段生成的键值对消息不能单独使用
+			if m.IsMapEntry() {
+				continue
+			}
+			n := scope + string(m.Name())
+			if n == name {
+				found = m
+			}
+			names = append(names, n)
+			walk(m.Messages(), n+".")
+		}
+	}
+	walk(f.fd.Messages(), "")
+	if found != nil {
+		return found, nil
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("message %q not found in proto file (available: %s)", name, strings.Join(names, ", "))
+}
+
+// encodeProtoJSON 按 protobuf 的 JSON 映射把请求体解析为 m 类型的消息，再编码为二进制 protobuf
+func encodeProtoJSON(m protoreflect.MessageDescriptor, body string) ([]byte, error) {
+	msg := dynamicpb.NewMessage(m)
+	if err := protojson.Unmarshal([]byte(body), msg); err != nil {
+		return nil, fmt.Errorf("invalid JSON body for message %s: %v", m.FullName(), err)
+	}
+	// dynamicpb 默认按不固定的顺序写出字段，Deterministic 使同一请求体每次编码的结果相同
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+// decodeProtoJSON 把 m 类型的二进制 protobuf 解码为 JSON：字段名为 lowerCamelCase，64 位整数为字符串，枚举为名称，未知字段忽略
+func decodeProtoJSON(m protoreflect.MessageDescriptor, data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(m)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return protojson.Marshal(msg)
+}
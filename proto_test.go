@@ -0,0 +1,414 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// structProto 与 google/protobuf/struct.proto 的消息定义相同，只是 package 不同，
+// 因此编码结果可以直接用 structpb 解码比较
+const structProto = `
+syntax = "proto3";
+package test.v1;
+
+// Struct 为 JSON 对象
+message Struct {
+  map<string, Value> fields = 1;
+}
+
+message Value {
+  oneof kind {
+    NullValue null_value = 1;
+    double number_value = 2;
+    string string_value = 3;
+    bool bool_value = 4;
+    Struct struct_value = 5;
+    ListValue list_value = 6;
+  }
+}
+
+enum NullValue {
+  NULL_VALUE = 0;
+}
+
+message ListValue {
+  repeated Value values = 1;
+}
+
+message Duration {
+  int64 seconds = 1;
+  int32 nanos = 2;
+}
+`
+
+const orderProto = `
+syntax = "proto3";
+package shop.v1;
+
+import "google/protobuf/timestamp.proto";
+option go_package = "example.com/shop";
+
+/* 订单 */
+message Order {
+  enum Status {
+    option allow_alias = true;
+    STATUS_UNSPECIFIED = 0;
+    PAID = 1;
+    SETTLED = 1;
+    SHIPPED = 2;
+  }
+  message Item {
+    string sku = 1;
+    uint32 quantity = 2;
+    sint64 delta = 3;
+  }
+  reserved 20 to 30;
+  int64 id = 1;
+  Status status = 2;
+  repeated Item items = 3;
+  map<string, int32> counts = 4;
+  repeated fixed32 codes = 5 [packed = true];
+  bytes token = 6;
+  optional bool gift = 7;
+  double price = 8;
+  oneof contact {
+    string email = 9;
+    string phone = 10;
+  }
+  string customer_name = 11;
+  map<int64, Item> items_by_id = 12;
+  google.protobuf.Timestamp created_at = 13;
+}
+
+service Shop {
+  rpc Create(Order) returns (Order);
+}
+`
+
+func mustProtoMessage(t *testing.T, src, name string) protoreflect.MessageDescriptor {
+	t.Helper()
+	file, err := parseProtoFile("test.proto", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := file.message(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// jsonEqual 比较两段 JSON 的内容，忽略字段顺序与空白
+func jsonEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid expected JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestProtoEncodeMatchesGeneratedCode(t *testing.T) {
+	m := mustProtoMessage(t, structProto, "Struct")
+	body := `{"fields": {
+		"name": {"stringValue": "alice"},
+		"age": {"numberValue": 30},
+		"admin": {"boolValue": true},
+		"nothing": {"nullValue": "NULL_VALUE"},
+		"tags": {"listValue": {"values": [{"stringValue": "a"}, {"numberValue": 1.5}]}},
+		"address": {"structValue": {"fields": {"city": {"stringValue": "Paris"}}}}
+	}}`
+	data, err := encodeProtoJSON(m, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got structpb.Struct
+	if err := proto.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want, err := structpb.NewStruct(map[string]interface{}{
+		"name":    "alice",
+		"age":     30,
+		"admin":   true,
+		"nothing": nil,
+		"tags":    []interface{}{"a", 1.5},
+		"address": map[string]interface{}{"city": "Paris"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&got, want) {
+		t.Errorf("decoded %v, want %v", &got, want)
+	}
+
+	// 反方向：generated code 编码的消息解码为 JSON
+	encoded, err := proto.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeProtoJSON(m, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonEqual(t, decoded, `{"fields": {
+		"name": {"stringValue": "alice"},
+		"age": {"numberValue": 30},
+		"admin": {"boolValue": true},
+		"nothing": {"nullValue": "NULL_VALUE"},
+		"tags": {"listValue": {"values": [{"stringValue": "a"}, {"numberValue": 1.5}]}},
+		"address": {"structValue": {"fields": {"city": {"stringValue": "Paris"}}}}
+	}}`)
+
+	d := mustProtoMessage(t, structProto, "test.v1.Duration")
+	data, err = encodeProtoJSON(d, `{"seconds": "-5", "nanos": -10}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBytes, err := proto.Marshal(durationpb.New(-5*time.Second - 10*time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(wantBytes) {
+		t.Errorf("encoded %x, want %x", data, wantBytes)
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	m := mustProtoMessage(t, orderProto, "Order")
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "scalars",
+			body: `{"id": 42, "price": 9.5, "customer_name": "Bob", "token": "AQID"}`,
+			want: `{"id": "42", "price": 9.5, "customerName": "Bob", "token": "AQID"}`,
+		},
+		{
+			name: "enum by number and name",
+			body: `{"status": 2}`,
+			want: `{"status": "SHIPPED"}`,
+		},
+		{
+			name: "enum alias",
+			body: `{"status": "SETTLED"}`,
+			want: `{"status": "PAID"}`,
+		},
+		{
+			name: "nested and repeated messages",
+			body: `{"items": [{"sku": "a", "quantity": 2, "delta": "-3"}, {"sku": "b"}]}`,
+			want: `{"items": [{"sku": "a", "quantity": 2, "delta": "-3"}, {"sku": "b"}]}`,
+		},
+		{
+			name: "maps",
+			body: `{"counts": {"x": 1, "y": -2}, "itemsById": {"7": {"sku": "s"}}}`,
+			want: `{"counts": {"x": 1, "y": -2}, "itemsById": {"7": {"sku": "s"}}}`,
+		},
+		{
+			name: "packed repeated",
+			body: `{"codes": [1, 4294967295]}`,
+			want: `{"codes": [1, 4294967295]}`,
+		},
+		{
+			name: "proto3 optional keeps presence",
+			body: `{"gift": false, "price": 0}`,
+			want: `{"gift": false}`,
+		},
+		{
+			name: "oneof",
+			body: `{"phone": "123"}`,
+			want: `{"phone": "123"}`,
+		},
+		{
+			name: "well-known type",
+			body: `{"createdAt": "2024-01-02T03:04:05.5+01:00"}`,
+			want: `{"createdAt": "2024-01-02T02:04:05.500Z"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeProtoJSON(m, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decodeProtoJSON(m, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jsonEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestProtoWireFormat(t *testing.T) {
+	// proto3 的 repeated 数值字段为 packed 编码，proto2 默认不是
+	var packed []byte
+	packed = protowire.AppendTag(packed, 5, protowire.BytesType)
+	packed = protowire.AppendVarint(packed, 8)
+	packed = protowire.AppendFixed32(packed, 1)
+	packed = protowire.AppendFixed32(packed, 2)
+	data, err := encodeProtoJSON(mustProtoMessage(t, orderProto, "Order"), `{"codes": [1, 2]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(packed) {
+		t.Errorf("proto3 encoded %x, want %x", data, packed)
+	}
+
+	const proto2 = `message M { repeated int32 a = 1; required string b = 2; optional sint32 c = 3; }`
+	var unpacked []byte
+	unpacked = protowire.AppendTag(unpacked, 1, protowire.VarintType)
+	unpacked = protowire.AppendVarint(unpacked, 1)
+	unpacked = protowire.AppendTag(unpacked, 1, protowire.VarintType)
+	unpacked = protowire.AppendVarint(unpacked, 1<<64-1)
+	unpacked = protowire.AppendTag(unpacked, 2, protowire.BytesType)
+	unpacked = protowire.AppendString(unpacked, "x")
+	unpacked = protowire.AppendTag(unpacked, 3, protowire.VarintType)
+	unpacked = protowire.AppendVarint(unpacked, protowire.EncodeZigZag(-2))
+	m := mustProtoMessage(t, proto2, "M")
+	data, err = encodeProtoJSON(m, `{"a": [1, -1], "b": "x", "c": -2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(unpacked) {
+		t.Errorf("proto2 encoded %x, want %x", data, unpacked)
+	}
+	if _, err := encodeProtoJSON(m, `{"a": [1]}`); err == nil {
+		t.Error("expected an error for a missing required field")
+	}
+
+	// editions 默认显式 presence，零值也会写出
+	data, err = encodeProtoJSON(mustProtoMessage(t, `edition = "2023"; message E { int32 a = 1; }`, "E"), `{"a": 0}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 0); string(data) != string(want) {
+		t.Errorf("edition 2023 encoded %x, want %x", data, want)
+	}
+
+	// 未知字段在解码时忽略
+	withUnknown := protowire.AppendTag(append([]byte(nil), unpacked...), 99, protowire.VarintType)
+	withUnknown = protowire.AppendVarint(withUnknown, 7)
+	got, err := decodeProtoJSON(m, withUnknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonEqual(t, got, `{"a": [1, -1], "b": "x", "c": -2}`)
+}
+
+func TestProtoErrors(t *testing.T) {
+	m := mustProtoMessage(t, orderProto, "shop.v1.Order")
+	for _, body := range []string{`{"unknown": 1}`, `{"id": "abc"}`, `{"status": "LOST"}`, `[1]`, `{`} {
+		if _, err := encodeProtoJSON(m, body); err == nil {
+			t.Errorf("expected an error encoding %s", body)
+		}
+	}
+	if _, err := decodeProtoJSON(m, []byte{0x0a, 0x05, 0x01}); err == nil {
+		t.Error("expected an error decoding a truncated message")
+	}
+
+	file, err := parseProtoFile("test.proto", orderProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.message("Missing")
+	if err == nil || !strings.Contains(err.Error(), "available: Order, Order.Item") || strings.Contains(err.Error(), "Entry") {
+		t.Errorf("unexpected error for a missing message: %v", err)
+	}
+
+	for _, src := range []string{
+		`syntax = "proto3"; message A { Missing b = 1; }`,
+		`syntax = "proto3"; message A { string b = 1; string c = 1; }`,
+		`syntax = "proto3"; enum E { FIRST = 1; }`,
+		`syntax = "proto4";`,
+		`message A { string b = 1`,
+	} {
+		if _, err := parseProtoFile("test.proto", src); err == nil {
+			t.Errorf("expected an error parsing %q", src)
+		}
+	}
+}
+
+func TestProtoImports(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"api.proto": `syntax = "proto3";
+package api.v1;
+import "common/money.proto";
+import "google/protobuf/timestamp.proto";
+message Payment {
+  common.Money amount = 1;
+  google.protobuf.Timestamp at = 2;
+}`,
+		"common/money.proto": `syntax = "proto3";
+package common;
+message Money {
+  string currency = 1;
+  int64 units = 2;
+}`,
+		"broken.proto": `syntax = "proto3";
+import "missing.proto";
+message A { Missing b = 1; }`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := loadProtoFile(filepath.Join(dir, "api.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := file.message("api.v1.Payment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeProtoJSON(m, `{"amount": {"currency": "EUR", "units": "12"}, "at": "2024-01-02T03:04:05Z"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 导入的 common.Money 按字段编码，at 与 generated code 编码的 Timestamp 相同
+	amount := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "EUR")
+	amount = protowire.AppendVarint(protowire.AppendTag(amount, 2, protowire.VarintType), 12)
+	at, err := proto.Marshal(timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := protowire.AppendTag(nil, 1, protowire.BytesType)
+	want = protowire.AppendBytes(want, amount)
+	want = protowire.AppendTag(want, 2, protowire.BytesType)
+	want = protowire.AppendBytes(want, at)
+	if string(data) != string(want) {
+		t.Errorf("encoded %x, want %x", data, want)
+	}
+	if _, err := file.message("common.Money"); err == nil {
+		t.Error("messages of imported files should not be selectable")
+	}
+
+	_, err = loadProtoFile(filepath.Join(dir, "broken.proto"))
+	if err == nil || !strings.Contains(err.Error(), "broken.proto:2:8") || !strings.Contains(err.Error(), "missing.proto") {
+		t.Errorf("unexpected error for a missing import: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// queuedEntry 为 -once-per-entry 工作队列中的一项
+type queuedEntry struct {
+	// Index 为该项在加载的请求中的序号（从 0 开始）
+	Index int
+	spec  *requestSpec
+	// attempts 为已发送的次数
+	attempts int
+}
+
+// entryQueue 把加载的每个请求作为工作队列中的一项，由所有 worker 并行取用，每项只成功发送一次；
+// 失败的项在未超过 maxRequeue 次时放回队尾重新发送
+type entryQueue struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	pending    []*queuedEntry
+	inFlight   int
+	maxRequeue int
+
+	succeeded int
+	requeued  int
+	failed    []int
+}
+
+// newEntryQueue 按加载顺序为每个请求创建一项
+func newEntryQueue(specs []*requestSpec, maxRequeue int) *entryQueue {
+	q := &entryQueue{maxRequeue: maxRequeue}
+	q.cond = sync.NewCond(&q.mu)
+	for i, spec := range specs {
+		q.pending = append(q.pending, &queuedEntry{Index: i, spec: spec})
+	}
+	return q
+}
+
+// next 取出下一项；队列为空但仍有项在发送时等待，因为它们可能失败后被放回。
+// 所有项都已完成或需要停止时返回 false
+func (q *entryQueue) next() (*queuedEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.pending) == 0 && q.inFlight > 0 && !shouldStop() {
+		q.cond.Wait()
+	}
+	if len(q.pending) == 0 || shouldStop() {
+		return nil, false
+	}
+	entry := q.pending[0]
+	q.pending = q.pending[1:]
+	q.inFlight++
+	entry.attempts++
+	return entry, true
+}
+
+// done 记录一次发送的结果，返回该项是否已完成（成功，或失败且不再放回）
+func (q *entryQueue) done(entry *queuedEntry, success bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.cond.Broadcast()
+	q.inFlight--
+	if success {
+		q.succeeded++
+		return true
+	}
+	if entry.attempts <= q.maxRequeue {
+		q.requeued++
+		q.pending = append(q.pending, entry)
+		return false
+	}
+	q.failed = append(q.failed, entry.Index)
+	return true
+}
+
+// maxFailedEntries 为汇总中列出的失败项序号的最大数量
+const maxFailedEntries = 20
+
+// report 输出队列的完成情况，列出最终失败的项的序号以便单独重放
+func (q *entryQueue) report(total int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fmt.Println("\n📦  Once-Per-Entry Summary:")
+	fmt.Printf("  - Entries: %d\n", total)
+	fmt.Printf("  - Succeeded: %d\n", q.succeeded)
+	fmt.Printf("  - Failed: %d\n", len(q.failed))
+	fmt.Printf("  - Re-queued Attempts: %d\n", q.requeued)
+	if notSent := total - q.succeeded - len(q.failed); notSent > 0 {
+		fmt.Printf("  - Not Sent (interrupted): %d\n", notSent)
+	}
+	if len(q.failed) == 0 {
+		return
+	}
+	sort.Ints(q.failed)
+	indexes := make([]string, 0, maxFailedEntries)
+	for i, index := range q.failed {
+		if i == maxFailedEntries {
+			break
+		}
+		indexes = append(indexes, fmt.Sprintf("%d", index))
+	}
+	line := strings.Join(indexes, ", ")
+	if len(q.failed) > maxFailedEntries {
+		line += fmt.Sprintf(" ... and %d more", len(q.failed)-maxFailedEntries)
+	}
+	fmt.Printf("  - Failed Entries (0-based): %s\n", line)
+}
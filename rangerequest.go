@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// rangeSizeValue 为 -range-size：对 -url 的大对象发送随机偏移、每次 rangeSize 字节的 Range 请求，
+// 校验响应为 206、Content-Range 与请求的范围一致且 body 字节数正确，用于压测视频、文件等源站
+var (
+	rangeSizeValue string
+	rangeSize      int64
+	// rangeURL、rangeObjectSize 为发送 Range 请求的对象及其大小，启动时探测
+	rangeURL        string
+	rangeObjectSize int64
+)
+
+// byteRange 为一个 Range 请求的闭区间 [Start, End]
+type byteRange struct {
+	Start, End int64
+}
+
+// setupRanges 以 Range: bytes=0-0 请求 url，从 Content-Range 得到对象大小，同时确认服务端支持 Range
+func setupRanges(url string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := clientKeepAlive.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("expected 206 for Range: bytes=0-0, got %d", resp.StatusCode)
+	}
+	_, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || size <= 0 {
+		return fmt.Errorf("unusable Content-Range %q, the object size must be known", resp.Header.Get("Content-Range"))
+	}
+	rangeURL, rangeObjectSize = req.URL.String(), size
+	return nil
+}
+
+// parseContentRange 解析 "bytes 0-1023/4096" 形式的 Content-Range，返回范围与对象大小；大小为 * 时返回 -1
+func parseContentRange(value string) (byteRange, int64, bool) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return byteRange{}, 0, false
+	}
+	span, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return byteRange{}, 0, false
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return byteRange{}, 0, false
+	}
+	var r byteRange
+	var err error
+	if r.Start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return byteRange{}, 0, false
+	}
+	if r.End, err = strconv.ParseInt(last, 10, 64); err != nil {
+		return byteRange{}, 0, false
+	}
+	size := int64(-1)
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			return byteRange{}, 0, false
+		}
+	}
+	return r, size, true
+}
+
+// setRange 为对 rangeURL 的 GET 请求加上随机偏移的 Range header，返回请求的范围；不需要 Range 时返回 nil。
+// 请求本身（如 -H）已带 Range 时不覆盖
+func setRange(req *http.Request, reqURL string) *byteRange {
+	if rangeSize <= 0 || req.Method != http.MethodGet || reqURL != rangeURL || req.Header.Get("Range") != "" {
+		return nil
+	}
+	r := byteRange{End: rangeObjectSize - 1}
+	if rangeSize < rangeObjectSize {
+		r.Start = sharedRand.Int63n(rangeObjectSize - rangeSize + 1)
+		r.End = r.Start + rangeSize - 1
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.Start, r.End))
+	return &r
+}
+
+// check 校验 2xx 响应是否正确处理了 Range 请求，返回不通过的原因，通过时返回空字符串
+func (r *byteRange) check(resp *http.Response, bytesRead int64) string {
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Sprintf("range: status %d instead of 206", resp.StatusCode)
+	}
+	got, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok {
+		return "range: missing or invalid Content-Range"
+	}
+	if got != *r {
+		return "range: Content-Range does not match the requested range"
+	}
+	if size >= 0 && size != rangeObjectSize {
+		return "range: object size changed"
+	}
+	if bytesRead != r.End-r.Start+1 {
+		return "range: body length does not match Content-Range"
+	}
+	return ""
+}
+
+// reportRanges 输出 Range 请求的数量与校验通过的数量，未通过的原因见内容校验失败的统计
+func reportRanges(sent, verified int64) {
+	if rangeSize <= 0 || sent == 0 {
+		return
+	}
+	fmt.Printf("\n🎞️   Range Requests: %d sent, %d verified as 206 with a matching Content-Range and length (%.2f%%)\n",
+		sent, verified, float64(verified)/float64(sent)*100)
+}
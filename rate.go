@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ratePattern 根据压测开始后的时间返回基础速率的倍数
+type ratePattern interface {
+	factor(elapsed time.Duration) float64
+}
+
+// spikePattern 在 at 时刻起的 duration 内把速率放大 multiplier 倍，every 大于 0 时周期性重复
+type spikePattern struct {
+	multiplier float64
+	duration   time.Duration
+	at         time.Duration
+	every      time.Duration
+}
+
+func (p spikePattern) factor(elapsed time.Duration) float64 {
+	if elapsed < p.at {
+		return 1
+	}
+	since := elapsed - p.at
+	if p.every > 0 {
+		since %= p.every
+	}
+	if since < p.duration {
+		return p.multiplier
+	}
+	return 1
+}
+
+// stepPattern 依次使用 levels 中的倍数，每一级保持 hold 时长，最后一级一直保持
+type stepPattern struct {
+	levels []float64
+	hold   time.Duration
+}
+
+func (p stepPattern) factor(elapsed time.Duration) float64 {
+	i := int(elapsed / p.hold)
+	if i >= len(p.levels) {
+		i = len(p.levels) - 1
+	}
+	return p.levels[i]
+}
+
+// sinePattern 以 period 为周期在 1±amplitude 之间正弦波动
+type sinePattern struct {
+	period    time.Duration
+	amplitude float64
+}
+
+func (p sinePattern) factor(elapsed time.Duration) float64 {
+	return 1 + p.amplitude*math.Sin(2*math.Pi*elapsed.Seconds()/p.period.Seconds())
+}
+
+// patternFlags 实现 flag.Value，允许多次指定 -pattern，多个模式的倍数相乘
+type patternFlags []ratePattern
+
+func (p *patternFlags) String() string {
+	return fmt.Sprintf("%d patterns", len(*p))
+}
+
+func (p *patternFlags) Set(value string) error {
+	pattern, err := parseRatePattern(value)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+// parseRatePattern 解析速率模式：
+//   - spike:10x:5s[:at=30s][:every=60s]  在 at（默认 10s）时刻起 5s 内速率放大 10 倍，可周期重复
+//   - step:1x,2x,4x:30s                  每 30s 提升一级，最后一级一直保持
+//   - sine:period=60s[:amplitude=0.5]    以 60s 为周期在 1±0.5 倍之间波动
+func parseRatePattern(value string) (ratePattern, error) {
+	parts := strings.Split(value, ":")
+	options := make(map[string]string)
+	var positional []string
+	for _, part := range parts[1:] {
+		if k, v, found := strings.Cut(part, "="); found {
+			options[k] = v
+		} else {
+			positional = append(positional, part)
+		}
+	}
+
+	switch parts[0] {
+	case "spike":
+		if len(positional) != 2 {
+			return nil, fmt.Errorf("invalid spike pattern %q, expected spike:<multiplier>x:<duration>", value)
+		}
+		multiplier, err := parseMultiplier(positional[0])
+		if err != nil {
+			return nil, err
+		}
+		duration, err := time.ParseDuration(positional[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid spike duration %q", positional[1])
+		}
+		p := spikePattern{multiplier: multiplier, duration: duration, at: 10 * time.Second}
+		if v, ok := options["at"]; ok {
+			if p.at, err = time.ParseDuration(v); err != nil {
+				return nil, fmt.Errorf("invalid spike start %q", v)
+			}
+		}
+		if v, ok := options["every"]; ok {
+			if p.every, err = time.ParseDuration(v); err != nil || p.every <= p.duration {
+				return nil, fmt.Errorf("invalid spike interval %q, must be longer than the spike", v)
+			}
+		}
+		return p, nil
+	case "step":
+		if len(positional) != 2 {
+			return nil, fmt.Errorf("invalid step pattern %q, expected step:<m1>x,<m2>x,...:<hold>", value)
+		}
+		p := stepPattern{}
+		for _, level := range strings.Split(positional[0], ",") {
+			m, err := parseMultiplier(level)
+			if err != nil {
+				return nil, err
+			}
+			p.levels = append(p.levels, m)
+		}
+		hold, err := time.ParseDuration(positional[1])
+		if err != nil || hold <= 0 {
+			return nil, fmt.Errorf("invalid step hold %q", positional[1])
+		}
+		p.hold = hold
+		return p, nil
+	case "sine":
+		p := sinePattern{amplitude: 0.5}
+		period, err := time.ParseDuration(options["period"])
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid sine pattern %q, expected sine:period=<duration>", value)
+		}
+		p.period = period
+		if v, ok := options["amplitude"]; ok {
+			if p.amplitude, err = strconv.ParseFloat(v, 64); err != nil || p.amplitude < 0 || p.amplitude > 1 {
+				return nil, fmt.Errorf("invalid sine amplitude %q, must be within 0..1", v)
+			}
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown rate pattern %q", parts[0])
+}
+
+// parseMultiplier 解析 "10x" 或 "2.5" 形式的倍数
+func parseMultiplier(s string) (float64, error) {
+	m, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "x"), 64)
+	if err != nil || m < 0 {
+		return 0, fmt.Errorf("invalid multiplier %q", s)
+	}
+	return m, nil
+}
+
+// feedRatedRequests 以开放模型按 baseRate（请求/秒）乘以所有模式倍数后的速率投递 total 个请求；
+// 下一个请求的发送时间由当前速率决定，落后于计划时立即补发
+func feedRatedRequests(total int, baseRate float64, patterns []ratePattern, next func(i int) *requestSpec, jobs chan<- *requestSpec) {
+	defer close(jobs)
+	start := time.Now()
+	nextAt := start
+	for i := 0; i < total && !shouldStop(); i++ {
+		if wait := time.Until(nextAt); wait > 0 {
+			time.Sleep(wait)
+		}
+		scheduled := *next(i)
+		scheduled.Scheduled = nextAt
+		jobs <- &scheduled
+
+		rate := baseRate
+		for _, p := range patterns {
+			rate *= p.factor(nextAt.Sub(start))
+		}
+		// 速率为 0 时暂停投递，稍后重新计算
+		for rate <= 0 {
+			if shouldStop() {
+				return
+			}
+			nextAt = nextAt.Add(10 * time.Millisecond)
+			time.Sleep(time.Until(nextAt))
+			rate = baseRate
+			for _, p := range patterns {
+				rate *= p.factor(nextAt.Sub(start))
+			}
+		}
+		nextAt = nextAt.Add(time.Duration(float64(time.Second) / rate))
+	}
+}
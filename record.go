@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// recordSkippedHeaders 为录制时不保存的请求头：逐跳头、由发送方重新计算的头与代理自身的头
+var recordSkippedHeaders = map[string]bool{
+	"Host": true, "Content-Length": true, "Connection": true, "Keep-Alive": true, "Proxy-Connection": true,
+	"Proxy-Authorization": true, "Te": true, "Trailer": true, "Transfer-Encoding": true, "Upgrade": true,
+	"Accept-Encoding": true,
+}
+
+// recordedRequest 为代理录下的一个请求
+type recordedRequest struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    string
+	At      time.Time
+}
+
+// recordedScenario、recordedStep 为录制结果的 -scenarios 格式：一个按顺序执行的场景，步骤之间的间隔作为思考时间
+type recordedScenario struct {
+	Name     string         `yaml:"name"`
+	Journey  bool           `yaml:"journey"`
+	Requests []recordedStep `yaml:"requests"`
+}
+
+type recordedStep struct {
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+	Think   time.Duration     `yaml:"think,omitempty"`
+}
+
+// recorder 保存录下的请求，每录下一个请求就重写一次输出文件，中途退出也不会丢失
+type recorder struct {
+	mu       sync.Mutex
+	out      string
+	match    *regexp.Regexp
+	requests []recordedRequest
+}
+
+// capture 读取并录下请求的 body 后放回，返回是否录制
+func (rec *recorder) capture(r *http.Request, target string) (int, bool, error) {
+	if rec.match != nil && !rec.match.MatchString(target) {
+		return 0, false, nil
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return 0, false, err
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	headers := make(map[string]string)
+	for name, values := range r.Header {
+		if !recordSkippedHeaders[name] {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, recordedRequest{Method: r.Method, URL: target, Headers: headers, Body: string(body), At: time.Now()})
+	return len(rec.requests), true, rec.write()
+}
+
+// write 按输出文件的扩展名写出：.yaml/.yml 为 -scenarios 格式，其余为 -bodyfile 格式；须持有 rec.mu
+func (rec *recorder) write() error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(rec.out)) {
+	case ".yaml", ".yml":
+		sc := recordedScenario{Name: "recorded", Journey: true}
+		for i, r := range rec.requests {
+			step := recordedStep{Method: r.Method, URL: r.URL, Body: r.Body}
+			if len(r.Headers) > 0 {
+				step.Headers = r.Headers
+			}
+			if i+1 < len(rec.requests) {
+				step.Think = rec.requests[i+1].At.Sub(r.At).Round(time.Millisecond)
+			}
+			sc.Requests = append(sc.Requests, step)
+		}
+		data, err = yaml.Marshal([]recordedScenario{sc})
+	default:
+		// -bodyfile 格式只有 URL 与 body，方法由 -X 决定
+		entries := make([][]string, len(rec.requests))
+		for i, r := range rec.requests {
+			entries[i] = []string{r.URL, r.Body}
+		}
+		data, err = json.MarshalIndent(entries, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	tmp := rec.out + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, rec.out)
+}
+
+// statusWriter 记录代理返回的状态码
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// tunnel 转发 CONNECT 请求：HTTPS 流量加密，只能原样转发而无法录制
+func tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, 10*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	go func() {
+		io.Copy(upstream, client)
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+}
+
+// runRecordCommand 解析 record 子命令参数并运行录制代理：
+// 指定 -target 时作为反向代理把请求转发到 -target，否则作为正向代理（浏览器或 HTTP_PROXY 指向它）转发到请求中的地址
+func runRecordCommand(args []string) {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	var listen, out, target, match string
+	fs.StringVar(&listen, "listen", ":8888", "Address the recording proxy listens on")
+	fs.StringVar(&out, "out", "captured.json", "File the captured requests are written to: .yaml/.yml in the -scenarios format, otherwise the -bodyfile format")
+	fs.StringVar(&target, "target", "", "Reverse proxy mode: forward every request to this base URL, e.g. https://staging.example.com (default: forward proxy)")
+	fs.StringVar(&match, "match", "", "Only record requests whose URL matches this regular expression, e.g. /api/")
+	fs.Parse(args)
+
+	rec := &recorder{out: out}
+	if match != "" {
+		var err error
+		if rec.match, err = regexp.Compile(match); err != nil {
+			slog.Error("Invalid -match", "err", err)
+			exit(1)
+		}
+	}
+	if info, err := os.Stat(filepath.Dir(out)); err != nil || !info.IsDir() {
+		slog.Error(fmt.Sprintf("Directory of -out %s does not exist", out))
+		exit(1)
+	}
+	var upstream *url.URL
+	if target != "" {
+		var err error
+		if upstream, err = url.Parse(target); err != nil || upstream.Scheme == "" || upstream.Host == "" {
+			slog.Error(fmt.Sprintf("Invalid -target %q, expected e.g. https://staging.example.com", target))
+			exit(1)
+		}
+	}
+
+	proxy := &httputil.ReverseProxy{Director: func(r *http.Request) {
+		if upstream != nil {
+			r.URL.Scheme, r.URL.Host = upstream.Scheme, upstream.Host
+			r.URL.Path = singleJoiningSlash(upstream.Path, r.URL.Path)
+			r.Host = upstream.Host
+		}
+		r.Header.Del("Proxy-Connection")
+	}}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			fmt.Printf("🔒  Tunneling %s (HTTPS through a forward proxy cannot be recorded, use -target)\n", r.Host)
+			tunnel(w, r)
+			return
+		}
+		if upstream == nil && !r.URL.IsAbs() {
+			http.Error(w, "not a proxy request; set this address as the HTTP proxy or start with -target", http.StatusBadRequest)
+			return
+		}
+		u := *r.URL
+		if upstream != nil {
+			u.Scheme, u.Host = upstream.Scheme, upstream.Host
+			u.Path = singleJoiningSlash(upstream.Path, r.URL.Path)
+		}
+		n, recorded, err := rec.capture(r, u.String())
+		if err != nil {
+			slog.Error("Unable to record", "method", r.Method, "url", u.String(), "err", err)
+		}
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		proxy.ServeHTTP(sw, r)
+		if recorded {
+			fmt.Printf("📼  #%d %s %s -> %d (%s)\n", n, r.Method, u.String(), sw.status, time.Since(start).Round(time.Millisecond))
+		}
+	})
+
+	mode := "forward proxy"
+	if upstream != nil {
+		mode = "reverse proxy to " + upstream.String()
+	}
+	fmt.Printf("🎙️   Recording on %s (%s) -> %s, press Ctrl+C to stop\n", listen, mode, out)
+	if err := http.ListenAndServe(listen, handler); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+}
+
+// singleJoiningSlash 拼接 -target 的路径与请求路径，两者之间只保留一个 /
+func singleJoiningSlash(a, b string) string {
+	switch {
+	case strings.HasSuffix(a, "/") && strings.HasPrefix(b, "/"):
+		return a + b[1:]
+	case !strings.HasSuffix(a, "/") && !strings.HasPrefix(b, "/") && b != "":
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// combinedLogPattern 匹配 Apache/Nginx 的 common 与 combined 日志格式
+var combinedLogPattern = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "(\S+) (\S+)(?: [^"]*)?" (\d{3}|-) (\S+)(?: "([^"]*)" "([^"]*)")?`)
+
+// combinedTimeLayout 为 combined 日志中的时间格式
+const combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// loadAccessLog 读取访问日志（combined 或每行一个 JSON 对象），按时间排序后追加到 requestPool，
+// 相对路径基于 baseURL 拼接，返回加载的请求数
+func loadAccessLog(filename, format, baseURL string) int {
+	file, err := os.Open(filename)
+	if err != nil {
+		slog.Error("Unable to read access log", "err", err)
+		return 0
+	}
+	defer file.Close()
+
+	type timedSpec struct {
+		started time.Time
+		spec    *requestSpec
+	}
+	var entries []timedSpec
+	skipped := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var spec *requestSpec
+		var started time.Time
+		switch format {
+		case "json":
+			spec, started = parseJSONLogLine(line)
+		default:
+			spec, started = parseCombinedLogLine(line)
+		}
+		if spec == nil {
+			skipped++
+			continue
+		}
+		if !strings.Contains(spec.URL, "://") {
+			spec.URL = strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(spec.URL, "/")
+		}
+		entries = append(entries, timedSpec{started: started, spec: spec})
+	}
+	if skipped > 0 {
+		slog.Warn("Skipped unparsable access log lines", "lines", skipped)
+	}
+	if len(entries) == 0 {
+		return 0
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].started.Before(entries[j].started) })
+	first := entries[0].started
+	for _, e := range entries {
+		if !e.started.IsZero() && !first.IsZero() {
+			e.spec.Offset = e.started.Sub(first)
+		}
+		requestPool = append(requestPool, e.spec)
+	}
+	return len(entries)
+}
+
+// parseCombinedLogLine 解析一行 combined/common 格式日志，同时回放原始的 User-Agent 与 Referer
+func parseCombinedLogLine(line string) (*requestSpec, time.Time) {
+	m := combinedLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, time.Time{}
+	}
+	started, _ := time.Parse(combinedTimeLayout, m[3])
+	spec := &requestSpec{Method: m[4], URL: m[5], Headers: http.Header{}}
+	if m[8] != "" && m[8] != "-" {
+		spec.Headers.Set("Referer", m[8])
+	}
+	if m[9] != "" && m[9] != "-" {
+		spec.Headers.Set("User-Agent", m[9])
+	}
+	return spec, started
+}
+
+// parseJSONLogLine 解析一行 JSON 日志，识别常见字段名：
+// time/timestamp/ts（RFC3339 或 Unix 秒）、method、url/uri/path/request_uri、user_agent、referer、body
+func parseJSONLogLine(line string) (*requestSpec, time.Time) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, time.Time{}
+	}
+	str := func(keys ...string) string {
+		for _, k := range keys {
+			if v, ok := fields[k].(string); ok && v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	spec := &requestSpec{
+		Method:  strings.ToUpper(str("method", "request_method", "verb")),
+		URL:     str("url", "uri", "request_uri", "path"),
+		Body:    str("body", "request_body"),
+		Headers: http.Header{},
+	}
+	if spec.URL == "" {
+		return nil, time.Time{}
+	}
+	if spec.Method == "" {
+		spec.Method = http.MethodGet
+	}
+	if ua := str("user_agent", "http_user_agent", "userAgent"); ua != "" {
+		spec.Headers.Set("User-Agent", ua)
+	}
+	if referer := str("referer", "http_referer"); referer != "" {
+		spec.Headers.Set("Referer", referer)
+	}
+
+	var started time.Time
+	for _, k := range []string{"time", "timestamp", "ts", "@timestamp"} {
+		switch v := fields[k].(type) {
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				started = t
+			} else if t, err := time.Parse(combinedTimeLayout, v); err == nil {
+				started = t
+			} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+				started = time.Unix(0, int64(f*float64(time.Second)))
+			}
+		case float64:
+			started = time.Unix(0, int64(v*float64(time.Second)))
+		}
+		if !started.IsZero() {
+			break
+		}
+	}
+	return spec, started
+}
+
+// parseSpeed 解析回放速度，支持 "2x"、"0.5x" 与纯数字写法
+func parseSpeed(s string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("invalid replay speed %q", s)
+	}
+	return speed, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"time"
+)
+
+// runReport 为 -o json 的输出内容，也可以作为之后运行的 -baseline
+type runReport struct {
+	Summary    statsSummary      `json:"summary"`
+	Thresholds []thresholdResult `json:"thresholds,omitempty"`
+	// Config 为本次运行实际生效的配置，作为 -baseline 读取时忽略
+	Config *runConfig `json:"config,omitempty"`
+}
+
+// loadBaseline 读取 -o json 的输出或 soak 检查点文件中的汇总结果
+func loadBaseline(filename string) (*statsSummary, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var report runReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("unable to parse baseline %s: %v", filename, err)
+	}
+	if report.Summary.Timestamp.IsZero() {
+		return nil, fmt.Errorf("baseline %s has no summary", filename)
+	}
+	return &report.Summary, nil
+}
+
+// reportJSON 以 JSON 输出最终汇总与规则判定结果
+func reportJSON(w io.Writer, summary statsSummary, results []thresholdResult) {
+	config := effectiveConfig()
+	data, err := json.MarshalIndent(runReport{Summary: summary, Thresholds: results, Config: &config}, "", "  ")
+	if err != nil {
+		slog.Error("Unable to encode report", "err", err)
+		return
+	}
+	fmt.Fprintln(w, string(data))
+}
+
+// reportMarkdown 输出适合直接贴到 PR 评论中的 Markdown 汇总；baseline 非空时附带对比
+func reportMarkdown(w io.Writer, summary statsSummary, results []thresholdResult, baseline *statsSummary, target, method string, concurrency int) {
+	elapsed := time.Duration(summary.ElapsedSeconds * float64(time.Second)).Round(time.Millisecond)
+	fmt.Fprintln(w, "### Load test summary")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "`%s %s` · %d workers · %s\n\n", method, target, concurrency, elapsed)
+	if len(summary.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: `%s`\n\n", formatTags(summary.Tags))
+	}
+
+	type row struct {
+		name     string
+		format   string
+		value    func(s statsSummary) float64
+		isFailed bool
+	}
+	rows := []row{
+		{"Requests", "%.0f", func(s statsSummary) float64 { return float64(s.TotalRequests) }, false},
+		{"Throughput (req/s)", "%.2f", func(s statsSummary) float64 { return s.QPS }, false},
+		{"Successful (req/s)", "%.2f", func(s statsSummary) float64 { return s.TPS }, false},
+		{"P50 (ms)", "%.0f", func(s statsSummary) float64 { return s.P50Ms }, false},
+		{"P95 (ms)", "%.0f", func(s statsSummary) float64 { return s.P95Ms }, false},
+		{"P99 (ms)", "%.0f", func(s statsSummary) float64 { return s.P99Ms }, false},
+		{"Errors", "%.0f", func(s statsSummary) float64 { return float64(s.FailedRequests) }, true},
+	}
+	if summary.Apdex != nil {
+		rows = append(rows, row{"Apdex", "%.2f", func(s statsSummary) float64 { return thresholdMetric(s, "apdex") }, false})
+	}
+
+	if baseline != nil {
+		fmt.Fprintln(w, "| Metric | Value | Baseline | Δ |")
+		fmt.Fprintln(w, "|---|---:|---:|---:|")
+	} else {
+		fmt.Fprintln(w, "| Metric | Value |")
+		fmt.Fprintln(w, "|---|---:|")
+	}
+	for _, r := range rows {
+		value := fmt.Sprintf(r.format, r.value(summary))
+		if r.isFailed {
+			value += fmt.Sprintf(" (%.2f%%)", errorRate(summary))
+		}
+		if baseline == nil {
+			fmt.Fprintf(w, "| %s | %s |\n", r.name, value)
+			continue
+		}
+		base := fmt.Sprintf(r.format, r.value(*baseline))
+		if r.isFailed {
+			base += fmt.Sprintf(" (%.2f%%)", errorRate(*baseline))
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n", r.name, value, base, percentDelta(r.value(summary), r.value(*baseline)))
+	}
+
+	if len(results) > 0 {
+		passed := 0
+		for _, r := range results {
+			if r.Passed {
+				passed++
+			}
+		}
+		fmt.Fprintf(w, "\n**Thresholds:** %d/%d passed\n\n", passed, len(results))
+		fmt.Fprintln(w, "| Threshold | Actual | Result |")
+		fmt.Fprintln(w, "|---|---:|:---:|")
+		for _, r := range results {
+			result := "✅"
+			if !r.Passed {
+				result = "❌"
+			}
+			fmt.Fprintf(w, "| `%s` | %.2f | %s |\n", r.Threshold, r.Actual, result)
+		}
+	}
+}
+
+// percentDelta 返回 value 相对 base 的变化百分比
+func percentDelta(value, base float64) string {
+	if base == 0 {
+		if value == 0 {
+			return "0.0%"
+		}
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", (value-base)/base*100)
+}
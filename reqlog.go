@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestIDHeader 非空时为每个请求生成一个 UUID 并写入该 header，便于与服务端日志关联
+var requestIDHeader string
+
+// requestLogEntry 为请求日志中的一行
+type requestLogEntry struct {
+	// Time 为请求开始的墙钟时间（按 -timezone），OffsetMs 为其相对运行开始的单调时钟偏移
+	Time      time.Time `json:"time"`
+	OffsetMs  float64   `json:"offset_ms"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Target    string    `json:"target,omitempty"`
+	// Tags 为 -scenarios 中请求的标签，便于事后按标签切分
+	Tags map[string]string `json:"tags,omitempty"`
+	// Status 为 0 时表示请求未得到响应，原因见 Error
+	Status     int     `json:"status,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Bytes      int64   `json:"bytes"`
+}
+
+// requestLogger 把每个请求的结果按 NDJSON（每行一个 JSON 对象）写入文件，可并发调用
+type requestLogger struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// requestLog 为 -request-log 打开的请求日志，为 nil 时不记录
+var requestLog *requestLogger
+
+// openRequestLog 创建请求日志文件
+func openRequestLog(filename string) (*requestLogger, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &requestLogger{f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+// write 追加一行日志；l 为 nil 时不做任何事
+func (l *requestLogger) write(entry requestLogEntry) {
+	if l == nil {
+		return
+	}
+	entry.OffsetMs = offsetMs(entry.Time)
+	entry.Time = wallTime(entry.Time)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enc.Encode(entry)
+}
+
+// Close 写出缓冲区并关闭文件
+func (l *requestLogger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.w.Flush(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// maxFailedIDs 为记录并输出的失败请求 ID 的最大数量
+const maxFailedIDs = 20
+
+// appendFailedID 记录失败请求的 ID，最多保留 maxFailedIDs 个
+func appendFailedID(ids []string, id string) []string {
+	if id == "" || len(ids) >= maxFailedIDs {
+		return ids
+	}
+	return append(ids, id)
+}
+
+// reportFailedIDs 输出失败请求的 ID，便于在服务端日志中查找对应的请求
+func reportFailedIDs(ids []string, failed int64) {
+	if requestIDHeader == "" || len(ids) == 0 {
+		return
+	}
+	fmt.Printf("\n🆔  Failed Request IDs (%s):\n", requestIDHeader)
+	fmt.Printf("  %s\n", strings.Join(ids, "\n  "))
+	if failed > int64(len(ids)) {
+		fmt.Printf("  ... and %d more (see -request-log for all of them)\n", failed-int64(len(ids)))
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// requestSpec 描述一个待发送的请求；Method、URL 为空时分别使用 -X 与 -url
+type requestSpec struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    string
+	// Name 非空时按名称（而非 URL）聚合统计
+	Name string
+	// Weight 为混合压测时被选中的相对权重
+	Weight float64
+	// Offset 为录制时相对第一个请求的时间偏移，仅在按原始节奏回放时使用
+	Offset time.Duration
+	// Scheduled 为按 -rate 或录制节奏投递时该请求计划的发送时间，用于检测压测工具自身的调度延迟
+	Scheduled time.Time
+	// Target 为 -targets-compare 下该请求发往的目标，用于分别统计
+	Target string
+	// SHA256 为 bodyfile 中指定的响应 body 应有的哈希
+	SHA256 string
+	// URLTemplate 为渲染前的 URL 模板，按 URL 统计时以它代替实际 URL，避免每个不同的路径各占一行
+	URLTemplate string
+	// Query 为 -param 渲染出的查询参数，发送时追加到 URL
+	Query url.Values
+	// Timeout 为该请求的超时，0 时使用客户端的超时
+	Timeout time.Duration
+	// Extract 为 -scenarios 中从响应提取变量与自定义指标的规则
+	Extract []*extractRule
+	// Tags 为 -scenarios 中请求的标签，按标签分组统计并供 -report-filter 筛选
+	Tags map[string]string
+	// UserAgent 为 renderSpec 按 -user-agents 为该请求选中的 User-Agent，为空时按 pickUserAgent 选择
+	UserAgent string
+	// release 为 -burst 下该请求所在一轮的屏障，关闭后才发送
+	release <-chan struct{}
+	// urlTpl、bodyTpl 为编译后的 URL 与请求体模板，不含模板时为 nil
+	urlTpl  *requestTemplate
+	bodyTpl *requestTemplate
+	// headerTpls 为包含模板的 header 值，下标与 Headers 中的值对应，不含模板的值为 nil
+	headerTpls map[string][]*requestTemplate
+}
+
+// requestPool 为所有可发送请求的集合，由 -bodyfile、-har 等加载
+var requestPool []*requestSpec
+
+// requestWeights 为 requestPool 的累计权重，为空时均匀随机选择
+var requestWeights []float64
+
+// buildRequestWeights 在加载完所有请求后计算累计权重；所有请求权重均为 0 时保持均匀选择
+func buildRequestWeights() {
+	requestWeights = nil
+	var total float64
+	cumulative := make([]float64, len(requestPool))
+	for i, spec := range requestPool {
+		total += spec.Weight
+		cumulative[i] = total
+	}
+	if total > 0 {
+		requestWeights = cumulative
+	}
+}
+
+// pickWeightedRequest 按累计权重随机选择一个请求
+func pickWeightedRequest(rng *rand.Rand) *requestSpec {
+	return requestPool[pickWeighted(requestWeights, rng)]
+}
+
+// pickWeighted 按累计权重 cumulative 用 rng 随机选择一项，返回其下标
+func pickWeighted(cumulative []float64, rng *rand.Rand) int {
+	target := rng.Float64() * cumulative[len(cumulative)-1]
+	i := sort.SearchFloat64s(cumulative, target)
+	// SearchFloat64s 返回第一个 >= target 的位置，权重为 0 的请求与前一项累计值相同因而不会被选中
+	for i < len(cumulative)-1 && cumulative[i] <= target {
+		i++
+	}
+	return i
+}
+
+// newHTTPRequest 根据 requestSpec 构造 http.Request，请求自带的 header 覆盖默认 header
+func newHTTPRequest(spec *requestSpec, defaultURL, defaultMethod string) (*http.Request, error) {
+	method := spec.Method
+	if method == "" {
+		method = defaultMethod
+	}
+	reqURL := spec.URL
+	if reqURL == "" {
+		reqURL = defaultURL
+	}
+	payload := spec.Body
+	if protoRequest != nil && payload != "" {
+		encoded, err := encodeProtoJSON(protoRequest, payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = string(encoded)
+	}
+	var body io.Reader = http.NoBody
+	if chunkedBody && payload != "" {
+		body = newPacedReader(payload)
+	}
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if !chunkedBody && payload != "" {
+		setPooledBody(req, payload)
+	}
+	req.Header = pooledHeader()
+	appendQuery(req.URL, spec.Query)
+	if chunkedBody && spec.Body != "" {
+		// 长度未知的 body 由 Transport 以 chunked 编码发送
+		req.TransferEncoding = []string{"chunked"}
+	}
+	userAgent := spec.UserAgent
+	if userAgent == "" {
+		userAgent = pickUserAgent(sharedRand)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Content-Type", "application/json")
+	if protoRequest != nil {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	} else if contentType := xmlContentType(payload); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+		if soapAction != "" && !strings.HasPrefix(contentType, "application/soap+xml") {
+			req.Header.Set("SOAPAction", `"`+soapAction+`"`)
+		}
+	}
+	if protoResponse != nil {
+		req.Header.Set("Accept", "application/x-protobuf")
+	}
+	if expectContinue && spec.Body != "" {
+		req.Header.Set("Expect", "100-continue")
+	}
+	for name, values := range spec.Headers {
+		req.Header[name] = values
+	}
+	return req, nil
+}
+
+// templateStatsKey 返回 URL 模板的统计 key：模板中的每个动作写作 {名称}，如
+// http://host/users/{{.id}}/orders/{{uuid}} 统计为 http://host/users/{id}/orders/{uuid}，并去掉查询参数
+func templateStatsKey(method, tpl string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(tpl, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(tpl[start:], "}}")
+		if end < 0 {
+			break
+		}
+		action := strings.Trim(tpl[start+2:start+end], "- ")
+		name := action
+		if fields := strings.Fields(action); len(fields) > 0 {
+			name = strings.TrimLeft(fields[0], ".$")
+		}
+		b.WriteString(tpl[:start])
+		b.WriteString("{" + name + "}")
+		tpl = tpl[start+end+2:]
+	}
+	b.WriteString(tpl)
+	key := b.String()
+	if i := strings.IndexAny(key, "?#"); i >= 0 {
+		key = key[:i]
+	}
+	return method + " " + key
+}
+
+// urlStatsKey 返回按 URL 聚合统计时使用的 key：方法 + 不含查询参数的 URL
+func urlStatsKey(method, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	return method + " " + u.String()
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// resolverAddr、dohURL 为 -resolver 与 -doh：把压测请求的域名解析固定到指定的 DNS 服务器或 DNS-over-HTTPS 地址，
+// 不依赖压测机的系统解析器；每个域名的解析结果在首次解析与结果变化时输出到日志，结束时汇总。
+// /etc/hosts 中的域名仍按 hosts 解析
+var (
+	resolverAddr string
+	dohURL       string
+)
+
+// dialResolver 为 -resolver 或 -doh 对应的解析器，未设置时为 nil，即使用系统解析器
+var dialResolver *net.Resolver
+
+// dnsTimeout 为一次 DNS 查询的超时时间
+const dnsTimeout = 5 * time.Second
+
+// setupResolver 按 -resolver 或 -doh 创建 dialResolver
+func setupResolver() error {
+	switch {
+	case resolverAddr != "" && dohURL != "":
+		return fmt.Errorf("-resolver cannot be combined with -doh")
+	case resolverAddr != "":
+		addr := resolverAddr
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "53")
+		}
+		if host, _, _ := net.SplitHostPort(addr); net.ParseIP(host) == nil {
+			return fmt.Errorf("invalid -resolver %q (expected an IP address with an optional port, e.g. 1.1.1.1:53)", resolverAddr)
+		}
+		resolverAddr = addr
+		dialResolver = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: dnsTimeout}).DialContext(ctx, network, addr)
+		}}
+	case dohURL != "":
+		if u, err := neturl.Parse(dohURL); err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return fmt.Errorf("invalid -doh %q (expected a URL such as https://dns.google/dns-query)", dohURL)
+		}
+		client := &http.Client{Timeout: dnsTimeout}
+		dialResolver = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, client: client}, nil
+		}}
+	}
+	return nil
+}
+
+// describeResolver 返回使用的解析器，未设置 -resolver 与 -doh 时返回空字符串
+func describeResolver() string {
+	switch {
+	case resolverAddr != "":
+		return resolverAddr
+	case dohURL != "":
+		return "DoH " + dohURL
+	}
+	return ""
+}
+
+// dohConn 把 Go 解析器经 TCP 格式（2 字节长度前缀）写出的 DNS 查询以 RFC 8484 的 POST 发送到 -doh，
+// 并把应答按同样的格式交给解析器读取
+type dohConn struct {
+	ctx      context.Context
+	client   *http.Client
+	deadline time.Time
+	query    bytes.Buffer
+	answer   bytes.Buffer
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.query.Write(b)
+	for c.query.Len() >= 2 {
+		size := int(binary.BigEndian.Uint16(c.query.Bytes()))
+		if c.query.Len() < 2+size {
+			break
+		}
+		msg := c.query.Next(2 + size)[2:]
+		answer, err := c.exchange(msg)
+		if err != nil {
+			return 0, err
+		}
+		binary.Write(&c.answer, binary.BigEndian, uint16(len(answer)))
+		c.answer.Write(answer)
+	}
+	return len(b), nil
+}
+
+// exchange 发送一个 DNS 查询并返回应答
+func (c *dohConn) exchange(msg []byte) ([]byte, error) {
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dohURL, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned %d", resp.StatusCode)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.answer.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.answer.Read(b)
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr{} }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// dohAddr 为 dohConn 的地址
+type dohAddr struct{}
+
+func (dohAddr) Network() string { return "https" }
+func (dohAddr) String() string  { return dohURL }
+
+// resolution 为一个域名的解析统计，Addrs 为出现过的所有地址
+type resolution struct {
+	Lookups  int64
+	Failures int64
+	Changes  int64
+	Total    time.Duration
+	Addrs    map[string]bool
+	last     string
+}
+
+// resolutions 为 -resolver 或 -doh 下各域名的解析统计，由 DNSDone 回调记录
+var resolutions = struct {
+	mu    sync.Mutex
+	hosts map[string]*resolution
+}{hosts: make(map[string]*resolution)}
+
+// recordResolution 记录一次域名解析，在首次解析与解析结果变化时输出日志；未设置 -resolver 与 -doh 时不做任何事
+func recordResolution(host string, info httptrace.DNSDoneInfo, d time.Duration) {
+	if dialResolver == nil || host == "" {
+		return
+	}
+	addrs := make([]string, 0, len(info.Addrs))
+	for _, addr := range info.Addrs {
+		addrs = append(addrs, addr.IP.String())
+	}
+	sort.Strings(addrs)
+	answer := strings.Join(addrs, ", ")
+	resolutions.mu.Lock()
+	r := resolutions.hosts[host]
+	if r == nil {
+		r = &resolution{Addrs: make(map[string]bool)}
+		resolutions.hosts[host] = r
+	}
+	r.Lookups++
+	r.Total += d
+	first := r.Lookups == 1
+	changed := false
+	if info.Err != nil {
+		r.Failures++
+	} else {
+		changed = !first && answer != r.last
+		if changed {
+			r.Changes++
+		}
+		r.last = answer
+		for _, addr := range addrs {
+			r.Addrs[addr] = true
+		}
+	}
+	resolutions.mu.Unlock()
+	switch {
+	case info.Err != nil:
+		slog.Debug("DNS lookup failed", "host", host, "resolver", describeResolver(), "err", info.Err)
+	case first:
+		slog.Info("Resolved", "host", host, "addrs", answer, "resolver", describeResolver(), "ms", fmt.Sprintf("%.1f", durationMs(d)))
+	case changed:
+		slog.Info("DNS answer changed", "host", host, "addrs", answer, "resolver", describeResolver())
+	default:
+		slog.Debug("Resolved", "host", host, "addrs", answer, "ms", fmt.Sprintf("%.1f", durationMs(d)))
+	}
+}
+
+// resolveTarget 在压测开始前用 dialResolver 解析 target 的域名，返回启动时输出的说明；IP 地址或解析器未设置时返回空字符串
+func resolveTarget(target string) string {
+	u, err := neturl.Parse(target)
+	if dialResolver == nil || err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(runContext, dnsTimeout)
+	defer cancel()
+	start := time.Now()
+	addrs, err := dialResolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Sprintf("%s does not resolve: %v", u.Hostname(), err)
+	}
+	return fmt.Sprintf("%s → %s (%s)", u.Hostname(), strings.Join(addrs, ", "), time.Since(start).Round(100*time.Microsecond))
+}
+
+// reportResolutions 输出各域名的解析次数、失败与出现过的地址
+func reportResolutions() {
+	if dialResolver == nil || len(resolutions.hosts) == 0 {
+		return
+	}
+	hosts := make([]string, 0, len(resolutions.hosts))
+	for host := range resolutions.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	fmt.Printf("\n🧭  DNS Resolution (%s):\n", describeResolver())
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Host", "Lookups", "Failed", "Avg", "Answer Changes", "Addresses"})
+	for _, host := range hosts {
+		r := resolutions.hosts[host]
+		addrs := make([]string, 0, len(r.Addrs))
+		for addr := range r.Addrs {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		table.Append([]string{host, fmt.Sprintf("%d", r.Lookups), fmt.Sprintf("%d", r.Failures),
+			fmt.Sprintf("%.1f ms", durationMs(r.Total/time.Duration(r.Lookups))), fmt.Sprintf("%d", r.Changes), strings.Join(addrs, ", ")})
+	}
+	table.Render()
+}
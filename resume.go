@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"time"
+)
+
+// resumeFile 为 -resume：从该检查点继续中断的 soak 测试，计数、直方图与趋势沿用检查点中的值，只运行 -soak 剩余的时长
+var resumeFile string
+
+// checkpointState 为检查点中续跑所需、而汇总结果中没有的累计值
+type checkpointState struct {
+	// Elapsed 为写检查点时已运行的时长
+	Elapsed   time.Duration `json:"elapsed"`
+	TotalTime time.Duration `json:"total_time"`
+	Apdex     apdexCounts   `json:"apdex"`
+	Digest    sparseDigest  `json:"digest"`
+}
+
+// resumedCheckpoint 为 -resume 读取的检查点，未续跑时为 nil
+var resumedCheckpoint *soakCheckpoint
+
+// loadCheckpoint 读取 -resume 指定的检查点，soak 为本次的 -soak，须长于检查点中已运行的时长
+func loadCheckpoint(filename string, soak time.Duration) (*soakCheckpoint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint soakCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("unable to parse checkpoint %s: %v", filename, err)
+	}
+	if checkpoint.State == nil {
+		return nil, fmt.Errorf("checkpoint %s has no resume state (written by an older version?)", filename)
+	}
+	if soak <= 0 {
+		return nil, fmt.Errorf("-resume continues a soak test, set -soak to the run's total duration")
+	}
+	if checkpoint.State.Elapsed >= soak {
+		return nil, fmt.Errorf("the run in %s already lasted %s, which is not shorter than -soak %s", filename, checkpoint.State.Elapsed.Round(time.Second), soak)
+	}
+	return &checkpoint, nil
+}
+
+// restore 把检查点中的累计值计入 ws（worker 0 的累计统计）并恢复趋势数组，返回检查点中已运行的时长
+func (c *soakCheckpoint) restore(ws *WorkerStats) time.Duration {
+	ws.TotalRequests += c.Summary.TotalRequests
+	ws.SuccessRequests += c.Summary.SuccessRequests
+	ws.FailedRequests += c.Summary.FailedRequests
+	ws.Late.TotalRequests += c.Summary.LateRequests
+	ws.TotalTime += c.State.TotalTime
+	for code, count := range c.Summary.StatusCodes {
+		ws.StatusCodes[code] += count
+	}
+	ws.Apdex.add(c.State.Apdex)
+	ws.Digest.add(c.State.Digest.digest())
+
+	trendTimes = append([]time.Time(nil), c.Trend.Time...)
+	tpsHistory = append([]float64(nil), c.Trend.TPS...)
+	qpsHistory = append([]float64(nil), c.Trend.QPS...)
+	p50History = append([]float64(nil), c.Trend.P50Ms...)
+	p95History = append([]float64(nil), c.Trend.P95Ms...)
+	p99History = append([]float64(nil), c.Trend.P99Ms...)
+	apdexHistory = append([]float64(nil), c.Trend.Apdex...)
+	for name, series := range c.Trend.Metrics {
+		metricHistory[name] = append([]float64(nil), series...)
+	}
+	return c.State.Elapsed
+}
+
+// reportResume 输出续跑的起点，检查点来自另一个目标时给出警告
+func (c *soakCheckpoint) reportResume(filename, target string, soak time.Duration) {
+	fmt.Printf("🔁  Resuming from %s (written %s): %d requests over %s, %s left\n", filename,
+		c.Summary.Timestamp.Format(time.RFC3339), c.Summary.TotalRequests, c.State.Elapsed.Round(time.Second), (soak - c.State.Elapsed).Round(time.Second))
+	if c.Config != nil {
+		if prev, cur := c.Config.Flags["url"], redactFlag("url", target); prev != "" && prev != cur {
+			slog.Warn(fmt.Sprintf("The checkpoint was written by a run against %s, not -url %s", prev, cur))
+		}
+	}
+}
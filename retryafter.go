@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// respectRetryAfter 为 true 时，收到带 Retry-After 的 429/503 响应的 worker 按其要求暂停后再发下一个请求
+var respectRetryAfter bool
+
+// retryAfterMax 为单次暂停的上限，防止服务端返回过长的 Retry-After 让压测停滞
+var retryAfterMax time.Duration
+
+// throttleCounts 为因 Retry-After 暂停的次数与累计时长
+type throttleCounts struct {
+	Backoffs int64
+	Time     time.Duration
+}
+
+// add 合并另一个 worker 的计数
+func (t *throttleCounts) add(other throttleCounts) {
+	t.Backoffs += other.Backoffs
+	t.Time += other.Time
+}
+
+// throttleSummary 为导出汇总中的限流统计
+type throttleSummary struct {
+	Backoffs int64   `json:"backoffs"`
+	Seconds  float64 `json:"seconds"`
+	// Share 为暂停时长占所有 worker 运行时长的百分比
+	Share float64 `json:"share_pct"`
+}
+
+// summary 按 workers 个 worker 运行 elapsed 的总时长计算限流统计；未开启 -respect-retry-after 时返回 nil
+func (t throttleCounts) summary(elapsed time.Duration, workers int) *throttleSummary {
+	if !respectRetryAfter {
+		return nil
+	}
+	s := &throttleSummary{Backoffs: t.Backoffs, Seconds: t.Time.Seconds()}
+	if total := elapsed.Seconds() * float64(workers); total > 0 {
+		s.Share = s.Seconds / total * 100
+	}
+	return s
+}
+
+// retryAfterDelay 返回 429/503 响应要求的等待时长，不需要等待时返回 0；
+// Retry-After 可以是秒数或 HTTP 日期
+func retryAfterDelay(resp *http.Response, now time.Time) time.Duration {
+	if !respectRetryAfter || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	var delay time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		delay = time.Duration(seconds * float64(time.Second))
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = at.Sub(now)
+	}
+	if delay < 0 {
+		return 0
+	}
+	if retryAfterMax > 0 && delay > retryAfterMax {
+		delay = retryAfterMax
+	}
+	return delay
+}
+
+// backoff 暂停 d，压测需要停止时提前返回，返回实际暂停的时长
+func backoff(d time.Duration) time.Duration {
+	start := time.Now()
+	deadline := start.Add(d)
+	for !shouldStop() {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		if remaining > 100*time.Millisecond {
+			remaining = 100 * time.Millisecond
+		}
+		time.Sleep(remaining)
+	}
+	return time.Since(start)
+}
+
+// reportThrottling 输出因 Retry-After 暂停的次数与时长
+func reportThrottling(s *throttleSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Println("\n⏳  Retry-After Throttling:")
+	fmt.Printf("  - Backoffs: %d\n", s.Backoffs)
+	fmt.Printf("  - Time Throttled: %.2f s (%.1f%% of worker time)\n", s.Seconds, s.Share)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// 判定压测工具自身饱和的阈值：一秒内至少 saturatedShare 的请求晚于计划 lateThreshold 以上发出，
+// 或等待可用连接超过 lateThreshold，或出现本地端口耗尽，或 CPU 达到 cpuBottleneckPercent，即认为这一秒是饱和的
+const (
+	lateThreshold  = 10 * time.Millisecond
+	saturatedShare = 0.1
+)
+
+// saturationCounters 为热路径上以原子操作累计的饱和迹象
+type saturationCounters struct {
+	requests  int64
+	scheduled int64
+	late      int64
+	lagSum    int64
+	lagMax    int64
+	waited    int64
+	waitSum   int64
+	waitMax   int64
+	// portErrors 为本地端口耗尽（EADDRNOTAVAIL）导致的拨号失败
+	portErrors int64
+}
+
+// saturation 为整次压测的饱和迹象
+var saturation saturationCounters
+
+// storeMax 以 CAS 更新最大值
+func storeMax(addr *int64, v int64) {
+	for {
+		old := atomic.LoadInt64(addr)
+		if v <= old || atomic.CompareAndSwapInt64(addr, old, v) {
+			return
+		}
+	}
+}
+
+// record 记录一个请求的调度延迟（scheduled 非零时）、连接等待时间与拨号错误；start 为实际开始发送的时间
+func (c *saturationCounters) record(scheduled, start time.Time, trace *requestTrace, err error) {
+	atomic.AddInt64(&c.requests, 1)
+	if !scheduled.IsZero() {
+		lag := start.Sub(scheduled)
+		if lag < 0 {
+			lag = 0
+		}
+		atomic.AddInt64(&c.scheduled, 1)
+		atomic.AddInt64(&c.lagSum, int64(lag))
+		storeMax(&c.lagMax, int64(lag))
+		if lag > lateThreshold {
+			atomic.AddInt64(&c.late, 1)
+		}
+	}
+	if wait := trace.poolWait(); wait > lateThreshold {
+		atomic.AddInt64(&c.waited, 1)
+		atomic.AddInt64(&c.waitSum, int64(wait))
+		storeMax(&c.waitMax, int64(wait))
+	}
+	if err != nil && errors.Is(err, syscall.EADDRNOTAVAIL) {
+		atomic.AddInt64(&c.portErrors, 1)
+	}
+}
+
+// saturationDetector 每秒比较一次计数器的增量，记录出现饱和的秒数及首次饱和时的吞吐
+type saturationDetector struct {
+	last      saturationCounters
+	lastTime  time.Time
+	seconds   int
+	saturated int
+	// firstRPS 为第一次饱和的那一秒的请求速率
+	firstRPS float64
+	reasons  map[string]int
+}
+
+// newSaturationDetector 以 start 为起点创建检测
+func newSaturationDetector(start time.Time) *saturationDetector {
+	return &saturationDetector{lastTime: start, reasons: make(map[string]int)}
+}
+
+// snapshot 读取计数器的当前值
+func (c *saturationCounters) snapshot() saturationCounters {
+	return saturationCounters{
+		requests:   atomic.LoadInt64(&c.requests),
+		scheduled:  atomic.LoadInt64(&c.scheduled),
+		late:       atomic.LoadInt64(&c.late),
+		waited:     atomic.LoadInt64(&c.waited),
+		portErrors: atomic.LoadInt64(&c.portErrors),
+	}
+}
+
+// sample 检查自上一秒以来是否出现饱和；cpuPercent 为这一秒压测工具的 CPU 使用率，由每秒的 ticker 调用
+func (d *saturationDetector) sample(now time.Time, cpuPercent float64) {
+	cur := saturation.snapshot()
+	requests := cur.requests - d.last.requests
+	var reasons []string
+	if scheduled := cur.scheduled - d.last.scheduled; scheduled > 0 && float64(cur.late-d.last.late) >= saturatedShare*float64(scheduled) {
+		reasons = append(reasons, "send lag")
+	}
+	if requests > 0 && float64(cur.waited-d.last.waited) >= saturatedShare*float64(requests) {
+		reasons = append(reasons, "connection wait")
+	}
+	if cur.portErrors > d.last.portErrors {
+		reasons = append(reasons, "port exhaustion")
+	}
+	if cpuPercent >= cpuBottleneckPercent {
+		reasons = append(reasons, "CPU")
+	}
+	d.seconds++
+	if len(reasons) > 0 {
+		if d.saturated == 0 {
+			if elapsed := now.Sub(d.lastTime).Seconds(); elapsed > 0 {
+				d.firstRPS = float64(requests) / elapsed
+			}
+		}
+		d.saturated++
+		for _, r := range reasons {
+			d.reasons[r]++
+		}
+	}
+	d.last = cur
+	d.lastTime = now
+}
+
+// report 在出现过饱和的秒时输出各项指标，并提示高于首次饱和时速率的结果受压测工具限制
+func (d *saturationDetector) report() {
+	total := saturation.snapshot()
+	lagMax := time.Duration(atomic.LoadInt64(&saturation.lagMax))
+	waitMax := time.Duration(atomic.LoadInt64(&saturation.waitMax))
+	if d.saturated == 0 {
+		return
+	}
+	fmt.Println("\n🚦  Load Generator Saturation:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Symptom", "Requests", "Average", "Max"})
+	table.SetAutoWrapText(false)
+	avg := func(sum, n int64) string {
+		if n == 0 {
+			return "-"
+		}
+		return (time.Duration(sum / n)).Round(time.Microsecond).String()
+	}
+	if total.scheduled > 0 {
+		table.Append([]string{fmt.Sprintf("Sent > %s after schedule", lateThreshold), fmt.Sprintf("%d of %d", total.late, total.scheduled),
+			avg(atomic.LoadInt64(&saturation.lagSum), total.scheduled), lagMax.Round(time.Microsecond).String()})
+	}
+	table.Append([]string{fmt.Sprintf("Waited > %s for a connection", lateThreshold), fmt.Sprintf("%d of %d", total.waited, total.requests),
+		avg(atomic.LoadInt64(&saturation.waitSum), total.waited), waitMax.Round(time.Microsecond).String()})
+	table.Append([]string{"Ephemeral port exhaustion", fmt.Sprintf("%d", total.portErrors), "-", "-"})
+	table.Render()
+	var reasons []string
+	for _, r := range []string{"send lag", "connection wait", "port exhaustion", "CPU"} {
+		if n := d.reasons[r]; n > 0 {
+			reasons = append(reasons, fmt.Sprintf("%s %ds", r, n))
+		}
+	}
+	fmt.Printf("⚠️   The load generator was saturated in %d of %d seconds (%s), first at ~%.0f req/s: results above that rate are generator-limited, not target-limited. Raise -c or spread the load over more instances.\n",
+		d.saturated, d.seconds, strings.Join(reasons, ", "), d.firstRPS)
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+// scenariosFile 为 -scenarios，同时运行的多个场景的 YAML（或 JSON）配置
+var scenariosFile string
+
+// scenarios 为 -scenarios 加载的场景；workerScenarios 为每个 worker 所属的场景
+var (
+	scenarios       []*scenarioConfig
+	workerScenarios []*scenarioConfig
+)
+
+// scenarioConfig 为一个场景：自己的 worker 数、请求数或速率，以及请求组合。
+//   - Weight 决定分到的 -c 与 -n 的份额，设置了 Concurrency 时 worker 数以它为准
+//   - Rate 大于 0 时按开放模型以该速率投递
+//   - Journey 为 true 时每个 worker 按顺序执行 Requests（每轮一个新会话、一行数据），否则按请求的权重随机选择
+type scenarioConfig struct {
+	Name        string  `yaml:"name"`
+	Weight      float64 `yaml:"weight"`
+	Concurrency int     `yaml:"concurrency"`
+	Rate        float64 `yaml:"rate"`
+	Journey     bool    `yaml:"journey"`
+	// Tags 为场景中所有请求共有的标签，请求自己的同名标签优先；每个请求还自动带有 scenario=<Name>
+	Tags map[string]string `yaml:"tags"`
+	// IncludeThinkTime 为 true 时事务的耗时包含其最后一步之后的思考时间
+	IncludeThinkTime bool              `yaml:"include_think_time"`
+	Requests         []scenarioRequest `yaml:"requests"`
+
+	specs   []*requestSpec
+	weights []float64
+	workers int
+	total   int
+	sched   *scheduler
+	jobs    chan *requestSpec
+	// first 为该场景第一个 worker 的下标，场景的 worker 连续编号
+	first int
+	// passes、aborted、skipped、retries 为按顺序执行时开始的轮数、放弃的轮数、跳过的步骤数与重试次数，由各 worker 原子累加
+	passes, aborted, skipped, retries int64
+	// runners 为按顺序执行时各 worker 的进度，下标为 worker 在场景中的序号，用于结束后汇总事务
+	runners []*journeyRunner
+}
+
+// scenarioRequest 为场景中的一个请求；Method、URL 为空时分别使用 -X 与 -url。
+// 以下设置只用于按顺序执行的场景（Timeout 除外）：
+//   - OnFailure 为失败时的处理：continue（默认）继续下一步，abort 放弃这一轮，retry 重试 Retries 次（默认 1）后仍失败则放弃这一轮
+//   - When 非空时只有之前的响应满足条件才执行这一步，否则跳过
+//   - Think 为这一步之后的思考时间
+//   - Transaction 非空时这一步属于该事务，连续执行的同名步骤合起来计时
+//
+// Extract 在两种场景中都可使用：取到的数值可记为自定义指标，按顺序执行时取到的值还作为后续步骤的变量
+type scenarioRequest struct {
+	Name        string            `yaml:"name"`
+	Method      string            `yaml:"method"`
+	URL         string            `yaml:"url"`
+	Headers     map[string]string `yaml:"headers"`
+	Body        string            `yaml:"body"`
+	Weight      float64           `yaml:"weight"`
+	Timeout     time.Duration     `yaml:"timeout"`
+	OnFailure   string            `yaml:"on_failure"`
+	Retries     int               `yaml:"retries"`
+	When        *stepCondition    `yaml:"when"`
+	Think       time.Duration     `yaml:"think"`
+	Transaction string            `yaml:"transaction"`
+	Extract     []*extractRule    `yaml:"extract"`
+	Tags        map[string]string `yaml:"tags"`
+}
+
+// stepCondition 为步骤的执行条件：Step 为空时看这一轮中上一个执行的步骤，否则看这一轮中名为 Step 的步骤；
+// Status 非空时其状态码须在其中，Success 非空时其是否成功须与之相同。被看的步骤在这一轮中没有执行时条件不满足
+type stepCondition struct {
+	Step    string `yaml:"step"`
+	Status  []int  `yaml:"status"`
+	Success *bool  `yaml:"success"`
+}
+
+// checkStep 校验场景中第 i 个请求的失败处理与执行条件
+func (sc *scenarioConfig) checkStep(i int) error {
+	r := &sc.Requests[i]
+	if r.Timeout < 0 || r.Retries < 0 || r.Think < 0 {
+		return fmt.Errorf("scenario %s: timeout, retries and think must not be negative", sc.Name)
+	}
+	switch r.OnFailure {
+	case "", "continue", "abort", "retry":
+	default:
+		return fmt.Errorf("scenario %s: unknown on_failure %q (expected abort, continue or retry)", sc.Name, r.OnFailure)
+	}
+	if !sc.Journey && (r.OnFailure != "" || r.Retries > 0 || r.When != nil || r.Think > 0 || r.Transaction != "") {
+		return fmt.Errorf("scenario %s: on_failure, retries, when, think and transaction require journey: true", sc.Name)
+	}
+	if r.OnFailure == "retry" && r.Retries == 0 {
+		r.Retries = 1
+	}
+	for _, rule := range r.Extract {
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("scenario %s: %v", sc.Name, err)
+		}
+	}
+	if r.When == nil || r.When.Step == "" {
+		return nil
+	}
+	for _, prev := range sc.Requests[:i] {
+		if prev.Name == r.When.Step {
+			return nil
+		}
+	}
+	return fmt.Errorf("scenario %s: when.step %q does not name an earlier request", sc.Name, r.When.Step)
+}
+
+// loadScenarios 读取场景配置，按权重把 concurrency 个 worker 与 total 个请求分给各场景，返回实际的 worker 总数
+func loadScenarios(filename string, concurrency, total int) (int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read scenarios: %v", err)
+	}
+	var loaded []*scenarioConfig
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return 0, fmt.Errorf("unable to parse scenarios: %v", err)
+	}
+	if len(loaded) == 0 {
+		return 0, fmt.Errorf("no scenarios defined in %s", filename)
+	}
+	var weightSum, sharedWeight float64
+	seen := make(map[string]bool)
+	for i, sc := range loaded {
+		if sc.Name == "" {
+			return 0, fmt.Errorf("scenario %d: name is required", i+1)
+		}
+		if seen[sc.Name] {
+			return 0, fmt.Errorf("scenario %s is defined twice", sc.Name)
+		}
+		seen[sc.Name] = true
+		if len(sc.Requests) == 0 {
+			return 0, fmt.Errorf("scenario %s: no requests", sc.Name)
+		}
+		if sc.Weight < 0 || sc.Concurrency < 0 || sc.Rate < 0 {
+			return 0, fmt.Errorf("scenario %s: weight, concurrency and rate must not be negative", sc.Name)
+		}
+		if sc.Weight == 0 {
+			sc.Weight = 1
+		}
+		weightSum += sc.Weight
+		if sc.Concurrency == 0 {
+			sharedWeight += sc.Weight
+		}
+		var cumulative float64
+		for i, r := range sc.Requests {
+			if err := sc.checkStep(i); err != nil {
+				return 0, err
+			}
+			spec := &requestSpec{Name: r.Name, Method: r.Method, URL: r.URL, Body: r.Body, Weight: r.Weight, Timeout: r.Timeout, Extract: r.Extract, Headers: http.Header{}}
+			for name, value := range r.Headers {
+				spec.Headers.Set(name, value)
+			}
+			spec.Tags = map[string]string{"scenario": sc.Name}
+			for _, tags := range []map[string]string{sc.Tags, r.Tags} {
+				for k, v := range tags {
+					spec.Tags[k] = v
+				}
+			}
+			if r.Weight < 0 {
+				return 0, fmt.Errorf("scenario %s: request weight must not be negative", sc.Name)
+			}
+			cumulative += r.Weight
+			sc.specs = append(sc.specs, spec)
+			sc.weights = append(sc.weights, cumulative)
+		}
+		if cumulative == 0 {
+			sc.weights = nil
+		}
+	}
+	workers := 0
+	for _, sc := range loaded {
+		sc.workers = sc.Concurrency
+		if sc.workers == 0 {
+			sc.workers = int(math.Round(float64(concurrency) * sc.Weight / sharedWeight))
+			if sc.workers < 1 {
+				sc.workers = 1
+			}
+		}
+		sc.first = workers
+		workers += sc.workers
+		sc.total = int(math.Round(float64(total) * sc.Weight / weightSum))
+		if total == math.MaxInt32 {
+			sc.total = total
+		}
+		for i := 0; i < sc.workers; i++ {
+			workerScenarios = append(workerScenarios, sc)
+		}
+		if sc.Journey {
+			sc.runners = make([]*journeyRunner, sc.workers)
+		}
+	}
+	scenarios = loaded
+	return workers, nil
+}
+
+// scenarioRequestSpecs 返回所有场景的请求，用于编译模板与预检
+func scenarioRequestSpecs() []*requestSpec {
+	var specs []*requestSpec
+	for _, sc := range scenarios {
+		specs = append(specs, sc.specs...)
+	}
+	return specs
+}
+
+// pick 返回场景中的第 i 个请求：按顺序执行时为占位，由 worker 换为自己的下一步，否则按权重随机选择
+func (sc *scenarioConfig) pick(i int) *requestSpec {
+	if sc.Journey {
+		return emptyRequest
+	}
+	rng := requestRand(int64(i)+1, randBody)
+	if sc.weights == nil {
+		return sc.specs[rng.Intn(len(sc.specs))]
+	}
+	return sc.specs[pickWeighted(sc.weights, rng)]
+}
+
+// startScenarios 为各场景创建调度器，按速率投递的场景在此开始投递
+func startScenarios() {
+	for _, sc := range scenarios {
+		if sc.Rate > 0 {
+			sc.jobs = make(chan *requestSpec)
+			go feedRatedRequests(sc.total, sc.Rate, nil, sc.pick, sc.jobs)
+		}
+		var jobs <-chan *requestSpec
+		if sc.jobs != nil {
+			jobs = sc.jobs
+		}
+		sc.sched = newScheduler(jobs, sc.total, sc.pick)
+	}
+}
+
+// journeyRunner 为一个 worker 按顺序执行场景请求的进度，每轮开始时换新的会话并取一行数据
+type journeyRunner struct {
+	sc      *scenarioConfig
+	next    int
+	clients *vuClients
+	row     map[string]string
+	// results 为这一轮中已执行步骤的结果，last 为上一个执行的步骤（没有时为 nil）
+	results map[string]stepResult
+	last    *stepResult
+	// attempts 为当前步骤已发送的次数
+	attempts int
+	// txn 为正在进行的事务，transactions 为该 worker 已结束的事务
+	txn          *openTransaction
+	transactions map[string]*transactionStats
+}
+
+// stepResult 为一个步骤的响应；请求未得到响应时 Status 为 0，Values 为按 extract 取到的值
+type stepResult struct {
+	Status  int
+	Success bool
+	Values  map[string]string
+}
+
+// journeyStep 为场景中的一步
+type journeyStep struct {
+	*scenarioRequest
+	spec *requestSpec
+}
+
+// newJourneyRunner 为第 worker 个 worker 创建按顺序执行 sc 的请求的进度
+func newJourneyRunner(sc *scenarioConfig, worker int) *journeyRunner {
+	j := &journeyRunner{sc: sc, clients: newVUClients(), results: make(map[string]stepResult)}
+	sc.runners[worker-sc.first] = j
+	return j
+}
+
+// step 返回下一个满足执行条件的步骤及其请求；token 为调度器分配的占位请求，其计划发送时间转给这一步。
+// 一整轮的步骤都被跳过时返回 nil，避免条件永远不满足时空转
+func (j *journeyRunner) step(token *requestSpec, data *dataSource) (*journeyStep, *requestSpec) {
+	for range j.sc.Requests {
+		if j.next == 0 {
+			j.endTransaction(false)
+			j.clients.resetSession()
+			// 复制数据行，提取到的变量只在这一轮中可见，也不会改动其他 worker 共用的行
+			j.row = make(map[string]string)
+			for k, v := range data.row() {
+				j.row[k] = v
+			}
+			clear(j.results)
+			j.last = nil
+			atomic.AddInt64(&j.sc.passes, 1)
+		}
+		step := &journeyStep{scenarioRequest: &j.sc.Requests[j.next], spec: j.sc.specs[j.next]}
+		j.next = (j.next + 1) % len(j.sc.Requests)
+		if !j.matches(step.When) {
+			atomic.AddInt64(&j.sc.skipped, 1)
+			continue
+		}
+		j.attempts = 0
+		j.beginTransaction(step, time.Now())
+		if token.Scheduled.IsZero() {
+			return step, step.spec
+		}
+		scheduled := *step.spec
+		scheduled.Scheduled = token.Scheduled
+		return step, &scheduled
+	}
+	return nil, nil
+}
+
+// matches 判断这一轮中之前的响应是否满足执行条件
+func (j *journeyRunner) matches(cond *stepCondition) bool {
+	if cond == nil {
+		return true
+	}
+	r := j.last
+	if cond.Step != "" {
+		result, ok := j.results[cond.Step]
+		if !ok {
+			return false
+		}
+		r = &result
+	}
+	if r == nil {
+		return false
+	}
+	if cond.Success != nil && *cond.Success != r.Success {
+		return false
+	}
+	if len(cond.Status) == 0 {
+		return true
+	}
+	for _, code := range cond.Status {
+		if code == r.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// done 记录步骤的结果并按 on_failure 处理失败，返回是否应重发这一步
+func (j *journeyRunner) done(step *journeyStep, result stepResult) bool {
+	j.attempts++
+	if step.Name != "" {
+		j.results[step.Name] = result
+	}
+	j.last = &result
+	for name, value := range result.Values {
+		j.row[name] = value
+	}
+	if j.txn != nil {
+		j.txn.end = time.Now()
+	}
+	if result.Success {
+		return false
+	}
+	if step.OnFailure == "retry" && j.attempts <= step.Retries {
+		atomic.AddInt64(&j.sc.retries, 1)
+		return true
+	}
+	if j.txn != nil {
+		j.txn.failed = true
+	}
+	if step.OnFailure == "retry" || step.OnFailure == "abort" {
+		j.abort()
+	}
+	return false
+}
+
+// abort 放弃这一轮剩下的步骤，正在进行的事务记为失败，下一次从第一步开始新的一轮
+func (j *journeyRunner) abort() {
+	j.next = 0
+	j.endTransaction(true)
+	atomic.AddInt64(&j.sc.aborted, 1)
+}
+
+// finish 在 worker 退出时调用：正在进行的事务后面没有同名的步骤时已经完整，计入统计，否则丢弃
+func (j *journeyRunner) finish() {
+	if j.txn != nil && j.next != 0 && j.sc.Requests[j.next].Transaction == j.txn.name {
+		j.txn = nil
+	}
+	j.endTransaction(false)
+}
+
+// reportScenarioSetup 在开始前输出各场景分到的 worker、请求数或速率
+func reportScenarioSetup() {
+	fmt.Printf("🎭  Scenarios: %d, Workers: %d\n", len(scenarios), len(workerScenarios))
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Scenario", "Workers", "Load", "Requests", "Mode"})
+	for _, sc := range scenarios {
+		load := fmt.Sprintf("%d requests", sc.total)
+		if sc.total == math.MaxInt32 {
+			load = "until -soak ends"
+		}
+		if sc.Rate > 0 {
+			load = fmt.Sprintf("%.2f req/s, ", sc.Rate) + load
+		}
+		mode := "weighted mix"
+		if sc.Journey {
+			mode = "journey (in order)"
+		}
+		table.Append([]string{sc.Name, fmt.Sprintf("%d", sc.workers), load, fmt.Sprintf("%d", len(sc.specs)), mode})
+	}
+	table.Render()
+}
+
+// scenarioSummary 为一个场景的汇总
+type scenarioSummary struct {
+	Name         string               `json:"name"`
+	Workers      int                  `json:"workers"`
+	Journey      *journeySummary      `json:"journey,omitempty"`
+	Transactions []transactionSummary `json:"transactions,omitempty"`
+	statsSummary
+}
+
+// journeySummary 为按顺序执行的场景的轮数、放弃的轮数、跳过的步骤数与重试次数
+type journeySummary struct {
+	Passes       int64 `json:"passes"`
+	Aborted      int64 `json:"aborted"`
+	SkippedSteps int64 `json:"skipped_steps"`
+	Retries      int64 `json:"retries"`
+}
+
+// summarizeScenarios 按场景汇总各自 worker 的统计数据
+func summarizeScenarios(workers []*WorkerStats, startTime, now time.Time) []scenarioSummary {
+	var result []scenarioSummary
+	for _, sc := range scenarios {
+		stats := aggregateWorkerStats(workers[sc.first : sc.first+sc.workers])
+		summary := scenarioSummary{Name: sc.Name, Workers: sc.workers, statsSummary: summarizeStats(&stats, startTime, now)}
+		if sc.Journey {
+			summary.Journey = &journeySummary{
+				Passes:       atomic.LoadInt64(&sc.passes),
+				Aborted:      atomic.LoadInt64(&sc.aborted),
+				SkippedSteps: atomic.LoadInt64(&sc.skipped),
+				Retries:      atomic.LoadInt64(&sc.retries),
+			}
+			summary.Transactions = summarizeTransactions(sc)
+		}
+		result = append(result, summary)
+	}
+	return result
+}
+
+// reportScenarios 输出每个场景的请求数、吞吐与时延百分位
+func reportScenarios(summaries []scenarioSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	var total int64
+	for _, s := range summaries {
+		total += s.TotalRequests
+	}
+	sorted := append([]scenarioSummary(nil), summaries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TotalRequests > sorted[j].TotalRequests })
+	fmt.Println("\n🎭  Per-Scenario Statistics:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Scenario", "Workers", "Requests", "Share", "Failed", "TPS", "P50", "P95", "P99"})
+	for _, s := range sorted {
+		var share float64
+		if total > 0 {
+			share = float64(s.TotalRequests) / float64(total) * 100
+		}
+		table.Append([]string{
+			s.Name,
+			fmt.Sprintf("%d", s.Workers),
+			fmt.Sprintf("%d", s.TotalRequests),
+			fmt.Sprintf("%.1f%%", share),
+			fmt.Sprintf("%d", s.FailedRequests),
+			fmt.Sprintf("%.2f", s.TPS),
+			fmt.Sprintf("%.0f ms", s.P50Ms),
+			fmt.Sprintf("%.0f ms", s.P95Ms),
+			fmt.Sprintf("%.0f ms", s.P99Ms),
+		})
+	}
+	table.Render()
+	reportJourneys(summaries)
+	reportTransactions(summaries)
+}
+
+// reportJourneys 输出按顺序执行的场景中放弃的轮数、跳过的步骤与重试
+func reportJourneys(summaries []scenarioSummary) {
+	var rows [][]string
+	for _, s := range summaries {
+		if s.Journey == nil {
+			continue
+		}
+		j := s.Journey
+		var abortedPct float64
+		if j.Passes > 0 {
+			abortedPct = float64(j.Aborted) / float64(j.Passes) * 100
+		}
+		rows = append(rows, []string{
+			s.Name,
+			fmt.Sprintf("%d", j.Passes),
+			fmt.Sprintf("%d (%.1f%%)", j.Aborted, abortedPct),
+			fmt.Sprintf("%d", j.SkippedSteps),
+			fmt.Sprintf("%d", j.Retries),
+		})
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println("\n🧭  Journey Outcomes:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Scenario", "Passes", "Aborted", "Skipped Steps", "Retries"})
+	table.AppendBulk(rows)
+	table.Render()
+}
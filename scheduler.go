@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// maxInFlight 为 -max-inflight，所有 worker 同时在途的请求数上限，0 表示不限制（即 -c）
+var maxInFlight int
+
+// scheduler 统一为各 worker 分配请求：
+//   - 闭环模型（默认）下按 total 计数，worker 发完一个立即取下一个，请求由 pick 选择
+//   - 开放模型（-rate、按录制节奏回放）下请求由投递方按计划时间放入 jobs
+//
+// 两种模型下都按 -max-inflight 限制在途请求数，并记录每个请求的排队时间
+type scheduler struct {
+	jobs  <-chan *requestSpec
+	total int
+	pick  func(i int) *requestSpec
+	// slots 为在途名额，不限制时为 nil
+	slots chan struct{}
+	// issued 为闭环模型下已分配的请求数
+	issued int64
+	// queued 表示请求可能排队（开放模型或限制了在途数），此时才记录排队时间
+	queued bool
+}
+
+// newScheduler 创建调度器；jobs 非空时为开放模型，否则按 total 与 pick 分配
+func newScheduler(jobs <-chan *requestSpec, total int, pick func(i int) *requestSpec) *scheduler {
+	s := &scheduler{jobs: jobs, total: total, pick: pick, queued: jobs != nil || maxInFlight > 0}
+	if maxInFlight > 0 {
+		s.slots = make(chan struct{}, maxInFlight)
+	}
+	return s
+}
+
+// ticket 为调度器分配给 worker 的一个请求
+type ticket struct {
+	num  int64
+	spec *requestSpec
+	// wait 为排队时间：有计划发送时间时为计划时间到取得在途名额的间隔，否则为等待在途名额的时间
+	wait time.Duration
+}
+
+// next 取下一个请求并占用一个在途名额，没有更多请求或压测中止时返回 false；请求结束后须调用 release
+func (s *scheduler) next() (ticket, bool) {
+	var t ticket
+	if s.jobs != nil {
+		select {
+		case spec, ok := <-s.jobs:
+			if !ok {
+				return t, false
+			}
+			t.spec = spec
+			if spec.release != nil {
+				select {
+				case <-spec.release:
+				case <-runStopped:
+					return t, false
+				}
+			}
+		case <-runStopped:
+			// 投递方可能正按计划等待下一个请求，中止时不再等它关闭 jobs
+			return t, false
+		}
+		t.num = atomic.AddInt64(&globalTotalRequests, 1)
+	} else {
+		n := atomic.AddInt64(&s.issued, 1)
+		if n > int64(s.total) || shouldStop() {
+			return t, false
+		}
+		t.num = atomic.AddInt64(&globalTotalRequests, 1)
+		t.spec = s.pick(int(n) - 1)
+	}
+	wait, ok := s.acquire()
+	if !ok {
+		return t, false
+	}
+	t.wait = wait
+	if !t.spec.Scheduled.IsZero() {
+		t.wait = time.Since(t.spec.Scheduled)
+		if t.wait < 0 {
+			t.wait = 0
+		}
+		if s.slots != nil {
+			// 限制在途数时的排队是有意的，不算作压测工具自身的调度延迟；投递方为每个请求复制了 spec，可以直接修改
+			t.spec.Scheduled = time.Now()
+		}
+	}
+	return t, true
+}
+
+// acquire 占用一个在途名额，返回等待的时间；压测中止时返回 false
+func (s *scheduler) acquire() (time.Duration, bool) {
+	if s.slots == nil {
+		return 0, true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return 0, true
+	default:
+	}
+	start := time.Now()
+	select {
+	case s.slots <- struct{}{}:
+		return time.Since(start), true
+	case <-runStopped:
+		return 0, false
+	}
+}
+
+// release 归还在途名额
+func (s *scheduler) release() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// record 把排队时间计入 worker 的统计
+func (s *scheduler) record(ws *WorkerStats, wait time.Duration) {
+	if s.queued {
+		ws.QueueWaits = append(ws.QueueWaits, wait)
+	}
+}
+
+// queueWaitStats 为排队时间的直方图与最大值
+type queueWaitStats struct {
+	Digest latencyDigest
+	Max    time.Duration
+}
+
+// record 记录一个排队时间
+func (q *queueWaitStats) record(d time.Duration) {
+	q.Digest.record(d)
+	if d > q.Max {
+		q.Max = d
+	}
+}
+
+// add 合并另一个 worker 的排队时间
+func (q *queueWaitStats) add(other queueWaitStats) {
+	q.Digest.add(other.Digest)
+	if other.Max > q.Max {
+		q.Max = other.Max
+	}
+}
+
+// queueWaitSummary 为导出汇总中的排队时间
+type queueWaitSummary struct {
+	Requests int64   `json:"requests"`
+	P50Ms    float64 `json:"p50_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// summary 返回排队时间的汇总，没有记录时返回 nil
+func (q queueWaitStats) summary() *queueWaitSummary {
+	if q.Digest.total == 0 {
+		return nil
+	}
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	return &queueWaitSummary{
+		Requests: q.Digest.total,
+		P50Ms:    ms(q.Digest.percentile(50)),
+		P95Ms:    ms(q.Digest.percentile(95)),
+		P99Ms:    ms(q.Digest.percentile(99)),
+		MaxMs:    ms(q.Max),
+	}
+}
+
+// reportQueueWait 输出请求从计划发送（或开始等待在途名额）到真正发出的排队时间
+func reportQueueWait(s *queueWaitSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Println("\n🚏  Queue Wait (scheduled -> sent):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Requests", "P50", "P95", "P99", "Max"})
+	format := func(ms float64) string { return fmt.Sprintf("%.1f ms", ms) }
+	table.Append([]string{fmt.Sprintf("%d", s.Requests), format(s.P50Ms), format(s.P95Ms), format(s.P99Ms), format(s.MaxMs)})
+	table.Render()
+}
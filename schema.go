@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// schemaMaxDepth 为解析 $ref 的最大层数，防止循环引用导致无限递归
+const schemaMaxDepth = 32
+
+// responseSchema 为 -response-schema 加载的 JSON Schema，为 nil 时不校验
+var responseSchema *jsonSchema
+
+// schemaSample 为按 schema 校验的 2xx 响应比例，1 表示全部校验
+var schemaSample = 1.0
+
+// jsonSchema 为 JSON Schema（draft-07 / 2020-12）常用关键字的校验器：
+// type、enum、const、properties、patternProperties、required、additionalProperties、items、min/maxItems、
+// min/maxLength、pattern、minimum、maximum、exclusiveMinimum/Maximum、allOf、anyOf、oneOf、not，
+// 以及本文档内的 $ref（#/definitions/... 或 #/$defs/...），与 2020-12 相同，$ref 旁的关键字同样生效；format 等其余关键字忽略
+type jsonSchema struct {
+	doc map[string]interface{}
+	// patterns 缓存编译后的 pattern
+	mu       sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
+
+// loadResponseSchema 读取 JSON Schema 文件
+func loadResponseSchema(filename string) (*jsonSchema, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response schema: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse response schema: %v", err)
+	}
+	s := &jsonSchema{doc: doc, patterns: make(map[string]*regexp.Regexp)}
+	// 无法解析的 $ref 在校验时不起作用，引用写错会让所有响应都通过，因此加载时就报错
+	if err := s.checkRefs(doc, "#"); err != nil {
+		return nil, fmt.Errorf("invalid response schema: %v", err)
+	}
+	return s, nil
+}
+
+// schemaDataKeywords 的值是 JSON 数据而不是子 schema，其中的 $ref 不是引用
+var schemaDataKeywords = map[string]bool{"enum": true, "const": true, "default": true, "examples": true}
+
+// schemaMapKeywords 的值以属性名或定义名为键、子 schema 为值，键名可以与关键字同名（如名为 enum 的属性）
+var schemaMapKeywords = map[string]bool{"properties": true, "patternProperties": true, "$defs": true, "definitions": true, "dependentSchemas": true}
+
+// checkRefs 检查 node 及其子 schema 中的每个 $ref 都指向本文档内存在的 schema，location 为 node 在文档中的位置
+func (s *jsonSchema) checkRefs(node interface{}, location string) error {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := node["$ref"].(string); ok {
+			if !strings.HasPrefix(ref, "#") {
+				return fmt.Errorf("%s: $ref %q is not a reference within the schema document", location, ref)
+			}
+			switch s.resolve(ref).(type) {
+			case map[string]interface{}, bool:
+			default:
+				return fmt.Errorf("%s: $ref %q does not resolve to a schema", location, ref)
+			}
+		}
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if schemaDataKeywords[key] {
+				continue
+			}
+			if members, ok := node[key].(map[string]interface{}); ok && schemaMapKeywords[key] {
+				names := make([]string, 0, len(members))
+				for name := range members {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					if err := s.checkRefs(members[name], location+"/"+key+"/"+name); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := s.checkRefs(node[key], location+"/"+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range node {
+			if err := s.checkRefs(item, location+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateBody 校验响应 body，返回第一处不符合的描述，符合时返回空字符串。
+// 描述中的数组下标统一写作 [*]，使同一类问题汇总为一行
+func (s *jsonSchema) validateBody(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "invalid JSON"
+	}
+	return s.validate(s.doc, value, "$", 0)
+}
+
+// resolve 按 JSON Pointer 解析本文档内的 $ref（如 #/$defs/a~1b），找不到时返回 nil；加载时已确认每个 $ref 都能解析
+func (s *jsonSchema) resolve(ref string) interface{} {
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil
+	}
+	var cur interface{} = s.doc
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			cur = node[part]
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			cur = node[i]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+func (s *jsonSchema) validate(node interface{}, value interface{}, path string, depth int) string {
+	if b, ok := node.(bool); ok {
+		if !b {
+			return path + ": not allowed"
+		}
+		return ""
+	}
+	schema, _ := node.(map[string]interface{})
+	if depth > schemaMaxDepth || schema == nil {
+		return ""
+	}
+	if ref, ok := schema["$ref"].(string); ok && strings.HasPrefix(ref, "#") {
+		if msg := s.validate(s.resolve(ref), value, path, depth+1); msg != "" {
+			return msg
+		}
+	}
+
+	if t, ok := schema["type"]; ok {
+		if !matchesType(t, value) {
+			return fmt.Sprintf("%s: expected %s", path, describeType(t))
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, v := range enum {
+			if reflect.DeepEqual(v, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return path + ": not one of the enum values"
+		}
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		return path + ": does not match const"
+	}
+	for _, sub := range asSchemaList(schema["allOf"]) {
+		if msg := s.validate(sub, value, path, depth+1); msg != "" {
+			return msg
+		}
+	}
+	if anyOf := asSchemaList(schema["anyOf"]); len(anyOf) > 0 {
+		matched := false
+		for _, sub := range anyOf {
+			if s.validate(sub, value, path, depth+1) == "" {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return path + ": matches none of anyOf"
+		}
+	}
+	if oneOf := asSchemaList(schema["oneOf"]); len(oneOf) > 0 {
+		matched := 0
+		for _, sub := range oneOf {
+			if s.validate(sub, value, path, depth+1) == "" {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Sprintf("%s: matches %d of oneOf, expected exactly 1", path, matched)
+		}
+	}
+	if not, ok := schema["not"]; ok && s.validate(not, value, path, depth+1) == "" {
+		return path + ": matches not"
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.validateObject(schema, v, path, depth)
+	case []interface{}:
+		if n, ok := schema["minItems"].(float64); ok && float64(len(v)) < n {
+			return fmt.Sprintf("%s: fewer than %g items", path, n)
+		}
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(v)) > n {
+			return fmt.Sprintf("%s: more than %g items", path, n)
+		}
+		if items, ok := schema["items"]; ok {
+			for _, item := range v {
+				if msg := s.validate(items, item, path+"[*]", depth+1); msg != "" {
+					return msg
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := schema["minLength"].(float64); ok && length < n {
+			return fmt.Sprintf("%s: shorter than %g characters", path, n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && length > n {
+			return fmt.Sprintf("%s: longer than %g characters", path, n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re := s.compile(pattern); re != nil && !re.MatchString(v) {
+				return fmt.Sprintf("%s: does not match pattern %s", path, pattern)
+			}
+		}
+	case float64:
+		if n, ok := schema["minimum"].(float64); ok && v < n {
+			return fmt.Sprintf("%s: less than minimum %g", path, n)
+		}
+		if n, ok := schema["maximum"].(float64); ok && v > n {
+			return fmt.Sprintf("%s: greater than maximum %g", path, n)
+		}
+		if n, ok := schema["exclusiveMinimum"].(float64); ok && v <= n {
+			return fmt.Sprintf("%s: not greater than %g", path, n)
+		}
+		if n, ok := schema["exclusiveMaximum"].(float64); ok && v >= n {
+			return fmt.Sprintf("%s: not less than %g", path, n)
+		}
+	}
+	return ""
+}
+
+// validateObject 校验对象的 required、properties、patternProperties 与 additionalProperties，按属性名排序以保证结果稳定；
+// additionalProperties 只作用于既不在 properties 中、也不匹配任何 patternProperties 的属性
+func (s *jsonSchema) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string, depth int) string {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := obj[name]; !present {
+					return fmt.Sprintf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	patterns := make([]string, 0, len(patternProperties))
+	for pattern := range patternProperties {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	additional, hasAdditional := schema["additionalProperties"]
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		matched := false
+		if sub, ok := properties[name]; ok {
+			matched = true
+			if msg := s.validate(sub, obj[name], path+"."+name, depth+1); msg != "" {
+				return msg
+			}
+		}
+		for _, pattern := range patterns {
+			if re := s.compile(pattern); re != nil && re.MatchString(name) {
+				matched = true
+				if msg := s.validate(patternProperties[pattern], obj[name], path+"."+name, depth+1); msg != "" {
+					return msg
+				}
+			}
+		}
+		if !matched && hasAdditional {
+			if msg := s.validate(additional, obj[name], path+"."+name, depth+1); msg != "" {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+// compile 返回编译后的 pattern，pattern 不合法时返回 nil（即不校验）
+func (s *jsonSchema) compile(pattern string) *regexp.Regexp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	re, ok := s.patterns[pattern]
+	if !ok {
+		re, _ = regexp.Compile(pattern)
+		s.patterns[pattern] = re
+	}
+	return re
+}
+
+// asSchemaList 将 allOf/anyOf/oneOf 节点转换为子 schema 列表
+func asSchemaList(node interface{}) []interface{} {
+	list, _ := node.([]interface{})
+	return list
+}
+
+// matchesType 判断 value 是否符合 type（字符串或字符串数组）
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// describeType 返回 type 的描述，如 "string" 或 "string or null"
+func describeType(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			names = append(names, fmt.Sprint(item))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// schemaTestGroup 为 JSON-Schema-Test-Suite（github.com/json-schema-org/JSON-Schema-Test-Suite）的用例格式，
+// testdata/schema 下的用例取自其 draft2020-12 目录中只用到本校验器所支持关键字的分组
+type schemaTestGroup struct {
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema"`
+	Tests       []struct {
+		Description string          `json:"description"`
+		Data        json.RawMessage `json:"data"`
+		Valid       bool            `json:"valid"`
+	} `json:"tests"`
+}
+
+func TestJSONSchemaTestSuite(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "schema", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files in testdata/schema")
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var groups []schemaTestGroup
+		if err := json.Unmarshal(data, &groups); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			for _, group := range groups {
+				s := &jsonSchema{doc: group.Schema, patterns: make(map[string]*regexp.Regexp)}
+				if err := s.checkRefs(group.Schema, "#"); err != nil {
+					t.Errorf("%s: %v", group.Description, err)
+					continue
+				}
+				for _, tt := range group.Tests {
+					t.Run(group.Description+"/"+tt.Description, func(t *testing.T) {
+						got := s.validateBody(tt.Data)
+						if valid := got == ""; valid != tt.Valid {
+							t.Errorf("data %s: valid = %v (%q), want %v", tt.Data, valid, got, tt.Valid)
+						}
+					})
+				}
+			}
+		})
+	}
+}
+
+func TestLoadResponseSchema(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "schema.json")
+	if err := os.WriteFile(file, []byte(`{
+		"type": "object",
+		"required": ["id", "tags"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"tags": {"type": "array", "items": {"$ref": "#/definitions/tag"}, "maxItems": 2}
+		},
+		"definitions": {"tag": {"type": "string", "pattern": "^[a-z]+$"}}
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadResponseSchema(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"id": 1, "tags": ["a", "b"]}`, ""},
+		{`{"id": 0, "tags": []}`, "$.id: less than minimum 1"},
+		{`{"id": 1}`, `$: missing required property "tags"`},
+		{`{"id": 1, "tags": ["a", "B"]}`, "$.tags[*]: does not match pattern ^[a-z]+$"},
+		{`{"id": 1, "tags": ["a", "b", "c"]}`, "$.tags: more than 2 items"},
+		{`{"id": "1", "tags": []}`, "$.id: expected integer"},
+		{`not json`, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		if got := s.validateBody([]byte(tt.body)); got != tt.want {
+			t.Errorf("validateBody(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+
+	if err := os.WriteFile(file, []byte(`{"type": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadResponseSchema(file); err == nil {
+		t.Error("expected an error for an invalid schema file")
+	}
+}
+
+func TestLoadResponseSchemaRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{
+			name:    "typo in a definition name",
+			schema:  `{"properties": {"tags": {"items": {"$ref": "#/definitions/tga"}}}, "definitions": {"tag": {"type": "string"}}}`,
+			wantErr: `#/properties/tags/items: $ref "#/definitions/tga" does not resolve to a schema`,
+		},
+		{
+			name:    "array index out of range",
+			schema:  `{"allOf": [{"$ref": "#/allOf/3"}]}`,
+			wantErr: `#/allOf/0: $ref "#/allOf/3" does not resolve to a schema`,
+		},
+		{
+			name:    "not a schema",
+			schema:  `{"required": ["id"], "properties": {"id": {"$ref": "#/required"}}}`,
+			wantErr: `#/properties/id: $ref "#/required" does not resolve to a schema`,
+		},
+		{
+			name:    "other document",
+			schema:  `{"$ref": "common.json#/definitions/id"}`,
+			wantErr: `#: $ref "common.json#/definitions/id" is not a reference within the schema document`,
+		},
+		{
+			name:    "property named like a data keyword",
+			schema:  `{"properties": {"enum": {"$ref": "#/$defs/missing"}}}`,
+			wantErr: `#/properties/enum: $ref "#/$defs/missing" does not resolve to a schema`,
+		},
+		{
+			name:   "$ref inside enum is data",
+			schema: `{"enum": [{"$ref": "#/missing"}], "const": {"$ref": "#/missing"}}`,
+		},
+		{
+			name:   "valid refs",
+			schema: `{"$defs": {"a~b": {"type": "integer"}, "t": true}, "properties": {"x": {"$ref": "#/$defs/a~0b"}, "y": {"$ref": "#/$defs/t"}, "z": {"$ref": "#"}}}`,
+		},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, "schema.json")
+			if err := os.WriteFile(file, []byte(tt.schema), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadResponseSchema(file)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if want := "invalid response schema: " + tt.wantErr; err == nil || err.Error() != want {
+				t.Errorf("error = %v, want %s", err, want)
+			}
+		})
+	}
+}
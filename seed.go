@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 派生请求随机数时区分用途，避免同一请求的不同决策使用同一随机序列而相互关联
+const (
+	randBody = iota + 1
+	randKeepAlive
+	randTarget
+	randPayload
+	randMethod
+	randUserAgent
+	randConn
+	// randTemplate 为模板函数的用途，与模板文本的哈希组合，使同一请求中的不同模板各用一个序列
+	randTemplate
+)
+
+// runSeed 为本次运行的随机种子；-seed 指定时固定，否则按启动时间随机
+var runSeed = time.Now().UnixNano()
+
+// sharedRand 为加锁的全局随机数生成器，用于加载阶段生成请求，由 runSeed 确定；
+// 压测阶段每个请求的决策使用 requestRand，每条连接的决策使用 connRand
+var sharedRand = rand.New(&lockedSource{src: rand.NewSource(runSeed)})
+
+// setSeed 固定随机种子，使两次运行生成相同的请求序列
+func setSeed(seed int64) {
+	runSeed = seed
+	sharedRand = rand.New(&lockedSource{src: rand.NewSource(seed)})
+}
+
+// requestRand 返回第 n 个请求在某一用途上使用的随机数生成器。
+// 它只由 runSeed、n 与用途决定，与请求落在哪个 worker、何时发出无关，因此并发下序列也可复现。
+// n 与用途都经过 splitMix64 的混合函数再作为初始状态：若按步长线性叠加，相邻请求的序列只是错开一步，
+// 第 n+1 个请求的随机数与第 n 个请求的后续随机数相同
+func requestRand(n int64, purpose uint64) *rand.Rand {
+	return rand.New(&splitMix64{state: mix64(mix64(uint64(runSeed)^mix64(purpose)) ^ uint64(n))})
+}
+
+// connSeq 为已建立（或开始建立）的连接数，用于派生每条连接自己的随机数生成器
+var connSeq int64
+
+// connRand 返回一条新连接使用的随机数生成器，由 runSeed 与连接的序号决定；连接上的读写可能来自不同的 goroutine，因此加锁。
+// 注入的故障与时延抖动按连接各用一个序列，不与其他连接争用；但哪个请求落在第几条连接上取决于调度，因此不保证复现
+func connRand() *rand.Rand {
+	return rand.New(&lockedSource{src: requestRand(atomic.AddInt64(&connSeq, 1), randConn)})
+}
+
+// splitMix64 为构造开销极小的 rand.Source，适合每个请求单独创建
+type splitMix64 struct {
+	state uint64
+}
+
+func (s *splitMix64) Seed(seed int64) { s.state = uint64(seed) }
+func (s *splitMix64) Int63() int64    { return int64(s.Uint64() >> 1) }
+
+func (s *splitMix64) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	return mix64(s.state)
+}
+
+// mix64 为 splitMix64 的混合函数，是 uint64 上的双射，相邻的输入得到互不相关的输出
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// lockedSource 为可并发使用的 rand.Source
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
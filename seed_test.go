@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRequestRandStreamsDoNotOverlap(t *testing.T) {
+	saved := runSeed
+	defer func() { runSeed = saved }()
+	const draws = 64
+	for _, seed := range []int64{0, 1, 42, -7} {
+		runSeed = seed
+		seen := make(map[uint64]string)
+		record := func(n int64, purpose uint64) {
+			stream := fmt.Sprintf("request %d purpose %d", n, purpose)
+			rng := requestRand(n, purpose)
+			for i := 0; i < draws; i++ {
+				v := rng.Uint64()
+				if prev, ok := seen[v]; ok {
+					t.Fatalf("seed %d: draw %d of %s repeats a draw of %s", seed, i, stream, prev)
+				}
+				seen[v] = stream
+			}
+		}
+		// 相邻的请求序号，以及 ws.go 中由连接与消息序号组合的 n
+		for n := int64(0); n < 500; n++ {
+			for _, purpose := range []uint64{randBody, randKeepAlive, randUserAgent, randTemplate} {
+				record(n, purpose)
+			}
+		}
+		for seq := int64(0); seq < 50; seq++ {
+			record(3<<32|seq, randBody)
+		}
+	}
+}
+
+func TestRequestRandReproducible(t *testing.T) {
+	saved := runSeed
+	defer func() { runSeed = saved }()
+	runSeed = 42
+	a := requestRand(7, randBody).Int63()
+	if b := requestRand(7, randBody).Int63(); a != b {
+		t.Errorf("request 7 drew %d, then %d", a, b)
+	}
+	if c := requestRand(7, randKeepAlive).Int63(); c == a {
+		t.Errorf("purposes share the draw %d", a)
+	}
+}
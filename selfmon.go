@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// pprofAddr 为 -pprof，非空时在该地址提供 /debug/pprof/，用于分析压测工具自身
+var pprofAddr string
+
+// cpuBottleneckPercent 为判定压测工具自身成为瓶颈的 CPU 使用率（占全部核心的百分比）
+const cpuBottleneckPercent = 90
+
+// startPprof 在 -pprof 指定的地址上提供 pprof
+func startPprof(addr string) {
+	if addr == "" {
+		return
+	}
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			slog.Error("pprof server", "err", err)
+		}
+	}()
+	fmt.Printf("🩺  pprof: http://%s/debug/pprof/\n", addr)
+}
+
+// selfMonitor 每秒记录一次压测进程自身的资源使用：CPU、堆内存、goroutine、GC 停顿与打开的文件描述符
+type selfMonitor struct {
+	lastTime time.Time
+	lastCPU  time.Duration
+	lastGC   uint32
+	samples  int
+	cpuSeen  int
+
+	cpuSum, cpuPeak       float64
+	heapSum, heapPeak     uint64
+	goroutineSum, goPeak  int
+	fdSum, fdPeak, fdSeen int
+	gcCount               uint32
+	gcPauseTotal          time.Duration
+	gcPauseMax            time.Duration
+}
+
+// newSelfMonitor 以 start 为起点创建监控
+func newSelfMonitor(start time.Time) *selfMonitor {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	cpu, _ := processCPUTime()
+	return &selfMonitor{lastTime: start, lastCPU: cpu, lastGC: ms.NumGC}
+}
+
+// sample 记录自上次采样以来的资源使用并返回这段时间的 CPU 使用率，由每秒的 ticker 调用
+func (m *selfMonitor) sample(now time.Time) float64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	m.samples++
+
+	var percent float64
+	if cpu, ok := processCPUTime(); ok {
+		if wall := now.Sub(m.lastTime); wall > 0 {
+			percent = float64(cpu-m.lastCPU) / float64(wall) / float64(runtime.NumCPU()) * 100
+			m.cpuSeen++
+			m.cpuSum += percent
+			if percent > m.cpuPeak {
+				m.cpuPeak = percent
+			}
+		}
+		m.lastCPU = cpu
+	}
+	m.lastTime = now
+
+	m.heapSum += ms.HeapAlloc
+	if ms.HeapAlloc > m.heapPeak {
+		m.heapPeak = ms.HeapAlloc
+	}
+	goroutines := runtime.NumGoroutine()
+	m.goroutineSum += goroutines
+	if goroutines > m.goPeak {
+		m.goPeak = goroutines
+	}
+	if fds, ok := openFileDescriptors(); ok {
+		m.fdSeen++
+		m.fdSum += fds
+		if fds > m.fdPeak {
+			m.fdPeak = fds
+		}
+	}
+	// PauseNs 为最近 256 次 GC 停顿的环形缓冲区
+	for gc := m.lastGC + 1; gc <= ms.NumGC && ms.NumGC-gc < uint32(len(ms.PauseNs)); gc++ {
+		pause := time.Duration(ms.PauseNs[(gc+255)%256])
+		m.gcPauseTotal += pause
+		if pause > m.gcPauseMax {
+			m.gcPauseMax = pause
+		}
+	}
+	m.gcCount += ms.NumGC - m.lastGC
+	m.lastGC = ms.NumGC
+	return percent
+}
+
+// report 输出压测工具自身的资源使用；CPU 接近满载时警告，此时测得的可能是压测工具而不是目标的极限
+func (m *selfMonitor) report() {
+	if m.samples == 0 {
+		return
+	}
+	n := float64(m.samples)
+	fmt.Println("\n🩺  Load Generator Health:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Metric", "Average", "Peak"})
+	if m.cpuSeen > 0 {
+		table.Append([]string{"CPU (% of all cores)", fmt.Sprintf("%.1f%%", m.cpuSum/float64(m.cpuSeen)), fmt.Sprintf("%.1f%%", m.cpuPeak)})
+	}
+	table.Append([]string{"Heap", formatBytes(int64(float64(m.heapSum) / n)), formatBytes(int64(m.heapPeak))})
+	table.Append([]string{"Goroutines", fmt.Sprintf("%.0f", float64(m.goroutineSum)/n), fmt.Sprintf("%d", m.goPeak)})
+	if m.fdSeen > 0 {
+		table.Append([]string{"Open FDs", fmt.Sprintf("%.0f", float64(m.fdSum)/float64(m.fdSeen)), fmt.Sprintf("%d", m.fdPeak)})
+	}
+	table.Append([]string{"GC Pauses", fmt.Sprintf("%d GCs, %s total", m.gcCount, m.gcPauseTotal.Round(time.Microsecond)), m.gcPauseMax.Round(time.Microsecond).String()})
+	table.Render()
+	if m.cpuPeak >= cpuBottleneckPercent {
+		fmt.Printf("⚠️   The load generator peaked at %.0f%% CPU: latency and throughput may be limited by this machine rather than the target. Lower -c or spread the load over more instances.\n", m.cpuPeak)
+	}
+}
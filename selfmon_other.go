@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// processCPUTime 在不支持的系统上不可用
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
+
+// openFileDescriptors 在不支持的系统上不可用
+func openFileDescriptors() (int, bool) {
+	return 0, false
+}
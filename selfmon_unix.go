@@ -0,0 +1,29 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// processCPUTime 返回进程累计使用的用户态与内核态 CPU 时间
+func processCPUTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
+
+// openFileDescriptors 返回进程打开的文件描述符数，只支持有 /proc 或 /dev/fd 的系统
+func openFileDescriptors() (int, bool) {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := os.ReadDir(dir); err == nil {
+			// 读取目录本身占用一个描述符，不计入
+			return len(entries) - 1, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// sloBucketsValue、sloTarget 为 -slo-buckets 与 -slo-target：报告完整耗时不超过各时延上限的请求占全部请求的百分比，
+// 失败的请求不在任何上限之内；并按目标百分比（如 99% 的请求在 250ms 之内）计算每个上限的错误预算消耗
+var (
+	sloBucketsValue string
+	sloTarget       float64
+	// sloBuckets 为 -slo-buckets 解析后按升序排列的时延上限
+	sloBuckets []time.Duration
+)
+
+// parseSLOBuckets 解析逗号分隔的时延上限，如 50ms,100ms,250ms,500ms
+func parseSLOBuckets(s string) ([]time.Duration, error) {
+	var buckets []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid -slo-buckets %q, expected e.g. 50ms,100ms,250ms,500ms", s)
+		}
+		buckets = append(buckets, d)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return buckets, nil
+}
+
+// sloCounts 为 -slo-buckets 下的请求计数：Within[i] 为完整耗时不超过 sloBuckets[i] 的成功请求数，Total 为全部请求数
+type sloCounts struct {
+	Within []int64
+	Total  int64
+}
+
+// record 记录一个请求，ok 为是否得到了成功的响应，d 为完整耗时；未设置 -slo-buckets 时不做任何事
+func (c *sloCounts) record(ok bool, d time.Duration) {
+	if len(sloBuckets) == 0 {
+		return
+	}
+	c.Total++
+	if !ok {
+		return
+	}
+	if c.Within == nil {
+		c.Within = make([]int64, len(sloBuckets))
+	}
+	for i, bucket := range sloBuckets {
+		if d <= bucket {
+			c.Within[i]++
+		}
+	}
+}
+
+// add 合并另一组计数
+func (c *sloCounts) add(other sloCounts) {
+	c.Total += other.Total
+	if other.Within == nil {
+		return
+	}
+	if c.Within == nil {
+		c.Within = make([]int64, len(other.Within))
+	}
+	for i, n := range other.Within {
+		c.Within[i] += n
+	}
+}
+
+// sloBucketSummary 为一个时延上限的达标情况；Burn 为错误预算消耗倍数，1 表示恰好用完 100% - 目标百分比的预算
+type sloBucketSummary struct {
+	ThresholdMs float64 `json:"threshold_ms"`
+	Within      int64   `json:"within"`
+	Percent     float64 `json:"percent"`
+	Burn        float64 `json:"budget_burn"`
+}
+
+// sloSummary 为写入 JSON 汇总的 SLO 分桶统计
+type sloSummary struct {
+	TargetPercent float64            `json:"target_percent"`
+	Total         int64              `json:"total"`
+	Buckets       []sloBucketSummary `json:"buckets"`
+}
+
+// summarizeSLO 计算各时延上限内的请求百分比与错误预算消耗；未设置 -slo-buckets 时返回 nil
+func summarizeSLO(c sloCounts) *sloSummary {
+	if len(sloBuckets) == 0 {
+		return nil
+	}
+	summary := &sloSummary{TargetPercent: sloTarget, Total: c.Total}
+	for i, bucket := range sloBuckets {
+		b := sloBucketSummary{ThresholdMs: durationMs(bucket)}
+		if c.Within != nil {
+			b.Within = c.Within[i]
+		}
+		if c.Total > 0 {
+			b.Percent = float64(b.Within) / float64(c.Total) * 100
+			b.Burn = (100 - b.Percent) / (100 - sloTarget)
+		}
+		summary.Buckets = append(summary.Buckets, b)
+	}
+	return summary
+}
+
+// reportSLO 输出各时延上限内的请求百分比，以及按 -slo-target 计算的错误预算消耗
+func reportSLO(s *sloSummary) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("\n🎯  Latency SLO (target %g%% of requests within the bound):\n", s.TargetPercent)
+	if s.Total == 0 {
+		fmt.Println("  - no requests")
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Bound", "Within", "Percent", "Budget Burn", "Met"})
+	for _, b := range s.Buckets {
+		met := "yes"
+		if b.Percent < s.TargetPercent {
+			met = "NO"
+		}
+		table.Append([]string{
+			fmt.Sprintf("%g ms", b.ThresholdMs),
+			fmt.Sprintf("%d", b.Within),
+			fmt.Sprintf("%.3f%%", b.Percent),
+			fmt.Sprintf("%.2fx", b.Burn),
+			met,
+		})
+	}
+	table.Render()
+	fmt.Printf("  - Requests are measured by total time and failed ones are never within a bound; a burn of 1.00x uses exactly the %g%% error budget\n", 100-s.TargetPercent)
+}
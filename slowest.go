@@ -0,0 +1,105 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// slowestLimit 为记录的最慢请求数量，0 表示不记录
+var slowestLimit int
+
+// slowRequest 为一条最慢请求的记录
+type slowRequest struct {
+	Time time.Time
+	// ID 为 -request-id-header 下注入的请求 ID
+	ID     string
+	Method string
+	URL    string
+	// Status 为 0 时表示请求未得到响应，原因见 Error
+	Status int
+	Error  string
+	Total  time.Duration
+	Phases requestPhases
+}
+
+// slowHeap 为按 Total 排序的小顶堆，堆顶是已记录请求中最快的一条，便于淘汰
+type slowHeap []slowRequest
+
+func (h slowHeap) Len() int            { return len(h) }
+func (h slowHeap) Less(i, j int) bool  { return h[i].Total < h[j].Total }
+func (h slowHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *slowHeap) Push(x interface{}) { *h = append(*h, x.(slowRequest)) }
+func (h *slowHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// offer 在堆未满或 r 比堆顶更慢时记录 r，保证堆中最多保留 slowestLimit 条
+func (h *slowHeap) offer(r slowRequest) {
+	if slowestLimit <= 0 {
+		return
+	}
+	if h.Len() < slowestLimit {
+		heap.Push(h, r)
+		return
+	}
+	if r.Total > (*h)[0].Total {
+		(*h)[0] = r
+		heap.Fix(h, 0)
+	}
+}
+
+// mergeSlowest 合并各 worker 的记录，按耗时降序返回最慢的 slowestLimit 条
+func mergeSlowest(all []slowRequest) []slowRequest {
+	sort.Slice(all, func(i, j int) bool { return all[i].Total > all[j].Total })
+	if len(all) > slowestLimit {
+		all = all[:slowestLimit]
+	}
+	return all
+}
+
+// reportSlowest 输出最慢请求及其各阶段耗时
+func reportSlowest(slowest []slowRequest) {
+	if len(slowest) == 0 {
+		return
+	}
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.1f", float64(d.Microseconds())/1000)
+	}
+	fmt.Printf("\n🐢  Top %d Slowest Requests (ms):\n", len(slowest))
+	table := tablewriter.NewWriter(os.Stdout)
+	header := []string{"#", "Time", "Request", "Status", "Total", "DNS", "Connect", "TLS", "Server", "Transfer"}
+	if requestIDHeader != "" {
+		header = append(header[:2], append([]string{"Request ID"}, header[2:]...)...)
+	}
+	table.SetHeader(header)
+	for i, r := range slowest {
+		status := fmt.Sprintf("%d", r.Status)
+		if r.Status == 0 {
+			status = "ERR: " + r.Error
+		}
+		row := []string{fmt.Sprintf("%d", i+1), wallTime(r.Time).Format("15:04:05.000")}
+		if requestIDHeader != "" {
+			row = append(row, r.ID)
+		}
+		table.Append(append(row,
+			r.Method+" "+r.URL,
+			status,
+			ms(r.Total),
+			ms(r.Phases.DNS),
+			ms(r.Phases.Connect),
+			ms(r.Phases.TLS),
+			ms(r.Phases.Server),
+			ms(r.Phases.Transfer),
+		))
+	}
+	table.Render()
+}
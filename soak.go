@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// soakSampleBudget 为 soak 模式下所有 worker 合计保留的时延样本上限
+const soakSampleBudget = 200000
+
+// runDeadline 非零时表示按时长运行（如 -soak），到达后不再发出新请求
+var runDeadline time.Time
+
+// sampleLimit 为每个 worker（以及每个 URL）保留的时延样本上限，0 表示不限制
+var sampleLimit int
+
+// interrupted 为 1 时表示收到了中断信号，不再发出新请求
+var interrupted int32
+
+// shouldStop 判断是否已到达运行截止时间或收到了中断信号
+func shouldStop() bool {
+	return atomic.LoadInt32(&interrupted) == 1 || (!runDeadline.IsZero() && time.Now().After(runDeadline))
+}
+
+// appendSample 追加一个时延样本；超过 sampleLimit 后改为蓄水池抽样，保证内存有界且样本仍均匀覆盖整个运行期
+func appendSample(samples []time.Duration, seen *int64, d time.Duration) []time.Duration {
+	*seen++
+	if sampleLimit <= 0 || len(samples) < sampleLimit {
+		return append(samples, d)
+	}
+	if j := rand.Int63n(*seen); j < int64(sampleLimit) {
+		samples[j] = d
+	}
+	return samples
+}
+
+// trendSnapshot 为趋势数组的快照
+type trendSnapshot struct {
+	// Time 为各窗口结束的墙钟时间（按 -timezone），OffsetS 为其相对运行开始的单调时钟偏移（秒）
+	Time    []time.Time `json:"time"`
+	OffsetS []float64   `json:"offset_s"`
+	TPS     []float64   `json:"tps"`
+	QPS     []float64   `json:"qps"`
+	P50Ms   []float64   `json:"p50_ms"`
+	P95Ms   []float64   `json:"p95_ms"`
+	P99Ms   []float64   `json:"p99_ms"`
+	Apdex   []float64   `json:"apdex,omitempty"`
+	// Metrics 为从响应提取的各自定义指标每个窗口的均值
+	Metrics map[string][]float64 `json:"metrics,omitempty"`
+}
+
+// soakCheckpoint 为每个检查点写入磁盘的内容；State 与 Config 供 -resume 续跑
+type soakCheckpoint struct {
+	Summary statsSummary     `json:"summary"`
+	Trend   trendSnapshot    `json:"trend"`
+	State   *checkpointState `json:"state,omitempty"`
+	Config  *runConfig       `json:"config,omitempty"`
+}
+
+// writeCheckpoint 把当前累计统计与趋势快照写入 filename；先写临时文件再重命名，进程中途退出也不会留下半个文件
+func writeCheckpoint(filename string, stats *Stats, startTime, now time.Time) error {
+	config := effectiveConfig()
+	checkpoint := soakCheckpoint{
+		Summary: summarizeStats(stats, startTime, now),
+		Trend:   currentTrend(),
+		State: &checkpointState{
+			Elapsed:   now.Sub(startTime),
+			TotalTime: stats.TotalTime,
+			Apdex:     stats.Apdex,
+			Digest:    stats.Digest.sparse(),
+		},
+		Config: &config,
+	}
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// startAt 为 -start-at，到该时刻才开始发出请求；rendezvousAddr、rendezvousListen、rendezvousPeers 为 -rendezvous 系列选项，
+// 多个实例在协调者处会合，由协调者统一下发开始时刻。各实例按本机时钟等待，须事先用 NTP 等同步时钟
+var (
+	startAt          string
+	rendezvousAddr   string
+	rendezvousListen string
+	rendezvousPeers  int
+)
+
+// rendezvousLead 为协调者下发的开始时刻距所有实例到齐的时间，留出通知各实例的余量
+const rendezvousLead = 2 * time.Second
+
+// parseStartAt 解析 -start-at，接受 RFC 3339 时间（如 2024-06-01T02:00:00Z）
+func parseStartAt(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -start-at %q (expected RFC 3339, e.g. 2024-06-01T02:00:00Z)", value)
+	}
+	return t, nil
+}
+
+// checkStartOptions 校验 -start-at 与 -rendezvous 系列选项
+func checkStartOptions() error {
+	if startAt != "" {
+		if _, err := parseStartAt(startAt); err != nil {
+			return err
+		}
+	}
+	if rendezvousListen != "" && rendezvousAddr != "" {
+		return fmt.Errorf("-rendezvous and -rendezvous-listen cannot be combined")
+	}
+	if rendezvousListen != "" && rendezvousPeers <= 0 {
+		return fmt.Errorf("-rendezvous-listen requires -rendezvous-peers")
+	}
+	return nil
+}
+
+// coordinateStart 作为协调者等待 peers 个实例连接，向它们下发开始时刻后返回该时刻；
+// scheduled 非零且晚于到齐时刻 + rendezvousLead 时使用 scheduled
+func coordinateStart(listen string, peers int, scheduled time.Time) (time.Time, error) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("rendezvous: %v", err)
+	}
+	defer ln.Close()
+	fmt.Printf("🤝  Rendezvous: waiting for %d instance(s) on %s\n", peers, ln.Addr())
+	var conns []net.Conn
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+	for len(conns) < peers {
+		c, err := ln.Accept()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("rendezvous: %v", err)
+		}
+		conns = append(conns, c)
+		fmt.Printf("🤝  Rendezvous: %s joined (%d/%d)\n", c.RemoteAddr(), len(conns), peers)
+	}
+	start := time.Now().Add(rendezvousLead)
+	if scheduled.After(start) {
+		start = scheduled
+	}
+	for _, c := range conns {
+		c.SetWriteDeadline(time.Now().Add(rendezvousLead))
+		if _, err := fmt.Fprintf(c, "START %d\n", start.UnixNano()); err != nil {
+			return time.Time{}, fmt.Errorf("rendezvous: notifying %s: %v", c.RemoteAddr(), err)
+		}
+	}
+	return start, nil
+}
+
+// joinRendezvous 连接协调者并等待其下发开始时刻；协调者尚未启动时每秒重试
+func joinRendezvous(addr string) (time.Time, error) {
+	fmt.Printf("🤝  Rendezvous: joining %s\n", addr)
+	var conn net.Conn
+	for {
+		var err error
+		if conn, err = net.DialTimeout("tcp", addr, 5*time.Second); err == nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return time.Time{}, fmt.Errorf("rendezvous: %v", err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != "START" {
+		return time.Time{}, fmt.Errorf("rendezvous: unexpected message %q", strings.TrimSpace(line))
+	}
+	nanos, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("rendezvous: unexpected message %q", strings.TrimSpace(line))
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// waitForStart 按 -start-at 与 -rendezvous 确定开始时刻并等待到该时刻；均未指定时立即返回
+func waitForStart() error {
+	var start time.Time
+	if startAt != "" {
+		start, _ = parseStartAt(startAt)
+	}
+	switch {
+	case rendezvousListen != "":
+		var err error
+		if start, err = coordinateStart(rendezvousListen, rendezvousPeers, start); err != nil {
+			return err
+		}
+	case rendezvousAddr != "":
+		var err error
+		if start, err = joinRendezvous(rendezvousAddr); err != nil {
+			return err
+		}
+	}
+	if start.IsZero() {
+		return nil
+	}
+	wait := time.Until(start)
+	if wait <= 0 {
+		fmt.Printf("⏰  Start time %s has already passed, starting now\n", start.UTC().Format(time.RFC3339Nano))
+		return nil
+	}
+	fmt.Printf("⏰  Starting at %s (in %s)\n", start.UTC().Format(time.RFC3339Nano), wait.Round(time.Millisecond))
+	time.Sleep(wait)
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/schollz/progressbar/v3"
+)
+
+// streamStats 保存流式响应模式下的统计数据，所有连接共享，加锁确保并发安全
+type streamStats struct {
+	mu             sync.Mutex
+	Streams        int64
+	FailedStreams  int64
+	Events         int64
+	FirstEvent     []time.Duration
+	InterEvent     []time.Duration
+	ConnEventRates []float64
+	StatusCodes    map[int]int
+}
+
+// 连接失败或流未收到任何事件即结束时，重连前等待的初始与最长时间；连续失败时等待时间加倍，收到事件后恢复
+const (
+	streamRetryMin = 100 * time.Millisecond
+	streamRetryMax = 5 * time.Second
+)
+
+// runStream 以 concurrency 个连接持续消费长连接流式响应（SSE 或按行分隔的 chunked 响应），
+// 对端关闭流后重连，直到 duration 结束；失败的连接按指数退避重连，避免对不可用的目标空转
+func runStream(url, method string, concurrency int, duration time.Duration) {
+	fmt.Printf("\n🌍  Target URL: %s\n", url)
+	fmt.Printf("🌊  Streaming Connections: %d, Duration: %s\n", concurrency, duration)
+	fmt.Printf("📡  HTTP Method: %s\n", method)
+	fmt.Println("======================================")
+
+	// 流式响应持续时间不可预期，不能使用带整体超时的全局客户端
+	client := &http.Client{Transport: clientKeepAlive.Transport}
+	stats := &streamStats{StatusCodes: make(map[int]int)}
+	bar := progressbar.Default(-1, "events")
+
+	startTime := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), startTime.Add(duration))
+	defer cancel()
+
+	// seq 为已打开（或尝试打开）的流数，作为请求序号决定每个流的请求与模板渲染，指定 -seed 时可复现
+	var seq int64
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delay := streamRetryMin
+			for ctx.Err() == nil {
+				if consumeStream(ctx, client, url, method, atomic.AddInt64(&seq, 1), stats, bar) {
+					delay = streamRetryMin
+					continue
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(delay):
+				}
+				delay = min(delay*2, streamRetryMax)
+			}
+		}()
+	}
+	wg.Wait()
+	bar.Finish()
+
+	fmt.Println("\n======================================")
+	fmt.Println("✅  Test completed! Final statistics:")
+	reportStreamStats(stats, time.Since(startTime))
+}
+
+// consumeStream 以第 n 个请求打开一个流并逐个读取事件，记录首事件时间、事件间隔与该连接的事件速率；
+// 请求的选取与模板渲染与普通压测相同，由 n 决定。返回流是否成功建立并收到了至少一个事件
+func consumeStream(ctx context.Context, client *http.Client, url, method string, n int64, stats *streamStats, bar *progressbar.ProgressBar) bool {
+	spec := renderSpec(getRandomRequest(requestRand(n, randBody)), nil, n)
+	req, err := newHTTPRequest(spec, url, method)
+	if err != nil {
+		stats.mu.Lock()
+		stats.FailedStreams++
+		stats.mu.Unlock()
+		return false
+	}
+	req = req.WithContext(ctx)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream, application/x-ndjson, */*")
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			stats.mu.Lock()
+			stats.FailedStreams++
+			stats.mu.Unlock()
+		}
+		return false
+	}
+	defer resp.Body.Close()
+
+	stats.mu.Lock()
+	stats.Streams++
+	stats.StatusCodes[resp.StatusCode]++
+	stats.mu.Unlock()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		stats.mu.Lock()
+		stats.FailedStreams++
+		stats.mu.Unlock()
+		return false
+	}
+
+	sse := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	reader := bufio.NewReader(resp.Body)
+	var events int64
+	var last time.Time
+	pending := false
+	for {
+		line, err := reader.ReadString('\n')
+		trimmed := strings.TrimRight(line, "\r\n")
+		// SSE 以空行作为事件结束标志；其他流式响应按非空行计为一个事件
+		complete := false
+		if sse {
+			if trimmed == "" && pending {
+				complete = true
+				pending = false
+			} else if trimmed != "" && !strings.HasPrefix(trimmed, ":") {
+				pending = true
+			}
+		} else if trimmed != "" {
+			complete = true
+		}
+		if complete {
+			now := time.Now()
+			stats.mu.Lock()
+			if events == 0 {
+				stats.FirstEvent = append(stats.FirstEvent, now.Sub(start))
+			} else {
+				stats.InterEvent = append(stats.InterEvent, now.Sub(last))
+			}
+			stats.Events++
+			stats.mu.Unlock()
+			bar.Add(1)
+			events++
+			last = now
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if lifetime := time.Since(start); lifetime > 0 {
+		stats.mu.Lock()
+		stats.ConnEventRates = append(stats.ConnEventRates, float64(events)/lifetime.Seconds())
+		stats.mu.Unlock()
+	}
+	return events > 0
+}
+
+// reportStreamStats 输出流式响应模式的最终统计
+func reportStreamStats(stats *streamStats, elapsed time.Duration) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	sort.Slice(stats.FirstEvent, func(i, j int) bool { return stats.FirstEvent[i] < stats.FirstEvent[j] })
+	sort.Slice(stats.InterEvent, func(i, j int) bool { return stats.InterEvent[i] < stats.InterEvent[j] })
+	sort.Float64s(stats.ConnEventRates)
+
+	var eventRate, avgConnRate, minConnRate float64
+	if elapsed.Seconds() > 0 {
+		eventRate = float64(stats.Events) / elapsed.Seconds()
+	}
+	if len(stats.ConnEventRates) > 0 {
+		for _, r := range stats.ConnEventRates {
+			avgConnRate += r
+		}
+		avgConnRate /= float64(len(stats.ConnEventRates))
+		minConnRate = stats.ConnEventRates[0]
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Metric", "Value"})
+	table.Append([]string{"Streams Opened", fmt.Sprintf("%d", stats.Streams)})
+	table.Append([]string{"Failed Streams", fmt.Sprintf("%d", stats.FailedStreams)})
+	table.Append([]string{"Total Events", fmt.Sprintf("%d", stats.Events)})
+	table.Append([]string{"Events/s", fmt.Sprintf("%.2f", eventRate)})
+	table.Append([]string{"Events/s per Conn (avg)", fmt.Sprintf("%.2f", avgConnRate)})
+	table.Append([]string{"Events/s per Conn (min)", fmt.Sprintf("%.2f", minConnRate)})
+	table.Append([]string{"First Event P50", fmt.Sprintf("%d ms", percentile(stats.FirstEvent, 50).Milliseconds())})
+	table.Append([]string{"First Event P95", fmt.Sprintf("%d ms", percentile(stats.FirstEvent, 95).Milliseconds())})
+	table.Append([]string{"First Event P99", fmt.Sprintf("%d ms", percentile(stats.FirstEvent, 99).Milliseconds())})
+	table.Append([]string{"Inter-Event P50", fmt.Sprintf("%d ms", percentile(stats.InterEvent, 50).Milliseconds())})
+	table.Append([]string{"Inter-Event P95", fmt.Sprintf("%d ms", percentile(stats.InterEvent, 95).Milliseconds())})
+	table.Append([]string{"Inter-Event P99", fmt.Sprintf("%d ms", percentile(stats.InterEvent, 99).Milliseconds())})
+	table.Render()
+
+	fmt.Println("\n📡  HTTP Status Code Statistics:")
+	for code, count := range stats.StatusCodes {
+		fmt.Printf("  - %d: %d times\n", code, count)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+func TestConsumeStreamRendersTemplates(t *testing.T) {
+	bodies := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- r.Header.Get("X-Token") + " " + string(b)
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: ok\n\n")
+	}))
+	defer srv.Close()
+
+	savedPool := requestPool
+	defer func() { requestPool = savedPool }()
+	requestPool = []*requestSpec{{
+		Headers: http.Header{"X-Token": {"{{randInt 1 1000000000}}"}},
+		Body:    `{"n":{{fake.Int 1 1000000000}}}`,
+	}}
+	if err := compileRequestTemplates(srv.URL, false); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(n int64) string {
+		stats := &streamStats{StatusCodes: make(map[int]int)}
+		if !consumeStream(context.Background(), srv.Client(), srv.URL, http.MethodPost, n, stats, progressbar.DefaultSilent(-1)) {
+			t.Fatalf("stream %d delivered no events", n)
+		}
+		return <-bodies
+	}
+	first := send(1)
+	if strings.Contains(first, "{{") {
+		t.Fatalf("stream 1 sent %q, want a rendered request", first)
+	}
+	if again := send(1); again != first {
+		t.Errorf("stream 1 sent %q, then %q", first, again)
+	}
+	if other := send(2); other == first {
+		t.Errorf("streams 1 and 2 sent %q and %q", first, other)
+	}
+}
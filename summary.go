@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// statsSummary 为某一时刻累计统计数据的计算结果，供终端输出与文件导出共用
+type statsSummary struct {
+	// Timestamp、StartTime 为统计时刻与运行开始的墙钟时间（按 -timezone）
+	Timestamp       time.Time   `json:"timestamp"`
+	StartTime       time.Time   `json:"start_time"`
+	ElapsedSeconds  float64     `json:"elapsed_seconds"`
+	TotalRequests   int64       `json:"total_requests"`
+	SuccessRequests int64       `json:"success_requests"`
+	FailedRequests  int64       `json:"failed_requests"`
+	TPS             float64     `json:"tps"`
+	QPS             float64     `json:"qps"`
+	P50Ms           float64     `json:"p50_ms"`
+	P95Ms           float64     `json:"p95_ms"`
+	P99Ms           float64     `json:"p99_ms"`
+	StatusCodes     map[int]int `json:"status_codes"`
+	// LateRequests 为 -request-deadline 下迟到的成功请求数，不计入 SuccessRequests 与 FailedRequests
+	LateRequests int64 `json:"late_requests,omitempty"`
+	// Latency 为完整耗时、TTFB 与服务端耗时各自的百分位及 -latency-metric，只在最终结果中填写
+	Latency *latencySummary `json:"latency,omitempty"`
+	// Samples 为参与百分位计算的时延样本数
+	Samples int           `json:"samples"`
+	Apdex   *apdexSummary `json:"apdex,omitempty"`
+	// Engine 为 -engine 指定的客户端实现，不同实现的结果不宜直接比较
+	Engine string `json:"engine"`
+	// Tags 为 -tag 指定的运行元数据
+	Tags map[string]string `json:"tags,omitempty"`
+	// Throttled 为 -respect-retry-after 下因 Retry-After 暂停的统计
+	Throttled *throttleSummary `json:"throttled,omitempty"`
+	// QueueWait 为开放模型或 -max-inflight 下请求的排队时间
+	QueueWait *queueWaitSummary `json:"queue_wait,omitempty"`
+	// Metrics 为从响应提取的自定义指标
+	Metrics []metricSummary `json:"metrics,omitempty"`
+	// Scenarios 为 -scenarios 下各场景的汇总，只在最终结果中填写
+	Scenarios []scenarioSummary `json:"scenarios,omitempty"`
+	// ByTag 为按请求标签的统计，Filter 为满足 -report-filter 的请求的统计，只在最终结果中填写
+	ByTag  []tagSummary   `json:"by_tag,omitempty"`
+	Filter *filterSummary `json:"filter,omitempty"`
+	// HeaderValues 为 -capture-header 各 header 取值出现的次数，只在最终结果中填写
+	HeaderValues map[string]map[string]int64 `json:"header_values,omitempty"`
+	// Backends 为 -backend-header 下按后端实例的统计与公平性指数，只在最终结果中填写
+	Backends *backendSummary `json:"backends,omitempty"`
+	// Revalidation 为 -conditional 下条件请求的 304 比例与时延，只在最终结果中填写
+	Revalidation *revalidationSummary `json:"conditional,omitempty"`
+	// Preflight 为 -cors-origin 下 CORS 预检的数量、失败率与时延，只在最终结果中填写
+	Preflight *preflightSummary `json:"cors_preflight,omitempty"`
+	// Idempotency 为 -idempotency-header 下重复提交的统计，只在最终结果中填写
+	Idempotency *idempotencyStats `json:"idempotency,omitempty"`
+	// Churn 为 -close-every 下新连接与复用连接的时延与握手开销，只在最终结果中填写
+	Churn *churnSummary `json:"connection_churn,omitempty"`
+	// Chaos 为 -chaos-reset-rate 与 -chaos-stall 注入的故障次数，只在最终结果中填写
+	Chaos *chaosSummary `json:"chaos,omitempty"`
+	// Late 为 -request-deadline 下迟到的成功请求的数量与耗时，只在最终结果中填写
+	Late *lateSummary `json:"request_deadline,omitempty"`
+	// SLO 为 -slo-buckets 下各时延上限内的请求百分比与错误预算消耗，只在最终结果中填写
+	SLO *sloSummary `json:"slo,omitempty"`
+	// Capacity 为按 Little 定律分析的并发、计划与实际速率及吞吐量拐点，只在最终结果中填写
+	Capacity *littlesLawSummary `json:"capacity,omitempty"`
+}
+
+// summarizeStats 计算 startTime 到 now 区间的累计统计结果，会对 stats.ResponseTimes 原地排序；
+// ResponseTimes 为 nil（snapshotWorkerStats 的快照）时按直方图近似计算百分位
+func summarizeStats(stats *Stats, startTime, now time.Time) statsSummary {
+	summary := statsSummary{
+		Timestamp:       wallTime(now),
+		StartTime:       wallTime(startTime),
+		ElapsedSeconds:  now.Sub(startTime).Seconds(),
+		TotalRequests:   stats.TotalRequests,
+		SuccessRequests: stats.SuccessRequests,
+		FailedRequests:  stats.FailedRequests,
+		LateRequests:    stats.Late.TotalRequests,
+		StatusCodes:     stats.StatusCodes,
+		Samples:         len(stats.ResponseTimes),
+		Apdex:           stats.Apdex.summary(),
+		Engine:          engine,
+		Tags:            runTags,
+		Throttled:       stats.Throttle.summary(now.Sub(startTime), len(stats.WorkerRequests)),
+		QueueWait:       stats.QueueWait.summary(),
+		Metrics:         summarizeMetrics(stats.Metrics),
+	}
+	if summary.ElapsedSeconds > 0 {
+		summary.TPS = float64(stats.SuccessRequests) / summary.ElapsedSeconds
+		summary.QPS = float64(stats.TotalRequests) / summary.ElapsedSeconds
+	}
+	if stats.ResponseTimes == nil {
+		// 周期输出的快照只有直方图
+		summary.Samples = int(stats.Digest.total)
+		summary.P50Ms = float64(stats.Digest.percentile(50).Milliseconds())
+		summary.P95Ms = float64(stats.Digest.percentile(95).Milliseconds())
+		summary.P99Ms = float64(stats.Digest.percentile(99).Milliseconds())
+		return summary
+	}
+	sort.Slice(stats.ResponseTimes, func(i, j int) bool {
+		return stats.ResponseTimes[i] < stats.ResponseTimes[j]
+	})
+	summary.P50Ms = float64(percentile(stats.ResponseTimes, 50).Milliseconds())
+	summary.P95Ms = float64(percentile(stats.ResponseTimes, 95).Milliseconds())
+	summary.P99Ms = float64(percentile(stats.ResponseTimes, 99).Milliseconds())
+	return summary
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// runTags 为 -tag 指定的运行元数据，附加在所有导出的汇总数据上
+var runTags = map[string]string{}
+
+// tagFlags 实现 flag.Value，允许多次指定 -tag key=value，写入 runTags
+type tagFlags struct{}
+
+func (tagFlags) String() string {
+	return formatTags(runTags)
+}
+
+func (tagFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	key := strings.TrimSpace(kv[0])
+	if len(kv) != 2 || key == "" {
+		return fmt.Errorf("invalid tag %q, expected key=value", value)
+	}
+	runTags[key] = strings.TrimSpace(kv[1])
+	return nil
+}
+
+// formatTags 按 key 排序输出 k1=v1, k2=v2
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + tags[k]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reportFilter 为 -report-filter 指定的标签条件，非空时额外汇总同时带有所有这些标签的请求
+var reportFilter = map[string]string{}
+
+// reportFilterFlags 实现 flag.Value，允许多次指定 -report-filter key=value，写入 reportFilter
+type reportFilterFlags struct{}
+
+func (reportFilterFlags) String() string {
+	return formatTags(reportFilter)
+}
+
+func (reportFilterFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	key := strings.TrimSpace(kv[0])
+	if len(kv) != 2 || key == "" {
+		return fmt.Errorf("invalid report filter %q, expected key=value", value)
+	}
+	reportFilter[key] = strings.TrimSpace(kv[1])
+	return nil
+}
+
+// matchesReportFilter 判断请求的标签是否满足 -report-filter 的所有条件
+func matchesReportFilter(tags map[string]string) bool {
+	for k, v := range reportFilter {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// tagStats 返回标签 key=value 对应的统计数据，不存在时创建
+func (ws *WorkerStats) tagStats(key string) *URLStats {
+	ts, ok := ws.ByTag[key]
+	if !ok {
+		ts = &URLStats{}
+		ws.ByTag[key] = ts
+	}
+	return ts
+}
+
+// recordTags 把请求计入其各个标签以及 -report-filter 的统计；responded 为 false 时请求未得到响应，不记录时延
+func (ws *WorkerStats) recordTags(spec *requestSpec, success, responded bool, d time.Duration) {
+	if len(spec.Tags) == 0 {
+		return
+	}
+	record := func(us *URLStats) {
+		us.TotalRequests++
+		if !success {
+			us.FailedRequests++
+		}
+		if responded {
+			us.ResponseTimes = appendSample(us.ResponseTimes, &us.sampleCount, d)
+		}
+	}
+	for k, v := range spec.Tags {
+		record(ws.tagStats(k + "=" + v))
+	}
+	if len(reportFilter) > 0 && matchesReportFilter(spec.Tags) {
+		record(&ws.Filtered)
+	}
+}
+
+// tagSummary 为导出汇总中一个标签值的统计
+type tagSummary struct {
+	Tag            string  `json:"tag"`
+	Value          string  `json:"value"`
+	TotalRequests  int64   `json:"total_requests"`
+	FailedRequests int64   `json:"failed_requests"`
+	P50Ms          float64 `json:"p50_ms"`
+	P95Ms          float64 `json:"p95_ms"`
+	P99Ms          float64 `json:"p99_ms"`
+}
+
+// filterSummary 为导出汇总中满足 -report-filter 的请求的统计
+type filterSummary struct {
+	Filter         map[string]string `json:"filter"`
+	TotalRequests  int64             `json:"total_requests"`
+	FailedRequests int64             `json:"failed_requests"`
+	TPS            float64           `json:"tps"`
+	P50Ms          float64           `json:"p50_ms"`
+	P95Ms          float64           `json:"p95_ms"`
+	P99Ms          float64           `json:"p99_ms"`
+}
+
+// percentilesMs 对 us 的时延样本原地排序，返回 P50、P95、P99（毫秒）
+func percentilesMs(us *URLStats) (float64, float64, float64) {
+	sort.Slice(us.ResponseTimes, func(i, j int) bool { return us.ResponseTimes[i] < us.ResponseTimes[j] })
+	p := func(percent float64) float64 { return float64(percentile(us.ResponseTimes, percent).Milliseconds()) }
+	return p(50), p(95), p(99)
+}
+
+// summarizeTags 返回按标签名、请求数排序的各标签值统计
+func summarizeTags(byTag map[string]*URLStats) []tagSummary {
+	result := make([]tagSummary, 0, len(byTag))
+	for key, us := range byTag {
+		kv := strings.SplitN(key, "=", 2)
+		s := tagSummary{Tag: kv[0], Value: kv[1], TotalRequests: us.TotalRequests, FailedRequests: us.FailedRequests}
+		s.P50Ms, s.P95Ms, s.P99Ms = percentilesMs(us)
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tag != result[j].Tag {
+			return result[i].Tag < result[j].Tag
+		}
+		if result[i].TotalRequests != result[j].TotalRequests {
+			return result[i].TotalRequests > result[j].TotalRequests
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
+
+// summarizeFilter 返回满足 -report-filter 的请求的统计，未指定时返回 nil
+func summarizeFilter(filtered *URLStats, elapsed time.Duration) *filterSummary {
+	if len(reportFilter) == 0 {
+		return nil
+	}
+	s := &filterSummary{Filter: reportFilter, TotalRequests: filtered.TotalRequests, FailedRequests: filtered.FailedRequests}
+	if elapsed > 0 {
+		s.TPS = float64(filtered.TotalRequests-filtered.FailedRequests) / elapsed.Seconds()
+	}
+	s.P50Ms, s.P95Ms, s.P99Ms = percentilesMs(filtered)
+	return s
+}
+
+// reportTagStats 输出按标签分组的请求数、失败数与时延百分位，同一标签的各个值相邻
+func reportTagStats(tags []tagSummary) {
+	if len(tags) == 0 {
+		return
+	}
+	fmt.Println("\n🏷️   Per-Tag Statistics:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Tag", "Value", "Requests", "Failed", "P50", "P95", "P99"})
+	for _, t := range tags {
+		table.Append([]string{
+			t.Tag,
+			t.Value,
+			fmt.Sprintf("%d", t.TotalRequests),
+			fmt.Sprintf("%d", t.FailedRequests),
+			fmt.Sprintf("%.0f ms", t.P50Ms),
+			fmt.Sprintf("%.0f ms", t.P95Ms),
+			fmt.Sprintf("%.0f ms", t.P99Ms),
+		})
+	}
+	table.Render()
+}
+
+// reportFiltered 输出满足 -report-filter 的请求的统计及其占全部请求的比例
+func reportFiltered(s *filterSummary, total int64) {
+	if s == nil {
+		return
+	}
+	var share float64
+	if total > 0 {
+		share = float64(s.TotalRequests) / float64(total) * 100
+	}
+	fmt.Printf("\n🔎  Report Filter (%s):\n", formatTags(s.Filter))
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Requests", "Share", "Failed", "TPS", "P50", "P95", "P99"})
+	table.Append([]string{
+		fmt.Sprintf("%d", s.TotalRequests),
+		fmt.Sprintf("%.1f%%", share),
+		fmt.Sprintf("%d", s.FailedRequests),
+		fmt.Sprintf("%.2f", s.TPS),
+		fmt.Sprintf("%.0f ms", s.P50Ms),
+		fmt.Sprintf("%.0f ms", s.P95Ms),
+		fmt.Sprintf("%.0f ms", s.P99Ms),
+	})
+	table.Render()
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/schollz/progressbar/v3"
+)
+
+// tcpStats 保存 tcp 子命令的统计数据，所有 worker 共享，加锁确保并发安全
+type tcpStats struct {
+	mu             sync.Mutex
+	Attempts       int64
+	Success        int64
+	ConnectErrors  int64
+	HandshakeError int64
+	EchoErrors     int64
+	ConnectTimes   []time.Duration
+	HandshakeTimes []time.Duration
+	EchoTimes      []time.Duration
+}
+
+// runTCPCommand 解析 tcp 子命令参数并执行：每次迭代新建一条连接（可选 TLS 握手与回显负载）后关闭
+func runTCPCommand(args []string) {
+	fs := flag.NewFlagSet("tcp", flag.ExitOnError)
+	var addr string
+	var concurrency int
+	var total int
+	var useTLS bool
+	var insecure bool
+	var serverName string
+	var payload string
+	var timeout time.Duration
+	fs.StringVar(&addr, "addr", "localhost:8080", "Target address (host:port)")
+	fs.IntVar(&concurrency, "c", 10, "Number of concurrent workers")
+	fs.IntVar(&total, "n", 100, "Total number of connections")
+	fs.BoolVar(&useTLS, "tls", false, "Perform a TLS handshake after connecting")
+	fs.BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification")
+	fs.StringVar(&serverName, "sni", "", "TLS server name (defaults to the host part of -addr)")
+	fs.StringVar(&payload, "payload", "", "Payload to send after connecting; the same number of bytes is expected back (echo). Empty means handshake only")
+	fs.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for each connect, handshake and echo")
+	fs.Parse(args)
+
+	if serverName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			serverName = host
+		}
+	}
+	tlsConfig := &tls.Config{ServerName: serverName, InsecureSkipVerify: insecure}
+
+	fmt.Printf("\n🌍  Target Address: %s\n", addr)
+	fmt.Printf("🔄  Concurrency: %d, Total Connections: %d\n", concurrency, total)
+	fmt.Printf("🔐  TLS: %v, Echo Payload: %d bytes\n", useTLS, len(payload))
+	fmt.Println("======================================")
+
+	stats := &tcpStats{}
+	bar := progressbar.Default(int64(total))
+	var issued int64
+	startTime := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.AddInt64(&issued, 1) <= int64(total) {
+				tcpIteration(addr, useTLS, tlsConfig, []byte(payload), timeout, stats)
+				bar.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Println("\n======================================")
+	fmt.Println("✅  Test completed! Final statistics:")
+	reportTCPStats(stats, time.Since(startTime), useTLS, payload != "")
+}
+
+// tcpIteration 完成一次连接 -> (TLS 握手) -> (回显) -> 关闭，并记录各阶段耗时
+func tcpIteration(addr string, useTLS bool, tlsConfig *tls.Config, payload []byte, timeout time.Duration, stats *tcpStats) {
+	stats.mu.Lock()
+	stats.Attempts++
+	stats.mu.Unlock()
+
+	startConnect := time.Now()
+	rawConn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		stats.mu.Lock()
+		stats.ConnectErrors++
+		stats.mu.Unlock()
+		return
+	}
+	// 各阶段耗时在完成时即记录，后续阶段失败不影响已测得的建连与握手耗时
+	connectTime := time.Since(startConnect)
+	stats.mu.Lock()
+	stats.ConnectTimes = append(stats.ConnectTimes, connectTime)
+	stats.mu.Unlock()
+	conn := rawConn
+	defer func() { conn.Close() }()
+
+	if useTLS {
+		tlsConn := tls.Client(rawConn, tlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+		startHandshake := time.Now()
+		if err := tlsConn.Handshake(); err != nil {
+			stats.mu.Lock()
+			stats.HandshakeError++
+			stats.mu.Unlock()
+			return
+		}
+		handshakeTime := time.Since(startHandshake)
+		stats.mu.Lock()
+		stats.HandshakeTimes = append(stats.HandshakeTimes, handshakeTime)
+		stats.mu.Unlock()
+		conn = tlsConn
+	}
+
+	var echoTime time.Duration
+	if len(payload) > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+		startEcho := time.Now()
+		buf := make([]byte, len(payload))
+		_, err := conn.Write(payload)
+		if err == nil {
+			_, err = io.ReadFull(conn, buf)
+		}
+		if err != nil {
+			stats.mu.Lock()
+			stats.EchoErrors++
+			stats.mu.Unlock()
+			return
+		}
+		echoTime = time.Since(startEcho)
+	}
+
+	stats.mu.Lock()
+	stats.Success++
+	if len(payload) > 0 {
+		stats.EchoTimes = append(stats.EchoTimes, echoTime)
+	}
+	stats.mu.Unlock()
+}
+
+// reportTCPStats 输出 tcp 子命令的最终统计
+func reportTCPStats(stats *tcpStats, elapsed time.Duration, useTLS, echo bool) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	for _, durations := range [][]time.Duration{stats.ConnectTimes, stats.HandshakeTimes, stats.EchoTimes} {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	}
+	var connRate float64
+	if elapsed.Seconds() > 0 {
+		connRate = float64(stats.Success) / elapsed.Seconds()
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Metric", "Value"})
+	table.Append([]string{"Total Connections", fmt.Sprintf("%d", stats.Attempts)})
+	table.Append([]string{"Successful", fmt.Sprintf("%d", stats.Success)})
+	table.Append([]string{"Connect Errors", fmt.Sprintf("%d", stats.ConnectErrors)})
+	if useTLS {
+		table.Append([]string{"Handshake Errors", fmt.Sprintf("%d", stats.HandshakeError)})
+	}
+	if echo {
+		table.Append([]string{"Echo Errors", fmt.Sprintf("%d", stats.EchoErrors)})
+	}
+	table.Append([]string{"Connections/s", fmt.Sprintf("%.2f", connRate)})
+	appendDurationRows := func(name string, durations []time.Duration) {
+		for _, p := range []float64{50, 95, 99} {
+			table.Append([]string{fmt.Sprintf("%s P%.0f", name, p), fmt.Sprintf("%.2f ms", float64(percentile(durations, p).Microseconds())/1000)})
+		}
+	}
+	appendDurationRows("Connect", stats.ConnectTimes)
+	if useTLS {
+		appendDurationRows("TLS Handshake", stats.HandshakeTimes)
+	}
+	if echo {
+		appendDurationRows("Echo RTT", stats.EchoTimes)
+	}
+	table.Render()
+}
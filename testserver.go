@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// statusMix 为测试服务器按权重返回的状态码
+type statusMix struct {
+	codes      []int
+	cumulative []float64
+}
+
+// parseStatusMix 解析 200=95,500=4,503=1 形式的状态码组合，只写状态码时权重为 1
+func parseStatusMix(s string) (*statusMix, error) {
+	mix := &statusMix{}
+	var total float64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, weight := part, "1"
+		if k, v, found := strings.Cut(part, "="); found {
+			code, weight = k, v
+		}
+		c, err := strconv.Atoi(strings.TrimSpace(code))
+		if err != nil || c < 100 || c > 599 {
+			return nil, fmt.Errorf("invalid status code %q in -status", code)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q in -status", weight)
+		}
+		total += w
+		mix.codes = append(mix.codes, c)
+		mix.cumulative = append(mix.cumulative, total)
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("-status needs at least one status code with a positive weight")
+	}
+	return mix, nil
+}
+
+// pick 按权重随机选择一个状态码
+func (m *statusMix) pick() int {
+	target := rand.Float64() * m.cumulative[len(m.cumulative)-1]
+	for i, c := range m.cumulative {
+		if target < c {
+			return m.codes[i]
+		}
+	}
+	return m.codes[len(m.codes)-1]
+}
+
+// testServerStats 为测试服务器按状态码统计的响应数，用于与压测结果对照
+type testServerStats struct {
+	mu     sync.Mutex
+	counts map[int]int64
+	start  time.Time
+}
+
+func (s *testServerStats) record(status int) {
+	s.mu.Lock()
+	s.counts[status]++
+	s.mu.Unlock()
+}
+
+// report 输出各状态码的响应数与平均速率
+func (s *testServerStats) report() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	codes := make([]int, 0, len(s.counts))
+	var total int64
+	for code, n := range s.counts {
+		codes = append(codes, code)
+		total += n
+	}
+	sort.Ints(codes)
+	elapsed := time.Since(s.start)
+	fmt.Printf("\n🧮  Served %d responses in %s (%.2f req/s):\n", total, elapsed.Round(time.Second), float64(total)/elapsed.Seconds())
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Status", "Responses", "Share"})
+	for _, code := range codes {
+		table.Append([]string{strconv.Itoa(code), fmt.Sprintf("%d", s.counts[code]), fmt.Sprintf("%.2f%%", float64(s.counts[code])*100/float64(total))})
+	}
+	table.Render()
+}
+
+// runTestServerCommand 解析 server 子命令参数并运行测试服务器：每个请求等待 -delay 加上 [0, -jitter) 的随机时长后，
+// 按 -error-rate 与 -status 选择状态码，返回 -body-size 字节（或 -echo 时原样返回请求体）。
+// 请求可用查询参数 delay、status、size 覆盖对应的设置，如 /?delay=200ms&status=503
+func runTestServerCommand(args []string) {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	var listen, bodySizeValue, statusValue string
+	var delay, jitter time.Duration
+	var errorRate float64
+	var echo bool
+	fs.StringVar(&listen, "listen", ":8080", "Address the test server listens on")
+	fs.DurationVar(&delay, "delay", 0, "Fixed delay before every response")
+	fs.DurationVar(&jitter, "jitter", 0, "Extra random delay, uniform between 0 and this value")
+	fs.StringVar(&bodySizeValue, "body-size", "0", "Response body size, e.g. 512 or 4kb")
+	fs.StringVar(&statusValue, "status", "200", "Status code mix, e.g. 200=95,404=5 (weights are relative)")
+	fs.Float64Var(&errorRate, "error-rate", 0, "Fraction of responses (0-1) answered with 500 regardless of -status")
+	fs.BoolVar(&echo, "echo", false, "Echo the request body back instead of a generated body")
+	fs.Parse(args)
+
+	bodySize, err := parseByteSize(bodySizeValue)
+	if err != nil || bodySize < 0 {
+		slog.Error(fmt.Sprintf("Invalid -body-size %q", bodySizeValue))
+		exit(1)
+	}
+	mix, err := parseStatusMix(statusValue)
+	if err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+	if errorRate < 0 || errorRate > 1 || delay < 0 || jitter < 0 {
+		slog.Error("-error-rate must be between 0 and 1, -delay and -jitter must not be negative")
+		exit(1)
+	}
+	body := bytes.Repeat([]byte("x"), int(bodySize))
+	stats := &testServerStats{counts: make(map[int]int64), start: time.Now()}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ := ioutil.ReadAll(r.Body)
+		query := r.URL.Query()
+		wait := delay
+		if v := query.Get("delay"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+				wait = d
+			}
+		} else if jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(jitter)))
+		}
+		status := mix.pick()
+		if errorRate > 0 && rand.Float64() < errorRate {
+			status = http.StatusInternalServerError
+		}
+		if v := query.Get("status"); v != "" {
+			if c, err := strconv.Atoi(v); err == nil && c >= 100 && c <= 599 {
+				status = c
+			}
+		}
+		payload := body
+		if echo {
+			payload = reqBody
+		}
+		if v := query.Get("size"); v != "" {
+			if n, err := parseByteSize(v); err == nil && n >= 0 {
+				payload = bytes.Repeat([]byte("x"), int(n))
+			}
+		}
+		time.Sleep(wait)
+		if ct := r.Header.Get("Content-Type"); echo && ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.WriteHeader(status)
+		w.Write(payload)
+		stats.record(status)
+	})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		stats.report()
+		exit(0)
+	}()
+	fmt.Printf("🧰  Test server on %s: delay %s + jitter %s, body %d bytes (echo: %v), status %s, error rate %.2f%%\n",
+		listen, delay, jitter, bodySize, echo, statusValue, errorRate*100)
+	if err := http.ListenAndServe(listen, handler); err != nil {
+		slog.Error(err.Error())
+		exit(1)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// threshold 为一条通过/失败判定规则，如 p99<500ms
+type threshold struct {
+	Expr   string
+	Metric string
+	Op     string
+	Value  float64
+}
+
+// thresholdResult 为一条规则在最终汇总上的判定结果
+type thresholdResult struct {
+	Threshold string  `json:"threshold"`
+	Actual    float64 `json:"actual"`
+	Passed    bool    `json:"passed"`
+}
+
+// thresholdFlags 实现 flag.Value，允许多次指定 -threshold
+type thresholdFlags []threshold
+
+func (t *thresholdFlags) String() string {
+	return fmt.Sprintf("%d thresholds", len(*t))
+}
+
+func (t *thresholdFlags) Set(value string) error {
+	th, err := parseThreshold(value)
+	if err != nil {
+		return err
+	}
+	*t = append(*t, th)
+	return nil
+}
+
+var thresholdPattern = regexp.MustCompile(`^\s*([a-z0-9_]+)\s*(<=|>=|<|>)\s*(\S+)\s*$`)
+
+// parseThreshold 解析 metric<op>value，支持的 metric：
+//   - p50、p95、p99    值为时长（500ms、1s）或毫秒数
+//   - error_rate       失败请求百分比，可带 %
+//   - rps、tps         每秒请求数 / 每秒成功请求数
+//   - failed           失败请求数
+//   - apdex            Apdex 分数（需要 -apdex-t）
+//   - fairness         各后端实例的 Jain 公平性指数（需要 -backend-header）
+func parseThreshold(expr string) (threshold, error) {
+	m := thresholdPattern.FindStringSubmatch(expr)
+	if m == nil {
+		return threshold{}, fmt.Errorf("invalid threshold %q, expected e.g. p99<500ms", expr)
+	}
+	th := threshold{Expr: strings.TrimSpace(expr), Metric: m[1], Op: m[2]}
+	raw := m[3]
+	var err error
+	switch th.Metric {
+	case "p50", "p95", "p99":
+		if d, derr := time.ParseDuration(raw); derr == nil {
+			th.Value = float64(d) / float64(time.Millisecond)
+		} else {
+			th.Value, err = strconv.ParseFloat(raw, 64)
+		}
+	case "error_rate":
+		th.Value, err = strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+	case "rps", "tps", "failed", "apdex", "fairness":
+		th.Value, err = strconv.ParseFloat(raw, 64)
+	default:
+		return threshold{}, fmt.Errorf("unknown threshold metric %q in %q", th.Metric, expr)
+	}
+	if err != nil {
+		return threshold{}, fmt.Errorf("invalid threshold value in %q: %v", expr, err)
+	}
+	return th, nil
+}
+
+// thresholdMetric 从汇总结果中取出 metric 的实际值
+func thresholdMetric(s statsSummary, metric string) float64 {
+	switch metric {
+	case "p50":
+		return s.P50Ms
+	case "p95":
+		return s.P95Ms
+	case "p99":
+		return s.P99Ms
+	case "error_rate":
+		return errorRate(s)
+	case "rps":
+		return s.QPS
+	case "tps":
+		return s.TPS
+	case "failed":
+		return float64(s.FailedRequests)
+	case "apdex":
+		if s.Apdex != nil {
+			return s.Apdex.Score
+		}
+	case "fairness":
+		if s.Backends != nil {
+			return s.Backends.Fairness
+		}
+	}
+	return 0
+}
+
+// errorRate 返回失败请求占比（百分比）
+func errorRate(s statsSummary) float64 {
+	if s.TotalRequests == 0 {
+		return 0
+	}
+	return float64(s.FailedRequests) / float64(s.TotalRequests) * 100
+}
+
+// evaluateThresholds 依次判定所有规则
+func evaluateThresholds(thresholds []threshold, s statsSummary) []thresholdResult {
+	results := make([]thresholdResult, 0, len(thresholds))
+	for _, th := range thresholds {
+		actual := thresholdMetric(s, th.Metric)
+		var passed bool
+		switch th.Op {
+		case "<":
+			passed = actual < th.Value
+		case "<=":
+			passed = actual <= th.Value
+		case ">":
+			passed = actual > th.Value
+		case ">=":
+			passed = actual >= th.Value
+		}
+		results = append(results, thresholdResult{Threshold: th.Expr, Actual: actual, Passed: passed})
+	}
+	return results
+}
+
+// thresholdsPassed 判断是否所有规则都通过
+func thresholdsPassed(results []thresholdResult) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// reportThresholds 输出每条规则的判定结果
+func reportThresholds(results []thresholdResult) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Println("\n🎯  Thresholds:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Threshold", "Actual", "Result"})
+	for _, r := range results {
+		result := "✅ pass"
+		if !r.Passed {
+			result = "❌ fail"
+		}
+		table.Append([]string{r.Threshold, fmt.Sprintf("%.2f", r.Actual), result})
+	}
+	table.Render()
+}
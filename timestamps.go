@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// timezoneName 为 -timezone：输出中的墙钟时间按该时区显示（UTC、Local 或 IANA 名称如 Asia/Shanghai），
+// 便于与服务端日志、APM 追踪按同一时区对齐
+var timezoneName string
+
+// outputZone 为 -timezone 解析后的时区，默认为本地时区
+var outputZone = time.Local
+
+// runStartTime 为本次运行统计的起点；各输出中的偏移量均为相对它的单调时钟差值，不受运行期间系统时钟调整的影响
+var runStartTime time.Time
+
+// loadOutputZone 解析 -timezone，为空时使用本地时区
+func loadOutputZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid -timezone %q: %v", name, err)
+	}
+	outputZone = loc
+	return nil
+}
+
+// wallTime 返回按 -timezone 表示的墙钟时间（JSON 中为带时区偏移的 RFC 3339），结果不再带有单调时钟读数
+func wallTime(t time.Time) time.Time {
+	return t.In(outputZone)
+}
+
+// offsetMs 返回 t 相对 runStartTime 的毫秒数；两者都来自 time.Now() 时按单调时钟计算，运行开始前为 0
+func offsetMs(t time.Time) float64 {
+	if runStartTime.IsZero() || t.Before(runStartTime) {
+		return 0
+	}
+	return float64(t.Sub(runStartTime)) / float64(time.Millisecond)
+}
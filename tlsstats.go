@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// noSessionResumption 为 -no-session-resumption，禁用 TLS 会话恢复以测量完整握手的开销
+var noSessionResumption bool
+
+// enableSessionResumption 为两个客户端配置 TLS 会话缓存，使新连接可以凭 session ticket 恢复会话、跳过完整握手；
+// resume 为 false（-no-session-resumption）时每个新连接都做完整握手
+func enableSessionResumption(resume bool) {
+	for _, client := range []*http.Client{clientKeepAlive, clientNoKeepAlive} {
+		transport := client.Transport.(*http.Transport)
+		config := &tls.Config{}
+		if resume {
+			config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+		}
+		transport.TLSClientConfig = config
+		// 自定义 TLSClientConfig 后 Transport 默认不再尝试 HTTP/2，这里保持与默认配置相同
+		transport.ForceAttemptHTTP2 = true
+	}
+}
+
+// tlsStats 为新建连接的 TLS 握手统计，复用的连接不计入
+type tlsStats struct {
+	Handshakes int64
+	Resumed    int64
+	Failed     int64
+	Versions   map[string]int64
+	Ciphers    map[string]int64
+	ALPN       map[string]int64
+	// Times 为握手耗时样本，与时延样本一样受 -sample-limit 限制
+	Times       []time.Duration
+	sampleCount int64
+}
+
+// record 记录请求在新连接上完成（或失败）的握手
+func (s *tlsStats) record(t *requestTrace) {
+	t.mu.Lock()
+	start, done, state, err := t.tlsStart, t.tlsDone, t.tlsState, t.tlsErr
+	t.mu.Unlock()
+	if start.IsZero() || done.IsZero() {
+		return
+	}
+	if err != nil {
+		s.Failed++
+		return
+	}
+	if s.Versions == nil {
+		s.Versions = make(map[string]int64)
+		s.Ciphers = make(map[string]int64)
+		s.ALPN = make(map[string]int64)
+	}
+	s.Handshakes++
+	if state.DidResume {
+		s.Resumed++
+	}
+	s.Versions[tls.VersionName(state.Version)]++
+	s.Ciphers[tls.CipherSuiteName(state.CipherSuite)]++
+	alpn := state.NegotiatedProtocol
+	if alpn == "" {
+		alpn = "(none)"
+	}
+	s.ALPN[alpn]++
+	s.Times = appendSample(s.Times, &s.sampleCount, done.Sub(start))
+}
+
+// add 合并另一个 worker 的统计
+func (s *tlsStats) add(other tlsStats) {
+	s.Handshakes += other.Handshakes
+	s.Resumed += other.Resumed
+	s.Failed += other.Failed
+	if other.Versions != nil && s.Versions == nil {
+		s.Versions = make(map[string]int64)
+		s.Ciphers = make(map[string]int64)
+		s.ALPN = make(map[string]int64)
+	}
+	for k, v := range other.Versions {
+		s.Versions[k] += v
+	}
+	for k, v := range other.Ciphers {
+		s.Ciphers[k] += v
+	}
+	for k, v := range other.ALPN {
+		s.ALPN[k] += v
+	}
+	s.Times = append(s.Times, other.Times...)
+}
+
+// reportTLS 输出 TLS 握手的统计，没有新建 TLS 连接时不输出
+func reportTLS(s tlsStats) {
+	if s.Handshakes == 0 && s.Failed == 0 {
+		return
+	}
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.1f ms", float64(d.Microseconds())/1000)
+	}
+	sort.Slice(s.Times, func(i, j int) bool { return s.Times[i] < s.Times[j] })
+	fmt.Println("\n🔒  TLS Handshakes (new connections):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Metric", "Value"})
+	table.SetAutoWrapText(false)
+	table.Append([]string{"Handshakes", fmt.Sprintf("%d", s.Handshakes)})
+	if s.Handshakes > 0 {
+		table.Append([]string{"Full / Resumed", fmt.Sprintf("%d / %d (%.1f%% resumed)",
+			s.Handshakes-s.Resumed, s.Resumed, float64(s.Resumed)/float64(s.Handshakes)*100)})
+		table.Append([]string{"Handshake P50 / P95 / P99", ms(percentile(s.Times, 50)) + " / " + ms(percentile(s.Times, 95)) + " / " + ms(percentile(s.Times, 99))})
+		table.Append([]string{"Handshake Max", ms(s.Times[len(s.Times)-1])})
+	}
+	if s.Failed > 0 {
+		table.Append([]string{"Failed Handshakes", fmt.Sprintf("%d", s.Failed)})
+	}
+	for _, group := range []struct {
+		label  string
+		counts map[string]int64
+	}{{"Version", s.Versions}, {"Cipher Suite", s.Ciphers}, {"ALPN", s.ALPN}} {
+		names := make([]string, 0, len(group.counts))
+		for name := range group.counts {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if group.counts[names[i]] != group.counts[names[j]] {
+				return group.counts[names[i]] > group.counts[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names {
+			table.Append([]string{group.label + " " + name, fmt.Sprintf("%d (%.1f%%)", group.counts[name], float64(group.counts[name])/float64(s.Handshakes)*100)})
+		}
+	}
+	table.Render()
+}
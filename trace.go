@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// requestPhases 为一次请求各阶段的耗时；复用连接时 DNS、Connect、TLS 为 0
+type requestPhases struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	Write    time.Duration
+	Server   time.Duration
+	Transfer time.Duration
+}
+
+// phaseStats 累计某一阶段耗时的次数、总和、最小值与最大值
+type phaseStats struct {
+	Count int64
+	Sum   time.Duration
+	Min   time.Duration
+	Max   time.Duration
+}
+
+func (s *phaseStats) record(d time.Duration) {
+	if s.Count == 0 || d < s.Min {
+		s.Min = d
+	}
+	if d > s.Max {
+		s.Max = d
+	}
+	s.Count++
+	s.Sum += d
+}
+
+func (s *phaseStats) add(other phaseStats) {
+	if other.Count == 0 {
+		return
+	}
+	if s.Count == 0 || other.Min < s.Min {
+		s.Min = other.Min
+	}
+	if other.Max > s.Max {
+		s.Max = other.Max
+	}
+	s.Count += other.Count
+	s.Sum += other.Sum
+}
+
+func (s phaseStats) avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// phaseTotals 为所有得到响应的请求按阶段累计的耗时；Dial 为 DNS、TCP 建连与 TLS 握手之和
+type phaseTotals struct {
+	Dial     phaseStats
+	DNS      phaseStats
+	Write    phaseStats
+	Server   phaseStats
+	Transfer phaseStats
+}
+
+func (t *phaseTotals) record(p requestPhases) {
+	t.Dial.record(p.DNS + p.Connect + p.TLS)
+	t.DNS.record(p.DNS)
+	t.Write.record(p.Write)
+	t.Server.record(p.Server)
+	t.Transfer.record(p.Transfer)
+}
+
+func (t *phaseTotals) add(other phaseTotals) {
+	t.Dial.add(other.Dial)
+	t.DNS.add(other.DNS)
+	t.Write.add(other.Write)
+	t.Server.add(other.Server)
+	t.Transfer.add(other.Transfer)
+}
+
+// requestTrace 通过 httptrace 记录一次请求各阶段的时间点。
+// 拨号相关的回调可能在请求结束后（被其他请求复用的拨号）才触发，因此加锁保护
+type requestTrace struct {
+	mu           sync.Mutex
+	getConn      time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	dnsHost      string
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	tlsState     tls.ConnectionState
+	tlsErr       error
+	gotConn      time.Time
+	reused       bool
+	wroteHeaders time.Time
+	got100       time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+// clientTrace 返回写入该 requestTrace 的 httptrace.ClientTrace
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	mark := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(string) { mark(&t.getConn) },
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart, t.dnsHost = time.Now(), info.Host
+			t.mu.Unlock()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.dnsDone = time.Now()
+			host, d := t.dnsHost, t.dnsDone.Sub(t.dnsStart)
+			t.mu.Unlock()
+			recordResolution(host, info, d)
+		},
+		ConnectStart:      func(string, string) { mark(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { mark(&t.connectDone) },
+		TLSHandshakeStart: func() { mark(&t.tlsStart) },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.mu.Lock()
+			t.tlsDone, t.tlsState, t.tlsErr = time.Now(), state, err
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.gotConn, t.reused = time.Now(), info.Reused
+			t.mu.Unlock()
+		},
+		WroteHeaders:         func() { mark(&t.wroteHeaders) },
+		Got100Continue:       func() { mark(&t.got100) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+	}
+}
+
+// poolWait 返回等待可用连接的时间，即从开始获取连接到拿到连接之间去掉 DNS、建连与 TLS 握手的部分；
+// 连接数达到 MaxConnsPerHost 或拨号排队时该值增大
+func (t *requestTrace) poolWait() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.getConn.IsZero() || t.gotConn.IsZero() {
+		return 0
+	}
+	wait := t.gotConn.Sub(t.getConn)
+	for _, span := range [][2]time.Time{{t.dnsStart, t.dnsDone}, {t.connectStart, t.connectDone}, {t.tlsStart, t.tlsDone}} {
+		if !span[0].IsZero() && span[1].After(span[0]) {
+			wait -= span[1].Sub(span[0])
+		}
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// reusedConn 返回请求是否使用了连接池中已有的连接
+func (t *requestTrace) reusedConn() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.reused
+}
+
+// firstResponseByte 返回收到响应首字节的时间，未收到时为零值。
+// 收到 100 Continue 时 httptrace 报告的是 100 响应的首字节，无法得知最终响应的首字节，因此也返回零值
+func (t *requestTrace) firstResponseByte() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.got100.IsZero() {
+		return time.Time{}
+	}
+	return t.firstByte
+}
+
+// phases 计算各阶段耗时，end 为响应 body 读取完毕（或请求失败）的时间
+func (t *requestTrace) phases(end time.Time) requestPhases {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return 0
+		}
+		return to.Sub(from)
+	}
+	p := requestPhases{
+		DNS:     span(t.dnsStart, t.dnsDone),
+		Connect: span(t.connectStart, t.connectDone),
+		TLS:     span(t.tlsStart, t.tlsDone),
+		Write:   span(t.gotConn, t.wroteRequest),
+		Server:  span(t.wroteRequest, t.firstByte),
+	}
+	if !t.got100.IsZero() {
+		// 同 firstResponseByte，最终响应的等待与传输无法区分，全部计入 Server
+		p.Server = span(t.wroteRequest, end)
+	} else if !t.firstByte.IsZero() {
+		p.Transfer = span(t.firstByte, end)
+	}
+	return p
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// openTransaction 为一个 worker 正在进行的事务：从其第一步开始发送到最后一步结束（include_think_time 时含最后一步之后的思考时间）
+type openTransaction struct {
+	name       string
+	start, end time.Time
+	failed     bool
+}
+
+// transactionStats 为一个事务的次数、失败次数与成功事务的端到端耗时
+type transactionStats struct {
+	Count  int64
+	Failed int64
+	Digest latencyDigest
+	Max    time.Duration
+}
+
+// add 合并另一个 worker 的同名事务
+func (t *transactionStats) add(other *transactionStats) {
+	t.Count += other.Count
+	t.Failed += other.Failed
+	t.Digest.add(other.Digest)
+	if other.Max > t.Max {
+		t.Max = other.Max
+	}
+}
+
+// beginTransaction 在执行 step 之前调用：换了事务（或开始新的一轮）时结束之前的事务，step 属于新的事务时开始计时
+func (j *journeyRunner) beginTransaction(step *journeyStep, now time.Time) {
+	if j.txn != nil && j.txn.name == step.Transaction {
+		return
+	}
+	j.endTransaction(false)
+	if step.Transaction != "" {
+		j.txn = &openTransaction{name: step.Transaction, start: now, end: now}
+	}
+}
+
+// endTransaction 结束正在进行的事务并计入统计；failed 为 true 时（如放弃了这一轮）记为失败
+func (j *journeyRunner) endTransaction(failed bool) {
+	txn := j.txn
+	if txn == nil {
+		return
+	}
+	j.txn = nil
+	if j.transactions == nil {
+		j.transactions = make(map[string]*transactionStats)
+	}
+	stats, ok := j.transactions[txn.name]
+	if !ok {
+		stats = &transactionStats{}
+		j.transactions[txn.name] = stats
+	}
+	stats.Count++
+	if failed || txn.failed {
+		stats.Failed++
+		return
+	}
+	d := txn.end.Sub(txn.start)
+	stats.Digest.record(d)
+	if d > stats.Max {
+		stats.Max = d
+	}
+}
+
+// think 在步骤之后按 think 暂停，模拟用户的思考时间；中止时提前结束
+func (j *journeyRunner) think(step *journeyStep) {
+	if step.Think <= 0 {
+		return
+	}
+	backoff(step.Think)
+	if j.sc.IncludeThinkTime && j.txn != nil {
+		j.txn.end = time.Now()
+	}
+}
+
+// transactionSummary 为导出汇总中的一个事务
+type transactionSummary struct {
+	Name   string  `json:"name"`
+	Count  int64   `json:"count"`
+	Failed int64   `json:"failed"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// summarizeTransactions 合并场景中各 worker 的事务统计，须在 worker 全部结束后调用
+func summarizeTransactions(sc *scenarioConfig) []transactionSummary {
+	merged := make(map[string]*transactionStats)
+	for _, j := range sc.runners {
+		if j == nil {
+			continue
+		}
+		for name, stats := range j.transactions {
+			agg, ok := merged[name]
+			if !ok {
+				agg = &transactionStats{}
+				merged[name] = agg
+			}
+			agg.add(stats)
+		}
+	}
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	var result []transactionSummary
+	for name, t := range merged {
+		result = append(result, transactionSummary{
+			Name:   name,
+			Count:  t.Count,
+			Failed: t.Failed,
+			P50Ms:  ms(t.Digest.percentile(50)),
+			P95Ms:  ms(t.Digest.percentile(95)),
+			P99Ms:  ms(t.Digest.percentile(99)),
+			MaxMs:  ms(t.Max),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// reportTransactions 输出各场景中事务的端到端耗时百分位，失败的事务不计入耗时
+func reportTransactions(summaries []scenarioSummary) {
+	var rows [][]string
+	format := func(ms float64) string { return fmt.Sprintf("%.1f ms", ms) }
+	for _, s := range summaries {
+		for _, t := range s.Transactions {
+			rows = append(rows, []string{
+				s.Name,
+				t.Name,
+				fmt.Sprintf("%d", t.Count),
+				fmt.Sprintf("%d", t.Failed),
+				format(t.P50Ms),
+				format(t.P95Ms),
+				format(t.P99Ms),
+				format(t.MaxMs),
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println("\n💼  Transactions (end-to-end):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Scenario", "Transaction", "Count", "Failed", "P50", "P95", "P99", "Max"})
+	table.AppendBulk(rows)
+	table.Render()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/guptarohit/asciigraph"
+)
+
+// trendWindow 为趋势数组的时间窗口，每个窗口独立计算 TPS、QPS 与时延百分位
+var trendWindow time.Duration
+
+// trendTimes 为每个趋势点对应窗口的结束时间
+var trendTimes []time.Time
+
+// graphWidth、graphHeight 为趋势图的宽度与高度，宽度为 0 时每个趋势点占一列
+var (
+	graphWidth  int
+	graphHeight int
+)
+
+// windowStats 为 worker 在当前趋势窗口内的统计数据，每个窗口结束时清空
+type windowStats struct {
+	TotalRequests   int64
+	SuccessRequests int64
+	ResponseTimes   []time.Duration
+	Apdex           apdexCounts
+	// Metrics 为窗口内从响应提取的自定义指标
+	Metrics     map[string]*metricStats
+	sampleCount int64
+}
+
+// fail 记录一次未得到响应的请求
+func (w *windowStats) fail() {
+	w.TotalRequests++
+	w.Apdex.Frustrated++
+}
+
+// sample 记录一次得到响应的请求
+func (w *windowStats) sample(success bool, d time.Duration) {
+	w.TotalRequests++
+	if success {
+		w.SuccessRequests++
+		w.Apdex.record(d)
+	} else {
+		w.Apdex.Frustrated++
+	}
+	w.ResponseTimes = appendSample(w.ResponseTimes, &w.sampleCount, d)
+}
+
+// collectTrendWindow 取出各 worker 在 [start, end) 窗口内的数据并清空，计算后追加一个趋势点
+func collectTrendWindow(workers []*WorkerStats, start, end time.Time) {
+	intervals.emit(workers, start, end)
+	var window windowStats
+	for _, ws := range workers {
+		window.TotalRequests += ws.Window.TotalRequests
+		window.SuccessRequests += ws.Window.SuccessRequests
+		window.ResponseTimes = append(window.ResponseTimes, ws.Window.ResponseTimes...)
+		window.Apdex.add(ws.Window.Apdex)
+		window.Metrics = addMetrics(window.Metrics, ws.Window.Metrics)
+		ws.Window = windowStats{}
+	}
+	seconds := end.Sub(start).Seconds()
+	if seconds <= 0 {
+		return
+	}
+	sort.Slice(window.ResponseTimes, func(i, j int) bool {
+		return window.ResponseTimes[i] < window.ResponseTimes[j]
+	})
+	trendTimes = append(trendTimes, end)
+	tpsHistory = append(tpsHistory, float64(window.SuccessRequests)/seconds)
+	qpsHistory = append(qpsHistory, float64(window.TotalRequests)/seconds)
+	p50History = append(p50History, float64(percentile(window.ResponseTimes, 50).Milliseconds()))
+	p95History = append(p95History, float64(percentile(window.ResponseTimes, 95).Milliseconds()))
+	p99History = append(p99History, float64(percentile(window.ResponseTimes, 99).Milliseconds()))
+	if apdex := window.Apdex.summary(); apdex != nil {
+		apdexHistory = append(apdexHistory, apdex.Score)
+	}
+	appendMetricPoints(window.Metrics, len(tpsHistory))
+}
+
+// plotTrend 绘制趋势图，并在图下方以窗口结束时间作为 x 轴标签
+func plotTrend(series []float64, options ...asciigraph.Option) string {
+	plot := asciigraph.Plot(series, options...)
+	if len(trendTimes) == 0 {
+		return plot
+	}
+	lines := strings.Split(plot, "\n")
+	last := lines[len(lines)-1]
+	// 纵轴字符之前是 y 轴标签，其后的宽度即为曲线宽度
+	axis := strings.IndexAny(last, "┤┼")
+	if axis < 0 {
+		return plot
+	}
+	offset := utf8.RuneCountInString(last[:axis]) + 1
+	width := utf8.RuneCountInString(last) - offset
+	first := trendTimes[0].Format("15:04:05")
+	end := trendTimes[len(trendTimes)-1].Format("15:04:05")
+	if width < len(first)+len(end)+1 {
+		return plot + "\n" + strings.Repeat(" ", offset) + first + " - " + end
+	}
+	return plot + "\n" + strings.Repeat(" ", offset) + first + strings.Repeat(" ", width-len(first)-len(end)) + end
+}
+
+// graphOptions 返回按 -graph-width、-graph-height 配置的绘图参数，高度至少为 1
+func graphOptions(height int) []asciigraph.Option {
+	if height < 1 {
+		height = 1
+	}
+	options := []asciigraph.Option{asciigraph.Height(height)}
+	if graphWidth > 0 {
+		options = append(options, asciigraph.Width(graphWidth))
+	}
+	return options
+}
+
+// currentTrend 返回趋势数组的快照
+func currentTrend() trendSnapshot {
+	times := make([]time.Time, len(trendTimes))
+	offsets := make([]float64, len(trendTimes))
+	for i, t := range trendTimes {
+		times[i] = wallTime(t)
+		offsets[i] = offsetMs(t) / 1000
+	}
+	return trendSnapshot{
+		Time:    times,
+		OffsetS: offsets,
+		TPS:     tpsHistory,
+		QPS:     qpsHistory,
+		P50Ms:   p50History,
+		P95Ms:   p95History,
+		P99Ms:   p99History,
+		Apdex:   apdexHistory,
+		Metrics: metricHistory,
+	}
+}
+
+// writeTrendExport 按扩展名把趋势数组写为 CSV（每个窗口一行）或 JSON
+func writeTrendExport(filename string) error {
+	trend := currentTrend()
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		data, err := json.MarshalIndent(trend, "", "  ")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filename, data, 0644)
+	case ".csv":
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w := csv.NewWriter(f)
+		header := []string{"time", "offset_s", "tps", "qps", "p50_ms", "p95_ms", "p99_ms"}
+		if len(trend.Apdex) > 0 {
+			header = append(header, "apdex")
+		}
+		metrics := make([]string, 0, len(trend.Metrics))
+		for name := range trend.Metrics {
+			metrics = append(metrics, name)
+		}
+		sort.Strings(metrics)
+		for _, name := range metrics {
+			header = append(header, "metric:"+name)
+		}
+		w.Write(header)
+		format := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+		for i, t := range trend.Time {
+			row := []string{t.Format("2006-01-02T15:04:05.000Z07:00"), strconv.FormatFloat(trend.OffsetS[i], 'f', 3, 64), format(trend.TPS[i]), format(trend.QPS[i]),
+				format(trend.P50Ms[i]), format(trend.P95Ms[i]), format(trend.P99Ms[i])}
+			if len(trend.Apdex) > 0 {
+				row = append(row, format(trend.Apdex[i]))
+			}
+			for _, name := range metrics {
+				row = append(row, format(trend.Metrics[name][i]))
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		return fmt.Errorf("unsupported trend export format %q, use .csv or .json", filepath.Ext(filename))
+	}
+}
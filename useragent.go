@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultUserAgent 为未指定 -user-agents 时发送的 User-Agent
+const defaultUserAgent = "Go-HTTP-LoadTester"
+
+// userAgents 为 -user-agents 加载的 User-Agent，userAgentWeights 为其累计权重
+var (
+	userAgents       []string
+	userAgentWeights []float64
+)
+
+// loadUserAgents 读取每行一个 User-Agent 的文件，返回加载的数量；
+// 行首可以用 "权重|" 指定相对权重（默认为 1），空行与 # 开头的行忽略
+func loadUserAgents(filename string) (int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read user agents: %v", err)
+	}
+	defer f.Close()
+
+	var total float64
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		ua := strings.TrimSpace(scanner.Text())
+		if ua == "" || strings.HasPrefix(ua, "#") {
+			continue
+		}
+		weight := 1.0
+		if i := strings.Index(ua, "|"); i > 0 {
+			if w, err := strconv.ParseFloat(strings.TrimSpace(ua[:i]), 64); err == nil {
+				if w < 0 {
+					return 0, fmt.Errorf("%s:%d: negative weight", filename, line)
+				}
+				weight = w
+				ua = strings.TrimSpace(ua[i+1:])
+			}
+		}
+		total += weight
+		userAgents = append(userAgents, ua)
+		userAgentWeights = append(userAgentWeights, total)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("unable to read user agents: %v", err)
+	}
+	if len(userAgents) > 0 && total == 0 {
+		return 0, fmt.Errorf("%s: all weights are 0", filename)
+	}
+	return len(userAgents), nil
+}
+
+// pickUserAgent 用 rng 按权重随机选择一个 User-Agent，未加载时返回 defaultUserAgent
+func pickUserAgent(rng *rand.Rand) string {
+	if len(userAgents) == 0 {
+		return defaultUserAgent
+	}
+	target := rng.Float64() * userAgentWeights[len(userAgentWeights)-1]
+	i := sort.SearchFloat64s(userAgentWeights, target)
+	// 与 pickWeightedRequest 相同，跳过权重为 0 的项
+	for i < len(userAgentWeights)-1 && userAgentWeights[i] <= target {
+		i++
+	}
+	return userAgents[i]
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// expectSHA256 非空时每个 2xx 响应 body 的 SHA-256 须与之相同，否则记为失败；bodyfile 中单个请求的哈希优先
+var expectSHA256 string
+
+// expectedSHA256 返回 spec 的响应 body 应有的 SHA-256，为空时不校验
+func expectedSHA256(spec *requestSpec) string {
+	if spec.SHA256 != "" {
+		return spec.SHA256
+	}
+	return expectSHA256
+}
+
+// validateResponse 校验 2xx 响应的内容，返回失败原因，通过时返回空字符串；
+// sum 为 body 的 SHA-256，checkSchema 为 true 时按 -response-schema 校验 body（指定 -proto-response 时先解码为 JSON），
+// 指定 -xpath 时 body 须满足所有 XPath 断言
+func validateResponse(spec *requestSpec, sum string, body []byte, checkSchema bool) string {
+	if expected := expectedSHA256(spec); expected != "" && !strings.EqualFold(expected, sum) {
+		return "sha256 mismatch"
+	}
+	if checkSchema {
+		if protoResponse != nil {
+			decoded, err := decodeProtoJSON(protoResponse, body)
+			if err != nil {
+				return "protobuf: " + err.Error()
+			}
+			body = decoded
+		}
+		if msg := responseSchema.validateBody(body); msg != "" {
+			return "schema: " + msg
+		}
+	}
+	if len(xpathAssertions) > 0 {
+		return checkXPathAssertions(body)
+	}
+	return ""
+}
+
+// sampleSchema 决定本次响应是否按 schema 校验
+func sampleSchema() bool {
+	return responseSchema != nil && (schemaSample >= 1 || sharedRand.Float64() < schemaSample)
+}
+
+// reportValidationFailures 输出状态码为 2xx 但内容校验未通过的请求数，按原因分类；schemaChecked 为按 schema 校验过的响应数
+func reportValidationFailures(failures map[string]int, schemaChecked int64) {
+	if responseSchema != nil {
+		fmt.Printf("\n📐  Responses Validated Against Schema: %d\n", schemaChecked)
+	}
+	if len(failures) == 0 {
+		return
+	}
+	reasons := make([]string, 0, len(failures))
+	for reason := range failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if failures[reasons[i]] != failures[reasons[j]] {
+			return failures[reasons[i]] > failures[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	fmt.Println("\n🧪  Response Validation Failures (counted as failed requests):")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Reason", "Requests"})
+	for _, reason := range reasons {
+		table.Append([]string{reason, fmt.Sprintf("%d", failures[reason])})
+	}
+	table.Render()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+)
+
+// vuClients 为一个虚拟用户独占的客户端：与全局客户端共用连接池，但拥有自己的 cookie jar，
+// 因此登录等会话状态在一次迭代的各个请求之间保持，且不会与其他用户混用
+type vuClients struct {
+	keepAlive   *http.Client
+	noKeepAlive *http.Client
+}
+
+// newVUClients 创建一个虚拟用户的客户端
+func newVUClients() *vuClients {
+	c := &vuClients{
+		keepAlive:   &http.Client{Transport: clientKeepAlive.Transport, Timeout: clientKeepAlive.Timeout},
+		noKeepAlive: &http.Client{Transport: clientNoKeepAlive.Transport, Timeout: clientNoKeepAlive.Timeout},
+	}
+	c.resetSession()
+	return c
+}
+
+// resetSession 换用新的 cookie jar；每次迭代开始时调用，使每次迭代（每行数据）都是一个新的会话
+func (c *vuClients) resetSession() {
+	jar, _ := cookiejar.New(nil)
+	c.keepAlive.Jar = jar
+	c.noKeepAlive.Jar = jar
+}
+
+// pick 用 rng 按 keepAliveRatio 随机选择是否使用 Keep-Alive 客户端；policy 非空时使用该策略的连接池，cookie jar 不变
+func (c *vuClients) pick(rng *rand.Rand, keepAliveRatio float64, policy *connectionPolicy) *http.Client {
+	if policy != nil {
+		base := policy.noKeepAlive
+		if rng.Float64() < policy.keepAliveRatio(keepAliveRatio) {
+			base = policy.keepAlive
+		}
+		return &http.Client{Transport: base.Transport, Timeout: base.Timeout, Jar: c.keepAlive.Jar}
+	}
+	if rng.Float64() < keepAliveRatio {
+		return c.keepAlive
+	}
+	return c.noKeepAlive
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// addLatency、addJitter 为 -add-latency 与 -add-jitter：在客户端的连接上注入往返时延，在本地压测机上模拟远距离的用户，
+// 不需要 tc/netem 的权限。建连时多等一个往返，之后每一次发送到收到响应（一个往返）多等一个往返时延，
+// 时延在 addLatency±addJitter 之间均匀分布
+var (
+	addLatency time.Duration
+	addJitter  time.Duration
+)
+
+// dialFunc 为 Transport 建立连接所用的函数
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// wanDelay 用 rng 返回一个往返的注入时延
+func wanDelay(rng *rand.Rand) time.Duration {
+	d := addLatency
+	if addJitter > 0 {
+		d += time.Duration(rng.Int63n(int64(2*addJitter)+1)) - addJitter
+	}
+	return max(d, 0)
+}
+
+// wanDial 返回用 d 建立连接的函数，连接带上注入的时延、-bandwidth 的限速与 -chaos-* 的故障，并按 -dial-strategy 统计；
+// 都未设置时即 d.DialContext。TCP 握手的一个往返在 ControlContext 中等待，即 socket 创建之后、发起连接之前，因此计入建连耗时
+func wanDial(d *net.Dialer) dialFunc {
+	if addLatency <= 0 && bandwidth <= 0 && !chaosEnabled() && !dialStrategySet {
+		return d.DialContext
+	}
+	d.ControlContext = dialControl
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := d.DialContext(ctx, network, addr)
+		recordDial(conn, err)
+		if err != nil {
+			return nil, err
+		}
+		if chaosEnabled() {
+			conn = newChaosConn(conn)
+		}
+		if bandwidth > 0 {
+			conn = newThrottledConn(conn)
+		}
+		if addLatency > 0 {
+			conn = &wanConn{Conn: conn, rng: connRand()}
+		}
+		return conn, nil
+	}
+}
+
+// dialControl 为 net.Dialer 的 ControlContext，在每次尝试连接一个地址之前调用
+func dialControl(ctx context.Context, network, address string, c syscall.RawConn) error {
+	recordDialAttempt(network)
+	if addLatency > 0 {
+		return wanHandshake(ctx, network, address, c)
+	}
+	return nil
+}
+
+// wanHandshake 等待 TCP 握手的一个往返
+func wanHandshake(ctx context.Context, network, address string, _ syscall.RawConn) error {
+	select {
+	case <-time.After(wanDelay(connRand())):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wanConn 为注入时延的连接：Write 完成时确定对端的响应最早何时到达，Read 读到数据后等到该时刻再返回。
+// 连续的多次 Write 属于同一次发送，以最后一次为准，只计一个往返
+type wanConn struct {
+	net.Conn
+	// readyAt 为最近一次发送的响应最早到达的时刻（UnixNano），0 表示已经到达
+	readyAt atomic.Int64
+	// rng 为该连接抽取时延抖动的随机数生成器
+	rng *rand.Rand
+}
+
+func (c *wanConn) Write(b []byte) (int, error) {
+	// 在 Write 之后计时，-bandwidth 限速时发送本身的耗时不与往返时延重叠
+	n, err := c.Conn.Write(b)
+	c.readyAt.Store(time.Now().Add(wanDelay(c.rng)).UnixNano())
+	return n, err
+}
+
+func (c *wanConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		if at := c.readyAt.Swap(0); at != 0 {
+			if wait := time.Until(time.Unix(0, at)); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+	}
+	return n, err
+}
+
+// newDialer 返回建连超时为 timeout 的拨号器，其余与 http.DefaultTransport 相同；
+// 使用 -resolver 或 -doh 的解析器与 -dial-strategy 的 Happy Eyeballs 设置
+func newDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Resolver: dialResolver, FallbackDelay: dialFallbackDelay()}
+}
+
+// setupWANLatency 检查 -add-latency 与 -add-jitter，为全局客户端的连接注入时延、-bandwidth 的限速与 -chaos-* 的故障，
+// 并使用 -resolver 或 -doh 的解析器与 -dial-strategy；fasthttp 引擎与连接策略的客户端在各自建连时注入
+func setupWANLatency() error {
+	if addJitter < 0 || addLatency < 0 {
+		return fmt.Errorf("-add-latency and -add-jitter must not be negative")
+	}
+	if addJitter > 0 && addLatency <= 0 {
+		return fmt.Errorf("-add-jitter requires -add-latency")
+	}
+	if addLatency <= 0 && bandwidth <= 0 && !chaosEnabled() && dialResolver == nil && !dialStrategySet {
+		return nil
+	}
+	for _, client := range []*http.Client{clientKeepAlive, clientNoKeepAlive} {
+		client.Transport.(*http.Transport).DialContext = wanDial(newDialer(30 * time.Second))
+	}
+	return nil
+}
+
+// describeWANLatency 返回启动时输出的时延注入说明
+func describeWANLatency() string {
+	s := fmt.Sprintf("+%s RTT", addLatency)
+	if addJitter > 0 {
+		s += fmt.Sprintf(" ±%s", addJitter)
+	}
+	return s + " per round trip (connect, TLS handshake, request)"
+}
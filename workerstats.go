@@ -0,0 +1,226 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// worker 的统计不加锁：每个 worker 只写自己的本地增量，按 statsFlushInterval 或每 statsFlushRequests 个请求
+// 把增量整个交给汇总 goroutine 并换上新的一份；汇总 goroutine 是各 worker 累计统计的唯一写者，
+// 周期输出、检查点与趋势窗口都在汇总 goroutine 中读取累计统计，不会与热路径争抢锁而影响时延测量
+const (
+	statsFlushInterval = 100 * time.Millisecond
+	// statsFlushRequests 小于 sampleLimit 的下限 1000，保证增量内的样本不会先被蓄水池抽样
+	statsFlushRequests = 512
+)
+
+// statsDelta 为一个 worker 交给汇总 goroutine 的统计增量
+type statsDelta struct {
+	worker int
+	stats  *WorkerStats
+}
+
+// newWorkerStats 创建空的统计数据，时延样本预留 samples 个的容量
+func newWorkerStats(samples int) *WorkerStats {
+	return &WorkerStats{
+		ResponseTimes: make([]time.Duration, 0, samples),
+		StatusCodes:   make(map[int]int),
+		URLStats:      make(map[string]*URLStats),
+		Errors:        make(map[string]int),
+		Targets:       make(map[string]*URLStats),
+		ByTag:         make(map[string]*URLStats),
+		BodyHashes:    make(bodyHashes),
+		HeaderValues:  make(headerValues),
+		Backends:      make(map[string]*URLStats),
+		Validation:    make(map[string]int),
+	}
+}
+
+// maxPresizedSamples 为每个 worker 预分配的时延样本数上限，避免 -n 很大而提前结束时白白占用内存
+const maxPresizedSamples = 1 << 20
+
+// expectedWorkerSamples 返回每个 worker 累计时延样本的预估数量，用于预分配；有 sampleLimit 时不超过它
+func expectedWorkerSamples(totalRequests, concurrency int) int {
+	n := totalRequests / concurrency
+	if sampleLimit > 0 && n > sampleLimit {
+		n = sampleLimit
+	}
+	if n > maxPresizedSamples {
+		n = maxPresizedSamples
+	}
+	return n
+}
+
+// deltaPool 复用 worker 交出的增量，汇总 goroutine 合并完后放回
+var deltaPool = sync.Pool{New: func() any {
+	ws := newWorkerStats(statsFlushRequests)
+	ws.Window.ResponseTimes = make([]time.Duration, 0, statsFlushRequests)
+	return ws
+}}
+
+// getDelta 取出一份空的增量
+func getDelta() *WorkerStats {
+	return deltaPool.Get().(*WorkerStats)
+}
+
+// putDelta 清空合并完的增量并放回 deltaPool，保留 map 与样本切片已分配的空间
+func putDelta(ws *WorkerStats) {
+	clear(ws.StatusCodes)
+	clear(ws.URLStats)
+	clear(ws.Errors)
+	clear(ws.Targets)
+	clear(ws.ByTag)
+	clear(ws.BodyHashes)
+	clear(ws.HeaderValues)
+	clear(ws.Backends)
+	clear(ws.Validation)
+	*ws = WorkerStats{
+		ResponseTimes: ws.ResponseTimes[:0],
+		StatusCodes:   ws.StatusCodes,
+		URLStats:      ws.URLStats,
+		Errors:        ws.Errors,
+		Targets:       ws.Targets,
+		ByTag:         ws.ByTag,
+		BodyHashes:    ws.BodyHashes,
+		HeaderValues:  ws.HeaderValues,
+		Backends:      ws.Backends,
+		Validation:    ws.Validation,
+		Window:        windowStats{ResponseTimes: ws.Window.ResponseTimes[:0]},
+		TotalTimes:    ws.TotalTimes[:0],
+		TTFBTimes:     ws.TTFBTimes[:0],
+		ServerTimes:   ws.ServerTimes[:0],
+		QueueWaits:    ws.QueueWaits[:0],
+	}
+	deltaPool.Put(ws)
+}
+
+// statsRecorder 为一个 worker 的统计记录器，只在该 worker 的 goroutine 中使用
+type statsRecorder struct {
+	worker  int
+	stats   *WorkerStats
+	flushed time.Time
+	out     chan<- statsDelta
+}
+
+// newStatsRecorder 创建 worker 的统计记录器，增量发送到 out
+func newStatsRecorder(worker int, out chan<- statsDelta) *statsRecorder {
+	return &statsRecorder{worker: worker, stats: getDelta(), flushed: time.Now(), out: out}
+}
+
+// done 在每个请求结束后调用，距上次交出已超过 statsFlushInterval 或请求数已达 statsFlushRequests 时交出增量；
+// 请求间隔较长时每个请求结束都会立即交出，因此汇总数据最多落后一个请求的耗时或 statsFlushInterval
+func (r *statsRecorder) done() {
+	if r.stats.TotalRequests >= statsFlushRequests || time.Since(r.flushed) >= statsFlushInterval {
+		r.flush()
+	}
+}
+
+// flush 交出当前增量并换上新的一份；worker 退出前须调用一次
+func (r *statsRecorder) flush() {
+	r.flushed = time.Now()
+	if r.stats.TotalRequests == 0 {
+		return
+	}
+	r.out <- statsDelta{worker: r.worker, stats: r.stats}
+	r.stats = getDelta()
+}
+
+// mergeSamples 把增量中的样本逐个追加到累计样本中，超过 sampleLimit 后按蓄水池抽样
+func mergeSamples(samples []time.Duration, seen *int64, delta []time.Duration) []time.Duration {
+	for _, d := range delta {
+		samples = appendSample(samples, seen, d)
+	}
+	return samples
+}
+
+// merge 把 worker 交出的增量合并进该 worker 的累计统计，只在汇总 goroutine 中调用
+func (ws *WorkerStats) merge(delta *WorkerStats) {
+	ws.TotalRequests += delta.TotalRequests
+	ws.SuccessRequests += delta.SuccessRequests
+	ws.FailedRequests += delta.FailedRequests
+	ws.TotalTime += delta.TotalTime
+	ws.ResponseTimes = mergeSamples(ws.ResponseTimes, &ws.sampleCount, delta.ResponseTimes)
+	for _, d := range delta.ResponseTimes {
+		ws.Digest.record(d)
+	}
+	for _, d := range delta.QueueWaits {
+		ws.QueueWait.record(d)
+	}
+	for code, count := range delta.StatusCodes {
+		ws.StatusCodes[code] += count
+	}
+	for key, us := range delta.URLStats {
+		ws.urlStats(key).merge(us)
+	}
+	for target, ts := range delta.Targets {
+		ws.targetStats(target).merge(ts)
+	}
+	for tag, ts := range delta.ByTag {
+		ws.tagStats(tag).merge(ts)
+	}
+	ws.Filtered.merge(&delta.Filtered)
+	ws.Apdex.add(delta.Apdex)
+	for _, r := range delta.Slowest {
+		ws.Slowest.offer(r)
+	}
+	ws.BytesRead += delta.BytesRead
+	ws.Phases.add(delta.Phases)
+	for msg, count := range delta.Errors {
+		ws.Errors[msg] += count
+	}
+	ws.SocketErrors.add(delta.SocketErrors)
+	ws.Heatmap.add(delta.Heatmap)
+	ws.Window.merge(delta.Window)
+	// 增量只在趋势窗口中记录自定义指标，累计值在合并时一并计入
+	ws.Metrics = addMetrics(ws.Metrics, delta.Window.Metrics)
+	ws.TotalTimes = mergeSamples(ws.TotalTimes, &ws.totalSampleCount, delta.TotalTimes)
+	ws.TTFBTimes = mergeSamples(ws.TTFBTimes, &ws.ttfbSampleCount, delta.TTFBTimes)
+	ws.ServerTimes = mergeSamples(ws.ServerTimes, &ws.serverSampleCount, delta.ServerTimes)
+	for _, f := range delta.Failures.Items {
+		ws.Failures.record(f)
+	}
+	ws.Failures.Dropped += delta.Failures.Dropped
+	for _, id := range delta.FailedIDs {
+		ws.FailedIDs = appendFailedID(ws.FailedIDs, id)
+	}
+	ws.Throttle.add(delta.Throttle)
+	ws.Continue.add(delta.Continue)
+	ws.BodyHashes.add(delta.BodyHashes)
+	ws.HeaderValues.add(delta.HeaderValues)
+	for id, bs := range delta.Backends {
+		ws.backendStats(id).merge(bs)
+	}
+	ws.Revalidation.add(&delta.Revalidation)
+	ws.Preflight.add(&delta.Preflight)
+	ws.Idempotency.add(delta.Idempotency)
+	ws.Churn.add(&delta.Churn)
+	ws.Late.merge(&delta.Late)
+	ws.SLO.add(delta.SLO)
+	for reason, count := range delta.Validation {
+		ws.Validation[reason] += count
+	}
+	ws.SchemaChecked += delta.SchemaChecked
+	ws.RangeRequests += delta.RangeRequests
+	ws.RangeVerified += delta.RangeVerified
+	ws.KeepAliveRequests += delta.KeepAliveRequests
+	tlsDelta := delta.TLS
+	tlsDelta.Times = nil
+	ws.TLS.add(tlsDelta)
+	ws.TLS.Times = mergeSamples(ws.TLS.Times, &ws.TLS.sampleCount, delta.TLS.Times)
+}
+
+// merge 合并同一 URL 的增量
+func (us *URLStats) merge(delta *URLStats) {
+	us.TotalRequests += delta.TotalRequests
+	us.FailedRequests += delta.FailedRequests
+	us.ResponseTimes = mergeSamples(us.ResponseTimes, &us.sampleCount, delta.ResponseTimes)
+}
+
+// merge 合并同一趋势窗口的增量
+func (w *windowStats) merge(delta windowStats) {
+	w.TotalRequests += delta.TotalRequests
+	w.SuccessRequests += delta.SuccessRequests
+	w.Apdex.add(delta.Apdex)
+	w.Metrics = addMetrics(w.Metrics, delta.Metrics)
+	w.ResponseTimes = mergeSamples(w.ResponseTimes, &w.sampleCount, delta.ResponseTimes)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/olekukonko/tablewriter"
+	"github.com/schollz/progressbar/v3"
+)
+
+// wsReplyTimeout 为等待单条消息回复的最长时间
+const wsReplyTimeout = 10 * time.Second
+
+// wsStats 保存 WebSocket 模式下的统计数据，所有连接共享，加锁确保并发安全
+type wsStats struct {
+	mu               sync.Mutex
+	Connected        int64
+	ConnectFailures  int64
+	Dropped          int64
+	MessagesSent     int64
+	MessagesReceived int64
+	ConnectTimes     []time.Duration
+	RoundTrips       []time.Duration
+}
+
+// wsMessage 为一条预先解析好的消息模板
+type wsMessage struct {
+	raw string
+	tpl *requestTemplate
+}
+
+// runWebSocket 建立 concurrency 个 WebSocket 连接并保持 duration 时长；
+// 有消息时每个连接按 rate（条/秒，0 表示收到回复后立即发送下一条）发送消息并等待回复以统计往返时延
+func runWebSocket(url string, concurrency int, duration time.Duration, rate float64, message string) {
+	messages := buildWebSocketMessages(message)
+
+	fmt.Printf("\n🌍  Target URL: %s\n", url)
+	fmt.Printf("🔌  WebSocket Connections: %d, Duration: %s\n", concurrency, duration)
+	if len(messages) > 0 {
+		fmt.Printf("✉️   Message Rate: %.2f msg/s per connection, Templates: %d\n", rate, len(messages))
+	} else {
+		slog.Warn("No messages configured, WebSocket connections are held idle")
+	}
+	fmt.Println("======================================")
+
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	stats := &wsStats{}
+	bar := progressbar.Default(-1, "messages")
+	startTime := time.Now()
+	deadline := startTime.Add(duration)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			wsConnLoop(id, url, deadline, interval, messages, stats, bar)
+		}(i)
+	}
+	wg.Wait()
+	bar.Finish()
+
+	fmt.Println("\n======================================")
+	fmt.Println("✅  Test completed! Final statistics:")
+	reportWebSocketStats(stats, time.Since(startTime))
+}
+
+// buildWebSocketMessages 优先使用 -ws-message，其次使用 bodyfile 中的 body 作为消息模板
+func buildWebSocketMessages(message string) []wsMessage {
+	var raws []string
+	if message != "" {
+		raws = append(raws, message)
+	} else {
+		for _, spec := range requestPool {
+			raws = append(raws, spec.Body)
+		}
+	}
+	messages := make([]wsMessage, 0, len(raws))
+	for _, raw := range raws {
+		tpl, err := compileTemplate(raw)
+		if err != nil {
+			slog.Error("Invalid message template", "template", raw, "err", err)
+			continue
+		}
+		messages = append(messages, wsMessage{raw: raw, tpl: tpl})
+	}
+	return messages
+}
+
+// wsConnLoop 负责单个连接的建立、收发与掉线判定；截止时间之前出现的读写错误均视为掉线
+func wsConnLoop(id int, url string, deadline time.Time, interval time.Duration, messages []wsMessage, stats *wsStats, bar *progressbar.ProgressBar) {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	header := http.Header{}
+	header.Set("User-Agent", pickUserAgent(requestRand(int64(id), randUserAgent)))
+
+	startConn := time.Now()
+	conn, _, err := dialer.Dial(url, header)
+	if err != nil {
+		stats.mu.Lock()
+		stats.ConnectFailures++
+		stats.mu.Unlock()
+		return
+	}
+	defer conn.Close()
+	// 无论因截止时间、掉线还是出错结束，关闭前都发送正常关闭帧，让对端区分压测结束与异常断开；
+	// 对端已断开时写入失败，不影响统计
+	defer func() {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}()
+	stats.mu.Lock()
+	stats.Connected++
+	stats.ConnectTimes = append(stats.ConnectTimes, time.Since(startConn))
+	stats.mu.Unlock()
+
+	dropped := func() {
+		if time.Now().Before(deadline) {
+			stats.mu.Lock()
+			stats.Dropped++
+			stats.mu.Unlock()
+		}
+	}
+
+	if len(messages) == 0 {
+		// 仅保持连接：持续读取直到截止时间，期间的读错误说明连接被对端断开
+		conn.SetReadDeadline(deadline)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				dropped()
+				return
+			}
+		}
+	}
+
+	for seq := 0; time.Now().Before(deadline); seq++ {
+		n := int64(id)<<32 | int64(seq)
+		msg := messages[requestRand(n, randBody).Intn(len(messages))]
+		payload := renderTemplate(msg.tpl, msg.raw, map[string]interface{}{"conn": id, "seq": seq}, n)
+
+		sendAt := time.Now()
+		conn.SetWriteDeadline(sendAt.Add(wsReplyTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+			dropped()
+			return
+		}
+		stats.mu.Lock()
+		stats.MessagesSent++
+		stats.mu.Unlock()
+
+		readDeadline := sendAt.Add(wsReplyTimeout)
+		if readDeadline.After(deadline) {
+			readDeadline = deadline
+		}
+		conn.SetReadDeadline(readDeadline)
+		if _, _, err := conn.ReadMessage(); err != nil {
+			dropped()
+			return
+		}
+		rtt := time.Since(sendAt)
+		stats.mu.Lock()
+		stats.MessagesReceived++
+		stats.RoundTrips = append(stats.RoundTrips, rtt)
+		stats.mu.Unlock()
+		bar.Add(1)
+
+		if interval > 0 {
+			wait := time.Until(sendAt.Add(interval))
+			if remaining := time.Until(deadline); wait > remaining {
+				wait = remaining
+			}
+			if wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+	}
+}
+
+// reportWebSocketStats 输出 WebSocket 模式的最终统计
+func reportWebSocketStats(stats *wsStats, elapsed time.Duration) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	sort.Slice(stats.ConnectTimes, func(i, j int) bool { return stats.ConnectTimes[i] < stats.ConnectTimes[j] })
+	sort.Slice(stats.RoundTrips, func(i, j int) bool { return stats.RoundTrips[i] < stats.RoundTrips[j] })
+
+	var msgRate float64
+	if elapsed.Seconds() > 0 {
+		msgRate = float64(stats.MessagesReceived) / elapsed.Seconds()
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Metric", "Value"})
+	table.Append([]string{"Connections", fmt.Sprintf("%d", stats.Connected)})
+	table.Append([]string{"Connect Failures", fmt.Sprintf("%d", stats.ConnectFailures)})
+	table.Append([]string{"Dropped Connections", fmt.Sprintf("%d", stats.Dropped)})
+	table.Append([]string{"Connect P50", fmt.Sprintf("%d ms", percentile(stats.ConnectTimes, 50).Milliseconds())})
+	table.Append([]string{"Connect P95", fmt.Sprintf("%d ms", percentile(stats.ConnectTimes, 95).Milliseconds())})
+	table.Append([]string{"Connect P99", fmt.Sprintf("%d ms", percentile(stats.ConnectTimes, 99).Milliseconds())})
+	table.Append([]string{"Messages Sent", fmt.Sprintf("%d", stats.MessagesSent)})
+	table.Append([]string{"Messages Received", fmt.Sprintf("%d", stats.MessagesReceived)})
+	table.Append([]string{"Messages/s", fmt.Sprintf("%.2f", msgRate)})
+	table.Append([]string{"RTT P50", fmt.Sprintf("%d ms", percentile(stats.RoundTrips, 50).Milliseconds())})
+	table.Append([]string{"RTT P95", fmt.Sprintf("%d ms", percentile(stats.RoundTrips, 95).Milliseconds())})
+	table.Append([]string{"RTT P99", fmt.Sprintf("%d ms", percentile(stats.RoundTrips, 99).Milliseconds())})
+	table.Render()
+}
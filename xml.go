@@ -0,0 +1,500 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// soapAction 非空时作为 SOAP 1.1 的 SOAPAction header（SOAP 1.2 为 Content-Type 的 action 参数）发送
+var soapAction string
+
+// soap12Namespace 为 SOAP 1.2 信封的命名空间，请求体中出现时按 SOAP 1.2 设置 Content-Type
+const soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+
+// loadXMLBody 读取 XML 请求体（如 SOAP 信封）文件并加入 requestPool；配合 -datafile 时可使用 {{.field}} 模板
+func loadXMLBody(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("unable to read XML body: %v", err)
+	}
+	body := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(body, "<") {
+		return fmt.Errorf("%s does not look like an XML document", filename)
+	}
+	requestPool = append(requestPool, &requestSpec{Body: body})
+	return nil
+}
+
+// xmlContentType 返回 XML 请求体应使用的 Content-Type，body 不是 XML 时返回空字符串
+func xmlContentType(body string) string {
+	if !strings.HasPrefix(strings.TrimSpace(body), "<") {
+		return ""
+	}
+	if strings.Contains(body, soap12Namespace) {
+		if soapAction != "" {
+			return fmt.Sprintf("application/soap+xml; charset=utf-8; action=%q", soapAction)
+		}
+		return "application/soap+xml; charset=utf-8"
+	}
+	return "text/xml; charset=utf-8"
+}
+
+// xmlEscape 为模板函数 xml，转义数据行中的值，使其可以安全地嵌入 XML 信封
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// xpathAssertions 为 -xpath 指定的响应断言，每个 2xx 响应都须满足，否则记为失败
+var xpathAssertions xpathFlags
+
+// xpathFlags 实现 flag.Value，支持多次指定 -xpath
+type xpathFlags []*xpathAssertion
+
+func (f *xpathFlags) String() string {
+	raws := make([]string, len(*f))
+	for i, a := range *f {
+		raws[i] = a.raw
+	}
+	return strings.Join(raws, ", ")
+}
+
+func (f *xpathFlags) Set(value string) error {
+	a, err := parseXPathAssertion(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, a)
+	return nil
+}
+
+// xpathAssertion 为一条 XPath 断言：
+//   - EXPR         至少匹配一个节点
+//   - !EXPR        不匹配任何节点，如 !//soap:Fault
+//   - EXPR=value   至少一个匹配节点的值等于 value
+//   - EXPR!=value  没有匹配节点的值等于 value
+type xpathAssertion struct {
+	raw    string
+	path   []xpathStep
+	op     string
+	value  string
+	negate bool
+}
+
+// xpathStep 为路径中的一步；descendant 为 true 表示以 // 开头
+type xpathStep struct {
+	descendant bool
+	// kind 为 element、attr 或 text
+	kind       string
+	name       string
+	predicates []xpathPredicate
+}
+
+// xpathPredicate 为 [n]、[@attr='v'] 或 [child='v'] 形式的谓词
+type xpathPredicate struct {
+	index int
+	attr  bool
+	name  string
+	value string
+}
+
+// parseXPathAssertion 解析 -xpath 的值；XPath 只支持常用的子集：/ 与 // 路径、*、@attr、text()
+// 以及上述谓词，元素名与属性名的命名空间前缀（如 soap:Body）在匹配时忽略
+func parseXPathAssertion(raw string) (*xpathAssertion, error) {
+	a := &xpathAssertion{raw: raw}
+	expr := strings.TrimSpace(raw)
+	if strings.HasPrefix(expr, "!") {
+		a.negate = true
+		expr = strings.TrimSpace(expr[1:])
+	}
+	if i, op := findXPathOperator(expr); i >= 0 {
+		if a.negate {
+			return nil, fmt.Errorf("invalid -xpath %q: ! cannot be combined with a comparison", raw)
+		}
+		a.op = op
+		a.value = unquoteXPath(strings.TrimSpace(expr[i+len(op):]))
+		expr = strings.TrimSpace(expr[:i])
+	}
+	path, err := parseXPath(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -xpath %q: %v", raw, err)
+	}
+	a.path = path
+	return a, nil
+}
+
+// findXPathOperator 查找谓词与引号之外的第一个 = 或 !=
+func findXPathOperator(expr string) (int, string) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0 && c == '!' && i+1 < len(expr) && expr[i+1] == '=':
+			return i, "!="
+		case depth == 0 && c == '=':
+			return i, "="
+		}
+	}
+	return -1, ""
+}
+
+func unquoteXPath(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func parseXPath(expr string) ([]xpathStep, error) {
+	if !strings.HasPrefix(expr, "/") {
+		return nil, fmt.Errorf("path must start with / or //")
+	}
+	var steps []xpathStep
+	for len(expr) > 0 {
+		step := xpathStep{kind: "element"}
+		if strings.HasPrefix(expr, "//") {
+			step.descendant = true
+			expr = expr[2:]
+		} else if strings.HasPrefix(expr, "/") {
+			expr = expr[1:]
+		} else {
+			return nil, fmt.Errorf("unexpected %q", expr)
+		}
+		// 步骤名到下一个不在谓词中的 / 为止
+		end := len(expr)
+		for i := 0; i < len(expr); i++ {
+			if expr[i] == '[' {
+				if n := predicateEnd(expr[i:]); n > 0 {
+					i += n
+				}
+			} else if expr[i] == '/' {
+				end = i
+				break
+			}
+		}
+		part := expr[:end]
+		expr = expr[end:]
+		name := part
+		if i := strings.Index(part, "["); i >= 0 {
+			name = part[:i]
+			preds, err := parseXPathPredicates(part[i:])
+			if err != nil {
+				return nil, err
+			}
+			step.predicates = preds
+		}
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("empty step")
+		case name == "text()":
+			step.kind = "text"
+		case strings.HasPrefix(name, "@"):
+			step.kind = "attr"
+			step.name = localName(name[1:])
+		default:
+			step.name = localName(name)
+		}
+		if step.kind != "element" && len(expr) > 0 {
+			return nil, fmt.Errorf("%s must be the last step", name)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parseXPathPredicates(s string) ([]xpathPredicate, error) {
+	var preds []xpathPredicate
+	for len(s) > 0 {
+		end := predicateEnd(s)
+		if !strings.HasPrefix(s, "[") || end < 0 {
+			return nil, fmt.Errorf("invalid predicate %q", s)
+		}
+		body := strings.TrimSpace(s[1:end])
+		s = s[end+1:]
+		if n, err := strconv.Atoi(body); err == nil {
+			if n < 1 {
+				return nil, fmt.Errorf("positions start at 1")
+			}
+			preds = append(preds, xpathPredicate{index: n})
+			continue
+		}
+		i := strings.Index(body, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("unsupported predicate [%s]", body)
+		}
+		pred := xpathPredicate{name: strings.TrimSpace(body[:i]), value: unquoteXPath(strings.TrimSpace(body[i+1:]))}
+		if strings.HasPrefix(pred.name, "@") {
+			pred.attr = true
+			pred.name = pred.name[1:]
+		}
+		if !isXPathName(pred.name) {
+			return nil, fmt.Errorf("unsupported predicate [%s]", body)
+		}
+		pred.name = localName(pred.name)
+		preds = append(preds, pred)
+	}
+	return preds, nil
+}
+
+// predicateEnd 返回 s 开头的谓词结尾 ] 的下标，跳过引号中的 ]；没有结尾时返回 -1
+func predicateEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// isXPathName 判断 s 是否为（可带命名空间前缀的）元素名或属性名，谓词中的 text()、. 与 != 等不在支持的子集中
+func isXPathName(s string) bool {
+	if s == "" || s[0] == '.' || s[0] == '-' || s[0] == ':' || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-.:", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// localName 去掉命名空间前缀
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// xmlNode 为解析后的 XML 元素或文本节点：文本节点的 name 为空，text 为其内容；
+// children 按文档顺序保存子元素与文本节点
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     string
+}
+
+func (n *xmlNode) isText() bool {
+	return n.name == ""
+}
+
+// parseXMLDocument 把响应 body 解析为元素树，返回一个以根元素为唯一子节点的文档节点
+func parseXMLDocument(body []byte) (*xmlNode, error) {
+	doc := &xmlNode{name: "#document"}
+	stack := []*xmlNode{doc}
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if len(stack) == 1 && len(doc.children) > 0 {
+				return doc, nil
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, attr := range t.Attr {
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			// 相邻的字符数据（如文本与 CDATA）合并为一个文本节点
+			parent := stack[len(stack)-1]
+			if last := len(parent.children) - 1; last >= 0 && parent.children[last].isText() {
+				parent.children[last].text += string(t)
+			} else if parent != doc {
+				parent.children = append(parent.children, &xmlNode{text: string(t)})
+			}
+		}
+	}
+}
+
+// value 返回元素的字符串值：所有后代文本按文档顺序拼接后去掉首尾空白
+func (n *xmlNode) value() string {
+	var b strings.Builder
+	var walk func(*xmlNode)
+	walk = func(node *xmlNode) {
+		b.WriteString(node.text)
+		for _, child := range node.children {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(b.String())
+}
+
+// evaluateXPath 返回路径在文档中匹配到的所有值
+func evaluateXPath(doc *xmlNode, steps []xpathStep) []string {
+	context := []*xmlNode{doc}
+	for _, step := range steps {
+		if step.descendant {
+			// //x 等价于在所有后代（含自身）之下取子元素 x；上下文中的元素互相嵌套时同一后代只取一次
+			var expanded []*xmlNode
+			seen := make(map[*xmlNode]bool)
+			for _, node := range context {
+				expanded = appendDescendants(expanded, node, seen)
+			}
+			context = expanded
+		}
+		switch step.kind {
+		case "attr":
+			var values []string
+			for _, node := range context {
+				if v, ok := node.attrs[step.name]; ok {
+					values = append(values, v)
+				}
+			}
+			return values
+		case "text":
+			// 每个文本节点一个值，只含空白的文本节点忽略
+			var values []string
+			for _, node := range context {
+				for _, child := range node.children {
+					if text := strings.TrimSpace(child.text); child.isText() && text != "" {
+						values = append(values, text)
+					}
+				}
+			}
+			return values
+		}
+		var next []*xmlNode
+		for _, node := range context {
+			var matched []*xmlNode
+			for _, child := range node.children {
+				if !child.isText() && (step.name == "*" || child.name == step.name) {
+					matched = append(matched, child)
+				}
+			}
+			next = append(next, applyXPathPredicates(matched, step.predicates)...)
+		}
+		context = next
+	}
+	values := make([]string, len(context))
+	for i, node := range context {
+		values[i] = node.value()
+	}
+	return values
+}
+
+func appendDescendants(nodes []*xmlNode, node *xmlNode, seen map[*xmlNode]bool) []*xmlNode {
+	if node.isText() || seen[node] {
+		return nodes
+	}
+	seen[node] = true
+	nodes = append(nodes, node)
+	for _, child := range node.children {
+		nodes = appendDescendants(nodes, child, seen)
+	}
+	return nodes
+}
+
+func applyXPathPredicates(nodes []*xmlNode, preds []xpathPredicate) []*xmlNode {
+	for _, pred := range preds {
+		if pred.index > 0 {
+			if pred.index > len(nodes) {
+				return nil
+			}
+			nodes = nodes[pred.index-1 : pred.index]
+			continue
+		}
+		var kept []*xmlNode
+		for _, node := range nodes {
+			if pred.attr {
+				if node.attrs[pred.name] == pred.value {
+					kept = append(kept, node)
+				}
+				continue
+			}
+			for _, child := range node.children {
+				if child.name == pred.name && child.value() == pred.value {
+					kept = append(kept, node)
+					break
+				}
+			}
+		}
+		nodes = kept
+	}
+	return nodes
+}
+
+// check 判断文档是否满足断言，不满足时返回原因
+func (a *xpathAssertion) check(doc *xmlNode) string {
+	values := evaluateXPath(doc, a.path)
+	switch {
+	case a.negate:
+		if len(values) > 0 {
+			return "xpath: " + a.raw + " (matched)"
+		}
+	case a.op == "":
+		if len(values) == 0 {
+			return "xpath: " + a.raw + " (no match)"
+		}
+	default:
+		found := false
+		for _, v := range values {
+			if v == a.value {
+				found = true
+				break
+			}
+		}
+		if a.op == "=" && !found {
+			if len(values) == 0 {
+				return "xpath: " + a.raw + " (no match)"
+			}
+			return "xpath: " + a.raw + " (value differs)"
+		}
+		if a.op == "!=" && found {
+			return "xpath: " + a.raw + " (value equal)"
+		}
+	}
+	return ""
+}
+
+// checkXPathAssertions 依次检查所有 -xpath 断言，返回第一条不满足的原因
+func checkXPathAssertions(body []byte) string {
+	doc, err := parseXMLDocument(body)
+	if err != nil {
+		return "xpath: invalid XML"
+	}
+	for _, a := range xpathAssertions {
+		if msg := a.check(doc); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
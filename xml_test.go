@@ -0,0 +1,172 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const xpathTestDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="urn:shop">
+  <soap:Header><m:Trace id="t-1"/></soap:Header>
+  <soap:Body>
+    <m:OrderResponse status="ok">
+      <m:Order id="1" currency="EUR">
+        <m:Sku>A-1</m:Sku>
+        <m:Price>10.00</m:Price>
+      </m:Order>
+      <m:Order id="2" currency="USD">
+        <m:Sku>B-2</m:Sku>
+        <m:Price>20.00</m:Price>
+        <m:Note>gift <m:Em>wrapped</m:Em> box</m:Note>
+      </m:Order>
+      <m:Order id="3" currency="EUR" label="a]b">
+        <m:Sku>C-3</m:Sku>
+        <m:Order id="3.1"><m:Sku>C-3-1</m:Sku></m:Order>
+      </m:Order>
+      <m:Total>30.00</m:Total>
+    </m:OrderResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestEvaluateXPath(t *testing.T) {
+	doc, err := parseXMLDocument([]byte(xpathTestDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		// child 步骤，命名空间前缀在匹配时忽略
+		{"/soap:Envelope/soap:Body/m:OrderResponse/m:Total", []string{"30.00"}},
+		{"/Envelope/Body/OrderResponse/Total", []string{"30.00"}},
+		{"/Envelope/Body/OrderResponse/Order/Sku", []string{"A-1", "B-2", "C-3"}},
+		{"/Envelope/*/OrderResponse/Total", []string{"30.00"}},
+		{"/Envelope/Body/OrderResponse/Order[1]/*", []string{"A-1", "10.00"}},
+		{"/Envelope/*/OrderResponse/*/@id", []string{"1", "2", "3"}},
+		// descendant 步骤
+		{"//Total", []string{"30.00"}},
+		{"//Order/Sku", []string{"A-1", "B-2", "C-3", "C-3-1"}},
+		{"/Envelope//Sku", []string{"A-1", "B-2", "C-3", "C-3-1"}},
+		{"//OrderResponse//Order//Sku", []string{"A-1", "B-2", "C-3", "C-3-1"}},
+		{"//Note", []string{"gift wrapped box"}},
+		// 属性
+		{"//OrderResponse/@status", []string{"ok"}},
+		{"//Order/@id", []string{"1", "2", "3", "3.1"}},
+		{"//@currency", []string{"EUR", "USD", "EUR"}},
+		{"//Trace/@id", []string{"t-1"}},
+		{"//Order/@missing", nil},
+		// text()
+		{"//Order/Sku/text()", []string{"A-1", "B-2", "C-3", "C-3-1"}},
+		{"//Note/text()", []string{"gift", "box"}},
+		// 位置谓词按每个父元素计数
+		{"//Order[1]/Sku", []string{"A-1", "C-3-1"}},
+		{"/Envelope/Body/OrderResponse/Order[2]/Sku", []string{"B-2"}},
+		{"/Envelope/Body/OrderResponse/Order[4]", nil},
+		// 属性与子元素谓词
+		{"//Order[@currency='EUR']/Sku", []string{"A-1", "C-3"}},
+		{`//Order[@currency="USD"]/Price`, []string{"20.00"}},
+		{"//Order[Sku='B-2']/@id", []string{"2"}},
+		{"//Order[m:Sku='C-3']/@id", []string{"3"}},
+		{"//Order[@currency='EUR'][2]/@id", []string{"3"}},
+		{"//Order[@label='a]b']/@id", []string{"3"}},
+		{"//Order[@currency='GBP']", nil},
+		// 不匹配的路径
+		{"/Body", nil},
+		{"/Envelope/Order", nil},
+		{"//Missing", nil},
+		{"//Missing/@id", nil},
+		{"//Missing/text()", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			steps, err := parseXPath(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := evaluateXPath(doc, steps); (len(got) > 0 || len(tt.want) > 0) && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXPathAssertion(t *testing.T) {
+	doc, err := parseXMLDocument([]byte(xpathTestDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"//Total", ""},
+		{"//Fault", "xpath: //Fault (no match)"},
+		{"!//soap:Fault", ""},
+		{"!//Total", "xpath: !//Total (matched)"},
+		{"//Total=30.00", ""},
+		{"//Total='30.00'", ""},
+		{"//Total = \"30.00\"", ""},
+		{"//Total=31.00", "xpath: //Total=31.00 (value differs)"},
+		{"//Fault=1", "xpath: //Fault=1 (no match)"},
+		{"//Order/Sku=C-3", ""},
+		{"//Order/Sku!=Z-9", ""},
+		{"//Order/Sku!=B-2", "xpath: //Order/Sku!=B-2 (value equal)"},
+		{"//Order[@id='2']/Price=20.00", ""},
+		{"//OrderResponse/@status!=error", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			a, err := parseXPathAssertion(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.check(doc); got != tt.want {
+				t.Errorf("check = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseXPathErrors(t *testing.T) {
+	for _, raw := range []string{
+		"Total",
+		"/",
+		"//",
+		"/a/",
+		"/a/@id/b",
+		"/a/text()/b",
+		"/a[0]",
+		"/a[last()]",
+		"/a[@id",
+		"/a[@id!='1']",
+		"/a[text()='x']",
+		"/a[.='x']",
+		"!/a=1",
+	} {
+		if _, err := parseXPathAssertion(raw); err == nil {
+			t.Errorf("expected an error for %q", raw)
+		}
+	}
+}
+
+func TestCheckXPathAssertions(t *testing.T) {
+	saved := xpathAssertions
+	defer func() { xpathAssertions = saved }()
+	xpathAssertions = nil
+	for _, raw := range []string{"//Total=30.00", "!//Fault"} {
+		if err := xpathAssertions.Set(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := checkXPathAssertions([]byte(xpathTestDocument)); got != "" {
+		t.Errorf("valid document: %q", got)
+	}
+	fault := `<Envelope><Body><Fault><faultstring>boom</faultstring></Fault><Total>30.00</Total></Body></Envelope>`
+	if got := checkXPathAssertions([]byte(fault)); got != "xpath: !//Fault (matched)" {
+		t.Errorf("fault document: %q", got)
+	}
+	if got := checkXPathAssertions([]byte("not xml")); got != "xpath: invalid XML" {
+		t.Errorf("invalid document: %q", got)
+	}
+}